@@ -0,0 +1,259 @@
+package cli
+
+import (
+	"anyzzapp/internal/app"
+	"anyzzapp/internal/config"
+	"anyzzapp/pkg/domain"
+	"bufio"
+	"context"
+	"flag"
+	"fmt"
+	"io"
+	"os"
+	"strconv"
+	"strings"
+	"sync"
+	"time"
+
+	"github.com/rs/zerolog"
+)
+
+const (
+	// chatCommandPrefix starts the commands of the chat, "/" being the prefix of the in-chat commands of the bot
+	chatCommandPrefix = ":"
+	// defaultChatSender is the wa_id the messages are sent from, a fictional US number
+	defaultChatSender = "15555550100"
+	// defaultChatTenant is the phone number ID receiving the messages when the configuration has no tenants
+	defaultChatTenant = "chat"
+	// chatBusinessAccountID is the WhatsApp Business Account ID of the webhooks of the chat
+	chatBusinessAccountID = "anyzzapp-chat"
+)
+
+// chatHelp lists the commands of the chat
+const chatHelp = `Type a message to send it to the bot as the contact. Commands:
+  :from <wa_id>          write as another contact
+  :tenant <id>           write to another phone number ID
+  :persona [<name>|none] show the personas or answer with another one, none for no persona
+  :help                  show this help
+  :quit                  leave, as does Ctrl+D
+`
+
+// chat runs the use case in-process and lets the user talk to the bot from the terminal as a contact would
+func chat(args []string, stdout, stderr io.Writer) int {
+	return chatWith(args, os.Stdin, stdout, stderr)
+}
+
+// chatWith is chat reading the messages from stdin
+func chatWith(args []string, stdin io.Reader, stdout, stderr io.Writer) int {
+	flags := flag.NewFlagSet("chat", flag.ContinueOnError)
+	flags.SetOutput(stderr)
+	from := flags.String("from", defaultChatSender, "wa_id of the contact writing to the bot")
+	tenant := flags.String("tenant", "", "phone number ID receiving the messages, the first tenant of the configuration when empty")
+	persona := flags.String("persona", "", "name of the persona answering instead of the one of the tenant, none for no persona")
+	configPath := flags.String("file", "", "configuration file, CONFIG_FILE when empty")
+	if err := flags.Parse(args); err != nil {
+		return 2
+	}
+
+	// The WhatsApp API is not used, its settings may be missing
+	cfg, err := loadPartialConfig(*configPath)
+	if err != nil {
+		fmt.Fprintln(stderr, err)
+		return 1
+	}
+	// The logs of every message would drown the conversation
+	if !strings.EqualFold(cfg.LogLevel, "debug") {
+		zerolog.SetGlobalLevel(zerolog.WarnLevel)
+	}
+	phoneNumberID, ok := tenantOrDefault(cfg, *tenant)
+	if !ok {
+		phoneNumberID = defaultChatTenant
+	}
+
+	s := &chatSession{
+		cfg:           cfg,
+		out:           stdout,
+		from:          *from,
+		phoneNumberID: phoneNumberID,
+		personas:      &personaOverride{},
+	}
+	if *persona != "" && !s.setPersona(*persona) {
+		fmt.Fprintf(stderr, "unknown persona %q\n", *persona)
+		return 2
+	}
+	a, err := app.New(cfg, *configPath, config.StorageMemory,
+		app.WithWhatsAppRepository(&terminalWhatsApp{out: stdout}),
+		app.WithSettings(s.personas.wrap))
+	if err != nil {
+		fmt.Fprintln(stderr, err)
+		return 1
+	}
+	defer a.Close(context.Background())
+	s.useCase = a.WhatsAppUseCase
+
+	fmt.Fprint(stdout, chatHelp)
+	s.run(stdin)
+	return 0
+}
+
+// chatSession is the state of a chat with the bot
+type chatSession struct {
+	cfg           config.Config
+	out           io.Writer
+	useCase       domain.WhatsAppUseCaseInterface
+	personas      *personaOverride
+	from          string
+	phoneNumberID string
+	nextID        int
+}
+
+// run reads the lines of the user until :quit or the end of the input
+func (s *chatSession) run(stdin io.Reader) {
+	scanner := bufio.NewScanner(stdin)
+	for {
+		fmt.Fprintf(s.out, "%s> ", s.from)
+		if !scanner.Scan() {
+			fmt.Fprintln(s.out)
+			return
+		}
+		line := strings.TrimSpace(scanner.Text())
+		switch {
+		case line == "":
+		case strings.HasPrefix(line, chatCommandPrefix):
+			if !s.command(strings.Fields(strings.TrimPrefix(line, chatCommandPrefix))) {
+				return
+			}
+		default:
+			s.say(line)
+		}
+	}
+}
+
+// command runs a command of the chat and reports whether the chat goes on
+func (s *chatSession) command(fields []string) bool {
+	if len(fields) == 0 {
+		fmt.Fprint(s.out, chatHelp)
+		return true
+	}
+	name, args := fields[0], fields[1:]
+	switch {
+	case name == "quit" || name == "q":
+		return false
+	case name == "from" && len(args) == 1:
+		s.from = args[0]
+		fmt.Fprintf(s.out, "writing as %s\n", s.from)
+	case name == "tenant" && len(args) == 1:
+		s.phoneNumberID = args[0]
+		fmt.Fprintf(s.out, "writing to phone number ID %s\n", s.phoneNumberID)
+	case name == "persona" && len(args) == 0:
+		s.listPersonas()
+	case name == "persona" && len(args) == 1:
+		if !s.setPersona(args[0]) {
+			fmt.Fprintf(s.out, "unknown persona %q\n", args[0])
+			s.listPersonas()
+			break
+		}
+		fmt.Fprintf(s.out, "answering as %s\n", args[0])
+	default:
+		fmt.Fprint(s.out, chatHelp)
+	}
+	return true
+}
+
+// say sends the text to the bot as a message of the contact, the replies are printed as they are sent
+func (s *chatSession) say(text string) {
+	s.nextID++
+	contact := domain.WebhookContact{WaID: s.from, Profile: domain.WebhookProfile{Name: "Chat " + s.from}}
+	id := "wamid.chat." + strconv.Itoa(s.nextID)
+	webhook := domain.NewTextWebhook(chatBusinessAccountID, s.phoneNumberID, contact, id, text, time.Now())
+	if err := s.useCase.ProcessIncomingWebhook(context.Background(), &webhook); err != nil {
+		fmt.Fprintf(s.out, "error: %v\n", err)
+	}
+}
+
+// setPersona makes the named persona answer, none for no persona, and reports whether it exists
+func (s *chatSession) setPersona(name string) bool {
+	if name == "none" {
+		s.personas.set("")
+		return true
+	}
+	for _, persona := range s.cfg.Personas {
+		if persona.Name == name {
+			s.personas.set(persona.Prompt)
+			return true
+		}
+	}
+	return false
+}
+
+// listPersonas prints the personas of the configuration
+func (s *chatSession) listPersonas() {
+	if len(s.cfg.Personas) == 0 {
+		fmt.Fprintln(s.out, "the configuration has no personas")
+		return
+	}
+	fmt.Fprintln(s.out, "personas:")
+	for _, persona := range s.cfg.Personas {
+		fmt.Fprintf(s.out, "  %s\n", persona.Name)
+	}
+}
+
+// personaOverride replaces the persona of every tenant once it is set
+type personaOverride struct {
+	mu     sync.Mutex
+	prompt *string
+}
+
+// set replaces the persona prompt, none when empty
+func (p *personaOverride) set(prompt string) {
+	p.mu.Lock()
+	defer p.mu.Unlock()
+	p.prompt = &prompt
+}
+
+// wrap returns the settings of the provider with the persona replaced
+func (p *personaOverride) wrap(next domain.SettingsProvider) domain.SettingsProvider {
+	return overriddenSettings{next: next, override: p}
+}
+
+// overriddenSettings are the settings of a provider with the persona of an override
+type overriddenSettings struct {
+	next     domain.SettingsProvider
+	override *personaOverride
+}
+
+// TenantSettings implements domain.SettingsProvider
+func (s overriddenSettings) TenantSettings(phoneNumberID string) domain.TenantSettings {
+	settings := s.next.TenantSettings(phoneNumberID)
+	s.override.mu.Lock()
+	defer s.override.mu.Unlock()
+	if s.override.prompt != nil {
+		settings.Persona = *s.override.prompt
+	}
+	return settings
+}
+
+// terminalWhatsApp prints the messages the bot sends instead of sending them
+type terminalWhatsApp struct {
+	out    io.Writer
+	mu     sync.Mutex
+	nextID int
+}
+
+// SendMessage implements domain.WhatsAppRepository
+func (t *terminalWhatsApp) SendMessage(ctx context.Context, message domain.Message) (*domain.SendMessageResponse, error) {
+	t.mu.Lock()
+	defer t.mu.Unlock()
+	t.nextID++
+	content := message.Content
+	if message.MessageType == "template" {
+		content = "[template " + content + "]"
+	}
+	fmt.Fprintf(t.out, "bot: %s\n", content)
+	return &domain.SendMessageResponse{MessageID: "wamid.chat.out." + strconv.Itoa(t.nextID), Status: "sent"}, nil
+}
+
+// MarkAsRead implements domain.WhatsAppRepository, the read receipts are not shown
+func (t *terminalWhatsApp) MarkAsRead(ctx context.Context, phoneNumberID, messageID string) error {
+	return nil
+}
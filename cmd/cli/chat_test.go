@@ -0,0 +1,62 @@
+package cli
+
+import (
+	"anyzzapp/internal/fakellm"
+	"bytes"
+	"fmt"
+	"os"
+	"path/filepath"
+	"strings"
+	"testing"
+
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+func TestChat(t *testing.T) {
+	llm := fakellm.New(t)
+	llm.ReplyNext("Hi, how can I help?", "Ahoy!", "Bye!")
+	// The WhatsApp API settings are not needed
+	t.Setenv("WHATSAPP_API_KEY", "")
+	configPath := filepath.Join(t.TempDir(), "config.yaml")
+	content := fmt.Sprintf("llm:\n  url: %s\npersonas:\n  - name: pirate\n    prompt: Talk like a pirate.\n"+
+		"tenants:\n  - phone_number_id: \"123\"\n", llm.URL)
+	require.NoError(t, os.WriteFile(configPath, []byte(content), 0o600))
+	input := strings.Join([]string{
+		"Hello",
+		":persona pirate",
+		":from 5491112345678",
+		"Who are you?",
+		":tenant 999",
+		":persona none",
+		"Bye",
+		":quit",
+		"never sent",
+	}, "\n")
+
+	var stdout, stderr bytes.Buffer
+	code := chatWith([]string{"-file", configPath}, strings.NewReader(input), &stdout, &stderr)
+
+	assert.Equal(t, 0, code, stderr.String())
+	output := stdout.String()
+	assert.Contains(t, output, defaultChatSender+"> bot: Hi, how can I help?\n")
+	assert.Contains(t, output, "answering as pirate\n")
+	assert.Contains(t, output, "writing as 5491112345678\n")
+	assert.Contains(t, output, "5491112345678> bot: Ahoy!\n")
+	assert.Contains(t, output, "writing to phone number ID 999\n")
+	assert.Contains(t, output, "bot: Bye!\n")
+
+	prompts := llm.Prompts()
+	require.Len(t, prompts, 3)
+	assert.NotContains(t, prompts[0], "pirate")
+	assert.Contains(t, prompts[1], "Talk like a pirate.")
+	assert.NotContains(t, prompts[2], "pirate")
+}
+
+func TestChat_UnknownPersona(t *testing.T) {
+	var stdout, stderr bytes.Buffer
+	code := chatWith([]string{"-persona", "missing"}, strings.NewReader(""), &stdout, &stderr)
+
+	assert.Equal(t, 2, code)
+	assert.Equal(t, "unknown persona \"missing\"\n", stderr.String())
+}
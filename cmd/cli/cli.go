@@ -0,0 +1,60 @@
+// Package cli implements the subcommands of the anyzzapp binary, serving the API being the default one
+package cli
+
+import (
+	"fmt"
+	"io"
+	"strings"
+)
+
+// usage is printed when the subcommand is unknown
+const usage = `Usage:
+  anyzzapp [serve] [-file <path>]       start the server
+  anyzzapp config check [-file <path>]  validate the configuration and exit
+  anyzzapp send -to <number> -text <text>|-template <name> [-language <code>] [-tenant <id>] [-transactional]
+                                        send a message as the server does
+  anyzzapp webhook simulate -from <wa_id> -text <text> [-name <name>] [-tenant <id>] [-url <url>]
+                                        post the webhook of a message of a contact to the server
+  anyzzapp webhook verify [-url <url>] [-token <token>]
+                                        run the verification request of Meta against the webhook
+  anyzzapp chat [-from <wa_id>] [-tenant <id>] [-persona <name>]
+                                        talk to the bot from the terminal, with the configured LLM
+  anyzzapp export [-format jsonl|csv|transcript] [-tenant <id>] [-since <date>] [-until <date>]
+                  [-mask wa_id,content,media] [-out <path>] [-db <path>]
+                                        export the stored conversations
+  anyzzapp replay [-target <url>] [-speed <factor>] [-file <path>] <file|dir>...
+                                        resend recorded webhooks to an instance or into the use case
+`
+
+// Run runs the subcommand named in args, the server without one, and returns the process exit code
+func Run(args []string, stdout, stderr io.Writer) int {
+	if len(args) == 0 || strings.HasPrefix(args[0], "-") {
+		return serve(args, stdout, stderr)
+	}
+	if args[0] == "serve" {
+		return serve(args[1:], stdout, stderr)
+	}
+	if len(args) >= 2 && args[0] == "config" && args[1] == "check" {
+		return configCheck(args[2:], stdout, stderr)
+	}
+	if args[0] == "send" {
+		return send(args[1:], stdout, stderr)
+	}
+	if len(args) >= 2 && args[0] == "webhook" && args[1] == "simulate" {
+		return webhookSimulate(args[2:], stdout, stderr)
+	}
+	if len(args) >= 2 && args[0] == "webhook" && args[1] == "verify" {
+		return webhookVerify(args[2:], stdout, stderr)
+	}
+	if args[0] == "chat" {
+		return chat(args[1:], stdout, stderr)
+	}
+	if args[0] == "export" {
+		return export(args[1:], stdout, stderr)
+	}
+	if args[0] == "replay" {
+		return replay(args[1:], stdout, stderr)
+	}
+	fmt.Fprint(stderr, usage)
+	return 2
+}
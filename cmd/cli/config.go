@@ -0,0 +1,39 @@
+package cli
+
+import (
+	"anyzzapp/internal/config"
+	"errors"
+	"flag"
+	"fmt"
+	"io"
+)
+
+// configCheck loads and validates the configuration exactly as the server does at startup
+func configCheck(args []string, stdout, stderr io.Writer) int {
+	flags := flag.NewFlagSet("config check", flag.ContinueOnError)
+	flags.SetOutput(stderr)
+	path := flags.String("file", "", "configuration file, CONFIG_FILE when empty")
+	if err := flags.Parse(args); err != nil {
+		return 2
+	}
+
+	if _, err := config.LoadFile(*path); err != nil {
+		printConfigError(stderr, err)
+		return 1
+	}
+	fmt.Fprintln(stdout, "configuration is valid")
+	return 0
+}
+
+// printConfigError writes why the configuration could not be loaded, one line per problem
+func printConfigError(stderr io.Writer, err error) {
+	var invalid *config.ValidationError
+	if errors.As(err, &invalid) {
+		fmt.Fprintf(stderr, "configuration has %d problem(s):\n", len(invalid.Problems))
+		for _, problem := range invalid.Problems {
+			fmt.Fprintf(stderr, "  - %s\n", problem)
+		}
+		return
+	}
+	fmt.Fprintln(stderr, err)
+}
@@ -0,0 +1,45 @@
+package cli
+
+import (
+	"bytes"
+	"os"
+	"path/filepath"
+	"testing"
+
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+func TestRun_ConfigCheck_Valid(t *testing.T) {
+	path := filepath.Join(t.TempDir(), "config.yaml")
+	content := "whatsapp:\n  api_key: key\n  webhook_verify_token: token\n"
+	require.NoError(t, os.WriteFile(path, []byte(content), 0o600))
+
+	var stdout, stderr bytes.Buffer
+	code := Run([]string{"config", "check", "-file", path}, &stdout, &stderr)
+
+	assert.Equal(t, 0, code)
+	assert.Equal(t, "configuration is valid\n", stdout.String())
+}
+
+func TestRun_ConfigCheck_Invalid(t *testing.T) {
+	path := filepath.Join(t.TempDir(), "config.yaml")
+	require.NoError(t, os.WriteFile(path, []byte("server:\n  port: \"0\"\n"), 0o600))
+	t.Setenv("WHATSAPP_API_KEY", "")
+	t.Setenv("WEBHOOK_VERIFY_TOKEN", "")
+
+	var stdout, stderr bytes.Buffer
+	code := Run([]string{"config", "check", "-file", path}, &stdout, &stderr)
+
+	assert.Equal(t, 1, code)
+	assert.Contains(t, stderr.String(), "configuration has 3 problem(s)")
+	assert.Contains(t, stderr.String(), "WhatsApp API key is required")
+	assert.Contains(t, stderr.String(), `server port "0"`)
+}
+
+func TestRun_UnknownCommand(t *testing.T) {
+	var stdout, stderr bytes.Buffer
+
+	assert.Equal(t, 2, Run([]string{"bogus"}, &stdout, &stderr))
+	assert.Contains(t, stderr.String(), "Usage:")
+}
@@ -0,0 +1,105 @@
+package cli
+
+import (
+	"anyzzapp/internal/config"
+	"anyzzapp/internal/infrastructure/boltdb"
+	"anyzzapp/pkg/application"
+	"anyzzapp/pkg/domain"
+	"bufio"
+	"context"
+	"flag"
+	"fmt"
+	"io"
+	"os"
+	"strings"
+	"time"
+)
+
+// export writes the stored conversations to a file or stdout, reading the database of the configuration
+func export(args []string, stdout, stderr io.Writer) int {
+	flags := flag.NewFlagSet("export", flag.ContinueOnError)
+	flags.SetOutput(stderr)
+	format := flags.String("format", domain.ExportFormatJSONL, "jsonl, csv or transcript")
+	tenant := flags.String("tenant", "", "phone number ID of the exported conversations, all when empty")
+	since := flags.String("since", "", "first day (YYYY-MM-DD) or time (RFC 3339) exported")
+	until := flags.String("until", "", "day or time the export stops at, excluded")
+	mask := flags.String("mask", "", "comma-separated fields to drop: "+strings.Join(domain.ExportMaskFields, ", "))
+	out := flags.String("out", "", "output file, stdout when empty")
+	dbPath := flags.String("db", "", "database file, the storage path of the configuration when empty")
+	configPath := flags.String("file", "", "configuration file, CONFIG_FILE when empty")
+	if err := flags.Parse(args); err != nil {
+		return 2
+	}
+
+	query := domain.ExportQuery{PhoneNumberID: *tenant, Format: *format}
+	var err error
+	if query.Since, err = parseTime(*since); err != nil {
+		fmt.Fprintf(stderr, "invalid -since: %v\n", err)
+		return 2
+	}
+	if query.Until, err = parseTime(*until); err != nil {
+		fmt.Fprintf(stderr, "invalid -until: %v\n", err)
+		return 2
+	}
+	if *mask != "" {
+		query.Mask = strings.Split(*mask, ",")
+	}
+	if err := query.Validate(); err != nil {
+		fmt.Fprintln(stderr, err)
+		return 2
+	}
+
+	path := *dbPath
+	if path == "" {
+		// The export only needs the storage path, the rest of the configuration may be incomplete
+		cfg, err := loadPartialConfig(*configPath)
+		if err != nil {
+			fmt.Fprintln(stderr, err)
+			return 1
+		}
+		path = cfg.StoragePath
+	}
+	if path == config.StorageMemory {
+		fmt.Fprintln(stderr, "messages are kept in memory, use GET /api/v1/admin/export on the running server")
+		return 1
+	}
+	db, err := boltdb.OpenReadOnly(path)
+	if err != nil {
+		fmt.Fprintf(stderr, "%v\nwhile the server is running, use GET /api/v1/admin/export instead\n", err)
+		return 1
+	}
+	defer db.Close()
+
+	w := stdout
+	if *out != "" {
+		file, err := os.Create(*out)
+		if err != nil {
+			fmt.Fprintln(stderr, err)
+			return 1
+		}
+		defer file.Close()
+		w = file
+	}
+	buffered := bufio.NewWriter(w)
+	history := application.NewHistoryUseCase(boltdb.NewMessageRepository(db), nil)
+	if err := history.Export(context.Background(), query, buffered); err != nil {
+		fmt.Fprintln(stderr, err)
+		return 1
+	}
+	if err := buffered.Flush(); err != nil {
+		fmt.Fprintln(stderr, err)
+		return 1
+	}
+	return 0
+}
+
+// parseTime parses an RFC 3339 time or a YYYY-MM-DD date, zero when empty
+func parseTime(value string) (time.Time, error) {
+	if value == "" {
+		return time.Time{}, nil
+	}
+	if t, err := time.Parse(time.RFC3339, value); err == nil {
+		return t, nil
+	}
+	return time.Parse(time.DateOnly, value)
+}
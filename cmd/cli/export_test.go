@@ -0,0 +1,66 @@
+package cli
+
+import (
+	"anyzzapp/internal/infrastructure/boltdb"
+	"anyzzapp/pkg/domain"
+	"bytes"
+	"context"
+	"os"
+	"path/filepath"
+	"testing"
+	"time"
+
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+// storeMessages creates a database holding the messages
+func storeMessages(t *testing.T, messages ...domain.StoredMessage) string {
+	t.Helper()
+	path := filepath.Join(t.TempDir(), "anyzzapp.db")
+	db, err := boltdb.Open(path)
+	require.NoError(t, err)
+	repo := boltdb.NewMessageRepository(db)
+	for i := range messages {
+		require.NoError(t, repo.Save(context.Background(), &messages[i]))
+	}
+	require.NoError(t, db.Close())
+	return path
+}
+
+func TestRun_Export(t *testing.T) {
+	path := storeMessages(t,
+		domain.StoredMessage{ID: "m1", PhoneNumberID: "123", WaID: "456", Author: "contact", Type: "text",
+			Content: "Hello", Timestamp: time.Date(2024, 1, 1, 10, 0, 0, 0, time.UTC)},
+		domain.StoredMessage{ID: "m2", PhoneNumberID: "999", WaID: "456", Author: "contact", Type: "text",
+			Content: "Other tenant", Timestamp: time.Date(2024, 1, 1, 10, 0, 0, 0, time.UTC)},
+	)
+	out := filepath.Join(t.TempDir(), "export.txt")
+
+	var stdout, stderr bytes.Buffer
+	code := Run([]string{"export", "-db", path, "-format", "transcript", "-tenant", "123", "-out", out}, &stdout, &stderr)
+
+	assert.Equal(t, 0, code, stderr.String())
+	content, err := os.ReadFile(out)
+	require.NoError(t, err)
+	assert.Equal(t, "== Conversation of 123 with +456 ==\n2024-01-01 10:00:00 - +456: Hello\n", string(content))
+}
+
+func TestRun_Export_Stdout(t *testing.T) {
+	path := storeMessages(t, domain.StoredMessage{ID: "m1", PhoneNumberID: "123", WaID: "456", Content: "Hello", Timestamp: time.Now()})
+
+	var stdout, stderr bytes.Buffer
+	code := Run([]string{"export", "-db", path, "-mask", "content"}, &stdout, &stderr)
+
+	assert.Equal(t, 0, code, stderr.String())
+	assert.Contains(t, stdout.String(), `"id":"m1"`)
+	assert.NotContains(t, stdout.String(), "Hello")
+}
+
+func TestRun_Export_Invalid(t *testing.T) {
+	var stdout, stderr bytes.Buffer
+
+	assert.Equal(t, 2, Run([]string{"export", "-format", "xml"}, &stdout, &stderr))
+	assert.Equal(t, 2, Run([]string{"export", "-since", "yesterday"}, &stdout, &stderr))
+	assert.Equal(t, 1, Run([]string{"export", "-db", filepath.Join(t.TempDir(), "missing.db")}, &stdout, &stderr))
+}
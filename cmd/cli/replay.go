@@ -0,0 +1,172 @@
+package cli
+
+import (
+	"anyzzapp/internal/app"
+	"anyzzapp/internal/config"
+	"anyzzapp/internal/infrastructure/recording"
+	"anyzzapp/pkg/domain"
+	"context"
+	"encoding/json"
+	"flag"
+	"fmt"
+	"io"
+	"net/http"
+	"os"
+	"sort"
+	"strings"
+	"time"
+)
+
+// replayTimeout bounds the delivery of each webhook
+const replayTimeout = 2 * time.Minute
+
+// replay resends recorded webhooks to a running instance, or straight into a use case built from the configuration
+func replay(args []string, stdout, stderr io.Writer) int {
+	flags := flag.NewFlagSet("replay", flag.ContinueOnError)
+	flags.SetOutput(stderr)
+	target := flags.String("target", "", "base URL of the instance receiving the webhooks, such as http://localhost:8080; "+
+		"when empty they are processed in-process with the WhatsApp API and LLM of the configuration")
+	speed := flags.Float64("speed", 1, "speed factor of the replay: 1 keeps the recorded pace, 10 is ten times faster, 0 doesn't wait")
+	configPath := flags.String("file", "", "configuration file, CONFIG_FILE when empty")
+	if err := flags.Parse(args); err != nil {
+		return 2
+	}
+	if flags.NArg() == 0 {
+		fmt.Fprintln(stderr, "at least one recording file or directory is required")
+		return 2
+	}
+	if *speed < 0 {
+		fmt.Fprintln(stderr, "invalid -speed: it cannot be negative")
+		return 2
+	}
+
+	recordings, err := readRecordings(flags.Args())
+	if err != nil {
+		fmt.Fprintln(stderr, err)
+		return 1
+	}
+
+	r := &replayer{speed: *speed, wait: time.Sleep}
+	if *target != "" {
+		r.deliver = postWebhook(&http.Client{Timeout: replayTimeout}, strings.TrimRight(*target, "/"))
+	} else {
+		cfg, err := config.LoadFile(*configPath)
+		if err != nil {
+			printConfigError(stderr, err)
+			return 1
+		}
+		// Conversations and messages are kept in memory so the database of a running server is left alone
+		a, err := app.New(cfg, *configPath, config.StorageMemory)
+		if err != nil {
+			fmt.Fprintln(stderr, err)
+			return 1
+		}
+		defer a.Close(context.Background())
+		r.deliver = processWebhook(a.WhatsAppUseCase)
+	}
+
+	failed := r.run(recordings, stderr)
+	fmt.Fprintf(stdout, "replayed %d webhook(s), %d failed\n", len(recordings), failed)
+	if failed > 0 {
+		return 1
+	}
+	return 0
+}
+
+// readRecordings reads the recordings of the files and of the recording files in the directories, oldest first
+func readRecordings(paths []string) ([]domain.WebhookRecording, error) {
+	var files []string
+	for _, path := range paths {
+		info, err := os.Stat(path)
+		if err != nil {
+			return nil, err
+		}
+		if !info.IsDir() {
+			files = append(files, path)
+			continue
+		}
+		found, err := recording.Files(path)
+		if err != nil {
+			return nil, err
+		}
+		files = append(files, found...)
+	}
+
+	var recordings []domain.WebhookRecording
+	for _, file := range files {
+		read, err := recording.ReadFile(file)
+		if err != nil {
+			return nil, err
+		}
+		recordings = append(recordings, read...)
+	}
+	sort.SliceStable(recordings, func(i, j int) bool {
+		return recordings[i].At.Before(recordings[j].At)
+	})
+	return recordings, nil
+}
+
+// replayer delivers recordings keeping the pace they were received at, scaled by speed
+type replayer struct {
+	speed   float64
+	wait    func(time.Duration)
+	deliver func(ctx context.Context, recording domain.WebhookRecording) error
+}
+
+// run delivers the recordings in order and returns how many failed, failures don't stop the replay
+func (r *replayer) run(recordings []domain.WebhookRecording, stderr io.Writer) int {
+	failed := 0
+	for i, recorded := range recordings {
+		if i > 0 && r.speed > 0 {
+			if gap := recorded.At.Sub(recordings[i-1].At); gap > 0 {
+				r.wait(time.Duration(float64(gap) / r.speed))
+			}
+		}
+		ctx, cancel := context.WithTimeout(context.Background(), replayTimeout)
+		err := r.deliver(ctx, recorded)
+		cancel()
+		if err != nil {
+			failed++
+			fmt.Fprintf(stderr, "webhook recorded at %s: %v\n", recorded.At.Format(time.RFC3339Nano), err)
+		}
+	}
+	return failed
+}
+
+// postWebhook delivers the recordings to the instance at baseURL, with their recorded headers
+func postWebhook(httpClient *http.Client, baseURL string) func(context.Context, domain.WebhookRecording) error {
+	return func(ctx context.Context, recorded domain.WebhookRecording) error {
+		req, err := http.NewRequestWithContext(ctx, recorded.Method, baseURL+recorded.Path, strings.NewReader(recorded.Body))
+		if err != nil {
+			return err
+		}
+		for name, values := range recorded.Header {
+			// The length is set from the body, which is masked when PII was masked
+			if http.CanonicalHeaderKey(name) == "Content-Length" {
+				continue
+			}
+			req.Header[name] = values
+		}
+		resp, err := httpClient.Do(req)
+		if err != nil {
+			return err
+		}
+		defer resp.Body.Close()
+		if resp.StatusCode < 200 || resp.StatusCode >= 300 {
+			body, _ := io.ReadAll(io.LimitReader(resp.Body, 512))
+			return fmt.Errorf("answered with status %d: %s", resp.StatusCode, strings.TrimSpace(string(body)))
+		}
+		return nil
+	}
+}
+
+// processWebhook hands the recordings straight to the use case
+func processWebhook(useCase domain.WhatsAppUseCaseInterface) func(context.Context, domain.WebhookRecording) error {
+	return func(ctx context.Context, recorded domain.WebhookRecording) error {
+		var webhook domain.WebhookRequest
+		if err := json.Unmarshal([]byte(recorded.Body), &webhook); err != nil {
+			return fmt.Errorf("invalid webhook: %w", err)
+		}
+		return useCase.ProcessIncomingWebhook(ctx, &webhook)
+	}
+}
@@ -0,0 +1,125 @@
+package cli
+
+import (
+	"anyzzapp/internal/fakegraph"
+	"anyzzapp/internal/fakellm"
+	"anyzzapp/internal/infrastructure/recording"
+	"anyzzapp/pkg/domain"
+	"bytes"
+	"context"
+	"encoding/json"
+	"fmt"
+	"io"
+	"net/http"
+	"net/http/httptest"
+	"sync"
+	"testing"
+	"time"
+
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+// recordWebhooks records the webhooks one second apart in a new directory
+func recordWebhooks(t *testing.T, webhooks ...domain.WebhookRequest) string {
+	t.Helper()
+	dir := t.TempDir()
+	recorder, err := recording.NewRecorder(dir, 1<<20, false)
+	require.NoError(t, err)
+	at := time.Date(2024, 1, 1, 10, 0, 0, 0, time.UTC)
+	for i, webhook := range webhooks {
+		body, err := json.Marshal(webhook)
+		require.NoError(t, err)
+		require.NoError(t, recorder.Record(context.Background(), domain.WebhookRecording{
+			At:     at.Add(time.Duration(i) * time.Second),
+			Method: "POST",
+			Path:   "/api/v1/whatsapp/webhook",
+			Header: http.Header{"Content-Type": {"application/json"}, "X-Hub-Signature-256": {fmt.Sprintf("sha256=%d", i)}},
+			Body:   string(body),
+		}))
+	}
+	require.NoError(t, recorder.Close())
+	return dir
+}
+
+func TestRun_Replay_Target(t *testing.T) {
+	dir := recordWebhooks(t,
+		fakegraph.TextMessage("123", "5491112345678", "wamid.1", "Hello"),
+		fakegraph.TextMessage("123", "5491112345678", "wamid.2", "Are you there?"))
+	var mu sync.Mutex
+	var received []*http.Request
+	var bodies []string
+	target := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		body, _ := io.ReadAll(r.Body)
+		mu.Lock()
+		received = append(received, r)
+		bodies = append(bodies, string(body))
+		mu.Unlock()
+	}))
+	defer target.Close()
+
+	var stdout, stderr bytes.Buffer
+	code := Run([]string{"replay", "-target", target.URL + "/", "-speed", "0", dir}, &stdout, &stderr)
+
+	assert.Equal(t, 0, code, stderr.String())
+	assert.Equal(t, "replayed 2 webhook(s), 0 failed\n", stdout.String())
+	require.Len(t, received, 2)
+	assert.Equal(t, "/api/v1/whatsapp/webhook", received[0].URL.Path)
+	assert.Equal(t, "sha256=0", received[0].Header.Get("X-Hub-Signature-256"))
+	assert.Contains(t, bodies[0], "wamid.1")
+	assert.Contains(t, bodies[1], "wamid.2")
+}
+
+func TestRun_Replay_TargetFailure(t *testing.T) {
+	dir := recordWebhooks(t, fakegraph.TextMessage("123", "5491112345678", "wamid.1", "Hello"))
+	target := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		http.Error(w, "boom", http.StatusInternalServerError)
+	}))
+	defer target.Close()
+
+	var stdout, stderr bytes.Buffer
+	code := Run([]string{"replay", "-target", target.URL, "-speed", "0", dir}, &stdout, &stderr)
+
+	assert.Equal(t, 1, code)
+	assert.Equal(t, "replayed 1 webhook(s), 1 failed\n", stdout.String())
+	assert.Contains(t, stderr.String(), "answered with status 500: boom")
+}
+
+func TestRun_Replay_UseCase(t *testing.T) {
+	graph := fakegraph.New(t)
+	llm := fakellm.New(t)
+	llm.ReplyNext("Hi from the replay")
+	configPath := writeConfig(t, graph.URL, llm.URL, "memory")
+	files, err := recording.Files(recordWebhooks(t, fakegraph.TextMessage("123", "5491112345678", "wamid.1", "Hello")))
+	require.NoError(t, err)
+
+	var stdout, stderr bytes.Buffer
+	code := Run([]string{"replay", "-file", configPath, "-speed", "0", files[0]}, &stdout, &stderr)
+
+	assert.Equal(t, 0, code, stderr.String())
+	assert.Equal(t, []string{"Hello"}, llm.Prompts())
+	if sent := graph.Sent(); assert.Len(t, sent, 1) {
+		assert.Equal(t, "Hi from the replay", sent[0].Text)
+	}
+}
+
+func TestReplayer_KeepsThePace(t *testing.T) {
+	at := time.Date(2024, 1, 1, 10, 0, 0, 0, time.UTC)
+	recordings := []domain.WebhookRecording{{At: at}, {At: at.Add(4 * time.Second)}, {At: at.Add(5 * time.Second)}}
+	var waits []time.Duration
+	r := &replayer{
+		speed:   2,
+		wait:    func(d time.Duration) { waits = append(waits, d) },
+		deliver: func(context.Context, domain.WebhookRecording) error { return nil },
+	}
+
+	assert.Equal(t, 0, r.run(recordings, io.Discard))
+	assert.Equal(t, []time.Duration{2 * time.Second, 500 * time.Millisecond}, waits)
+}
+
+func TestRun_Replay_Usage(t *testing.T) {
+	var stdout, stderr bytes.Buffer
+	assert.Equal(t, 2, Run([]string{"replay"}, &stdout, &stderr))
+	assert.Equal(t, 2, Run([]string{"replay", "-speed", "-1", "webhooks.jsonl"}, &stdout, &stderr))
+	assert.Equal(t, 1, Run([]string{"replay", "missing.jsonl"}, &stdout, &stderr))
+}
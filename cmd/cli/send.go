@@ -0,0 +1,100 @@
+package cli
+
+import (
+	"anyzzapp/internal/app"
+	"anyzzapp/internal/config"
+	"anyzzapp/pkg/domain"
+	"anyzzapp/pkg/domain/phone"
+	"context"
+	"errors"
+	"flag"
+	"fmt"
+	"io"
+	"time"
+)
+
+// sendTimeout bounds the delivery of the message to the WhatsApp API
+const sendTimeout = 30 * time.Second
+
+// send sends a text or template message with the use case of the server, recording it in its database
+func send(args []string, stdout, stderr io.Writer) int {
+	flags := flag.NewFlagSet("send", flag.ContinueOnError)
+	flags.SetOutput(stderr)
+	to := flags.String("to", "", "phone number of the recipient")
+	text := flags.String("text", "", "text of the message")
+	template := flags.String("template", "", "name of the approved template sent instead of a text")
+	language := flags.String("language", "", "language code of the template, en_US when empty")
+	tenant := flags.String("tenant", "", "phone number ID sending the message, the first tenant of the configuration when empty")
+	transactional := flags.Bool("transactional", false, "also send it if the contact opted out, for receipts and other transactional messages")
+	configPath := flags.String("file", "", "configuration file, CONFIG_FILE when empty")
+	if err := flags.Parse(args); err != nil {
+		return 2
+	}
+	if (*text == "") == (*template == "") {
+		fmt.Fprintln(stderr, "either -text or -template is required")
+		return 2
+	}
+	number, err := phone.Parse(*to)
+	if err != nil {
+		fmt.Fprintf(stderr, "invalid -to: %v\n", err)
+		return 2
+	}
+
+	cfg, err := config.LoadFile(*configPath)
+	if err != nil {
+		printConfigError(stderr, err)
+		return 1
+	}
+	phoneNumberID, ok := tenantOrDefault(cfg, *tenant)
+	if !ok {
+		fmt.Fprintln(stderr, "-tenant is required when the configuration has no tenants")
+		return 2
+	}
+	a, err := app.New(cfg, *configPath, cfg.StoragePath)
+	if err != nil {
+		fmt.Fprintf(stderr, "%v\nwhile the server is running, use POST /api/v1/whatsapp/send instead\n", err)
+		return 1
+	}
+	defer a.Close(context.Background())
+
+	message := domain.Message{
+		PhoneNumberID: phoneNumberID,
+		To:            number.String(),
+		Content:       *text,
+		MessageType:   "text",
+		Transactional: *transactional,
+	}
+	if *template != "" {
+		message.Content = *template
+		message.MessageType = "template"
+		message.Language = *language
+	}
+	ctx, cancel := context.WithTimeout(context.Background(), sendTimeout)
+	defer cancel()
+	response, err := a.WhatsAppUseCase.SendMessage(ctx, message)
+	if errors.Is(err, domain.ErrContactOptedOut) {
+		fmt.Fprintln(stderr, "the contact opted out, only -transactional messages can be sent to it")
+		return 1
+	}
+	if err != nil {
+		fmt.Fprintf(stderr, "failed to send the message: %v\n", err)
+		return 1
+	}
+	if response.Status == domain.SendStatusQueued {
+		fmt.Fprintln(stdout, "the message failed to be sent for now, it is queued in the outbox and sent by serve")
+		return 0
+	}
+	fmt.Fprintf(stdout, "sent message %s\n", response.MessageID)
+	return 0
+}
+
+// tenantOrDefault returns the phone number ID, or the one of the first tenant of the configuration when it is empty
+func tenantOrDefault(cfg config.Config, phoneNumberID string) (string, bool) {
+	if phoneNumberID != "" {
+		return phoneNumberID, true
+	}
+	if len(cfg.Tenants) == 0 {
+		return "", false
+	}
+	return cfg.Tenants[0].PhoneNumberID, true
+}
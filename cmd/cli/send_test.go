@@ -0,0 +1,102 @@
+package cli
+
+import (
+	"anyzzapp/internal/config"
+	"anyzzapp/internal/fakegraph"
+	"anyzzapp/internal/infrastructure/boltdb"
+	"anyzzapp/pkg/domain"
+	"anyzzapp/pkg/domain/phone"
+	"bytes"
+	"context"
+	"fmt"
+	"os"
+	"path/filepath"
+	"testing"
+	"time"
+
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+// writeConfig writes a valid configuration using the WhatsApp API and LLM at the URLs and returns its path
+func writeConfig(t *testing.T, graphURL, llmURL, storagePath string) string {
+	t.Helper()
+	path := filepath.Join(t.TempDir(), "config.yaml")
+	content := fmt.Sprintf("whatsapp:\n  api_key: %s\n  base_url: %s\n  webhook_verify_token: verify\n"+
+		"llm:\n  url: %s\nstorage:\n  path: %s\ntenants:\n  - phone_number_id: \"123\"\n",
+		fakegraph.DefaultToken, graphURL, llmURL, storagePath)
+	require.NoError(t, os.WriteFile(path, []byte(content), 0o600))
+	return path
+}
+
+func TestRun_Send_Text(t *testing.T) {
+	graph := fakegraph.New(t)
+	dbPath := filepath.Join(t.TempDir(), "anyzzapp.db")
+	configPath := writeConfig(t, graph.URL, "http://localhost:8081/api/v1/chat/ask", dbPath)
+
+	var stdout, stderr bytes.Buffer
+	code := Run([]string{"send", "-file", configPath, "-to", "+5491112345678", "-text", "Your order shipped"}, &stdout, &stderr)
+
+	assert.Equal(t, 0, code, stderr.String())
+	require.Len(t, graph.Sent(), 1)
+	sent := graph.Sent()[0]
+	assert.Equal(t, "123", sent.PhoneNumberID)
+	assert.Equal(t, "Your order shipped", sent.Text)
+	assert.Equal(t, fmt.Sprintf("sent message %s\n", sent.ID), stdout.String())
+
+	// The message is recorded like the ones sent by the server
+	db, err := boltdb.OpenReadOnly(dbPath)
+	require.NoError(t, err)
+	defer db.Close()
+	stored, err := boltdb.NewMessageRepository(db).Get(context.Background(), sent.ID)
+	require.NoError(t, err)
+	assert.Equal(t, "Your order shipped", stored.Content)
+}
+
+func TestRun_Send_Template(t *testing.T) {
+	graph := fakegraph.New(t)
+	configPath := writeConfig(t, graph.URL, "http://localhost:8081/api/v1/chat/ask", config.StorageMemory)
+
+	var stdout, stderr bytes.Buffer
+	code := Run([]string{"send", "-file", configPath, "-tenant", "456", "-to", "5491112345678",
+		"-template", "order_shipped", "-language", "es_AR"}, &stdout, &stderr)
+
+	assert.Equal(t, 0, code, stderr.String())
+	require.Len(t, graph.Sent(), 1)
+	sent := graph.Sent()[0]
+	assert.Equal(t, "456", sent.PhoneNumberID)
+	assert.Equal(t, "template", sent.Type)
+	assert.Equal(t, map[string]any{"name": "order_shipped", "language": map[string]any{"code": "es_AR"}}, sent.Payload["template"])
+}
+
+func TestRun_Send_OptedOut(t *testing.T) {
+	graph := fakegraph.New(t)
+	dbPath := filepath.Join(t.TempDir(), "anyzzapp.db")
+	db, err := boltdb.Open(dbPath)
+	require.NoError(t, err)
+	contact, err := phone.SendAddress("5491112345678")
+	require.NoError(t, err)
+	require.NoError(t, boltdb.NewSuppressionRepository(db).Add(context.Background(),
+		&domain.Suppression{PhoneNumberID: "123", Contact: contact, Keyword: "STOP", At: time.Now()}))
+	require.NoError(t, db.Close())
+	configPath := writeConfig(t, graph.URL, "http://localhost:8081/api/v1/chat/ask", dbPath)
+
+	var stdout, stderr bytes.Buffer
+	code := Run([]string{"send", "-file", configPath, "-to", "5491112345678", "-text", "Promo!"}, &stdout, &stderr)
+
+	assert.Equal(t, 1, code)
+	assert.Contains(t, stderr.String(), "the contact opted out")
+	assert.Empty(t, graph.Sent())
+
+	stderr.Reset()
+	code = Run([]string{"send", "-file", configPath, "-to", "5491112345678", "-text", "Your receipt", "-transactional"}, &stdout, &stderr)
+	assert.Equal(t, 0, code, stderr.String())
+	assert.Len(t, graph.Sent(), 1)
+}
+
+func TestRun_Send_Usage(t *testing.T) {
+	var stdout, stderr bytes.Buffer
+	assert.Equal(t, 2, Run([]string{"send", "-to", "5491112345678"}, &stdout, &stderr))
+	assert.Equal(t, 2, Run([]string{"send", "-to", "5491112345678", "-text", "Hi", "-template", "hello"}, &stdout, &stderr))
+	assert.Equal(t, 2, Run([]string{"send", "-to", "not a number", "-text", "Hi"}, &stdout, &stderr))
+}
@@ -0,0 +1,196 @@
+package cli
+
+import (
+	"anyzzapp/cmd/server"
+	"anyzzapp/internal/app"
+	"anyzzapp/internal/config"
+	"anyzzapp/internal/infrastructure/health"
+	"anyzzapp/internal/infrastructure/recording"
+	"anyzzapp/internal/infrastructure/tracing"
+	apphttp "anyzzapp/internal/interfaces/http"
+	"anyzzapp/pkg/application"
+	"anyzzapp/pkg/logging"
+	"context"
+	"errors"
+	"flag"
+	"fmt"
+	"io"
+	"net"
+	"net/http"
+	"os"
+	"os/signal"
+	"syscall"
+	"time"
+
+	"github.com/rs/zerolog/log"
+)
+
+const (
+	// readinessCacheTTL keeps probes from calling the Graph API and the LLM on every request
+	readinessCacheTTL = 10 * time.Second
+	// readinessCheckTimeout bounds each readiness check
+	readinessCheckTimeout = 3 * time.Second
+	// queueSaturationThreshold is the fraction of processing.max_pending past which the server is not ready,
+	// so that new webhooks go to other instances before they have to wait
+	queueSaturationThreshold = 0.9
+)
+
+// serve starts the server and runs it until SIGTERM or Ctrl+C
+func serve(args []string, stdout, stderr io.Writer) int {
+	flags := flag.NewFlagSet("serve", flag.ContinueOnError)
+	flags.SetOutput(stderr)
+	configPath := flags.String("file", "", "configuration file, CONFIG_FILE when empty")
+	if err := flags.Parse(args); err != nil {
+		return 2
+	}
+
+	// Refuse to start with an invalid configuration
+	cfg, err := config.LoadFile(*configPath)
+	if err != nil {
+		var invalid *config.ValidationError
+		if errors.As(err, &invalid) {
+			log.Error().Strs("problems", invalid.Problems).Msg("Invalid configuration")
+		} else {
+			log.Error().Err(err).Msg("Failed to load configuration")
+		}
+		return 1
+	}
+	logging.Setup(cfg.LogDebugPII)
+
+	// Stop gracefully on SIGTERM (deploys) and Ctrl+C
+	ctx, stop := signal.NotifyContext(context.Background(), syscall.SIGTERM, os.Interrupt)
+	defer stop()
+
+	listener, err := net.Listen("tcp", ":"+cfg.ServerPort)
+	if err != nil {
+		log.Error().Err(err).Msg("Failed to listen")
+		return 1
+	}
+	if err := run(ctx, cfg, *configPath, listener); err != nil {
+		log.Error().Err(err).Msg("Server did not stop cleanly")
+		return 1
+	}
+	return 0
+}
+
+// run serves the application of the configuration on the listener until ctx is done
+func run(ctx context.Context, cfg config.Config, configPath string, listener net.Listener) error {
+	// Webhooks are recorded to replay them when troubleshooting
+	var recorder *recording.Recorder
+	if cfg.Recording.Dir != "" {
+		var err error
+		recorder, err = recording.NewRecorder(cfg.Recording.Dir, cfg.Recording.MaxFileSize, cfg.Recording.MaskPII)
+		if err != nil {
+			listener.Close()
+			return fmt.Errorf("failed to start recording the webhooks: %w", err)
+		}
+		log.Info().Str("dir", cfg.Recording.Dir).Bool("mask_pii", cfg.Recording.MaskPII).Msg("Recording the webhooks")
+	}
+
+	// OpenTelemetry tracing
+	shutdownTracing, err := tracing.Setup(ctx, cfg)
+	if err != nil {
+		listener.Close()
+		return fmt.Errorf("failed to set up tracing: %w", err)
+	}
+
+	a, err := app.New(cfg, configPath, cfg.StoragePath)
+	if err != nil {
+		listener.Close()
+		return errors.Join(fmt.Errorf("failed to open the message store: %w", err), shutdownTracing(context.Background()))
+	}
+
+	// Tenants, personas and limits can be reloaded without a restart
+	go reloadOnSIGHUP(ctx, a.Store)
+
+	// Deletes the data past the retention of each tenant
+	retentionDone := make(chan struct{})
+	go func() {
+		defer close(retentionDone)
+		application.RunRetention(ctx, a.PrivacyUseCase, cfg.Retention.Interval, cfg.Retention.DryRun)
+	}()
+
+	// Answers again the messages the LLM or the WhatsApp API failed to answer
+	deadLettersDone := make(chan struct{})
+	go func() {
+		defer close(deadLettersDone)
+		application.RunDeadLetterRetries(ctx, a.DeadLetterUseCase, cfg.DeadLetters.Interval)
+	}()
+
+	// Sends the outbound messages left by the previous run, and the ones that failed for a temporary reason
+	outboxDone := make(chan struct{})
+	go func() {
+		defer close(outboxDone)
+		application.RunOutbox(ctx, a.Outbox, cfg.Outbox.Interval)
+	}()
+
+	// Readiness checks run by /readyz
+	probeClient := &http.Client{Timeout: readinessCheckTimeout}
+	checks := []health.Check{
+		health.LLMReachable("default", probeClient, cfg.LLMUrl),
+		health.GraphToken(probeClient, cfg.WhatsAppBaseURL, cfg.WhatsAppAPIKey),
+		health.ConversationStorage(a.Conversations),
+		health.QueueSaturation("processing", a.Executor.Pending, cfg.Processing.MaxPending, queueSaturationThreshold),
+	}
+	if a.DB != nil {
+		checks = append(checks, health.Writable("messages", a.DB.CheckWritable))
+	}
+	for _, backend := range cfg.LLMBackends {
+		checks = append(checks, health.LLMReachable(backend.Name, probeClient, backend.URL))
+	}
+	readiness := health.NewChecker(readinessCacheTTL, readinessCheckTimeout, checks...)
+
+	routerOptions := append(a.RouterOptions(), apphttp.WithReadiness(readiness))
+	if recorder != nil {
+		routerOptions = append(routerOptions, apphttp.WithWebhookRecorder(recorder))
+	}
+	router := apphttp.NewRouter(cfg, a.WhatsAppUseCase, routerOptions...)
+
+	srv := server.New(cfg, router)
+	// The messages held to be answered together are answered once the requests are drained
+	if a.Debouncer != nil {
+		srv.OnShutdown("debounce", a.Debouncer.Flush)
+	}
+	// The retention, dead letter and outbox jobs stop with the context, before the storage is closed
+	srv.OnShutdown("retention", waitFor(retentionDone))
+	srv.OnShutdown("dead letters", waitFor(deadLettersDone))
+	srv.OnShutdown("outbox", waitFor(outboxDone))
+	if recorder != nil {
+		srv.OnShutdown("recording", func(context.Context) error { return recorder.Close() })
+	}
+	// The debounce hook has answered the messages held, Close only has to close the database
+	srv.OnShutdown("storage", a.Close)
+	// Flush the spans of the last requests once they are drained
+	srv.OnShutdown("tracing", shutdownTracing)
+	return srv.Serve(ctx, listener)
+}
+
+// waitFor returns a shutdown hook waiting for a background job to close done
+func waitFor(done <-chan struct{}) func(context.Context) error {
+	return func(ctx context.Context) error {
+		select {
+		case <-done:
+			return nil
+		case <-ctx.Done():
+			return ctx.Err()
+		}
+	}
+}
+
+// reloadOnSIGHUP reloads the configuration every time the process gets SIGHUP, until ctx is done
+func reloadOnSIGHUP(ctx context.Context, store *config.Store) {
+	hangups := make(chan os.Signal, 1)
+	signal.Notify(hangups, syscall.SIGHUP)
+	defer signal.Stop(hangups)
+
+	for {
+		select {
+		case <-ctx.Done():
+			return
+		case <-hangups:
+			if _, err := store.Reload(); err != nil {
+				log.Error().Err(err).Msg("Failed to reload the configuration, keeping the current one")
+			}
+		}
+	}
+}
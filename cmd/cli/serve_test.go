@@ -0,0 +1,73 @@
+package cli
+
+import (
+	"anyzzapp/internal/config"
+	"anyzzapp/internal/fakegraph"
+	"anyzzapp/internal/fakellm"
+	"context"
+	"io"
+	"net"
+	"net/http"
+	"path/filepath"
+	"testing"
+	"time"
+
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+func TestRun_ServesUntilCancelled(t *testing.T) {
+	graph := fakegraph.New(t)
+	llm := fakellm.New(t)
+	llm.ReplyNext("Hi from the server")
+	cfg := config.Default()
+	cfg.WhatsAppAPIKey = fakegraph.DefaultToken
+	cfg.WhatsAppBaseURL = graph.URL
+	cfg.WebhookVerifyToken = "verify"
+	cfg.AdminToken = "admin-token-0123456789"
+	cfg.LLMUrl = llm.URL
+	cfg.StoragePath = filepath.Join(t.TempDir(), "anyzzapp.db")
+	cfg.Recording.Dir = filepath.Join(t.TempDir(), "recordings")
+	listener, err := net.Listen("tcp", "127.0.0.1:0")
+	require.NoError(t, err)
+	baseURL := "http://" + listener.Addr().String()
+
+	ctx, cancel := context.WithCancel(context.Background())
+	done := make(chan error, 1)
+	go func() { done <- run(ctx, cfg, "", listener) }()
+
+	resp, err := http.Get(baseURL + "/health")
+	require.NoError(t, err)
+	resp.Body.Close()
+	assert.Equal(t, http.StatusOK, resp.StatusCode)
+
+	resp, err = graph.SendWebhook(context.Background(), baseURL+webhookPath, fakegraph.TextMessage("123", "5491112345678", "wamid.1", "Hello"))
+	require.NoError(t, err)
+	resp.Body.Close()
+	assert.Equal(t, http.StatusOK, resp.StatusCode)
+	if sent := graph.Sent(); assert.Len(t, sent, 1) {
+		assert.Equal(t, "Hi from the server", sent[0].Text)
+	}
+	files, err := filepath.Glob(filepath.Join(cfg.Recording.Dir, "*.jsonl"))
+	require.NoError(t, err)
+	assert.Len(t, files, 1, "the webhook is recorded")
+
+	// The saturation of the processing queue is one of the readiness checks
+	req, err := http.NewRequest("GET", baseURL+"/readyz", nil)
+	require.NoError(t, err)
+	req.Header.Set("Authorization", "Bearer "+cfg.AdminToken)
+	resp, err = http.DefaultClient.Do(req)
+	require.NoError(t, err)
+	body, err := io.ReadAll(resp.Body)
+	resp.Body.Close()
+	require.NoError(t, err)
+	assert.Contains(t, string(body), `"queue:processing"`)
+
+	cancel()
+	select {
+	case err := <-done:
+		assert.NoError(t, err)
+	case <-time.After(10 * time.Second):
+		t.Fatal("the server did not stop")
+	}
+}
@@ -0,0 +1,161 @@
+package cli
+
+import (
+	"anyzzapp/internal/config"
+	"anyzzapp/pkg/domain"
+	"bytes"
+	"crypto/rand"
+	"encoding/hex"
+	"encoding/json"
+	"errors"
+	"flag"
+	"fmt"
+	"io"
+	"net/http"
+	"net/url"
+	"strings"
+	"time"
+)
+
+const (
+	// webhookPath is the route of the server receiving the webhooks
+	webhookPath = "/api/v1/whatsapp/webhook"
+	// simulatedBusinessAccountID is the WhatsApp Business Account ID of the simulated webhooks
+	simulatedBusinessAccountID = "anyzzapp-simulated"
+	// webhookTimeout bounds the requests to the webhook, the auto-reply is sent before it is answered
+	webhookTimeout = 2 * time.Minute
+)
+
+// webhookSimulate posts the webhook of a text message of a contact to a running server, as Meta does
+func webhookSimulate(args []string, stdout, stderr io.Writer) int {
+	flags := flag.NewFlagSet("webhook simulate", flag.ContinueOnError)
+	flags.SetOutput(stderr)
+	from := flags.String("from", "", "wa_id of the contact sending the message")
+	text := flags.String("text", "", "text of the message")
+	name := flags.String("name", "", "profile name of the contact")
+	tenant := flags.String("tenant", "", "phone number ID receiving the message, the first tenant of the configuration when empty")
+	webhookURL := flags.String("url", "", "webhook URL, the one of the local server of the configuration when empty")
+	configPath := flags.String("file", "", "configuration file, CONFIG_FILE when empty")
+	if err := flags.Parse(args); err != nil {
+		return 2
+	}
+	if *from == "" || *text == "" {
+		fmt.Fprintln(stderr, "-from and -text are required")
+		return 2
+	}
+
+	cfg, err := loadPartialConfig(*configPath)
+	if err != nil {
+		fmt.Fprintln(stderr, err)
+		return 1
+	}
+	phoneNumberID, ok := tenantOrDefault(cfg, *tenant)
+	if !ok {
+		fmt.Fprintln(stderr, "-tenant is required when the configuration has no tenants")
+		return 2
+	}
+
+	contact := domain.WebhookContact{WaID: *from, Profile: domain.WebhookProfile{Name: *name}}
+	webhook := domain.NewTextWebhook(simulatedBusinessAccountID, phoneNumberID, contact, "wamid.simulated."+randomHex(8), *text, time.Now())
+	body, err := json.Marshal(webhook)
+	if err != nil {
+		fmt.Fprintln(stderr, err)
+		return 1
+	}
+	httpClient := &http.Client{Timeout: webhookTimeout}
+	resp, err := httpClient.Post(localWebhookURL(cfg, *webhookURL), "application/json", bytes.NewReader(body))
+	if err != nil {
+		fmt.Fprintln(stderr, err)
+		return 1
+	}
+	defer resp.Body.Close()
+	answer, _ := io.ReadAll(io.LimitReader(resp.Body, 4096))
+	if resp.StatusCode < 200 || resp.StatusCode >= 300 {
+		fmt.Fprintf(stderr, "webhook answered with status %d: %s\n", resp.StatusCode, strings.TrimSpace(string(answer)))
+		return 1
+	}
+	fmt.Fprintf(stdout, "webhook accepted with status %d\n", resp.StatusCode)
+	return 0
+}
+
+// webhookVerify runs the verification request Meta sends when the webhook URL is configured
+func webhookVerify(args []string, stdout, stderr io.Writer) int {
+	flags := flag.NewFlagSet("webhook verify", flag.ContinueOnError)
+	flags.SetOutput(stderr)
+	webhookURL := flags.String("url", "", "webhook URL, the one of the local server of the configuration when empty")
+	token := flags.String("token", "", "verify token, the one of the configuration when empty")
+	configPath := flags.String("file", "", "configuration file, CONFIG_FILE when empty")
+	if err := flags.Parse(args); err != nil {
+		return 2
+	}
+
+	cfg, err := loadPartialConfig(*configPath)
+	if err != nil {
+		fmt.Fprintln(stderr, err)
+		return 1
+	}
+	if *token == "" {
+		*token = cfg.WebhookVerifyToken
+	}
+	if *token == "" {
+		fmt.Fprintln(stderr, "-token is required when the configuration has no webhook verify token")
+		return 2
+	}
+	target, err := url.Parse(localWebhookURL(cfg, *webhookURL))
+	if err != nil {
+		fmt.Fprintf(stderr, "invalid -url: %v\n", err)
+		return 2
+	}
+
+	challenge := randomHex(8)
+	query := target.Query()
+	query.Set("hub.mode", "subscribe")
+	query.Set("hub.verify_token", *token)
+	query.Set("hub.challenge", challenge)
+	target.RawQuery = query.Encode()
+	httpClient := &http.Client{Timeout: webhookTimeout}
+	resp, err := httpClient.Get(target.String())
+	if err != nil {
+		// The error of the client includes the URL, and so the token
+		var urlErr *url.Error
+		if errors.As(err, &urlErr) {
+			err = urlErr.Err
+		}
+		fmt.Fprintln(stderr, "failed to reach the webhook:", err)
+		return 1
+	}
+	defer resp.Body.Close()
+	answer, _ := io.ReadAll(io.LimitReader(resp.Body, 4096))
+	if resp.StatusCode != http.StatusOK || string(answer) != challenge {
+		fmt.Fprintf(stderr, "verification failed: status %d, the challenge was not echoed back\n", resp.StatusCode)
+		return 1
+	}
+	fmt.Fprintln(stdout, "webhook verified")
+	return 0
+}
+
+// loadPartialConfig loads the configuration without requiring it to be valid, for the subcommands
+// that only need a few of its values
+func loadPartialConfig(path string) (config.Config, error) {
+	cfg, err := config.LoadFile(path)
+	var invalid *config.ValidationError
+	if err != nil && !errors.As(err, &invalid) {
+		return cfg, err
+	}
+	return cfg, nil
+}
+
+// localWebhookURL returns the webhook URL, or the one of the server of the configuration on this host when it is empty
+func localWebhookURL(cfg config.Config, webhookURL string) string {
+	if webhookURL != "" {
+		return webhookURL
+	}
+	return "http://localhost:" + cfg.ServerPort + webhookPath
+}
+
+// randomHex returns n random bytes in hexadecimal
+func randomHex(n int) string {
+	b := make([]byte, n)
+	_, _ = rand.Read(b)
+	return hex.EncodeToString(b)
+}
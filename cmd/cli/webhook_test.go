@@ -0,0 +1,75 @@
+package cli
+
+import (
+	"anyzzapp/pkg/domain"
+	"bytes"
+	"encoding/json"
+	"net/http"
+	"net/http/httptest"
+	"testing"
+
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+func TestRun_WebhookSimulate(t *testing.T) {
+	var received domain.WebhookRequest
+	server := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		assert.Equal(t, webhookPath, r.URL.Path)
+		assert.Equal(t, "application/json", r.Header.Get("Content-Type"))
+		require.NoError(t, json.NewDecoder(r.Body).Decode(&received))
+	}))
+	defer server.Close()
+	configPath := writeConfig(t, "http://localhost:1/v20.0", "http://localhost:1/ask", "memory")
+
+	var stdout, stderr bytes.Buffer
+	code := Run([]string{"webhook", "simulate", "-file", configPath, "-url", server.URL + webhookPath,
+		"-from", "5491112345678", "-name", "Ana", "-text", "Hello"}, &stdout, &stderr)
+
+	assert.Equal(t, 0, code, stderr.String())
+	assert.Equal(t, "webhook accepted with status 200\n", stdout.String())
+	require.Len(t, received.Entry, 1)
+	value := received.Entry[0].Changes[0].Value
+	assert.Equal(t, "123", value.Metadata.PhoneNumberID, "the first tenant receives the message")
+	assert.Equal(t, "Ana", value.Contacts[0].Profile.Name)
+	require.Len(t, value.Messages, 1)
+	assert.Equal(t, "5491112345678", value.Messages[0].From)
+	assert.Equal(t, "Hello", value.Messages[0].Text.Body)
+	assert.NotEmpty(t, value.Messages[0].ID)
+}
+
+func TestRun_WebhookSimulate_Rejected(t *testing.T) {
+	server := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		http.Error(w, `{"error":"webhook_processing_failed"}`, http.StatusInternalServerError)
+	}))
+	defer server.Close()
+
+	var stdout, stderr bytes.Buffer
+	code := Run([]string{"webhook", "simulate", "-url", server.URL, "-tenant", "123", "-from", "5491112345678", "-text", "Hello"}, &stdout, &stderr)
+
+	assert.Equal(t, 1, code)
+	assert.Contains(t, stderr.String(), "webhook answered with status 500")
+}
+
+func TestRun_WebhookVerify(t *testing.T) {
+	server := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		query := r.URL.Query()
+		if query.Get("hub.mode") != "subscribe" || query.Get("hub.verify_token") != "verify" {
+			w.WriteHeader(http.StatusForbidden)
+			return
+		}
+		_, _ = w.Write([]byte(query.Get("hub.challenge")))
+	}))
+	defer server.Close()
+	configPath := writeConfig(t, "http://localhost:1/v20.0", "http://localhost:1/ask", "memory")
+
+	var stdout, stderr bytes.Buffer
+	code := Run([]string{"webhook", "verify", "-file", configPath, "-url", server.URL}, &stdout, &stderr)
+	assert.Equal(t, 0, code, stderr.String())
+	assert.Equal(t, "webhook verified\n", stdout.String())
+
+	stderr.Reset()
+	code = Run([]string{"webhook", "verify", "-url", server.URL, "-token", "wrong"}, &stdout, &stderr)
+	assert.Equal(t, 1, code)
+	assert.Equal(t, "verification failed: status 403, the challenge was not echoed back\n", stderr.String())
+}
@@ -2,18 +2,127 @@ package server
 
 import (
 	"anyzzapp/internal/config"
-	"anyzzapp/internal/interfaces/http"
-	"anyzzapp/pkg/domain"
-	"log"
+	"context"
+	"errors"
+	"fmt"
+	"net"
+	"net/http"
+	"sync"
+	"time"
+
+	"github.com/rs/zerolog/log"
 )
 
-func Run(config config.Config, whatsAppUsecase domain.WhatsAppUseCaseInterface) {
-	// Initialize HTTP handlers
-	router := http.NewRouter(config, whatsAppUsecase)
+// ShutdownHook flushes or stops a component once the server stopped accepting requests
+type ShutdownHook func(ctx context.Context) error
+
+// defaultShutdownTimeout is used when the configuration sets none
+const defaultShutdownTimeout = 30 * time.Second
+
+// namedHook is a shutdown hook with the name used in the logs
+type namedHook struct {
+	name string
+	hook ShutdownHook
+}
+
+// Server serves the API until its context is done, then drains the in-flight requests
+// and runs the shutdown hooks within the shutdown timeout
+type Server struct {
+	httpServer      *http.Server
+	shutdownTimeout time.Duration
+
+	mu    sync.Mutex
+	hooks []namedHook
+}
+
+// New creates a new instance of Server with the timeouts of the configuration
+func New(config config.Config, handler http.Handler) *Server {
+	shutdownTimeout := config.ShutdownTimeout
+	if shutdownTimeout <= 0 {
+		shutdownTimeout = defaultShutdownTimeout
+	}
+	return &Server{
+		httpServer: &http.Server{
+			Addr:              ":" + config.ServerPort,
+			Handler:           handler,
+			ReadTimeout:       config.ReadTimeout,
+			ReadHeaderTimeout: config.ReadTimeout,
+			WriteTimeout:      config.WriteTimeout,
+			IdleTimeout:       config.IdleTimeout,
+		},
+		shutdownTimeout: shutdownTimeout,
+	}
+}
+
+// OnShutdown registers a hook run after the in-flight requests are drained.
+// Hooks run in the order they were registered, e.g. drain queues before flushing stores and traces.
+func (s *Server) OnShutdown(name string, hook ShutdownHook) {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	s.hooks = append(s.hooks, namedHook{name: name, hook: hook})
+}
+
+// ListenAndServe listens on the configured port and serves until ctx is done
+func (s *Server) ListenAndServe(ctx context.Context) error {
+	listener, err := net.Listen("tcp", s.httpServer.Addr)
+	if err != nil {
+		return fmt.Errorf("failed to listen on %s: %w", s.httpServer.Addr, err)
+	}
+	return s.Serve(ctx, listener)
+}
+
+// Serve serves on the listener until ctx is done, then shuts down gracefully.
+// It returns nil on a clean shutdown.
+func (s *Server) Serve(ctx context.Context, listener net.Listener) error {
+	serveErr := make(chan error, 1)
+	go func() {
+		log.Info().Str("addr", listener.Addr().String()).Msg("Server starting")
+		serveErr <- s.httpServer.Serve(listener)
+	}()
+
+	select {
+	case err := <-serveErr:
+		// The server stopped on its own, still release what the hooks hold
+		shutdownCtx, cancel := context.WithTimeout(context.Background(), s.shutdownTimeout)
+		defer cancel()
+		return errors.Join(fmt.Errorf("server stopped: %w", err), s.runHooks(shutdownCtx))
+	case <-ctx.Done():
+	}
+
+	log.Info().Dur("timeout", s.shutdownTimeout).Msg("Shutting down, draining in-flight requests")
+	shutdownCtx, cancel := context.WithTimeout(context.Background(), s.shutdownTimeout)
+	defer cancel()
+
+	var errs []error
+	if err := s.httpServer.Shutdown(shutdownCtx); err != nil {
+		// Deadline reached: abort the requests still running
+		errs = append(errs, fmt.Errorf("failed to drain in-flight requests: %w", err))
+		_ = s.httpServer.Close()
+	}
+	if err := <-serveErr; err != nil && !errors.Is(err, http.ErrServerClosed) {
+		errs = append(errs, fmt.Errorf("server stopped: %w", err))
+	}
+	errs = append(errs, s.runHooks(shutdownCtx))
+
+	if err := errors.Join(errs...); err != nil {
+		return err
+	}
+	log.Info().Msg("Server stopped")
+	return nil
+}
+
+// runHooks runs every shutdown hook, even when a previous one failed
+func (s *Server) runHooks(ctx context.Context) error {
+	s.mu.Lock()
+	hooks := append([]namedHook(nil), s.hooks...)
+	s.mu.Unlock()
 
-	// Start server
-	log.Printf("Server starting on port %s", config.ServerPort)
-	if err := router.Run(":" + config.ServerPort); err != nil {
-		log.Fatal("Failed to start server:", err)
+	var errs []error
+	for _, h := range hooks {
+		if err := h.hook(ctx); err != nil {
+			log.Error().Err(err).Str("hook", h.name).Msg("Shutdown hook failed")
+			errs = append(errs, fmt.Errorf("shutdown hook %s: %w", h.name, err))
+		}
 	}
+	return errors.Join(errs...)
 }
@@ -0,0 +1,138 @@
+package server
+
+import (
+	"anyzzapp/internal/config"
+	"context"
+	"errors"
+	"io"
+	"net"
+	"net/http"
+	"testing"
+	"time"
+
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+// startServer serves the handler on a random local port until the returned cancel is called
+func startServer(t *testing.T, srv *Server) (string, context.CancelFunc, <-chan error) {
+	listener, err := net.Listen("tcp", "127.0.0.1:0")
+	require.NoError(t, err)
+
+	ctx, cancel := context.WithCancel(context.Background())
+	done := make(chan error, 1)
+	go func() {
+		done <- srv.Serve(ctx, listener)
+	}()
+	return "http://" + listener.Addr().String(), cancel, done
+}
+
+func TestServer_Serve_DrainsInFlightRequests(t *testing.T) {
+	started := make(chan struct{})
+	release := make(chan struct{})
+	handler := http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		close(started)
+		<-release
+		_, _ = io.WriteString(w, "answered")
+	})
+
+	srv := New(config.Config{ShutdownTimeout: 5 * time.Second}, handler)
+	var hookRanAfterRequest bool
+	requestDone := make(chan struct{})
+	srv.OnShutdown("store", func(ctx context.Context) error {
+		select {
+		case <-requestDone:
+			hookRanAfterRequest = true
+		default:
+		}
+		return nil
+	})
+	url, shutdown, done := startServer(t, srv)
+
+	responses := make(chan string, 1)
+	go func() {
+		defer close(requestDone)
+		resp, err := http.Get(url)
+		if err != nil {
+			responses <- err.Error()
+			return
+		}
+		defer resp.Body.Close()
+		body, _ := io.ReadAll(resp.Body)
+		responses <- string(body)
+	}()
+	<-started
+
+	shutdown()
+	select {
+	case <-done:
+		t.Fatal("server stopped before the in-flight request finished")
+	case <-time.After(50 * time.Millisecond):
+	}
+	close(release)
+
+	assert.Equal(t, "answered", <-responses)
+	assert.NoError(t, <-done)
+	assert.True(t, hookRanAfterRequest)
+}
+
+func TestServer_Serve_StopsAcceptingConnections(t *testing.T) {
+	srv := New(config.Config{}, http.NotFoundHandler())
+	url, shutdown, done := startServer(t, srv)
+
+	shutdown()
+	assert.NoError(t, <-done)
+
+	_, err := http.Get(url)
+	assert.Error(t, err)
+}
+
+func TestServer_Serve_DeadlineExceeded(t *testing.T) {
+	started := make(chan struct{})
+	handler := http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		close(started)
+		<-r.Context().Done()
+	})
+
+	srv := New(config.Config{ShutdownTimeout: 50 * time.Millisecond}, handler)
+	hookCalled := false
+	srv.OnShutdown("tracing", func(ctx context.Context) error {
+		hookCalled = true
+		return nil
+	})
+	url, shutdown, done := startServer(t, srv)
+
+	go func() {
+		if resp, err := http.Get(url); err == nil {
+			resp.Body.Close()
+		}
+	}()
+	<-started
+	shutdown()
+
+	err := <-done
+	assert.Error(t, err)
+	assert.ErrorIs(t, err, context.DeadlineExceeded)
+	assert.True(t, hookCalled)
+}
+
+func TestServer_Serve_HookErrors(t *testing.T) {
+	srv := New(config.Config{}, http.NotFoundHandler())
+	var order []string
+	srv.OnShutdown("queue", func(ctx context.Context) error {
+		order = append(order, "queue")
+		return errors.New("jobs left")
+	})
+	srv.OnShutdown("store", func(ctx context.Context) error {
+		order = append(order, "store")
+		return nil
+	})
+	_, shutdown, done := startServer(t, srv)
+
+	shutdown()
+	err := <-done
+
+	assert.Error(t, err)
+	assert.Contains(t, err.Error(), "shutdown hook queue: jobs left")
+	assert.Equal(t, []string{"queue", "store"}, order)
+}
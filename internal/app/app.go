@@ -0,0 +1,247 @@
+// Package app wires the repositories and the use cases of the configuration, for the server, the subcommands that
+// run the application in-process and the end-to-end tests
+package app
+
+import (
+	"anyzzapp/internal/config"
+	"anyzzapp/internal/infrastructure"
+	"anyzzapp/internal/infrastructure/boltdb"
+	"anyzzapp/internal/infrastructure/client"
+	"anyzzapp/internal/infrastructure/metrics"
+	"anyzzapp/internal/infrastructure/tracing"
+	apphttp "anyzzapp/internal/interfaces/http"
+	"anyzzapp/pkg/application"
+	"anyzzapp/pkg/domain"
+	"context"
+	"net/http"
+	"net/url"
+
+	"github.com/rs/zerolog/log"
+)
+
+// App holds the repositories and the use cases wired like the server
+type App struct {
+	Store         *config.Store
+	Metrics       *metrics.PrometheusMetrics
+	Conversations domain.ConversationRepository
+	Messages      domain.MessageRepository
+	DeadLetters   domain.DeadLetterRepository
+	Outbox        *application.Outbox
+	Executor      *application.KeyedExecutor
+	// Debouncer holds the bursts of messages of the contacts, nil when they are answered at once
+	Debouncer *application.Debouncer
+	// DB is the database storing the messages, nil when they are kept in memory
+	DB *boltdb.DB
+
+	WhatsAppUseCase   domain.WhatsAppUseCaseInterface
+	AgentUseCase      domain.AgentUseCaseInterface
+	HistoryUseCase    domain.HistoryUseCaseInterface
+	PrivacyUseCase    domain.PrivacyUseCaseInterface
+	DeadLetterUseCase *application.DeadLetterUseCase
+
+	whatsappRepo  domain.WhatsAppRepository
+	suppressions  domain.SuppressionRepository
+	outboxEntries domain.OutboxRepository
+	settings      domain.SettingsProvider
+}
+
+// Option replaces a dependency of the app
+type Option func(*App)
+
+// WithWhatsAppRepository sends the messages with the repository instead of the WhatsApp API of the configuration
+func WithWhatsAppRepository(repo domain.WhatsAppRepository) Option {
+	return func(a *App) {
+		a.whatsappRepo = repo
+	}
+}
+
+// WithSettings reads the settings of the tenants from the provider wrapping the configuration
+func WithSettings(wrap func(domain.SettingsProvider) domain.SettingsProvider) Option {
+	return func(a *App) {
+		a.settings = wrap(a.settings)
+	}
+}
+
+// New wires the application of the configuration, storing the messages at storagePath. The WhatsApp API and the
+// LLM backends are the ones at the URLs of the configuration. The configuration file at configPath, if any, is the
+// one reloaded by the store.
+func New(cfg config.Config, configPath, storagePath string, opts ...Option) (*App, error) {
+	a := &App{
+		Store:   config.NewStore(cfg, configPath),
+		Metrics: metrics.NewPrometheusMetrics(),
+	}
+	a.settings = a.Store
+	for _, opt := range opts {
+		opt(a)
+	}
+
+	if a.whatsappRepo == nil {
+		whatsappHttpClient := client.NewHttpClient(&http.Client{}, cfg.WhatsAppAPIKey)
+		a.whatsappRepo = tracing.InstrumentWhatsAppRepository(metrics.InstrumentWhatsAppRepository(
+			infrastructure.NewWhatsAppRepository(cfg, whatsappHttpClient), a.Metrics))
+	}
+	llmRepo := newLLMRepository(cfg, cfg.LLMUrl, cfg.LLMBearerToken, a.Metrics)
+	llmBackends := make(map[string]domain.LLMRepository, len(cfg.LLMBackends))
+	for _, backend := range cfg.LLMBackends {
+		llmBackends[backend.Name] = newLLMRepository(cfg, backend.URL, backend.BearerToken, a.Metrics)
+	}
+	var err error
+	a.Messages, a.DB, err = newMessageRepository(storagePath)
+	if err != nil {
+		return nil, err
+	}
+	a.Conversations = newConversationRepository(a.DB)
+	a.DeadLetters = newDeadLetterRepository(a.DB)
+	a.suppressions = newSuppressionRepository(a.DB)
+	a.outboxEntries = newOutboxRepository(a.DB)
+	a.Outbox = application.NewOutbox(a.outboxEntries, a.whatsappRepo, cfg.Outbox.RetrySchedule)
+	a.Executor = application.NewKeyedExecutor(cfg.Processing.MaxPending, cfg.Processing.IdleTimeout)
+
+	// In-chat commands answered before the LLM
+	commands := application.NewCommandRouter(cfg.CommandPrefixes...)
+	if err := application.RegisterBuiltinCommands(commands, a.Conversations); err != nil {
+		a.Close(context.Background())
+		return nil, err
+	}
+
+	options := []application.Option{
+		application.WithConversations(a.Conversations),
+		application.WithCommandRouter(commands),
+		application.WithMetrics(a.Metrics),
+		application.WithSettings(a.settings),
+		application.WithLLMBackends(llmBackends),
+		application.WithMessages(a.Messages),
+		application.WithOptOut(a.suppressions, optOutKeywords(cfg.OptOut)),
+		application.WithDeadLetters(a.DeadLetters, cfg.DeadLetters.RetrySchedule),
+		application.WithOutbox(a.Outbox),
+		application.WithKeyedExecutor(a.Executor),
+	}
+	if cfg.Processing.DebounceWindow > 0 {
+		a.Debouncer = application.NewDebouncer(cfg.Processing.DebounceWindow, cfg.Processing.DebounceMaxWait)
+		options = append(options, application.WithDebouncer(a.Debouncer))
+	}
+	a.WhatsAppUseCase = application.NewWhatsAppUseCase(a.whatsappRepo, llmRepo, options...)
+
+	a.AgentUseCase = application.NewAgentUseCase(a.whatsappRepo, a.Conversations,
+		application.WithAgentMessages(a.Messages),
+		application.WithAgentOutbox(a.Outbox),
+		application.WithAgentSuppressions(a.suppressions))
+	a.HistoryUseCase = application.NewHistoryUseCase(a.Messages, a.Conversations)
+	privacyOptions := []application.PrivacyOption{
+		application.WithPrivacyDeadLetters(a.DeadLetters),
+		application.WithPrivacyOutbox(a.outboxEntries),
+	}
+	if a.Debouncer != nil {
+		privacyOptions = append(privacyOptions, application.WithPrivacyDebouncer(a.Debouncer))
+	}
+	a.PrivacyUseCase = application.NewPrivacyUseCase(a.Messages, a.Conversations, newAuditRepository(a.DB), a.Store,
+		privacyOptions...)
+	a.DeadLetterUseCase = application.NewDeadLetterUseCase(a.DeadLetters, a.WhatsAppUseCase, cfg.DeadLetters.RetrySchedule,
+		application.WithDeadLetterMetrics(a.Metrics))
+	return a, nil
+}
+
+// RouterOptions returns the options serving the use cases, the metrics and the configuration reloads of the app
+func (a *App) RouterOptions() []apphttp.Option {
+	return []apphttp.Option{
+		apphttp.WithAgentUseCase(a.AgentUseCase),
+		apphttp.WithHistoryUseCase(a.HistoryUseCase),
+		apphttp.WithPrivacyUseCase(a.PrivacyUseCase),
+		apphttp.WithDeadLetterUseCase(a.DeadLetterUseCase),
+		apphttp.WithMetrics(a.Metrics, a.Metrics.Handler()),
+		apphttp.WithConfigReloader(a.Store),
+	}
+}
+
+// Close answers the messages still held by the debouncer, until ctx is done, and closes the database, if any
+func (a *App) Close(ctx context.Context) error {
+	if a.Debouncer != nil {
+		if err := a.Debouncer.Flush(ctx); err != nil {
+			log.Warn().Err(err).Msg("Failed to answer the messages held")
+		}
+	}
+	if a.DB == nil {
+		return nil
+	}
+	return a.DB.Close()
+}
+
+// newLLMRepository creates the instrumented repository of an LLM backend
+func newLLMRepository(cfg config.Config, llmURL, bearerToken string, m domain.Metrics) domain.LLMRepository {
+	cfg.LLMUrl = llmURL
+	httpClient := client.NewHttpClient(&http.Client{}, bearerToken)
+	return tracing.InstrumentLLMRepository(metrics.InstrumentLLMRepository(
+		infrastructure.NewLLMRepository(cfg, httpClient), m, llmBackend(llmURL)), llmBackend(llmURL))
+}
+
+// newMessageRepository opens the database storing the messages, or keeps them in memory.
+// The database is nil when the messages are kept in memory.
+func newMessageRepository(path string) (domain.MessageRepository, *boltdb.DB, error) {
+	if path == config.StorageMemory {
+		log.Warn().Msg("Messages are kept in memory and will be lost on restart")
+		return infrastructure.NewMessageRepository(), nil, nil
+	}
+	db, err := boltdb.Open(path)
+	if err != nil {
+		return nil, nil, err
+	}
+	return boltdb.NewMessageRepository(db), db, nil
+}
+
+// newConversationRepository keeps the conversations, with their handovers, in the database, or in memory when there is none
+func newConversationRepository(db *boltdb.DB) domain.ConversationRepository {
+	if db == nil {
+		return infrastructure.NewConversationRepository()
+	}
+	return boltdb.NewConversationRepository(db)
+}
+
+// newAuditRepository keeps the audit records in the database, or in memory when there is none
+func newAuditRepository(db *boltdb.DB) domain.AuditRepository {
+	if db == nil {
+		return infrastructure.NewAuditRepository()
+	}
+	return boltdb.NewAuditRepository(db)
+}
+
+// newSuppressionRepository keeps the contacts that opted out in the database, or in memory when there is none
+func newSuppressionRepository(db *boltdb.DB) domain.SuppressionRepository {
+	if db == nil {
+		return infrastructure.NewSuppressionRepository()
+	}
+	return boltdb.NewSuppressionRepository(db)
+}
+
+// newDeadLetterRepository keeps the messages that failed to be answered in the database, or in memory when there is none
+func newDeadLetterRepository(db *boltdb.DB) domain.DeadLetterRepository {
+	if db == nil {
+		return infrastructure.NewDeadLetterRepository()
+	}
+	return boltdb.NewDeadLetterRepository(db)
+}
+
+// newOutboxRepository keeps the outbound messages waiting to be sent in the database, or in memory when there is none
+func newOutboxRepository(db *boltdb.DB) domain.OutboxRepository {
+	if db == nil {
+		return infrastructure.NewOutboxRepository()
+	}
+	return boltdb.NewOutboxRepository(db)
+}
+
+// optOutKeywords converts the configured opt-out languages
+func optOutKeywords(languages []config.OptOutLanguage) []domain.OptOutKeywords {
+	keywords := make([]domain.OptOutKeywords, 0, len(languages))
+	for _, language := range languages {
+		keywords = append(keywords, domain.OptOutKeywords(language))
+	}
+	return keywords
+}
+
+// llmBackend returns the label of the LLM backend in the metrics: the host of its URL
+func llmBackend(llmURL string) string {
+	parsed, err := url.Parse(llmURL)
+	if err != nil || parsed.Host == "" {
+		return "unknown"
+	}
+	return parsed.Host
+}
@@ -1,13 +1,19 @@
 package config
 
 import (
+	"fmt"
 	"github.com/joho/godotenv"
 	"github.com/rs/zerolog"
-	"log"
+	"github.com/rs/zerolog/log"
 	"os"
+	"strconv"
 	"strings"
+	"time"
 )
 
+// StorageMemory is the storage path keeping the messages in memory, they are lost on restart
+const StorageMemory = "memory"
+
 // Config contains the application configuration
 type Config struct {
 	ServerPort         string
@@ -16,23 +22,251 @@ type Config struct {
 	WebhookVerifyToken string
 	LLMUrl             string
 	LLMBearerToken     string
+	CommandPrefixes    []string
+	TracingExporter    string
+	LogLevel           string
+	LogDebugPII        bool
+	ReadTimeout        time.Duration
+	WriteTimeout       time.Duration
+	IdleTimeout        time.Duration
+	ShutdownTimeout    time.Duration
+	// StoragePath is the database file storing the messages, they are kept in memory when it is "memory"
+	StoragePath string
+	// AdminToken authorizes the /admin endpoints, they are disabled when empty
+	AdminToken string
+	// LLMBackends are the named LLM backends tenants can use instead of the default one at LLMUrl
+	LLMBackends []LLMBackend
+	Tenants     []Tenant
+	Personas    []Persona
+	Limits      Limits
+	Retention   Retention
+	OptOut      []OptOutLanguage
+	Recording   Recording
+	DeadLetters DeadLetters
+	Outbox      Outbox
+	Processing  Processing
+}
+
+// LLMBackend is a named LLM endpoint
+type LLMBackend struct {
+	Name        string
+	URL         string
+	BearerToken string
+}
+
+// Tenant holds the settings of one WhatsApp business phone number
+type Tenant struct {
+	PhoneNumberID string
+	Name          string
+	// Persona is the name of the persona answering for this number, none when empty
+	Persona string
+	// LLMBackend is the name of the backend answering for this number, the default one when empty
+	LLMBackend string
+	// Allowlist restricts the auto-replies to these wa_ids, everybody is answered when empty
+	Allowlist []string
+	// Retention is how long the messages of this number are kept, Retention.MaxAge when 0
+	Retention time.Duration
+}
+
+// Persona is a system prompt given to the LLM before the message of the contact
+type Persona struct {
+	Name   string
+	Prompt string
+}
+
+// Limits bounds the work done for each contact
+type Limits struct {
+	// MaxHistory is the number of messages kept per conversation
+	MaxHistory int
+	// RateLimitPerMinute is the number of auto-replies per contact and minute, unlimited when 0
+	RateLimitPerMinute int
+}
+
+// Retention bounds how long the messages and conversations are kept
+type Retention struct {
+	// MaxAge is the age past which the data of the tenants without their own retention is deleted, never when 0
+	MaxAge time.Duration
+	// Interval is the time between two runs of the retention job
+	Interval time.Duration
+	// DryRun only logs what the retention job would delete
+	DryRun bool
+}
+
+// Recording saves the webhooks received to replay them later
+type Recording struct {
+	// Dir is the directory of the JSONL recording files, nothing is recorded when empty
+	Dir string
+	// MaxFileSize is the size in bytes past which a new recording file is started
+	MaxFileSize int64
+	// MaskPII masks the phone numbers and message content of the recorded bodies
+	MaskPII bool
+}
+
+// DeadLetters retries the messages that failed to be answered because the LLM or the WhatsApp API failed
+type DeadLetters struct {
+	// RetrySchedule are the delays before each automatic retry, past them a message waits for an operator
+	RetrySchedule []time.Duration
+	// Interval is the time between two checks for the messages due to be retried
+	Interval time.Duration
 }
 
-// Load loads configuration from environment variables or an .env file
-func Load() Config {
+// Outbox sends again the outbound messages that failed to be sent for a temporary reason
+type Outbox struct {
+	// RetrySchedule are the delays before each new attempt, past them a message is given up on
+	RetrySchedule []time.Duration
+	// Interval is the time between two checks for the messages due to be sent
+	Interval time.Duration
+}
+
+// Processing bounds the inbound messages processed concurrently, one at a time per conversation
+type Processing struct {
+	// MaxPending is the number of messages waiting or being processed past which the webhooks wait
+	MaxPending int
+	// IdleTimeout is the time after which a conversation without messages to process frees its worker
+	IdleTimeout time.Duration
+	// DebounceWindow is the time a text message waits for the next ones of the contact, to answer them with a
+	// single prompt. Messages are answered at once when 0.
+	DebounceWindow time.Duration
+	// DebounceMaxWait is the longest a message waits for the next ones
+	DebounceMaxWait time.Duration
+}
+
+// OptOutLanguage are the keywords contacts opt out and back in with in a language, and the replies confirming them
+type OptOutLanguage struct {
+	Language    string
+	OptOut      []string
+	OptIn       []string
+	OptOutReply string
+	OptInReply  string
+}
+
+// Default returns the configuration used when neither a file nor the environment set a value
+func Default() Config {
+	return Config{
+		ServerPort:      "8080",
+		WhatsAppBaseURL: "https://graph.facebook.com/v20.0",
+		LLMUrl:          "http://localhost:8081/api/v1/chat/ask",
+		CommandPrefixes: []string{"/"},
+		TracingExporter: "none",
+		LogLevel:        "info",
+		ReadTimeout:     15 * time.Second,
+		WriteTimeout:    90 * time.Second,
+		IdleTimeout:     120 * time.Second,
+		ShutdownTimeout: 30 * time.Second,
+		StoragePath:     "anyzzapp.db",
+		Limits: Limits{
+			MaxHistory: 100,
+		},
+		Retention: Retention{
+			Interval: time.Hour,
+		},
+		Recording: Recording{
+			MaxFileSize: 10 << 20,
+			MaskPII:     true,
+		},
+		DeadLetters: DeadLetters{
+			RetrySchedule: []time.Duration{time.Minute, 5 * time.Minute, 30 * time.Minute, 2 * time.Hour},
+			Interval:      30 * time.Second,
+		},
+		Outbox: Outbox{
+			RetrySchedule: []time.Duration{5 * time.Second, 30 * time.Second, 2 * time.Minute, 10 * time.Minute, 30 * time.Minute},
+			Interval:      5 * time.Second,
+		},
+		Processing: Processing{
+			MaxPending:      1000,
+			IdleTimeout:     time.Minute,
+			DebounceMaxWait: 10 * time.Second,
+		},
+		OptOut: []OptOutLanguage{
+			{
+				Language:    "en",
+				OptOut:      []string{"STOP", "UNSUBSCRIBE"},
+				OptIn:       []string{"START", "SUBSCRIBE"},
+				OptOutReply: "You have been unsubscribed and won't get more messages. Send START to subscribe again.",
+				OptInReply:  "You have been subscribed again. Send STOP to unsubscribe.",
+			},
+			{
+				Language:    "es",
+				OptOut:      []string{"BAJA", "PARAR", "CANCELAR"},
+				OptIn:       []string{"ALTA"},
+				OptOutReply: "Te dimos de baja y no recibirás más mensajes. Envía ALTA para volver a suscribirte.",
+				OptInReply:  "Te suscribiste de nuevo. Envía BAJA para darte de baja.",
+			},
+			{
+				Language:    "pt",
+				OptOut:      []string{"SAIR", "PARE"},
+				OptIn:       []string{"VOLTAR"},
+				OptOutReply: "Você foi descadastrado e não receberá mais mensagens. Envie VOLTAR para se cadastrar novamente.",
+				OptInReply:  "Você foi cadastrado novamente. Envie SAIR para se descadastrar.",
+			},
+		},
+	}
+}
+
+// Load loads the configuration file named in CONFIG_FILE, if any, overridden by environment
+// variables or an .env file, and validates it
+func Load() (Config, error) {
+	return LoadFile("")
+}
+
+// LoadFile is like Load but reads the given configuration file instead of CONFIG_FILE when path is not empty
+func LoadFile(path string) (Config, error) {
 	// Load .env file if it exists (ignore error if file doesn't exist)
-	if err := godotenv.Load(); err != nil {
-		log.Printf("No .env file found or error loading .env file: %v", err)
+	envErr := godotenv.Load()
+
+	cfg := Default()
+	if path == "" {
+		path = os.Getenv("CONFIG_FILE")
 	}
-	setLogLevel()
-	return Config{
-		ServerPort:         getEnv("SERVER_PORT", "8080"),
-		WhatsAppAPIKey:     getEnv("WHATSAPP_API_KEY", ""),
-		WhatsAppBaseURL:    getEnv("WHATSAPP_BASE_URL", "https://graph.facebook.com/v20.0"),
-		WebhookVerifyToken: getEnv("WEBHOOK_VERIFY_TOKEN", ""),
-		LLMUrl:             getEnv("LLM_URL", "http://localhost:8081/api/v1/chat/ask"),
-		LLMBearerToken:     getEnv("LLM_BEARER_TOKEN", ""),
+	if path != "" {
+		if err := readFile(path, &cfg); err != nil {
+			return cfg, err
+		}
 	}
+	envProblems := applyEnv(&cfg)
+
+	setLogLevel(cfg.LogLevel)
+	if envErr != nil {
+		log.Info().Err(envErr).Msg("No .env file found or error loading .env file")
+	}
+	return cfg, cfg.validate(envProblems)
+}
+
+// applyEnv overrides the configuration with the environment variables that are set.
+// It returns a problem for every variable whose value can't be parsed, which keeps its value.
+func applyEnv(cfg *Config) []string {
+	var problems []string
+	cfg.ServerPort = getEnv("SERVER_PORT", cfg.ServerPort)
+	cfg.WhatsAppAPIKey = getEnv("WHATSAPP_API_KEY", cfg.WhatsAppAPIKey)
+	cfg.WhatsAppBaseURL = getEnv("WHATSAPP_BASE_URL", cfg.WhatsAppBaseURL)
+	cfg.WebhookVerifyToken = getEnv("WEBHOOK_VERIFY_TOKEN", cfg.WebhookVerifyToken)
+	cfg.LLMUrl = getEnv("LLM_URL", cfg.LLMUrl)
+	cfg.LLMBearerToken = getEnv("LLM_BEARER_TOKEN", cfg.LLMBearerToken)
+	cfg.CommandPrefixes = getEnvList("COMMAND_PREFIXES", cfg.CommandPrefixes)
+	cfg.TracingExporter = getEnv("TRACING_EXPORTER", cfg.TracingExporter)
+	cfg.LogLevel = getEnv("LOG_LEVEL", cfg.LogLevel)
+	cfg.LogDebugPII = getEnvBool("LOG_DEBUG_PII", cfg.LogDebugPII, &problems)
+	cfg.ReadTimeout = getEnvDuration("SERVER_READ_TIMEOUT", cfg.ReadTimeout, &problems)
+	cfg.WriteTimeout = getEnvDuration("SERVER_WRITE_TIMEOUT", cfg.WriteTimeout, &problems)
+	cfg.IdleTimeout = getEnvDuration("SERVER_IDLE_TIMEOUT", cfg.IdleTimeout, &problems)
+	cfg.ShutdownTimeout = getEnvDuration("SHUTDOWN_TIMEOUT", cfg.ShutdownTimeout, &problems)
+	cfg.AdminToken = getEnv("ADMIN_TOKEN", cfg.AdminToken)
+	cfg.StoragePath = getEnv("STORAGE_PATH", cfg.StoragePath)
+	cfg.Retention.MaxAge = getEnvDuration("RETENTION_MAX_AGE", cfg.Retention.MaxAge, &problems)
+	cfg.Retention.Interval = getEnvDuration("RETENTION_INTERVAL", cfg.Retention.Interval, &problems)
+	cfg.Retention.DryRun = getEnvBool("RETENTION_DRY_RUN", cfg.Retention.DryRun, &problems)
+	cfg.Recording.Dir = getEnv("RECORDING_DIR", cfg.Recording.Dir)
+	cfg.Recording.MaxFileSize = getEnvInt64("RECORDING_MAX_FILE_SIZE", cfg.Recording.MaxFileSize, &problems)
+	cfg.Recording.MaskPII = getEnvBool("RECORDING_MASK_PII", cfg.Recording.MaskPII, &problems)
+	cfg.DeadLetters.RetrySchedule = getEnvDurations("DEAD_LETTER_RETRY_SCHEDULE", cfg.DeadLetters.RetrySchedule, &problems)
+	cfg.DeadLetters.Interval = getEnvDuration("DEAD_LETTER_INTERVAL", cfg.DeadLetters.Interval, &problems)
+	cfg.Outbox.RetrySchedule = getEnvDurations("OUTBOX_RETRY_SCHEDULE", cfg.Outbox.RetrySchedule, &problems)
+	cfg.Outbox.Interval = getEnvDuration("OUTBOX_INTERVAL", cfg.Outbox.Interval, &problems)
+	cfg.Processing.MaxPending = int(getEnvInt64("PROCESSING_MAX_PENDING", int64(cfg.Processing.MaxPending), &problems))
+	cfg.Processing.IdleTimeout = getEnvDuration("PROCESSING_IDLE_TIMEOUT", cfg.Processing.IdleTimeout, &problems)
+	cfg.Processing.DebounceWindow = getEnvDuration("PROCESSING_DEBOUNCE_WINDOW", cfg.Processing.DebounceWindow, &problems)
+	cfg.Processing.DebounceMaxWait = getEnvDuration("PROCESSING_DEBOUNCE_MAX_WAIT", cfg.Processing.DebounceMaxWait, &problems)
+	return problems
 }
 
 // getEnv retrieves environment variable with a default value
@@ -43,8 +277,100 @@ func getEnv(key, defaultValue string) string {
 	return defaultValue
 }
 
-// setLogLevel sets the log level defined in LOG_LEVEL environment variable
-func setLogLevel() {
+// getEnvList retrieves a comma-separated environment variable with a default value
+func getEnvList(key string, defaultValue []string) []string {
+	value := os.Getenv(key)
+	if value == "" {
+		return defaultValue
+	}
+	var list []string
+	for _, item := range strings.Split(value, ",") {
+		if item = strings.TrimSpace(item); item != "" {
+			list = append(list, item)
+		}
+	}
+	if len(list) == 0 {
+		return defaultValue
+	}
+	return list
+}
+
+// getEnvBool retrieves a boolean environment variable with a default value
+func getEnvBool(key string, defaultValue bool, problems *[]string) bool {
+	raw := os.Getenv(key)
+	if raw == "" {
+		return defaultValue
+	}
+	value, err := strconv.ParseBool(raw)
+	if err != nil {
+		*problems = append(*problems, fmt.Sprintf("%s=%q must be true or false", key, raw))
+		return defaultValue
+	}
+	return value
+}
+
+// getEnvInt64 retrieves an integer environment variable with a default value
+func getEnvInt64(key string, defaultValue int64, problems *[]string) int64 {
+	raw := os.Getenv(key)
+	if raw == "" {
+		return defaultValue
+	}
+	value, err := strconv.ParseInt(raw, 10, 64)
+	if err != nil {
+		*problems = append(*problems, fmt.Sprintf("%s=%q must be an integer", key, raw))
+		return defaultValue
+	}
+	return value
+}
+
+// getEnvDuration retrieves a duration environment variable such as "30s" with a default value,
+// 0 and "none" turn the setting off
+func getEnvDuration(key string, defaultValue time.Duration, problems *[]string) time.Duration {
+	raw := os.Getenv(key)
+	if raw == "" {
+		return defaultValue
+	}
+	value, err := parseDuration(raw)
+	if err != nil {
+		*problems = append(*problems, fmt.Sprintf("%s=%q must be a duration such as 30s", key, raw))
+		return defaultValue
+	}
+	return value
+}
+
+// getEnvDurations retrieves a comma-separated list of durations with a default value,
+// "none" for an empty list. The default is kept when any duration is invalid.
+func getEnvDurations(key string, defaultValue []time.Duration, problems *[]string) []time.Duration {
+	raw := os.Getenv(key)
+	switch strings.TrimSpace(raw) {
+	case "":
+		return defaultValue
+	case "none":
+		return []time.Duration{}
+	}
+	var durations []time.Duration
+	for _, item := range strings.Split(raw, ",") {
+		duration, err := time.ParseDuration(strings.TrimSpace(item))
+		if err != nil || duration <= 0 {
+			*problems = append(*problems, fmt.Sprintf("%s=%q must be a comma-separated list of durations greater than 0", key, raw))
+			return defaultValue
+		}
+		durations = append(durations, duration)
+	}
+	return durations
+}
+
+// parseDuration parses a duration such as "30s", where "0" and "none" mean 0
+func parseDuration(value string) (time.Duration, error) {
+	value = strings.TrimSpace(value)
+	if value == "none" {
+		return 0, nil
+	}
+	return time.ParseDuration(value)
+}
+
+// setLogLevel sets the global log level, info when the level is unknown
+func setLogLevel(levelName string) {
 	levels := map[string]zerolog.Level{
 		"debug": zerolog.DebugLevel,
 		"info":  zerolog.InfoLevel,
@@ -53,9 +379,7 @@ func setLogLevel() {
 		"fatal": zerolog.FatalLevel,
 		"panic": zerolog.PanicLevel,
 	}
-	levelEnv := strings.ToLower(getEnv("LOG_LEVEL", "info"))
-
-	level, ok := levels[levelEnv]
+	level, ok := levels[strings.ToLower(levelName)]
 	if !ok {
 		level = zerolog.InfoLevel
 	}
@@ -2,9 +2,12 @@ package config
 
 import (
 	"os"
+	"path/filepath"
 	"testing"
+	"time"
 
 	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
 )
 
 func TestGetEnv(t *testing.T) {
@@ -126,3 +129,121 @@ func TestConfigStruct(t *testing.T) {
 	assert.Equal(t, "https://llm.test.com", config.LLMUrl)
 	assert.Equal(t, "bearer-token", config.LLMBearerToken)
 }
+
+func TestGetEnvList(t *testing.T) {
+	defaultValue := []string{"/"}
+
+	os.Unsetenv("TEST_LIST")
+	assert.Equal(t, defaultValue, getEnvList("TEST_LIST", defaultValue))
+
+	os.Setenv("TEST_LIST", " /, !, ,#")
+	defer os.Unsetenv("TEST_LIST")
+	assert.Equal(t, []string{"/", "!", "#"}, getEnvList("TEST_LIST", defaultValue))
+
+	os.Setenv("TEST_LIST", " , ")
+	assert.Equal(t, defaultValue, getEnvList("TEST_LIST", defaultValue))
+}
+
+func TestGetEnvBool(t *testing.T) {
+	var problems []string
+	os.Unsetenv("TEST_BOOL")
+	assert.False(t, getEnvBool("TEST_BOOL", false, &problems))
+
+	os.Setenv("TEST_BOOL", "true")
+	defer os.Unsetenv("TEST_BOOL")
+	assert.True(t, getEnvBool("TEST_BOOL", false, &problems))
+	assert.Empty(t, problems)
+
+	os.Setenv("TEST_BOOL", "not-a-bool")
+	assert.True(t, getEnvBool("TEST_BOOL", true, &problems))
+	assert.Equal(t, []string{`TEST_BOOL="not-a-bool" must be true or false`}, problems)
+}
+
+func TestGetEnvDuration(t *testing.T) {
+	var problems []string
+	os.Unsetenv("TEST_DURATION")
+	assert.Equal(t, 30*time.Second, getEnvDuration("TEST_DURATION", 30*time.Second, &problems))
+
+	os.Setenv("TEST_DURATION", "2m")
+	defer os.Unsetenv("TEST_DURATION")
+	assert.Equal(t, 2*time.Minute, getEnvDuration("TEST_DURATION", 30*time.Second, &problems))
+
+	// An explicit 0 turns the setting off, negative values are left to Validate
+	for _, off := range []string{"0", "0s", "none"} {
+		os.Setenv("TEST_DURATION", off)
+		assert.Zero(t, getEnvDuration("TEST_DURATION", 30*time.Second, &problems), off)
+	}
+	os.Setenv("TEST_DURATION", "-1s")
+	assert.Equal(t, -time.Second, getEnvDuration("TEST_DURATION", 30*time.Second, &problems))
+	assert.Empty(t, problems)
+
+	os.Setenv("TEST_DURATION", "abc")
+	assert.Equal(t, 30*time.Second, getEnvDuration("TEST_DURATION", 30*time.Second, &problems))
+	assert.Equal(t, []string{`TEST_DURATION="abc" must be a duration such as 30s`}, problems)
+}
+
+func TestGetEnvInt64(t *testing.T) {
+	var problems []string
+	os.Unsetenv("TEST_INT64")
+	assert.Equal(t, int64(10), getEnvInt64("TEST_INT64", 10, &problems))
+
+	os.Setenv("TEST_INT64", "1048576")
+	defer os.Unsetenv("TEST_INT64")
+	assert.Equal(t, int64(1048576), getEnvInt64("TEST_INT64", 10, &problems))
+	assert.Empty(t, problems)
+
+	os.Setenv("TEST_INT64", "1MB")
+	assert.Equal(t, int64(10), getEnvInt64("TEST_INT64", 10, &problems))
+	assert.Equal(t, []string{`TEST_INT64="1MB" must be an integer`}, problems)
+}
+
+func TestGetEnvDurations(t *testing.T) {
+	var problems []string
+	defaults := []time.Duration{time.Minute}
+	os.Unsetenv("TEST_DURATIONS")
+	assert.Equal(t, defaults, getEnvDurations("TEST_DURATIONS", defaults, &problems))
+
+	os.Setenv("TEST_DURATIONS", "30s, 5m")
+	defer os.Unsetenv("TEST_DURATIONS")
+	assert.Equal(t, []time.Duration{30 * time.Second, 5 * time.Minute}, getEnvDurations("TEST_DURATIONS", defaults, &problems))
+
+	os.Setenv("TEST_DURATIONS", "none")
+	assert.Empty(t, getEnvDurations("TEST_DURATIONS", defaults, &problems))
+	assert.Empty(t, problems)
+
+	os.Setenv("TEST_DURATIONS", "30s,soon")
+	assert.Equal(t, defaults, getEnvDurations("TEST_DURATIONS", defaults, &problems))
+	assert.Equal(t, []string{`TEST_DURATIONS="30s,soon" must be a comma-separated list of durations greater than 0`}, problems)
+}
+
+func TestLoadFile_ReportsInvalidEnvironmentValues(t *testing.T) {
+	t.Setenv("WHATSAPP_API_KEY", "key")
+	t.Setenv("WEBHOOK_VERIFY_TOKEN", "token")
+	t.Setenv("SERVER_READ_TIMEOUT", "abc")
+	t.Setenv("PROCESSING_MAX_PENDING", "lots")
+	t.Setenv("RETENTION_DRY_RUN", "yes")
+
+	_, err := LoadFile("")
+
+	var invalid *ValidationError
+	require.ErrorAs(t, err, &invalid)
+	assert.Equal(t, []string{
+		`SERVER_READ_TIMEOUT="abc" must be a duration such as 30s`,
+		`RETENTION_DRY_RUN="yes" must be true or false`,
+		`PROCESSING_MAX_PENDING="lots" must be an integer`,
+	}, invalid.Problems)
+}
+
+func TestLoadFile_ExplicitZeroTurnsDurationsOff(t *testing.T) {
+	t.Setenv("WHATSAPP_API_KEY", "key")
+	t.Setenv("WEBHOOK_VERIFY_TOKEN", "token")
+	t.Setenv("PROCESSING_DEBOUNCE_WINDOW", "none")
+	path := filepath.Join(t.TempDir(), "config.yaml")
+	require.NoError(t, os.WriteFile(path, []byte("processing:\n  debounce_window: 2s\n  debounce_max_wait: 0\n"), 0o600))
+
+	cfg, err := LoadFile(path)
+
+	require.NoError(t, err)
+	assert.Zero(t, cfg.Processing.DebounceWindow)
+	assert.Zero(t, cfg.Processing.DebounceMaxWait)
+}
@@ -0,0 +1,220 @@
+package config
+
+import (
+	"bytes"
+	"errors"
+	"fmt"
+	"io"
+	"os"
+	"time"
+
+	"gopkg.in/yaml.v3"
+)
+
+// file is the schema of the YAML configuration file, see config.example.yaml
+type file struct {
+	Server struct {
+		Port            string    `yaml:"port"`
+		ReadTimeout     *duration `yaml:"read_timeout"`
+		WriteTimeout    *duration `yaml:"write_timeout"`
+		IdleTimeout     *duration `yaml:"idle_timeout"`
+		ShutdownTimeout *duration `yaml:"shutdown_timeout"`
+	} `yaml:"server"`
+	WhatsApp struct {
+		APIKey             string `yaml:"api_key"`
+		BaseURL            string `yaml:"base_url"`
+		WebhookVerifyToken string `yaml:"webhook_verify_token"`
+	} `yaml:"whatsapp"`
+	Admin struct {
+		Token string `yaml:"token"`
+	} `yaml:"admin"`
+	Storage struct {
+		Path string `yaml:"path"`
+	} `yaml:"storage"`
+	LLM struct {
+		URL         string `yaml:"url"`
+		BearerToken string `yaml:"bearer_token"`
+		Backends    []struct {
+			Name        string `yaml:"name"`
+			URL         string `yaml:"url"`
+			BearerToken string `yaml:"bearer_token"`
+		} `yaml:"backends"`
+	} `yaml:"llm"`
+	Tenants []struct {
+		PhoneNumberID string        `yaml:"phone_number_id"`
+		Name          string        `yaml:"name"`
+		Persona       string        `yaml:"persona"`
+		LLMBackend    string        `yaml:"llm_backend"`
+		Allowlist     []string      `yaml:"allowlist"`
+		Retention     time.Duration `yaml:"retention"`
+	} `yaml:"tenants"`
+	Personas []struct {
+		Name   string `yaml:"name"`
+		Prompt string `yaml:"prompt"`
+	} `yaml:"personas"`
+	Limits struct {
+		MaxHistory         *int `yaml:"max_history"`
+		RateLimitPerMinute *int `yaml:"rate_limit_per_minute"`
+	} `yaml:"limits"`
+	Retention struct {
+		MaxAge   *duration `yaml:"max_age"`
+		Interval *duration `yaml:"interval"`
+		DryRun   *bool     `yaml:"dry_run"`
+	} `yaml:"retention"`
+	Recording struct {
+		Dir         string `yaml:"dir"`
+		MaxFileSize *int64 `yaml:"max_file_size"`
+		MaskPII     *bool  `yaml:"mask_pii"`
+	} `yaml:"recording"`
+	DeadLetters struct {
+		RetrySchedule []time.Duration `yaml:"retry_schedule"`
+		Interval      *duration       `yaml:"interval"`
+	} `yaml:"dead_letters"`
+	Outbox struct {
+		RetrySchedule []time.Duration `yaml:"retry_schedule"`
+		Interval      *duration       `yaml:"interval"`
+	} `yaml:"outbox"`
+	Processing struct {
+		MaxPending      *int      `yaml:"max_pending"`
+		IdleTimeout     *duration `yaml:"idle_timeout"`
+		DebounceWindow  *duration `yaml:"debounce_window"`
+		DebounceMaxWait *duration `yaml:"debounce_max_wait"`
+	} `yaml:"processing"`
+	OptOut []struct {
+		Language    string   `yaml:"language"`
+		OptOut      []string `yaml:"opt_out"`
+		OptIn       []string `yaml:"opt_in"`
+		OptOutReply string   `yaml:"opt_out_reply"`
+		OptInReply  string   `yaml:"opt_in_reply"`
+	} `yaml:"opt_out"`
+	Commands struct {
+		Prefixes []string `yaml:"prefixes"`
+	} `yaml:"commands"`
+	Logging struct {
+		Level    string `yaml:"level"`
+		DebugPII *bool  `yaml:"debug_pii"`
+	} `yaml:"logging"`
+	Tracing struct {
+		Exporter string `yaml:"exporter"`
+	} `yaml:"tracing"`
+}
+
+// readFile reads the YAML configuration file at path over cfg
+func readFile(path string, cfg *Config) error {
+	data, err := os.ReadFile(path)
+	if err != nil {
+		return fmt.Errorf("failed to read config file: %w", err)
+	}
+	if err := decode(data, cfg); err != nil {
+		return fmt.Errorf("failed to parse config file %s: %w", path, err)
+	}
+	return nil
+}
+
+// decode sets the values present in the YAML document on cfg, unknown keys are rejected to catch typos
+func decode(data []byte, cfg *Config) error {
+	var f file
+	decoder := yaml.NewDecoder(bytes.NewReader(data))
+	decoder.KnownFields(true)
+	if err := decoder.Decode(&f); err != nil && !errors.Is(err, io.EOF) {
+		return err
+	}
+
+	setString(&cfg.ServerPort, f.Server.Port)
+	setDuration(&cfg.ReadTimeout, f.Server.ReadTimeout)
+	setDuration(&cfg.WriteTimeout, f.Server.WriteTimeout)
+	setDuration(&cfg.IdleTimeout, f.Server.IdleTimeout)
+	setDuration(&cfg.ShutdownTimeout, f.Server.ShutdownTimeout)
+	setString(&cfg.WhatsAppAPIKey, f.WhatsApp.APIKey)
+	setString(&cfg.WhatsAppBaseURL, f.WhatsApp.BaseURL)
+	setString(&cfg.WebhookVerifyToken, f.WhatsApp.WebhookVerifyToken)
+	setString(&cfg.AdminToken, f.Admin.Token)
+	setString(&cfg.StoragePath, f.Storage.Path)
+	setString(&cfg.LLMUrl, f.LLM.URL)
+	setString(&cfg.LLMBearerToken, f.LLM.BearerToken)
+	setString(&cfg.TracingExporter, f.Tracing.Exporter)
+	setString(&cfg.LogLevel, f.Logging.Level)
+	if f.Logging.DebugPII != nil {
+		cfg.LogDebugPII = *f.Logging.DebugPII
+	}
+	setDuration(&cfg.Retention.MaxAge, f.Retention.MaxAge)
+	setDuration(&cfg.Retention.Interval, f.Retention.Interval)
+	if f.Retention.DryRun != nil {
+		cfg.Retention.DryRun = *f.Retention.DryRun
+	}
+	setString(&cfg.Recording.Dir, f.Recording.Dir)
+	if f.Recording.MaxFileSize != nil {
+		cfg.Recording.MaxFileSize = *f.Recording.MaxFileSize
+	}
+	if f.Recording.MaskPII != nil {
+		cfg.Recording.MaskPII = *f.Recording.MaskPII
+	}
+	// An empty schedule disables the automatic retries
+	if f.DeadLetters.RetrySchedule != nil {
+		cfg.DeadLetters.RetrySchedule = f.DeadLetters.RetrySchedule
+	}
+	setDuration(&cfg.DeadLetters.Interval, f.DeadLetters.Interval)
+	// An empty schedule gives up on a message at its first failure
+	if f.Outbox.RetrySchedule != nil {
+		cfg.Outbox.RetrySchedule = f.Outbox.RetrySchedule
+	}
+	setDuration(&cfg.Outbox.Interval, f.Outbox.Interval)
+	if f.Processing.MaxPending != nil {
+		cfg.Processing.MaxPending = *f.Processing.MaxPending
+	}
+	setDuration(&cfg.Processing.IdleTimeout, f.Processing.IdleTimeout)
+	setDuration(&cfg.Processing.DebounceWindow, f.Processing.DebounceWindow)
+	setDuration(&cfg.Processing.DebounceMaxWait, f.Processing.DebounceMaxWait)
+	if len(f.Commands.Prefixes) > 0 {
+		cfg.CommandPrefixes = f.Commands.Prefixes
+	}
+	if f.Limits.MaxHistory != nil {
+		cfg.Limits.MaxHistory = *f.Limits.MaxHistory
+	}
+	if f.Limits.RateLimitPerMinute != nil {
+		cfg.Limits.RateLimitPerMinute = *f.Limits.RateLimitPerMinute
+	}
+	for _, backend := range f.LLM.Backends {
+		cfg.LLMBackends = append(cfg.LLMBackends, LLMBackend(backend))
+	}
+	for _, tenant := range f.Tenants {
+		cfg.Tenants = append(cfg.Tenants, Tenant(tenant))
+	}
+	for _, persona := range f.Personas {
+		cfg.Personas = append(cfg.Personas, Persona(persona))
+	}
+	// The keywords of the file replace the default ones
+	if len(f.OptOut) > 0 {
+		cfg.OptOut = nil
+		for _, language := range f.OptOut {
+			cfg.OptOut = append(cfg.OptOut, OptOutLanguage(language))
+		}
+	}
+	return nil
+}
+
+// setString replaces the value when the file sets one
+func setString(value *string, fromFile string) {
+	if fromFile != "" {
+		*value = fromFile
+	}
+}
+
+// setDuration replaces the value when the file sets one, 0 included
+func setDuration(value *time.Duration, fromFile *duration) {
+	if fromFile != nil {
+		*value = time.Duration(*fromFile)
+	}
+}
+
+// duration is a duration of the file such as "30s", where 0 and "none" turn the setting off
+type duration time.Duration
+
+func (d *duration) UnmarshalYAML(node *yaml.Node) error {
+	value, err := parseDuration(node.Value)
+	if err != nil {
+		return fmt.Errorf("line %d: %w", node.Line, err)
+	}
+	*d = duration(value)
+	return nil
+}
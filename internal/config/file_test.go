@@ -0,0 +1,146 @@
+package config
+
+import (
+	"os"
+	"path/filepath"
+	"testing"
+	"time"
+
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+func TestDecode_ExampleFile(t *testing.T) {
+	data, err := os.ReadFile("../../config.example.yaml")
+	require.NoError(t, err)
+
+	cfg := Default()
+	require.NoError(t, decode(data, &cfg))
+
+	assert.NoError(t, cfg.Validate())
+	assert.Equal(t, "your_whatsapp_api_key_here", cfg.WhatsAppAPIKey)
+	assert.Equal(t, 90*time.Second, cfg.WriteTimeout)
+	assert.Equal(t, "anyzzapp.db", cfg.StoragePath)
+	assert.Equal(t, []LLMBackend{{Name: "support-llm", URL: "http://localhost:8082/api/v1/chat/ask"}}, cfg.LLMBackends)
+	assert.Equal(t, []Tenant{{
+		PhoneNumberID: "123456789",
+		Name:          "Store",
+		Persona:       "store",
+		LLMBackend:    "support-llm",
+		Allowlist:     []string{},
+	}}, cfg.Tenants)
+	assert.Len(t, cfg.Personas, 1)
+	assert.Equal(t, Limits{MaxHistory: 100}, cfg.Limits)
+	assert.Equal(t, Retention{Interval: time.Hour}, cfg.Retention)
+}
+
+func TestDecode_KeepsDefaultsForMissingValues(t *testing.T) {
+	cfg := Default()
+	require.NoError(t, decode([]byte("whatsapp:\n  api_key: key\n"), &cfg))
+
+	assert.Equal(t, "key", cfg.WhatsAppAPIKey)
+	assert.Equal(t, "8080", cfg.ServerPort)
+	assert.Equal(t, 100, cfg.Limits.MaxHistory)
+	assert.Equal(t, []string{"/"}, cfg.CommandPrefixes)
+}
+
+func TestDecode_Retention(t *testing.T) {
+	cfg := Default()
+	data := "retention:\n  max_age: 720h\n  dry_run: true\ntenants:\n  - phone_number_id: \"123\"\n    retention: 48h\n"
+	require.NoError(t, decode([]byte(data), &cfg))
+
+	assert.Equal(t, Retention{MaxAge: 720 * time.Hour, Interval: time.Hour, DryRun: true}, cfg.Retention)
+	assert.Equal(t, 48*time.Hour, cfg.Tenants[0].Retention)
+}
+
+func TestDecode_Recording(t *testing.T) {
+	cfg := Default()
+	data := "recording:\n  dir: recordings\n  max_file_size: 1048576\n  mask_pii: false\n"
+	require.NoError(t, decode([]byte(data), &cfg))
+
+	assert.Equal(t, Recording{Dir: "recordings", MaxFileSize: 1 << 20, MaskPII: false}, cfg.Recording)
+}
+
+func TestDecode_DeadLetters(t *testing.T) {
+	cfg := Default()
+	data := "dead_letters:\n  retry_schedule: [30s, 10m]\n  interval: 5s\n"
+	require.NoError(t, decode([]byte(data), &cfg))
+
+	assert.Equal(t, DeadLetters{RetrySchedule: []time.Duration{30 * time.Second, 10 * time.Minute}, Interval: 5 * time.Second}, cfg.DeadLetters)
+
+	// An empty schedule leaves the retries to the operators
+	require.NoError(t, decode([]byte("dead_letters:\n  retry_schedule: []\n"), &cfg))
+	assert.Empty(t, cfg.DeadLetters.RetrySchedule)
+	require.NoError(t, decode([]byte("dead_letters:\n  interval: 1m\n"), &cfg))
+	assert.Empty(t, cfg.DeadLetters.RetrySchedule)
+}
+
+func TestDecode_Outbox(t *testing.T) {
+	cfg := Default()
+	data := "outbox:\n  retry_schedule: [1s, 1m]\n  interval: 2s\n"
+	require.NoError(t, decode([]byte(data), &cfg))
+
+	assert.Equal(t, Outbox{RetrySchedule: []time.Duration{time.Second, time.Minute}, Interval: 2 * time.Second}, cfg.Outbox)
+
+	// An empty schedule gives up on a message at its first failure
+	require.NoError(t, decode([]byte("outbox:\n  retry_schedule: []\n"), &cfg))
+	assert.Empty(t, cfg.Outbox.RetrySchedule)
+}
+
+func TestDecode_Processing(t *testing.T) {
+	cfg := Default()
+	data := "processing:\n  max_pending: 50\n  idle_timeout: 10s\n  debounce_window: 2s\n  debounce_max_wait: 8s\n"
+	require.NoError(t, decode([]byte(data), &cfg))
+
+	assert.Equal(t, Processing{MaxPending: 50, IdleTimeout: 10 * time.Second, DebounceWindow: 2 * time.Second,
+		DebounceMaxWait: 8 * time.Second}, cfg.Processing)
+}
+
+func TestDecode_OptOutReplacesDefaults(t *testing.T) {
+	cfg := Default()
+	data := "opt_out:\n  - language: fr\n    opt_out: [ARRET]\n    opt_in: [DEBUT]\n    opt_out_reply: Vous êtes désinscrit.\n"
+	require.NoError(t, decode([]byte(data), &cfg))
+
+	assert.Equal(t, []OptOutLanguage{{
+		Language:    "fr",
+		OptOut:      []string{"ARRET"},
+		OptIn:       []string{"DEBUT"},
+		OptOutReply: "Vous êtes désinscrit.",
+	}}, cfg.OptOut)
+}
+
+func TestDecode_Empty(t *testing.T) {
+	cfg := Default()
+
+	assert.NoError(t, decode(nil, &cfg))
+	assert.Equal(t, Default(), cfg)
+}
+
+func TestDecode_UnknownKey(t *testing.T) {
+	cfg := Default()
+
+	err := decode([]byte("whatsapp:\n  apikey: key\n"), &cfg)
+
+	assert.Error(t, err)
+	assert.Contains(t, err.Error(), "apikey")
+}
+
+func TestLoadFile_EnvironmentOverridesFile(t *testing.T) {
+	path := filepath.Join(t.TempDir(), "config.yaml")
+	content := "server:\n  port: \"9000\"\nwhatsapp:\n  api_key: from-file\n  webhook_verify_token: token\n"
+	require.NoError(t, os.WriteFile(path, []byte(content), 0o600))
+	t.Setenv("WHATSAPP_API_KEY", "from-env")
+
+	cfg, err := LoadFile(path)
+
+	assert.NoError(t, err)
+	assert.Equal(t, "9000", cfg.ServerPort)
+	assert.Equal(t, "from-env", cfg.WhatsAppAPIKey)
+}
+
+func TestLoadFile_MissingFile(t *testing.T) {
+	_, err := LoadFile(filepath.Join(t.TempDir(), "missing.yaml"))
+
+	assert.Error(t, err)
+	assert.Contains(t, err.Error(), "failed to read config file")
+}
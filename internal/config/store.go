@@ -0,0 +1,231 @@
+package config
+
+import (
+	"anyzzapp/pkg/domain"
+	"anyzzapp/pkg/logging"
+	"crypto/sha256"
+	"encoding/hex"
+	"fmt"
+	"sort"
+	"strconv"
+	"strings"
+	"sync"
+	"sync/atomic"
+	"time"
+
+	"github.com/rs/zerolog/log"
+)
+
+// snapshot is an immutable configuration with the tenant settings resolved for the hot path
+type snapshot struct {
+	config  Config
+	tenants map[string]domain.TenantSettings
+}
+
+// Store holds the current configuration and swaps it atomically when it is reloaded.
+// Only the tenants, personas, limits and logging settings change on reload, the rest
+// (port, timeouts, credentials, LLM backends...) needs a restart.
+type Store struct {
+	path    string
+	current atomic.Pointer[snapshot]
+	// reloads serializes the reloads, the readers never wait
+	reloads sync.Mutex
+	load    func(path string) (Config, error)
+}
+
+// NewStore creates a new instance of Store serving cfg, reloading from the file at path
+// (CONFIG_FILE when empty) and the environment
+func NewStore(cfg Config, path string) *Store {
+	s := &Store{path: path, load: LoadFile}
+	s.current.Store(newSnapshot(cfg))
+	return s
+}
+
+// Current returns the configuration in use
+func (s *Store) Current() Config {
+	return s.current.Load().config
+}
+
+// TenantSettings implements domain.SettingsProvider
+func (s *Store) TenantSettings(phoneNumberID string) domain.TenantSettings {
+	current := s.current.Load()
+	if settings, ok := current.tenants[phoneNumberID]; ok {
+		return settings
+	}
+	return domain.TenantSettings{
+		RateLimitPerMinute: current.config.Limits.RateLimitPerMinute,
+		MaxHistory:         current.config.Limits.MaxHistory,
+		Retention:          current.config.Retention.MaxAge,
+	}
+}
+
+// Reload loads and validates the configuration again and applies what can change at runtime.
+// The configuration in use is kept when the new one is invalid. It returns the applied changes.
+func (s *Store) Reload() ([]string, error) {
+	s.reloads.Lock()
+	defer s.reloads.Unlock()
+
+	old := s.Current()
+	loaded, err := s.load(s.path)
+	if err != nil {
+		// Loading sets the log level of the rejected configuration
+		setLogLevel(old.LogLevel)
+		return nil, err
+	}
+	next := old
+	next.Tenants = loaded.Tenants
+	next.Personas = loaded.Personas
+	next.Limits = loaded.Limits
+	next.Retention.MaxAge = loaded.Retention.MaxAge
+	next.LogLevel = loaded.LogLevel
+	next.LogDebugPII = loaded.LogDebugPII
+	// The tenants may only use the LLM backends created at startup
+	if err := next.Validate(); err != nil {
+		setLogLevel(old.LogLevel)
+		return nil, err
+	}
+
+	if ignored := diff(restartSettings(old), restartSettings(loaded)); len(ignored) > 0 {
+		log.Warn().Strs("changes", ignored).Msg("Configuration changes ignored until the next restart")
+	}
+	changes := diff(runtimeSettings(old), runtimeSettings(next))
+	s.current.Store(newSnapshot(next))
+	setLogLevel(next.LogLevel)
+	logging.SetDebugPII(next.LogDebugPII)
+
+	log.Info().Strs("changes", changes).Msg("Configuration reloaded")
+	return changes, nil
+}
+
+// newSnapshot resolves the settings of every tenant of the configuration
+func newSnapshot(cfg Config) *snapshot {
+	personas := make(map[string]string, len(cfg.Personas))
+	for _, persona := range cfg.Personas {
+		personas[persona.Name] = persona.Prompt
+	}
+	tenants := make(map[string]domain.TenantSettings, len(cfg.Tenants))
+	for _, tenant := range cfg.Tenants {
+		tenants[tenant.PhoneNumberID] = domain.TenantSettings{
+			Persona:            personas[tenant.Persona],
+			LLMBackend:         tenant.LLMBackend,
+			Allowlist:          tenant.Allowlist,
+			RateLimitPerMinute: cfg.Limits.RateLimitPerMinute,
+			MaxHistory:         cfg.Limits.MaxHistory,
+			Retention:          cfg.Retention.MaxAge,
+		}
+		if tenant.Retention > 0 {
+			settings := tenants[tenant.PhoneNumberID]
+			settings.Retention = tenant.Retention
+			tenants[tenant.PhoneNumberID] = settings
+		}
+	}
+	return &snapshot{config: cfg, tenants: tenants}
+}
+
+// runtimeSettings flattens the settings applied on reload, without personal data
+func runtimeSettings(cfg Config) map[string]string {
+	settings := map[string]string{
+		"logging.level":                cfg.LogLevel,
+		"logging.debug_pii":            strconv.FormatBool(cfg.LogDebugPII),
+		"limits.max_history":           strconv.Itoa(cfg.Limits.MaxHistory),
+		"limits.rate_limit_per_minute": strconv.Itoa(cfg.Limits.RateLimitPerMinute),
+		"retention.max_age":            cfg.Retention.MaxAge.String(),
+	}
+	for _, persona := range cfg.Personas {
+		settings["personas."+persona.Name+".prompt"] = fingerprint(persona.Prompt)
+	}
+	for _, tenant := range cfg.Tenants {
+		prefix := "tenants." + tenant.PhoneNumberID + "."
+		settings[prefix+"name"] = tenant.Name
+		settings[prefix+"persona"] = tenant.Persona
+		settings[prefix+"llm_backend"] = tenant.LLMBackend
+		settings[prefix+"allowlist"] = fingerprint(strings.Join(tenant.Allowlist, ","))
+		settings[prefix+"retention"] = tenant.Retention.String()
+	}
+	return settings
+}
+
+// restartSettings flattens the settings that only apply on restart, without secrets
+func restartSettings(cfg Config) map[string]string {
+	settings := map[string]string{
+		"server.port":                   cfg.ServerPort,
+		"server.read_timeout":           cfg.ReadTimeout.String(),
+		"server.write_timeout":          cfg.WriteTimeout.String(),
+		"server.idle_timeout":           cfg.IdleTimeout.String(),
+		"server.shutdown_timeout":       cfg.ShutdownTimeout.String(),
+		"whatsapp.api_key":              fingerprint(cfg.WhatsAppAPIKey),
+		"whatsapp.base_url":             cfg.WhatsAppBaseURL,
+		"whatsapp.webhook_verify_token": fingerprint(cfg.WebhookVerifyToken),
+		"admin.token":                   fingerprint(cfg.AdminToken),
+		"storage.path":                  cfg.StoragePath,
+		"retention.interval":            cfg.Retention.Interval.String(),
+		"retention.dry_run":             strconv.FormatBool(cfg.Retention.DryRun),
+		"recording.dir":                 cfg.Recording.Dir,
+		"recording.max_file_size":       strconv.FormatInt(cfg.Recording.MaxFileSize, 10),
+		"recording.mask_pii":            strconv.FormatBool(cfg.Recording.MaskPII),
+		"dead_letters.retry_schedule":   durations(cfg.DeadLetters.RetrySchedule),
+		"dead_letters.interval":         cfg.DeadLetters.Interval.String(),
+		"outbox.retry_schedule":         durations(cfg.Outbox.RetrySchedule),
+		"outbox.interval":               cfg.Outbox.Interval.String(),
+		"processing.max_pending":        strconv.Itoa(cfg.Processing.MaxPending),
+		"processing.idle_timeout":       cfg.Processing.IdleTimeout.String(),
+		"processing.debounce_window":    cfg.Processing.DebounceWindow.String(),
+		"processing.debounce_max_wait":  cfg.Processing.DebounceMaxWait.String(),
+		"llm.url":                       cfg.LLMUrl,
+		"llm.bearer_token":              fingerprint(cfg.LLMBearerToken),
+		"commands.prefixes":             strings.Join(cfg.CommandPrefixes, " "),
+		"tracing.exporter":              cfg.TracingExporter,
+	}
+	for _, backend := range cfg.LLMBackends {
+		prefix := "llm.backends." + backend.Name + "."
+		settings[prefix+"url"] = backend.URL
+		settings[prefix+"bearer_token"] = fingerprint(backend.BearerToken)
+	}
+	for _, language := range cfg.OptOut {
+		prefix := "opt_out." + language.Language + "."
+		settings[prefix+"opt_out"] = strings.Join(language.OptOut, " ")
+		settings[prefix+"opt_in"] = strings.Join(language.OptIn, " ")
+		settings[prefix+"opt_out_reply"] = language.OptOutReply
+		settings[prefix+"opt_in_reply"] = language.OptInReply
+	}
+	return settings
+}
+
+// diff describes the settings that differ, sorted by name
+func diff(old, next map[string]string) []string {
+	var changes []string
+	for name, value := range next {
+		previous, existed := old[name]
+		switch {
+		case !existed:
+			changes = append(changes, fmt.Sprintf("%s: added %q", name, value))
+		case previous != value:
+			changes = append(changes, fmt.Sprintf("%s: %q -> %q", name, previous, value))
+		}
+	}
+	for name := range old {
+		if _, exists := next[name]; !exists {
+			changes = append(changes, fmt.Sprintf("%s: removed", name))
+		}
+	}
+	sort.Strings(changes)
+	return changes
+}
+
+// fingerprint identifies a value that must not be logged, so that a change is still visible
+func fingerprint(value string) string {
+	if value == "" {
+		return ""
+	}
+	sum := sha256.Sum256([]byte(value))
+	return "sha256:" + hex.EncodeToString(sum[:4])
+}
+
+// durations joins the durations with spaces
+func durations(values []time.Duration) string {
+	formatted := make([]string, len(values))
+	for i, value := range values {
+		formatted[i] = value.String()
+	}
+	return strings.Join(formatted, " ")
+}
@@ -0,0 +1,111 @@
+package config
+
+import (
+	"anyzzapp/pkg/domain"
+	"errors"
+	"testing"
+	"time"
+
+	"github.com/stretchr/testify/assert"
+)
+
+// storeLoading returns a store whose reloads return the given configuration
+func storeLoading(initial Config, loaded Config, loadErr error) *Store {
+	store := NewStore(initial, "")
+	store.load = func(path string) (Config, error) {
+		return loaded, loadErr
+	}
+	return store
+}
+
+func TestStore_TenantSettings(t *testing.T) {
+	cfg := validConfig()
+	cfg.Limits.RateLimitPerMinute = 5
+	cfg.Personas = []Persona{{Name: "store", Prompt: "You are a store."}}
+	cfg.Tenants = []Tenant{{PhoneNumberID: "123", Persona: "store", Allowlist: []string{"549"}}}
+	store := NewStore(cfg, "")
+
+	assert.Equal(t, domain.TenantSettings{
+		Persona:            "You are a store.",
+		Allowlist:          []string{"549"},
+		RateLimitPerMinute: 5,
+		MaxHistory:         100,
+	}, store.TenantSettings("123"))
+	assert.Equal(t, domain.TenantSettings{RateLimitPerMinute: 5, MaxHistory: 100}, store.TenantSettings("999"))
+}
+
+func TestStore_TenantSettings_Retention(t *testing.T) {
+	cfg := validConfig()
+	cfg.Retention.MaxAge = 720 * time.Hour
+	cfg.Tenants = []Tenant{{PhoneNumberID: "123", Retention: 48 * time.Hour}, {PhoneNumberID: "456"}}
+	store := NewStore(cfg, "")
+
+	assert.Equal(t, 48*time.Hour, store.TenantSettings("123").Retention)
+	assert.Equal(t, 720*time.Hour, store.TenantSettings("456").Retention)
+	assert.Equal(t, 720*time.Hour, store.TenantSettings("999").Retention)
+}
+
+func TestStore_Reload(t *testing.T) {
+	initial := validConfig()
+	loaded := validConfig()
+	loaded.Limits.RateLimitPerMinute = 10
+	loaded.Personas = []Persona{{Name: "store", Prompt: "You are a store."}}
+	loaded.Tenants = []Tenant{{PhoneNumberID: "123", Persona: "store"}}
+	loaded.ServerPort = "9090"
+	store := storeLoading(initial, loaded, nil)
+
+	changes, err := store.Reload()
+
+	assert.NoError(t, err)
+	assert.Contains(t, changes, `limits.rate_limit_per_minute: "0" -> "10"`)
+	assert.Contains(t, changes, `tenants.123.persona: added "store"`)
+	assert.Equal(t, "You are a store.", store.TenantSettings("123").Persona)
+	// The port needs a restart
+	assert.Equal(t, "8080", store.Current().ServerPort)
+}
+
+func TestStore_Reload_InvalidKeepsCurrent(t *testing.T) {
+	initial := validConfig()
+	initial.Limits.RateLimitPerMinute = 3
+	store := storeLoading(initial, Config{}, &ValidationError{Problems: []string{"bad"}})
+
+	_, err := store.Reload()
+
+	assert.Error(t, err)
+	assert.Equal(t, 3, store.Current().Limits.RateLimitPerMinute)
+}
+
+func TestStore_Reload_UnknownBackend(t *testing.T) {
+	loaded := validConfig()
+	loaded.LLMBackends = []LLMBackend{{Name: "new", URL: "http://new"}}
+	loaded.Tenants = []Tenant{{PhoneNumberID: "123", LLMBackend: "new"}}
+	store := storeLoading(validConfig(), loaded, nil)
+
+	_, err := store.Reload()
+
+	var invalid *ValidationError
+	assert.True(t, errors.As(err, &invalid))
+	assert.Contains(t, err.Error(), `llm_backend "new" is not defined`)
+	assert.Empty(t, store.Current().Tenants)
+}
+
+func TestDiff(t *testing.T) {
+	changes := diff(
+		map[string]string{"a": "1", "b": "2", "c": "3"},
+		map[string]string{"a": "1", "b": "20", "d": "4"},
+	)
+
+	assert.Equal(t, []string{`b: "2" -> "20"`, "c: removed", `d: added "4"`}, changes)
+}
+
+func TestRuntimeSettings_HidesSecrets(t *testing.T) {
+	cfg := validConfig()
+	cfg.Tenants = []Tenant{{PhoneNumberID: "123", Allowlist: []string{"5491112345678"}}}
+
+	for _, value := range runtimeSettings(cfg) {
+		assert.NotContains(t, value, "5491112345678")
+	}
+	for _, value := range restartSettings(cfg) {
+		assert.NotEqual(t, "key", value)
+	}
+}
@@ -0,0 +1,227 @@
+package config
+
+import (
+	"fmt"
+	"net/url"
+	"strconv"
+	"strings"
+)
+
+// minAdminTokenLength keeps the admin token from being guessed
+const minAdminTokenLength = 16
+
+// ValidationError lists every problem found in a configuration
+type ValidationError struct {
+	Problems []string
+}
+
+func (e *ValidationError) Error() string {
+	return "invalid configuration:\n  - " + strings.Join(e.Problems, "\n  - ")
+}
+
+// Validate checks the configuration and returns a *ValidationError listing all the problems, if any
+func (c Config) Validate() error {
+	return c.validate(nil)
+}
+
+// validate is like Validate, listing first the given problems found while loading the configuration
+func (c Config) validate(problems []string) error {
+	add := func(format string, args ...interface{}) {
+		problems = append(problems, fmt.Sprintf(format, args...))
+	}
+
+	if port, err := strconv.Atoi(c.ServerPort); err != nil || port < 1 || port > 65535 {
+		add("server port %q must be a number between 1 and 65535", c.ServerPort)
+	}
+	if c.ReadTimeout <= 0 || c.WriteTimeout <= 0 || c.IdleTimeout <= 0 || c.ShutdownTimeout <= 0 {
+		add("server timeouts must be greater than 0")
+	}
+	if c.WhatsAppAPIKey == "" {
+		add("WhatsApp API key is required (WHATSAPP_API_KEY or whatsapp.api_key)")
+	}
+	// An empty verify token would let anyone sending an empty token subscribe the webhook
+	if c.WebhookVerifyToken == "" {
+		add("webhook verify token is required (WEBHOOK_VERIFY_TOKEN or whatsapp.webhook_verify_token)")
+	}
+	if c.AdminToken != "" && len(c.AdminToken) < minAdminTokenLength {
+		add("admin token must have at least %d characters", minAdminTokenLength)
+	}
+	if strings.TrimSpace(c.StoragePath) == "" {
+		add("storage path is required, use %q to keep the messages in memory", StorageMemory)
+	}
+	if !validURL(c.WhatsAppBaseURL) {
+		add("WhatsApp base URL %q must be an absolute http(s) URL", c.WhatsAppBaseURL)
+	}
+	if !validURL(c.LLMUrl) {
+		add("LLM URL %q must be an absolute http(s) URL", c.LLMUrl)
+	}
+	if len(c.CommandPrefixes) == 0 {
+		add("at least one command prefix is required")
+	}
+	switch c.TracingExporter {
+	case "none", "stdout", "otlp":
+	default:
+		add("tracing exporter %q must be none, stdout or otlp", c.TracingExporter)
+	}
+	switch strings.ToLower(c.LogLevel) {
+	case "debug", "info", "warn", "error", "fatal", "panic":
+	default:
+		add("log level %q must be debug, info, warn or error", c.LogLevel)
+	}
+	if c.Limits.MaxHistory < 1 {
+		add("limits.max_history must be at least 1")
+	}
+	if c.Limits.RateLimitPerMinute < 0 {
+		add("limits.rate_limit_per_minute cannot be negative")
+	}
+	if c.Retention.MaxAge < 0 {
+		add("retention.max_age cannot be negative")
+	}
+	if c.Retention.Interval <= 0 {
+		add("retention.interval must be greater than 0")
+	}
+	if c.Recording.Dir != "" && c.Recording.MaxFileSize <= 0 {
+		add("recording.max_file_size must be greater than 0")
+	}
+	// Neither the erasure of a contact nor the retention reach the recordings, only masked ones can be kept
+	if c.Recording.Dir != "" && !c.Recording.MaskPII && (c.AdminToken != "" || c.retains()) {
+		add("recording.mask_pii must be true when the retention or the erasure (admin token) is configured, the recordings are not erased")
+	}
+	for i, delay := range c.DeadLetters.RetrySchedule {
+		if delay <= 0 {
+			add("dead_letters.retry_schedule[%d] must be greater than 0", i)
+		}
+	}
+	if c.DeadLetters.Interval <= 0 {
+		add("dead_letters.interval must be greater than 0")
+	}
+	for i, delay := range c.Outbox.RetrySchedule {
+		if delay <= 0 {
+			add("outbox.retry_schedule[%d] must be greater than 0", i)
+		}
+	}
+	if c.Outbox.Interval <= 0 {
+		add("outbox.interval must be greater than 0")
+	}
+	if c.Processing.MaxPending <= 0 {
+		add("processing.max_pending must be greater than 0")
+	}
+	if c.Processing.IdleTimeout <= 0 {
+		add("processing.idle_timeout must be greater than 0")
+	}
+	if c.Processing.DebounceWindow < 0 {
+		add("processing.debounce_window must not be negative")
+	}
+	if c.Processing.DebounceWindow > 0 && c.Processing.DebounceMaxWait < c.Processing.DebounceWindow {
+		add("processing.debounce_max_wait must not be shorter than processing.debounce_window")
+	}
+
+	backends := make(map[string]bool)
+	for i, backend := range c.LLMBackends {
+		switch {
+		case backend.Name == "":
+			add("llm.backends[%d]: name is required", i)
+		case backends[backend.Name]:
+			add("llm.backends[%d]: name %q is used twice", i, backend.Name)
+		}
+		backends[backend.Name] = true
+		if !validURL(backend.URL) {
+			add("llm.backends[%d]: URL %q must be an absolute http(s) URL", i, backend.URL)
+		}
+	}
+
+	personas := make(map[string]bool)
+	for i, persona := range c.Personas {
+		switch {
+		case persona.Name == "":
+			add("personas[%d]: name is required", i)
+		case personas[persona.Name]:
+			add("personas[%d]: name %q is used twice", i, persona.Name)
+		}
+		personas[persona.Name] = true
+		if strings.TrimSpace(persona.Prompt) == "" {
+			add("personas[%d]: prompt is required", i)
+		}
+	}
+
+	tenants := make(map[string]bool)
+	for i, tenant := range c.Tenants {
+		switch {
+		case tenant.PhoneNumberID == "":
+			add("tenants[%d]: phone_number_id is required", i)
+		case !digitsOnly(tenant.PhoneNumberID):
+			add("tenants[%d]: phone_number_id %q must contain only digits", i, tenant.PhoneNumberID)
+		case tenants[tenant.PhoneNumberID]:
+			add("tenants[%d]: phone_number_id %s is configured twice", i, tenant.PhoneNumberID)
+		}
+		tenants[tenant.PhoneNumberID] = true
+		if tenant.Persona != "" && !personas[tenant.Persona] {
+			add("tenants[%d]: persona %q is not defined", i, tenant.Persona)
+		}
+		if tenant.LLMBackend != "" && !backends[tenant.LLMBackend] {
+			add("tenants[%d]: llm_backend %q is not defined", i, tenant.LLMBackend)
+		}
+		if tenant.Retention < 0 {
+			add("tenants[%d]: retention cannot be negative", i)
+		}
+		for _, waID := range tenant.Allowlist {
+			if !digitsOnly(waID) {
+				add("tenants[%d]: allowlist entry %q must be a wa_id with only digits", i, waID)
+			}
+		}
+	}
+
+	keywords := make(map[string]bool)
+	for i, language := range c.OptOut {
+		if len(language.OptOut) == 0 {
+			add("opt_out[%d]: at least one opt_out keyword is required", i)
+		}
+		for _, keyword := range append(append([]string{}, language.OptOut...), language.OptIn...) {
+			normalized := strings.ToUpper(strings.TrimSpace(keyword))
+			switch {
+			case normalized == "":
+				add("opt_out[%d]: keywords cannot be empty", i)
+			case keywords[normalized]:
+				add("opt_out[%d]: keyword %q is used twice", i, keyword)
+			}
+			keywords[normalized] = true
+		}
+	}
+
+	if len(problems) > 0 {
+		return &ValidationError{Problems: problems}
+	}
+	return nil
+}
+
+// retains reports whether data is deleted past a retention, of every tenant or of one
+func (c Config) retains() bool {
+	if c.Retention.MaxAge > 0 {
+		return true
+	}
+	for _, tenant := range c.Tenants {
+		if tenant.Retention > 0 {
+			return true
+		}
+	}
+	return false
+}
+
+// validURL reports whether value is an absolute http or https URL
+func validURL(value string) bool {
+	parsed, err := url.Parse(value)
+	return err == nil && (parsed.Scheme == "http" || parsed.Scheme == "https") && parsed.Host != ""
+}
+
+// digitsOnly reports whether value is a non-empty string of digits
+func digitsOnly(value string) bool {
+	if value == "" {
+		return false
+	}
+	for _, r := range value {
+		if r < '0' || r > '9' {
+			return false
+		}
+	}
+	return true
+}
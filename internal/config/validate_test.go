@@ -0,0 +1,210 @@
+package config
+
+import (
+	"errors"
+	"testing"
+	"time"
+
+	"github.com/stretchr/testify/assert"
+)
+
+// validConfig returns a configuration that passes the validation
+func validConfig() Config {
+	cfg := Default()
+	cfg.WhatsAppAPIKey = "key"
+	cfg.WebhookVerifyToken = "token"
+	return cfg
+}
+
+func TestValidate_Valid(t *testing.T) {
+	assert.NoError(t, validConfig().Validate())
+}
+
+func TestValidate_ListsEveryProblem(t *testing.T) {
+	cfg := Default()
+	cfg.ServerPort = "http"
+	cfg.LLMUrl = "localhost:8081"
+	cfg.TracingExporter = "jaeger"
+
+	err := cfg.Validate()
+
+	var invalid *ValidationError
+	assert.True(t, errors.As(err, &invalid))
+	assert.Len(t, invalid.Problems, 5)
+	assert.Contains(t, err.Error(), "WhatsApp API key is required")
+	assert.Contains(t, err.Error(), "webhook verify token is required")
+	assert.Contains(t, err.Error(), `server port "http"`)
+	assert.Contains(t, err.Error(), `LLM URL "localhost:8081"`)
+	assert.Contains(t, err.Error(), `tracing exporter "jaeger"`)
+}
+
+func TestValidate_StoragePath(t *testing.T) {
+	cfg := validConfig()
+	cfg.StoragePath = StorageMemory
+	assert.NoError(t, cfg.Validate())
+
+	cfg.StoragePath = " "
+	assert.ErrorContains(t, cfg.Validate(), "storage path is required")
+}
+
+func TestValidate_Retention(t *testing.T) {
+	cfg := validConfig()
+	cfg.Retention.MaxAge = -time.Hour
+	cfg.Retention.Interval = 0
+	cfg.Tenants = []Tenant{{PhoneNumberID: "123", Retention: -time.Hour}}
+
+	err := cfg.Validate()
+
+	assert.ErrorContains(t, err, "retention.max_age cannot be negative")
+	assert.ErrorContains(t, err, "retention.interval must be greater than 0")
+	assert.ErrorContains(t, err, "tenants[0]: retention cannot be negative")
+}
+
+func TestValidate_Recording(t *testing.T) {
+	cfg := validConfig()
+	cfg.Recording.MaxFileSize = 0
+	assert.NoError(t, cfg.Validate(), "the size doesn't matter when nothing is recorded")
+
+	cfg.Recording.Dir = "recordings"
+	assert.ErrorContains(t, cfg.Validate(), "recording.max_file_size must be greater than 0")
+}
+
+func TestValidate_RecordingInClear(t *testing.T) {
+	cfg := validConfig()
+	cfg.Recording.Dir = "recordings"
+	cfg.Recording.MaskPII = false
+	assert.NoError(t, cfg.Validate(), "nothing is erased")
+
+	for name, configure := range map[string]func(*Config){
+		"erasure":          func(cfg *Config) { cfg.AdminToken = "admin-token-0123456789" },
+		"retention":        func(cfg *Config) { cfg.Retention.MaxAge = 720 * time.Hour },
+		"tenant retention": func(cfg *Config) { cfg.Tenants = []Tenant{{PhoneNumberID: "123", Retention: 48 * time.Hour}} },
+	} {
+		t.Run(name, func(t *testing.T) {
+			cfg := cfg
+			configure(&cfg)
+			assert.ErrorContains(t, cfg.Validate(), "recording.mask_pii must be true")
+
+			cfg.Recording.MaskPII = true
+			assert.NoError(t, cfg.Validate())
+		})
+	}
+}
+
+func TestValidate_DeadLetters(t *testing.T) {
+	cfg := validConfig()
+	cfg.DeadLetters.RetrySchedule = []time.Duration{time.Minute, 0}
+	cfg.DeadLetters.Interval = 0
+
+	err := cfg.Validate()
+
+	assert.ErrorContains(t, err, "dead_letters.retry_schedule[1] must be greater than 0")
+	assert.ErrorContains(t, err, "dead_letters.interval must be greater than 0")
+}
+
+func TestValidate_Outbox(t *testing.T) {
+	cfg := validConfig()
+	cfg.Outbox.RetrySchedule = []time.Duration{-time.Second}
+	cfg.Outbox.Interval = 0
+
+	err := cfg.Validate()
+
+	assert.ErrorContains(t, err, "outbox.retry_schedule[0] must be greater than 0")
+	assert.ErrorContains(t, err, "outbox.interval must be greater than 0")
+}
+
+func TestValidate_Processing(t *testing.T) {
+	cfg := validConfig()
+	cfg.Processing = Processing{}
+
+	err := cfg.Validate()
+
+	assert.ErrorContains(t, err, "processing.max_pending must be greater than 0")
+	assert.ErrorContains(t, err, "processing.idle_timeout must be greater than 0")
+
+	cfg = validConfig()
+	cfg.Processing.DebounceWindow = 5 * time.Second
+	cfg.Processing.DebounceMaxWait = time.Second
+	assert.ErrorContains(t, cfg.Validate(), "processing.debounce_max_wait must not be shorter than processing.debounce_window")
+	cfg.Processing.DebounceWindow = -time.Second
+	assert.ErrorContains(t, cfg.Validate(), "processing.debounce_window must not be negative")
+}
+
+func TestValidate_OptOut(t *testing.T) {
+	cfg := validConfig()
+	cfg.OptOut = []OptOutLanguage{
+		{Language: "en", OptOut: []string{"STOP"}, OptIn: []string{"stop", " "}},
+		{Language: "es"},
+	}
+
+	err := cfg.Validate()
+
+	assert.ErrorContains(t, err, `opt_out[0]: keyword "stop" is used twice`)
+	assert.ErrorContains(t, err, "opt_out[0]: keywords cannot be empty")
+	assert.ErrorContains(t, err, "opt_out[1]: at least one opt_out keyword is required")
+}
+
+func TestValidate_Tenants(t *testing.T) {
+	tests := []struct {
+		name     string
+		tenants  []Tenant
+		expected string
+	}{
+		{
+			name:     "Missing phone number ID",
+			tenants:  []Tenant{{Name: "Store"}},
+			expected: "tenants[0]: phone_number_id is required",
+		},
+		{
+			name:     "Duplicated phone number ID",
+			tenants:  []Tenant{{PhoneNumberID: "123"}, {PhoneNumberID: "123"}},
+			expected: "tenants[1]: phone_number_id 123 is configured twice",
+		},
+		{
+			name:     "Unknown persona",
+			tenants:  []Tenant{{PhoneNumberID: "123", Persona: "pirate"}},
+			expected: `tenants[0]: persona "pirate" is not defined`,
+		},
+		{
+			name:     "Unknown LLM backend",
+			tenants:  []Tenant{{PhoneNumberID: "123", LLMBackend: "gpt"}},
+			expected: `tenants[0]: llm_backend "gpt" is not defined`,
+		},
+		{
+			name:     "Invalid allowlist entry",
+			tenants:  []Tenant{{PhoneNumberID: "123", Allowlist: []string{"+54 9 11"}}},
+			expected: `tenants[0]: allowlist entry "+54 9 11" must be a wa_id with only digits`,
+		},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			cfg := validConfig()
+			cfg.Tenants = tt.tenants
+
+			err := cfg.Validate()
+
+			assert.Error(t, err)
+			assert.Contains(t, err.Error(), tt.expected)
+		})
+	}
+}
+
+func TestValidate_BackendsAndPersonas(t *testing.T) {
+	cfg := validConfig()
+	cfg.LLMBackends = []LLMBackend{{Name: "a", URL: "http://a"}, {Name: "a", URL: "ftp://b"}}
+	cfg.Personas = []Persona{{Name: "store", Prompt: " "}}
+	cfg.Limits = Limits{MaxHistory: 0, RateLimitPerMinute: -1}
+
+	err := cfg.Validate()
+
+	var invalid *ValidationError
+	assert.True(t, errors.As(err, &invalid))
+	assert.ElementsMatch(t, []string{
+		`llm.backends[1]: name "a" is used twice`,
+		`llm.backends[1]: URL "ftp://b" must be an absolute http(s) URL`,
+		"personas[0]: prompt is required",
+		"limits.max_history must be at least 1",
+		"limits.rate_limit_per_minute cannot be negative",
+	}, invalid.Problems)
+}
@@ -0,0 +1,226 @@
+package e2e
+
+import (
+	"anyzzapp/internal/config"
+	"anyzzapp/internal/fakegraph"
+	"anyzzapp/internal/fakellm"
+	"anyzzapp/pkg/domain"
+	"context"
+	"net/http"
+	"testing"
+	"time"
+
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+func TestConversation_AutoReply(t *testing.T) {
+	h := newHarness(t)
+
+	status := h.say(t, contact, "Hello")
+
+	assert.Equal(t, http.StatusOK, status)
+	assert.Equal(t, []string{"Hello"}, h.llm.Prompts())
+	assert.Equal(t, []string{fakellm.DefaultReply}, h.replies())
+	assert.Equal(t, []string{"wamid.in.1"}, h.graph.Read())
+
+	// Both messages are in the history
+	var page domain.MessagePage
+	status = h.call(t, "GET", "/api/v1/admin/conversations/"+tenant+"-"+contact+"/messages", "", true, &page)
+	assert.Equal(t, http.StatusOK, status)
+	require.Len(t, page.Messages, 2)
+	assert.Equal(t, domain.MessageDirectionInbound, page.Messages[0].Direction)
+	assert.Equal(t, fakellm.DefaultReply, page.Messages[1].Content)
+}
+
+func TestConversation_DeliveryStatus(t *testing.T) {
+	h := newHarness(t)
+	require.Equal(t, http.StatusOK, h.say(t, contact, "Hello"))
+	reply := h.graph.SentTo(contactAddress)[0]
+
+	status := h.webhook(t, fakegraph.StatusUpdate(tenant, reply.ID, contact, domain.MessageStatusRead))
+
+	assert.Equal(t, http.StatusOK, status)
+	var page domain.MessagePage
+	h.call(t, "GET", "/api/v1/admin/conversations/"+tenant+"-"+contact+"/messages", "", true, &page)
+	require.Len(t, page.Messages, 2)
+	assert.Equal(t, domain.MessageStatusRead, page.Messages[1].Status)
+}
+
+func TestConversation_PersonaAndRules(t *testing.T) {
+	h := newHarness(t, func(cfg *config.Config) {
+		cfg.Personas = []config.Persona{{Name: "store", Prompt: "You are the assistant of a shoe store."}}
+		cfg.Tenants = []config.Tenant{{PhoneNumberID: tenant, Persona: "store"}}
+	})
+	h.llm.When("price", "The sneakers cost 100 USD.")
+	h.llm.When("thanks", "You're welcome!")
+
+	h.say(t, contact, "What is the price of the sneakers?")
+	h.say(t, contact, "Great, thanks")
+
+	assert.Equal(t, []string{"The sneakers cost 100 USD.", "You're welcome!"}, h.replies())
+	prompts := h.llm.Prompts()
+	require.Len(t, prompts, 2)
+	assert.Contains(t, prompts[0], "You are the assistant of a shoe store.")
+	assert.Contains(t, prompts[0], "What is the price of the sneakers?")
+}
+
+func TestConversation_Commands(t *testing.T) {
+	h := newHarness(t)
+
+	h.say(t, contact, "/lang Spanish")
+	h.say(t, contact, "Hello")
+
+	// The command is answered without the LLM, which is then asked to reply in the language
+	prompts := h.llm.Prompts()
+	require.Len(t, prompts, 1)
+	assert.Contains(t, prompts[0], "Reply in Spanish.")
+	assert.Len(t, h.replies(), 2)
+}
+
+func TestConversation_HumanHandover(t *testing.T) {
+	h := newHarness(t)
+	id := tenant + "-" + contact
+
+	h.say(t, contact, "/human")
+	h.say(t, contact, "I want to talk to a person")
+	assert.Empty(t, h.llm.Prompts())
+
+	status := h.call(t, "POST", "/api/v1/agent/conversations/"+id+"/reply", `{"content": "Hi, this is Ana."}`, true, nil)
+	assert.Equal(t, http.StatusOK, status)
+	assert.Contains(t, h.replies(), "Hi, this is Ana.")
+
+	// Back with the bot, the LLM answers again
+	assert.Equal(t, http.StatusOK, h.call(t, "POST", "/api/v1/agent/conversations/"+id+"/handback", "", true, nil))
+	h.say(t, contact, "Thanks")
+	assert.Equal(t, []string{"Thanks"}, h.llm.Prompts())
+}
+
+func TestConversation_OptOut(t *testing.T) {
+	h := newHarness(t)
+	send := func(transactional bool) int {
+		body := `{"phone_number_id": "` + tenant + `", "to": "` + contact + `", "content": "Our sale starts today"`
+		if transactional {
+			body += `, "transactional": true`
+		}
+		return h.call(t, "POST", "/api/v1/whatsapp/send", body+"}", false, nil)
+	}
+
+	h.say(t, contact, "BAJA")
+	h.say(t, contact, "Hello?")
+
+	assert.Empty(t, h.llm.Prompts())
+	replies := h.replies()
+	require.Len(t, replies, 1)
+	assert.Contains(t, replies[0], "ALTA")
+	assert.Equal(t, http.StatusForbidden, send(false))
+	assert.Equal(t, http.StatusOK, send(true))
+
+	h.say(t, contact, "alta")
+	assert.Equal(t, http.StatusOK, send(false))
+}
+
+func TestConversation_LLMFailure(t *testing.T) {
+	h := newHarness(t)
+	// The LLM repository reads a status other than 200 as a failure, not as an empty reply
+	h.llm.FailNext(fakellm.Unavailable)
+
+	status := h.say(t, contact, "Hello")
+
+	// Meta is not made to redeliver the webhook when only the LLM failed
+	assert.Equal(t, http.StatusOK, status)
+	assert.Empty(t, h.replies())
+	// The message is marked as read and kept even though it was not answered
+	assert.Len(t, h.graph.Read(), 1)
+}
+
+func TestConversation_DeadLetterRetry(t *testing.T) {
+	h := newHarness(t)
+	h.llm.FailNext(fakellm.Unavailable)
+
+	assert.Equal(t, http.StatusOK, h.say(t, contact, "Hello"))
+	assert.Empty(t, h.replies())
+
+	var list struct {
+		DeadLetters []domain.DeadLetter `json:"dead_letters"`
+	}
+	require.Equal(t, http.StatusOK, h.call(t, "GET", "/api/v1/admin/dead-letters", "", true, &list))
+	require.Len(t, list.DeadLetters, 1)
+	letter := list.DeadLetters[0]
+	assert.Equal(t, "Hello", letter.Message.Text.Body)
+	assert.Equal(t, 1, letter.Attempts)
+	assert.Contains(t, letter.Error, "llm")
+
+	// The operator retries once the LLM is back, the message is answered once
+	assert.Equal(t, http.StatusOK, h.call(t, "POST", "/api/v1/admin/dead-letters/"+letter.ID+"/retry", "", true, nil))
+	assert.Equal(t, []string{fakellm.DefaultReply}, h.replies())
+	assert.Equal(t, http.StatusNotFound, h.call(t, "GET", "/api/v1/admin/dead-letters/"+letter.ID, "", true, nil))
+	assert.Len(t, h.graph.Read(), 1)
+}
+
+func TestConversation_GraphFailure(t *testing.T) {
+	h := newHarness(t)
+	h.graph.FailNext(fakegraph.RateLimited)
+
+	assert.Equal(t, http.StatusOK, h.say(t, contact, "Hello"))
+	assert.Equal(t, http.StatusOK, h.say(t, contact, "Hello again"))
+
+	assert.Equal(t, []string{fakellm.DefaultReply}, h.replies())
+}
+
+func TestConversation_Outbox(t *testing.T) {
+	h := newHarness(t, func(cfg *config.Config) {
+		cfg.Outbox.RetrySchedule = []time.Duration{time.Nanosecond}
+	})
+	h.graph.FailNext(fakegraph.RateLimited, fakegraph.RateLimited)
+
+	// The reply and the message sent with the API are queued
+	assert.Equal(t, http.StatusOK, h.say(t, contact, "Hello"))
+	body := `{"phone_number_id": "` + tenant + `", "to": "` + contact + `", "content": "Your order shipped"}`
+	assert.Equal(t, http.StatusAccepted, h.call(t, "POST", "/api/v1/whatsapp/send", body, false, nil))
+	assert.Empty(t, h.replies())
+	dead, err := h.deadLetters.List(context.Background())
+	require.NoError(t, err)
+	assert.Empty(t, dead)
+
+	sent, failed, err := h.outbox.DispatchDue(context.Background())
+	require.NoError(t, err)
+	assert.Equal(t, 2, sent)
+	assert.Equal(t, 0, failed)
+	assert.Equal(t, []string{fakellm.DefaultReply}, h.replies())
+
+	// A webhook Meta redelivers is not answered twice
+	assert.Equal(t, http.StatusOK, h.webhook(t, fakegraph.TextMessage(tenant, contact, "wamid.in.1", "Hello")))
+	sent, _, err = h.outbox.DispatchDue(context.Background())
+	require.NoError(t, err)
+	assert.Zero(t, sent)
+	assert.Len(t, h.replies(), 1)
+
+	// The reply is in the history once sent
+	conversation, err := h.conversations.Get(domain.ConversationKey{PhoneNumberID: tenant, WaID: contact})
+	require.NoError(t, err)
+	var outbound []string
+	for _, message := range conversation.Messages {
+		if message.Direction == "outbound" {
+			outbound = append(outbound, message.Content)
+		}
+	}
+	assert.Equal(t, []string{fakellm.DefaultReply}, outbound)
+}
+
+func TestConversation_Debounce(t *testing.T) {
+	h := newHarness(t, func(cfg *config.Config) {
+		cfg.Processing.DebounceWindow = 50 * time.Millisecond
+	})
+
+	for _, text := range []string{"Hi", "I need help", "with my order"} {
+		assert.Equal(t, http.StatusOK, h.say(t, contact, text))
+	}
+	assert.Empty(t, h.graph.Read())
+
+	// The burst is answered once, with every message marked as read
+	assert.Eventually(t, func() bool { return len(h.replies()) == 1 }, 2*time.Second, 10*time.Millisecond)
+	assert.Equal(t, []string{fakellm.DefaultReply}, h.replies())
+	assert.Equal(t, []string{"Hi\nI need help\nwith my order"}, h.llm.Prompts())
+	assert.ElementsMatch(t, []string{"wamid.in.1", "wamid.in.2", "wamid.in.3"}, h.graph.Read())
+}
@@ -0,0 +1,128 @@
+// Package e2e drives the real HTTP router through conversations, with fakes of the WhatsApp Cloud API and the LLM
+package e2e
+
+import (
+	"anyzzapp/internal/app"
+	"anyzzapp/internal/config"
+	"anyzzapp/internal/fakegraph"
+	"anyzzapp/internal/fakellm"
+	apphttp "anyzzapp/internal/interfaces/http"
+	"anyzzapp/pkg/application"
+	"anyzzapp/pkg/domain"
+	"context"
+	"encoding/json"
+	"fmt"
+	"io"
+	"net/http"
+	"net/http/httptest"
+	"strings"
+	"sync/atomic"
+	"testing"
+
+	"github.com/gin-gonic/gin"
+	"github.com/stretchr/testify/require"
+)
+
+const (
+	// tenant is the phone number ID of the business number
+	tenant = "123456789"
+	// contact is the wa_id of the contact writing to the bot, an Argentinian mobile
+	contact = "5491112345678"
+	// contactAddress is the number the replies to contact are sent to
+	contactAddress = "541112345678"
+	// adminToken authorizes the admin endpoints
+	adminToken = "admin-token-0123456789"
+)
+
+// harness runs the application behind its HTTP router with fakes of its dependencies
+type harness struct {
+	graph *fakegraph.Server
+	llm   *fakellm.Server
+	app   *httptest.Server
+
+	messages      domain.MessageRepository
+	conversations domain.ConversationRepository
+	deadLetters   domain.DeadLetterRepository
+	outbox        *application.Outbox
+	nextID        atomic.Int64
+}
+
+// newHarness wires the application with app.New like the server does, configure changes the configuration before
+func newHarness(t *testing.T, configure ...func(*config.Config)) *harness {
+	t.Helper()
+	gin.SetMode(gin.TestMode)
+	h := &harness{
+		graph: fakegraph.New(t),
+		llm:   fakellm.New(t),
+	}
+
+	cfg := config.Default()
+	cfg.WhatsAppAPIKey = fakegraph.DefaultToken
+	cfg.WhatsAppBaseURL = h.graph.URL
+	cfg.WebhookVerifyToken = "verify-token"
+	cfg.LLMUrl = h.llm.URL
+	cfg.AdminToken = adminToken
+	cfg.StoragePath = config.StorageMemory
+	for _, change := range configure {
+		change(&cfg)
+	}
+	require.NoError(t, cfg.Validate())
+
+	a, err := app.New(cfg, "", cfg.StoragePath)
+	require.NoError(t, err)
+	h.messages = a.Messages
+	h.conversations = a.Conversations
+	h.deadLetters = a.DeadLetters
+	h.outbox = a.Outbox
+
+	h.app = httptest.NewServer(apphttp.NewRouter(cfg, a.WhatsAppUseCase, a.RouterOptions()...))
+	t.Cleanup(func() {
+		h.app.Close()
+		require.NoError(t, a.Close(context.Background()))
+	})
+	return h
+}
+
+// say sends the text as a message of the contact and returns the status answered to the webhook
+func (h *harness) say(t *testing.T, from, text string) int {
+	t.Helper()
+	id := fmt.Sprintf("wamid.in.%d", h.nextID.Add(1))
+	return h.webhook(t, fakegraph.TextMessage(tenant, from, id, text))
+}
+
+// webhook delivers the webhook like Meta does and returns the status answered
+func (h *harness) webhook(t *testing.T, webhook domain.WebhookRequest) int {
+	t.Helper()
+	resp, err := h.graph.SendWebhook(context.Background(), h.app.URL+"/api/v1/whatsapp/webhook", webhook)
+	require.NoError(t, err)
+	defer resp.Body.Close()
+	return resp.StatusCode
+}
+
+// call sends a request to the API, as an admin when admin is true, and decodes the JSON response into out
+func (h *harness) call(t *testing.T, method, path, body string, admin bool, out any) int {
+	t.Helper()
+	req, err := http.NewRequest(method, h.app.URL+path, strings.NewReader(body))
+	require.NoError(t, err)
+	req.Header.Set("Content-Type", "application/json")
+	if admin {
+		req.Header.Set("Authorization", "Bearer "+adminToken)
+	}
+	resp, err := http.DefaultClient.Do(req)
+	require.NoError(t, err)
+	defer resp.Body.Close()
+	if out != nil {
+		data, _ := io.ReadAll(resp.Body)
+		require.NoError(t, json.Unmarshal(data, out), string(data))
+	}
+	return resp.StatusCode
+}
+
+// replies returns the texts sent to the contact so far
+func (h *harness) replies() []string {
+	var texts []string
+	for _, message := range h.graph.SentTo(contactAddress) {
+		texts = append(texts, message.Text)
+	}
+	return texts
+}
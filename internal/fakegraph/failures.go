@@ -0,0 +1,78 @@
+package fakegraph
+
+import "net/http"
+
+// Failure is an error answered by the Graph API
+type Failure struct {
+	Status  int
+	Code    int
+	Type    string
+	Message string
+}
+
+var (
+	// InvalidToken is answered to the requests without the access token
+	InvalidToken = Failure{Status: http.StatusUnauthorized, Code: 190, Type: "OAuthException",
+		Message: "Invalid OAuth access token - Cannot parse access token"}
+	// RateLimited is answered when the business number sends too many messages
+	RateLimited = Failure{Status: http.StatusTooManyRequests, Code: 130429, Type: "OAuthException",
+		Message: "Rate limit hit"}
+	// InvalidNumber is answered when the recipient is not a WhatsApp user
+	InvalidNumber = Failure{Status: http.StatusBadRequest, Code: 131026, Type: "OAuthException",
+		Message: "Message undeliverable"}
+	// OutsideWindow is answered to free-form messages sent 24 hours after the last message of the contact
+	OutsideWindow = Failure{Status: http.StatusBadRequest, Code: 131047, Type: "OAuthException",
+		Message: "Re-engagement message"}
+	// Unavailable is answered when the Graph API is down
+	Unavailable = Failure{Status: http.StatusServiceUnavailable, Code: 131016, Type: "OAuthException",
+		Message: "Service unavailable"}
+
+	// mediaNotFound is answered for unknown media IDs
+	mediaNotFound = Failure{Status: http.StatusNotFound, Code: 100, Type: "GraphMethodException",
+		Message: "Unsupported get request. Object with the ID does not exist"}
+)
+
+// invalidParameter is answered to payloads that don't match the schema of the Cloud API
+func invalidParameter(detail string) Failure {
+	return Failure{Status: http.StatusBadRequest, Code: 100, Type: "OAuthException",
+		Message: "(#100) Invalid parameter: " + detail}
+}
+
+// FailNext makes the next sends fail with the failures, one send each and in order
+func (s *Server) FailNext(failures ...Failure) {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	s.failures = append(s.failures, failures...)
+}
+
+// FailRecipient makes every send to the recipient fail with the failure
+func (s *Server) FailRecipient(to string, failure Failure) {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	s.recipients[to] = failure
+}
+
+// nextFailure returns the failure the send to the recipient must answer, the caller holds the lock
+func (s *Server) nextFailure(to string) (Failure, bool) {
+	if failure, ok := s.recipients[to]; ok {
+		return failure, true
+	}
+	if len(s.failures) == 0 {
+		return Failure{}, false
+	}
+	failure := s.failures[0]
+	s.failures = s.failures[1:]
+	return failure, true
+}
+
+// writeError answers the failure in the error format of the Graph API
+func writeError(w http.ResponseWriter, failure Failure) {
+	writeJSON(w, failure.Status, map[string]any{
+		"error": map[string]any{
+			"message":    failure.Message,
+			"type":       failure.Type,
+			"code":       failure.Code,
+			"fbtrace_id": "fake-trace",
+		},
+	})
+}
@@ -0,0 +1,392 @@
+// Package fakegraph is a fake of the WhatsApp Cloud API for integration tests.
+//
+// The Server answers the messages, media and mark-as-read endpoints of the Graph API like Meta does: it checks
+// the bearer token and the payloads, records every message sent for assertions, fails on demand and sends
+// signed webhooks back to the application.
+package fakegraph
+
+import (
+	"anyzzapp/pkg/domain"
+	"bytes"
+	"context"
+	"crypto/hmac"
+	"crypto/sha256"
+	"encoding/hex"
+	"encoding/json"
+	"fmt"
+	"io"
+	"net/http"
+	"net/http/httptest"
+	"strconv"
+	"strings"
+	"sync"
+	"testing"
+	"time"
+)
+
+const (
+	// DefaultToken is the access token accepted when none is set with WithToken
+	DefaultToken = "fake-graph-token"
+	// DefaultAppSecret signs the webhooks when none is set with WithAppSecret
+	DefaultAppSecret = "fake-graph-app-secret"
+	// SignatureHeader is the header carrying the signature of the webhooks
+	SignatureHeader = "X-Hub-Signature-256"
+	// version is the Graph API version in the URL of the server
+	version = "v20.0"
+	// maxTextLength is the longest text body accepted by the Cloud API
+	maxTextLength = 4096
+)
+
+// SentMessage is a message accepted by the messages endpoint
+type SentMessage struct {
+	ID            string
+	PhoneNumberID string
+	To            string
+	Type          string
+	// Text is the body of text messages
+	Text string
+	// Payload is the request body as sent
+	Payload map[string]any
+	At      time.Time
+}
+
+// Media is a file uploaded to the media endpoint or added with AddMedia
+type Media struct {
+	ID       string
+	MimeType string
+	Data     []byte
+}
+
+// Server is a fake WhatsApp Cloud API running on a local httptest server
+type Server struct {
+	// URL is the base URL of the Graph API, to be used as WHATSAPP_BASE_URL
+	URL string
+
+	server    *httptest.Server
+	token     string
+	appSecret string
+
+	mu         sync.Mutex
+	sent       []SentMessage
+	read       []string
+	media      map[string]Media
+	failures   []Failure
+	recipients map[string]Failure
+	nextID     int
+}
+
+// Option configures the Server
+type Option func(*Server)
+
+// WithToken sets the access token the server accepts
+func WithToken(token string) Option {
+	return func(s *Server) {
+		s.token = token
+	}
+}
+
+// WithAppSecret sets the secret the webhooks are signed with
+func WithAppSecret(secret string) Option {
+	return func(s *Server) {
+		s.appSecret = secret
+	}
+}
+
+// New starts a Server that is closed when the test ends
+func New(t testing.TB, opts ...Option) *Server {
+	t.Helper()
+	s := &Server{
+		token:      DefaultToken,
+		appSecret:  DefaultAppSecret,
+		media:      make(map[string]Media),
+		recipients: make(map[string]Failure),
+	}
+	for _, opt := range opts {
+		opt(s)
+	}
+
+	mux := http.NewServeMux()
+	mux.HandleFunc("GET /"+version+"/me", s.authorized(s.me))
+	mux.HandleFunc("POST /"+version+"/{phone_number_id}/messages", s.authorized(s.messages))
+	mux.HandleFunc("POST /"+version+"/{phone_number_id}/media", s.authorized(s.uploadMedia))
+	mux.HandleFunc("GET /"+version+"/{media_id}", s.authorized(s.mediaURL))
+	mux.HandleFunc("DELETE /"+version+"/{media_id}", s.authorized(s.deleteMedia))
+	mux.HandleFunc("GET /media/{media_id}", s.authorized(s.downloadMedia))
+	s.server = httptest.NewServer(mux)
+	s.URL = s.server.URL + "/" + version
+	t.Cleanup(s.server.Close)
+	return s
+}
+
+// Sent returns the messages accepted so far, in order
+func (s *Server) Sent() []SentMessage {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	return append([]SentMessage(nil), s.sent...)
+}
+
+// SentTo returns the messages accepted for the recipient, in order
+func (s *Server) SentTo(to string) []SentMessage {
+	var sent []SentMessage
+	for _, message := range s.Sent() {
+		if message.To == to {
+			sent = append(sent, message)
+		}
+	}
+	return sent
+}
+
+// Read returns the IDs of the messages marked as read, in order
+func (s *Server) Read() []string {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	return append([]string(nil), s.read...)
+}
+
+// AddMedia stores a file as if a contact had sent it and returns its media ID
+func (s *Server) AddMedia(mimeType string, data []byte) string {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	id := s.newID("media")
+	s.media[id] = Media{ID: id, MimeType: mimeType, Data: data}
+	return id
+}
+
+// MediaFile returns the uploaded file with the ID
+func (s *Server) MediaFile(id string) (Media, bool) {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	media, ok := s.media[id]
+	return media, ok
+}
+
+// Reset forgets the recorded calls and the pending failures, the media is kept
+func (s *Server) Reset() {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	s.sent = nil
+	s.read = nil
+	s.failures = nil
+	s.recipients = make(map[string]Failure)
+}
+
+// authorized rejects the requests without the access token, as the Graph API does
+func (s *Server) authorized(next http.HandlerFunc) http.HandlerFunc {
+	return func(w http.ResponseWriter, r *http.Request) {
+		if r.Header.Get("Authorization") != "Bearer "+s.token {
+			writeError(w, InvalidToken)
+			return
+		}
+		next(w, r)
+	}
+}
+
+// me answers the token check of the readiness probe
+func (s *Server) me(w http.ResponseWriter, r *http.Request) {
+	writeJSON(w, http.StatusOK, map[string]string{"id": "fake-business"})
+}
+
+// messages sends a message or marks one as read
+func (s *Server) messages(w http.ResponseWriter, r *http.Request) {
+	var payload map[string]any
+	if err := json.NewDecoder(r.Body).Decode(&payload); err != nil {
+		writeError(w, invalidParameter("the body must be a JSON object"))
+		return
+	}
+	if payload["messaging_product"] != "whatsapp" {
+		writeError(w, invalidParameter(`messaging_product must be "whatsapp"`))
+		return
+	}
+	if payload["status"] != nil {
+		s.markAsRead(w, payload)
+		return
+	}
+
+	message, err := sentMessage(r.PathValue("phone_number_id"), payload)
+	if err != nil {
+		writeError(w, invalidParameter(err.Error()))
+		return
+	}
+	s.mu.Lock()
+	if failure, ok := s.nextFailure(message.To); ok {
+		s.mu.Unlock()
+		writeError(w, failure)
+		return
+	}
+	message.ID = s.newID("wamid")
+	s.sent = append(s.sent, message)
+	s.mu.Unlock()
+
+	writeJSON(w, http.StatusOK, map[string]any{
+		"messaging_product": "whatsapp",
+		"contacts":          []map[string]string{{"input": message.To, "wa_id": message.To}},
+		"messages":          []map[string]string{{"id": message.ID}},
+	})
+}
+
+// markAsRead records the read receipt of an incoming message
+func (s *Server) markAsRead(w http.ResponseWriter, payload map[string]any) {
+	messageID, _ := payload["message_id"].(string)
+	if payload["status"] != "read" || messageID == "" {
+		writeError(w, invalidParameter(`a read receipt needs status "read" and a message_id`))
+		return
+	}
+	s.mu.Lock()
+	s.read = append(s.read, messageID)
+	s.mu.Unlock()
+	writeJSON(w, http.StatusOK, map[string]bool{"success": true})
+}
+
+// sentMessage checks the payload of a message against the schema of the Cloud API
+func sentMessage(phoneNumberID string, payload map[string]any) (SentMessage, error) {
+	message := SentMessage{PhoneNumberID: phoneNumberID, Payload: payload, At: time.Now()}
+	message.To, _ = payload["to"].(string)
+	message.Type, _ = payload["type"].(string)
+	if recipientType, ok := payload["recipient_type"]; ok && recipientType != "individual" {
+		return message, fmt.Errorf(`recipient_type must be "individual"`)
+	}
+	if !isDigits(message.To) {
+		return message, fmt.Errorf("to must be a phone number with only digits, got %q", message.To)
+	}
+	if message.Type == "" {
+		message.Type = "text"
+	}
+	switch message.Type {
+	case "text":
+		text, _ := payload["text"].(map[string]any)
+		message.Text, _ = text["body"].(string)
+		if strings.TrimSpace(message.Text) == "" {
+			return message, fmt.Errorf("text.body is required")
+		}
+		if len([]rune(message.Text)) > maxTextLength {
+			return message, fmt.Errorf("text.body is longer than %d characters", maxTextLength)
+		}
+	case "image", "audio", "document", "video", "sticker":
+		media, _ := payload[message.Type].(map[string]any)
+		if media["id"] == nil && media["link"] == nil {
+			return message, fmt.Errorf("%s.id or %s.link is required", message.Type, message.Type)
+		}
+	case "template", "interactive", "location", "contacts", "reaction":
+		if payload[message.Type] == nil {
+			return message, fmt.Errorf("%s is required", message.Type)
+		}
+	default:
+		return message, fmt.Errorf("type %q is not supported", message.Type)
+	}
+	return message, nil
+}
+
+// uploadMedia stores the file of a multipart upload
+func (s *Server) uploadMedia(w http.ResponseWriter, r *http.Request) {
+	if r.FormValue("messaging_product") != "whatsapp" {
+		writeError(w, invalidParameter(`messaging_product must be "whatsapp"`))
+		return
+	}
+	file, header, err := r.FormFile("file")
+	if err != nil {
+		writeError(w, invalidParameter("file is required"))
+		return
+	}
+	defer file.Close()
+	data, err := io.ReadAll(file)
+	if err != nil {
+		writeError(w, invalidParameter("file could not be read"))
+		return
+	}
+	mimeType := r.FormValue("type")
+	if mimeType == "" {
+		mimeType = header.Header.Get("Content-Type")
+	}
+	writeJSON(w, http.StatusOK, map[string]string{"id": s.AddMedia(mimeType, data)})
+}
+
+// mediaURL answers the metadata and the download URL of a file
+func (s *Server) mediaURL(w http.ResponseWriter, r *http.Request) {
+	media, ok := s.MediaFile(r.PathValue("media_id"))
+	if !ok {
+		writeError(w, mediaNotFound)
+		return
+	}
+	sum := sha256.Sum256(media.Data)
+	writeJSON(w, http.StatusOK, map[string]any{
+		"messaging_product": "whatsapp",
+		"id":                media.ID,
+		"url":               s.server.URL + "/media/" + media.ID,
+		"mime_type":         media.MimeType,
+		"sha256":            hex.EncodeToString(sum[:]),
+		"file_size":         len(media.Data),
+	})
+}
+
+// downloadMedia answers the content of a file
+func (s *Server) downloadMedia(w http.ResponseWriter, r *http.Request) {
+	media, ok := s.MediaFile(r.PathValue("media_id"))
+	if !ok {
+		writeError(w, mediaNotFound)
+		return
+	}
+	w.Header().Set("Content-Type", media.MimeType)
+	_, _ = w.Write(media.Data)
+}
+
+// deleteMedia removes a file
+func (s *Server) deleteMedia(w http.ResponseWriter, r *http.Request) {
+	id := r.PathValue("media_id")
+	s.mu.Lock()
+	_, ok := s.media[id]
+	delete(s.media, id)
+	s.mu.Unlock()
+	if !ok {
+		writeError(w, mediaNotFound)
+		return
+	}
+	writeJSON(w, http.StatusOK, map[string]bool{"success": true})
+}
+
+// newID returns a new unique ID with the prefix, the caller holds the lock
+func (s *Server) newID(prefix string) string {
+	s.nextID++
+	return prefix + ".fake." + strconv.Itoa(s.nextID)
+}
+
+// SendWebhook posts the webhook to url with the signature Meta adds, and returns the response of the application
+func (s *Server) SendWebhook(ctx context.Context, url string, webhook domain.WebhookRequest) (*http.Response, error) {
+	body, err := json.Marshal(webhook)
+	if err != nil {
+		return nil, fmt.Errorf("failed to encode webhook: %w", err)
+	}
+	req, err := http.NewRequestWithContext(ctx, http.MethodPost, url, bytes.NewReader(body))
+	if err != nil {
+		return nil, fmt.Errorf("failed to create request: %w", err)
+	}
+	req.Header.Set("Content-Type", "application/json")
+	req.Header.Set(SignatureHeader, Sign(s.appSecret, body))
+	return http.DefaultClient.Do(req)
+}
+
+// Sign returns the X-Hub-Signature-256 header of the body: its HMAC-SHA256 with the app secret
+func Sign(appSecret string, body []byte) string {
+	mac := hmac.New(sha256.New, []byte(appSecret))
+	mac.Write(body)
+	return "sha256=" + hex.EncodeToString(mac.Sum(nil))
+}
+
+// writeJSON answers the value as JSON
+func writeJSON(w http.ResponseWriter, status int, value any) {
+	w.Header().Set("Content-Type", "application/json")
+	w.WriteHeader(status)
+	_ = json.NewEncoder(w).Encode(value)
+}
+
+// isDigits reports whether value is a non-empty string of digits
+func isDigits(value string) bool {
+	if value == "" {
+		return false
+	}
+	for _, r := range value {
+		if r < '0' || r > '9' {
+			return false
+		}
+	}
+	return true
+}
@@ -0,0 +1,192 @@
+package fakegraph_test
+
+import (
+	"anyzzapp/internal/config"
+	"anyzzapp/internal/fakegraph"
+	"anyzzapp/internal/infrastructure"
+	"anyzzapp/internal/infrastructure/client"
+	"anyzzapp/pkg/domain"
+	"bytes"
+	"context"
+	"encoding/json"
+	"errors"
+	"io"
+	"mime/multipart"
+	"net/http"
+	"net/http/httptest"
+	"strings"
+	"testing"
+
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+// newRepository returns the WhatsApp repository of the application talking to the fake
+func newRepository(graph *fakegraph.Server, token string) domain.WhatsAppRepository {
+	cfg := config.Config{WhatsAppBaseURL: graph.URL, WhatsAppAPIKey: token}
+	return infrastructure.NewWhatsAppRepository(cfg, client.NewHttpClient(&http.Client{}, token))
+}
+
+func TestServer_SendMessage(t *testing.T) {
+	graph := fakegraph.New(t)
+	repo := newRepository(graph, fakegraph.DefaultToken)
+
+	response, err := repo.SendMessage(context.Background(), domain.Message{
+		PhoneNumberID: "123", To: "541112345678", Content: "Hello!", MessageType: "text",
+	})
+
+	require.NoError(t, err)
+	sent := graph.SentTo("541112345678")
+	require.Len(t, sent, 1)
+	assert.Equal(t, response.MessageID, sent[0].ID)
+	assert.Equal(t, "123", sent[0].PhoneNumberID)
+	assert.Equal(t, "Hello!", sent[0].Text)
+	assert.Equal(t, "individual", sent[0].Payload["recipient_type"])
+}
+
+func TestServer_MarkAsRead(t *testing.T) {
+	graph := fakegraph.New(t)
+	repo := newRepository(graph, fakegraph.DefaultToken)
+
+	require.NoError(t, repo.MarkAsRead(context.Background(), "123", "wamid.in.1"))
+
+	assert.Equal(t, []string{"wamid.in.1"}, graph.Read())
+	assert.Empty(t, graph.Sent())
+}
+
+func TestServer_InvalidToken(t *testing.T) {
+	graph := fakegraph.New(t, fakegraph.WithToken("right-token"))
+	repo := newRepository(graph, "wrong-token")
+
+	_, err := repo.SendMessage(context.Background(), domain.Message{PhoneNumberID: "123", To: "541112345678", Content: "Hi"})
+
+	var apiErr *domain.APIError
+	require.True(t, errors.As(err, &apiErr))
+	assert.Equal(t, http.StatusUnauthorized, apiErr.StatusCode)
+	assert.Equal(t, 190, apiErr.Code)
+	assert.Empty(t, graph.Sent())
+}
+
+func TestServer_InjectedFailures(t *testing.T) {
+	graph := fakegraph.New(t)
+	repo := newRepository(graph, fakegraph.DefaultToken)
+	ctx := context.Background()
+	graph.FailNext(fakegraph.RateLimited)
+	graph.FailRecipient("549000", fakegraph.InvalidNumber)
+
+	_, err := repo.SendMessage(ctx, domain.Message{PhoneNumberID: "123", To: "541112345678", Content: "Hi"})
+	var apiErr *domain.APIError
+	require.True(t, errors.As(err, &apiErr))
+	assert.Equal(t, 130429, apiErr.Code)
+
+	// The failure is used once
+	_, err = repo.SendMessage(ctx, domain.Message{PhoneNumberID: "123", To: "541112345678", Content: "Hi"})
+	assert.NoError(t, err)
+
+	_, err = repo.SendMessage(ctx, domain.Message{PhoneNumberID: "123", To: "549000", Content: "Hi"})
+	require.True(t, errors.As(err, &apiErr))
+	assert.Equal(t, 131026, apiErr.Code)
+	assert.Len(t, graph.Sent(), 1)
+}
+
+func TestServer_RejectsInvalidPayloads(t *testing.T) {
+	graph := fakegraph.New(t)
+	tests := []struct {
+		name    string
+		payload string
+	}{
+		{"missing product", `{"to": "541112345678", "type": "text", "text": {"body": "Hi"}}`},
+		{"recipient with plus", `{"messaging_product": "whatsapp", "to": "+541112345678", "type": "text", "text": {"body": "Hi"}}`},
+		{"empty text", `{"messaging_product": "whatsapp", "to": "541112345678", "type": "text", "text": {"body": ""}}`},
+		{"image without media", `{"messaging_product": "whatsapp", "to": "541112345678", "type": "image", "image": {}}`},
+		{"unknown type", `{"messaging_product": "whatsapp", "to": "541112345678", "type": "fax"}`},
+		{"read without message", `{"messaging_product": "whatsapp", "status": "read"}`},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			req, _ := http.NewRequest(http.MethodPost, graph.URL+"/123/messages", strings.NewReader(tt.payload))
+			req.Header.Set("Authorization", "Bearer "+fakegraph.DefaultToken)
+			resp, err := http.DefaultClient.Do(req)
+			require.NoError(t, err)
+			defer resp.Body.Close()
+
+			assert.Equal(t, http.StatusBadRequest, resp.StatusCode)
+			var body struct {
+				Error struct {
+					Code int `json:"code"`
+				} `json:"error"`
+			}
+			assert.NoError(t, json.NewDecoder(resp.Body).Decode(&body))
+			assert.Equal(t, 100, body.Error.Code)
+		})
+	}
+	assert.Empty(t, graph.Sent())
+}
+
+func TestServer_Media(t *testing.T) {
+	graph := fakegraph.New(t)
+	authorized := func(req *http.Request) *http.Response {
+		req.Header.Set("Authorization", "Bearer "+fakegraph.DefaultToken)
+		resp, err := http.DefaultClient.Do(req)
+		require.NoError(t, err)
+		t.Cleanup(func() { resp.Body.Close() })
+		return resp
+	}
+
+	// Upload
+	var form bytes.Buffer
+	writer := multipart.NewWriter(&form)
+	_ = writer.WriteField("messaging_product", "whatsapp")
+	_ = writer.WriteField("type", "image/png")
+	part, _ := writer.CreateFormFile("file", "logo.png")
+	_, _ = part.Write([]byte("png-bytes"))
+	_ = writer.Close()
+	req, _ := http.NewRequest(http.MethodPost, graph.URL+"/123/media", &form)
+	req.Header.Set("Content-Type", writer.FormDataContentType())
+	var uploaded struct {
+		ID string `json:"id"`
+	}
+	require.NoError(t, json.NewDecoder(authorized(req).Body).Decode(&uploaded))
+
+	// Metadata and download
+	req, _ = http.NewRequest(http.MethodGet, graph.URL+"/"+uploaded.ID, nil)
+	var metadata struct {
+		URL      string `json:"url"`
+		MimeType string `json:"mime_type"`
+	}
+	require.NoError(t, json.NewDecoder(authorized(req).Body).Decode(&metadata))
+	assert.Equal(t, "image/png", metadata.MimeType)
+
+	req, _ = http.NewRequest(http.MethodGet, metadata.URL, nil)
+	data, _ := io.ReadAll(authorized(req).Body)
+	assert.Equal(t, "png-bytes", string(data))
+
+	// Delete
+	req, _ = http.NewRequest(http.MethodDelete, graph.URL+"/"+uploaded.ID, nil)
+	assert.Equal(t, http.StatusOK, authorized(req).StatusCode)
+	req, _ = http.NewRequest(http.MethodGet, graph.URL+"/"+uploaded.ID, nil)
+	assert.Equal(t, http.StatusNotFound, authorized(req).StatusCode)
+}
+
+func TestServer_SendWebhook(t *testing.T) {
+	graph := fakegraph.New(t, fakegraph.WithAppSecret("secret"))
+	var received domain.WebhookRequest
+	app := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		body, _ := io.ReadAll(r.Body)
+		if r.Header.Get(fakegraph.SignatureHeader) != fakegraph.Sign("secret", body) {
+			w.WriteHeader(http.StatusUnauthorized)
+			return
+		}
+		_ = json.Unmarshal(body, &received)
+	}))
+	defer app.Close()
+
+	resp, err := graph.SendWebhook(context.Background(), app.URL, fakegraph.TextMessage("123", "5491112345678", "wamid.in.1", "Hello"))
+
+	require.NoError(t, err)
+	resp.Body.Close()
+	assert.Equal(t, http.StatusOK, resp.StatusCode)
+	value := received.Entry[0].Changes[0].Value
+	assert.Equal(t, "123", value.Metadata.PhoneNumberID)
+	assert.Equal(t, "Hello", value.Messages[0].Text.Body)
+}
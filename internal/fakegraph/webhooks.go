@@ -0,0 +1,59 @@
+package fakegraph
+
+import (
+	"anyzzapp/pkg/domain"
+	"strconv"
+	"time"
+)
+
+// businessAccountID is the WhatsApp Business Account ID of the webhooks
+const businessAccountID = "fake-waba"
+
+// TextMessage builds the webhook of a text message sent by a contact to the business number
+func TextMessage(phoneNumberID, from, messageID, body string) domain.WebhookRequest {
+	return domain.NewTextWebhook(businessAccountID, phoneNumberID, contact(from), messageID, body, time.Now())
+}
+
+// MediaMessage builds the webhook of a file sent by a contact, mediaType is image, audio or document
+func MediaMessage(phoneNumberID, from, messageID, mediaType string, media domain.WebhookMedia) domain.WebhookRequest {
+	message := domain.WebhookMessage{
+		From:      from,
+		ID:        messageID,
+		Timestamp: strconv.FormatInt(time.Now().Unix(), 10),
+		Type:      mediaType,
+	}
+	switch mediaType {
+	case "image":
+		message.Image = &media
+	case "audio":
+		message.Audio = &media
+	case "document":
+		message.Document = &media
+	}
+	return messageWebhook(phoneNumberID, message)
+}
+
+// StatusUpdate builds the webhook notifying the delivery status of a message sent by the business number
+func StatusUpdate(phoneNumberID, messageID, recipient, status string) domain.WebhookRequest {
+	return domain.NewWebhook(businessAccountID, phoneNumberID, domain.WebhookValue{
+		Statuses: []domain.WebhookStatus{{
+			ID:          messageID,
+			Status:      status,
+			Timestamp:   strconv.FormatInt(time.Now().Unix(), 10),
+			RecipientID: recipient,
+		}},
+	})
+}
+
+// messageWebhook wraps a message of a contact in a webhook
+func messageWebhook(phoneNumberID string, message domain.WebhookMessage) domain.WebhookRequest {
+	return domain.NewWebhook(businessAccountID, phoneNumberID, domain.WebhookValue{
+		Contacts: []domain.WebhookContact{contact(message.From)},
+		Messages: []domain.WebhookMessage{message},
+	})
+}
+
+// contact returns the profile of a contact, named after its wa_id
+func contact(waID string) domain.WebhookContact {
+	return domain.WebhookContact{WaID: waID, Profile: domain.WebhookProfile{Name: "Contact " + waID}}
+}
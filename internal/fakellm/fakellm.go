@@ -0,0 +1,302 @@
+// Package fakellm is a scriptable fake LLM server for integration tests.
+//
+// The Server speaks the anyprompt format used by LLM_URL and the OpenAI-compatible chat completions format.
+// Replies are canned, queued or chosen by rules, and the server can be slowed down or made to fail.
+package fakellm
+
+import (
+	"encoding/json"
+	"fmt"
+	"net/http"
+	"net/http/httptest"
+	"strings"
+	"sync"
+	"testing"
+	"time"
+)
+
+const (
+	// DefaultReply is answered when no queued reply or rule applies
+	DefaultReply = "This is a reply of the fake LLM."
+	// AnypromptPath is the path of the anyprompt endpoint
+	AnypromptPath = "/api/v1/chat/ask"
+	// OpenAIPath is the path of the OpenAI-compatible chat completions endpoint
+	OpenAIPath = "/v1/chat/completions"
+)
+
+// Format is the API format of a request
+type Format string
+
+const (
+	// FormatAnyprompt is a {"prompt"} request answered with a {"response"}
+	FormatAnyprompt Format = "anyprompt"
+	// FormatOpenAI is an OpenAI chat completions request
+	FormatOpenAI Format = "openai"
+)
+
+// Request is a request received by the server
+type Request struct {
+	Format Format
+	// Prompt is the prompt of anyprompt requests, or the content of the chat messages joined by blank lines
+	Prompt string
+	// Model is the model asked by OpenAI requests
+	Model  string
+	Header http.Header
+	At     time.Time
+}
+
+// Rule returns the reply to a prompt, ok is false when the rule doesn't apply
+type Rule func(prompt string) (reply string, ok bool)
+
+// Failure is an error answered instead of a reply
+type Failure struct {
+	Status  int
+	Message string
+}
+
+var (
+	// RateLimited is answered when the LLM gets too many requests
+	RateLimited = Failure{Status: http.StatusTooManyRequests, Message: "rate limit exceeded"}
+	// Unavailable is answered when the LLM is down
+	Unavailable = Failure{Status: http.StatusServiceUnavailable, Message: "model is overloaded"}
+)
+
+// Server is a fake LLM running on a local httptest server
+type Server struct {
+	// URL is the anyprompt endpoint, to be used as LLM_URL
+	URL string
+	// OpenAIURL is the base URL of the OpenAI-compatible API, without /chat/completions
+	OpenAIURL string
+
+	server *httptest.Server
+
+	mu       sync.Mutex
+	token    string
+	reply    string
+	queued   []string
+	rules    []Rule
+	latency  time.Duration
+	failures []Failure
+	requests []Request
+	nextID   int
+}
+
+// Option configures the Server
+type Option func(*Server)
+
+// WithToken makes the server reject the requests without the bearer token
+func WithToken(token string) Option {
+	return func(s *Server) {
+		s.token = token
+	}
+}
+
+// WithReply sets the reply answered when no queued reply or rule applies
+func WithReply(reply string) Option {
+	return func(s *Server) {
+		s.reply = reply
+	}
+}
+
+// WithLatency delays every reply
+func WithLatency(latency time.Duration) Option {
+	return func(s *Server) {
+		s.latency = latency
+	}
+}
+
+// New starts a Server that is closed when the test ends
+func New(t testing.TB, opts ...Option) *Server {
+	t.Helper()
+	s := &Server{reply: DefaultReply}
+	for _, opt := range opts {
+		opt(s)
+	}
+
+	mux := http.NewServeMux()
+	mux.HandleFunc("POST "+AnypromptPath, s.anyprompt)
+	mux.HandleFunc("POST "+OpenAIPath, s.openAI)
+	s.server = httptest.NewServer(mux)
+	s.URL = s.server.URL + AnypromptPath
+	s.OpenAIURL = s.server.URL + "/v1"
+	t.Cleanup(s.server.Close)
+	return s
+}
+
+// ReplyNext queues replies answered in order to the next requests, before the rules
+func (s *Server) ReplyNext(replies ...string) {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	s.queued = append(s.queued, replies...)
+}
+
+// When answers reply to the prompts containing text, ignoring case. Rules are tried in the order they were added.
+func (s *Server) When(text, reply string) {
+	s.WhenFunc(func(prompt string) (string, bool) {
+		return reply, strings.Contains(strings.ToLower(prompt), strings.ToLower(text))
+	})
+}
+
+// WhenFunc adds a rule choosing the reply to a prompt
+func (s *Server) WhenFunc(rule Rule) {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	s.rules = append(s.rules, rule)
+}
+
+// SetLatency delays the next replies
+func (s *Server) SetLatency(latency time.Duration) {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	s.latency = latency
+}
+
+// FailNext makes the next requests fail with the failures, one request each and in order
+func (s *Server) FailNext(failures ...Failure) {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	s.failures = append(s.failures, failures...)
+}
+
+// Requests returns the requests received so far, in order
+func (s *Server) Requests() []Request {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	return append([]Request(nil), s.requests...)
+}
+
+// Prompts returns the prompts received so far, in order
+func (s *Server) Prompts() []string {
+	var prompts []string
+	for _, request := range s.Requests() {
+		prompts = append(prompts, request.Prompt)
+	}
+	return prompts
+}
+
+// anyprompt answers {"prompt": "..."} with {"response": "..."}
+func (s *Server) anyprompt(w http.ResponseWriter, r *http.Request) {
+	var body struct {
+		Prompt string `json:"prompt"`
+	}
+	if err := json.NewDecoder(r.Body).Decode(&body); err != nil || body.Prompt == "" {
+		writeJSON(w, http.StatusBadRequest, map[string]string{"error": "prompt is required"})
+		return
+	}
+	reply, failure, ok := s.answer(w, r, Request{Format: FormatAnyprompt, Prompt: body.Prompt})
+	switch {
+	case !ok:
+		return
+	case failure != nil:
+		writeJSON(w, failure.Status, map[string]string{"error": failure.Message})
+	default:
+		writeJSON(w, http.StatusOK, map[string]string{"response": reply})
+	}
+}
+
+// openAI answers a chat completions request with the reply as the assistant message
+func (s *Server) openAI(w http.ResponseWriter, r *http.Request) {
+	var body struct {
+		Model    string `json:"model"`
+		Messages []struct {
+			Role    string `json:"role"`
+			Content string `json:"content"`
+		} `json:"messages"`
+	}
+	if err := json.NewDecoder(r.Body).Decode(&body); err != nil || len(body.Messages) == 0 {
+		writeOpenAIError(w, Failure{Status: http.StatusBadRequest, Message: "messages is required"}, "invalid_request_error")
+		return
+	}
+	contents := make([]string, 0, len(body.Messages))
+	for _, message := range body.Messages {
+		contents = append(contents, message.Content)
+	}
+
+	reply, failure, ok := s.answer(w, r, Request{Format: FormatOpenAI, Prompt: strings.Join(contents, "\n\n"), Model: body.Model})
+	switch {
+	case !ok:
+		return
+	case failure != nil:
+		writeOpenAIError(w, *failure, "server_error")
+	default:
+		s.mu.Lock()
+		s.nextID++
+		id := fmt.Sprintf("chatcmpl-fake-%d", s.nextID)
+		s.mu.Unlock()
+		writeJSON(w, http.StatusOK, map[string]any{
+			"id":      id,
+			"object":  "chat.completion",
+			"created": time.Now().Unix(),
+			"model":   body.Model,
+			"choices": []map[string]any{{
+				"index":         0,
+				"message":       map[string]string{"role": "assistant", "content": reply},
+				"finish_reason": "stop",
+			}},
+			"usage": map[string]int{"prompt_tokens": 0, "completion_tokens": 0, "total_tokens": 0},
+		})
+	}
+}
+
+// answer records the request, waits for the latency and returns the reply or the failure.
+// ok is false when the response was already written or the caller went away.
+func (s *Server) answer(w http.ResponseWriter, r *http.Request, request Request) (string, *Failure, bool) {
+	s.mu.Lock()
+	token := s.token
+	latency := s.latency
+	s.mu.Unlock()
+	if token != "" && r.Header.Get("Authorization") != "Bearer "+token {
+		writeJSON(w, http.StatusUnauthorized, map[string]string{"error": "invalid token"})
+		return "", nil, false
+	}
+
+	request.Header = r.Header.Clone()
+	request.At = time.Now()
+	s.mu.Lock()
+	s.requests = append(s.requests, request)
+	reply, failure := s.next(request.Prompt)
+	s.mu.Unlock()
+
+	if latency > 0 {
+		select {
+		case <-time.After(latency):
+		case <-r.Context().Done():
+			return "", nil, false
+		}
+	}
+	return reply, failure, true
+}
+
+// next returns the failure, queued reply, rule reply or default reply for the prompt, the caller holds the lock
+func (s *Server) next(prompt string) (string, *Failure) {
+	if len(s.failures) > 0 {
+		failure := s.failures[0]
+		s.failures = s.failures[1:]
+		return "", &failure
+	}
+	if len(s.queued) > 0 {
+		reply := s.queued[0]
+		s.queued = s.queued[1:]
+		return reply, nil
+	}
+	for _, rule := range s.rules {
+		if reply, ok := rule(prompt); ok {
+			return reply, nil
+		}
+	}
+	return s.reply, nil
+}
+
+// writeOpenAIError answers the failure in the error format of the OpenAI API
+func writeOpenAIError(w http.ResponseWriter, failure Failure, errorType string) {
+	writeJSON(w, failure.Status, map[string]any{
+		"error": map[string]any{"message": failure.Message, "type": errorType, "code": nil},
+	})
+}
+
+// writeJSON answers the value as JSON
+func writeJSON(w http.ResponseWriter, status int, value any) {
+	w.Header().Set("Content-Type", "application/json")
+	w.WriteHeader(status)
+	_ = json.NewEncoder(w).Encode(value)
+}
@@ -0,0 +1,103 @@
+package fakellm_test
+
+import (
+	"anyzzapp/internal/config"
+	"anyzzapp/internal/fakellm"
+	"anyzzapp/internal/infrastructure"
+	"anyzzapp/internal/infrastructure/client"
+	"anyzzapp/pkg/domain"
+	"context"
+	"encoding/json"
+	"net/http"
+	"strings"
+	"testing"
+	"time"
+
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+// newRepository returns the LLM repository of the application talking to the fake
+func newRepository(llm *fakellm.Server, token string) domain.LLMRepository {
+	return infrastructure.NewLLMRepository(config.Config{LLMUrl: llm.URL}, client.NewHttpClient(&http.Client{}, token))
+}
+
+func TestServer_Anyprompt(t *testing.T) {
+	llm := fakellm.New(t)
+	repo := newRepository(llm, "")
+	ctx := context.Background()
+	llm.When("refund", "Refunds take 5 days.")
+	llm.ReplyNext("First!")
+
+	first, err := repo.SendMessage(ctx, "Where is my refund?")
+	require.NoError(t, err)
+	assert.Equal(t, "First!", first)
+
+	rule, _ := repo.SendMessage(ctx, "Where is my REFUND?")
+	assert.Equal(t, "Refunds take 5 days.", rule)
+
+	fallback, _ := repo.SendMessage(ctx, "Hello")
+	assert.Equal(t, fakellm.DefaultReply, fallback)
+
+	assert.Equal(t, []string{"Where is my refund?", "Where is my REFUND?", "Hello"}, llm.Prompts())
+	assert.Equal(t, fakellm.FormatAnyprompt, llm.Requests()[0].Format)
+}
+
+func TestServer_OpenAI(t *testing.T) {
+	llm := fakellm.New(t, fakellm.WithReply("Hi there"), fakellm.WithToken("sk-test"))
+	body := `{"model": "gpt-test", "messages": [{"role": "system", "content": "Be brief."}, {"role": "user", "content": "Hello"}]}`
+
+	req, _ := http.NewRequest(http.MethodPost, llm.OpenAIURL+"/chat/completions", strings.NewReader(body))
+	req.Header.Set("Authorization", "Bearer sk-test")
+	resp, err := http.DefaultClient.Do(req)
+	require.NoError(t, err)
+	defer resp.Body.Close()
+
+	var completion struct {
+		Model   string `json:"model"`
+		Choices []struct {
+			Message struct {
+				Role    string `json:"role"`
+				Content string `json:"content"`
+			} `json:"message"`
+		} `json:"choices"`
+	}
+	require.NoError(t, json.NewDecoder(resp.Body).Decode(&completion))
+	assert.Equal(t, "gpt-test", completion.Model)
+	assert.Equal(t, "assistant", completion.Choices[0].Message.Role)
+	assert.Equal(t, "Hi there", completion.Choices[0].Message.Content)
+	assert.Equal(t, "Be brief.\n\nHello", llm.Prompts()[0])
+	assert.Equal(t, "gpt-test", llm.Requests()[0].Model)
+}
+
+func TestServer_Token(t *testing.T) {
+	llm := fakellm.New(t, fakellm.WithToken("right-token"))
+
+	_, err := newRepository(llm, "wrong-token").SendMessage(context.Background(), "Hello")
+
+	assert.ErrorContains(t, err, "status 401")
+	assert.Empty(t, llm.Requests())
+}
+
+func TestServer_Failures(t *testing.T) {
+	llm := fakellm.New(t)
+	repo := newRepository(llm, "")
+	llm.FailNext(fakellm.Unavailable)
+
+	_, err := repo.SendMessage(context.Background(), "Hello")
+	assert.ErrorContains(t, err, "status 503")
+
+	reply, err := repo.SendMessage(context.Background(), "Hello")
+	assert.NoError(t, err)
+	assert.Equal(t, fakellm.DefaultReply, reply)
+}
+
+func TestServer_Latency(t *testing.T) {
+	llm := fakellm.New(t, fakellm.WithLatency(time.Second))
+	ctx, cancel := context.WithTimeout(context.Background(), 50*time.Millisecond)
+	defer cancel()
+
+	_, err := newRepository(llm, "").SendMessage(ctx, "Hello")
+
+	assert.ErrorIs(t, err, context.DeadlineExceeded)
+}
@@ -0,0 +1,46 @@
+package infrastructure
+
+import (
+	"anyzzapp/pkg/domain"
+	"context"
+	"fmt"
+	"sync"
+)
+
+// AuditRepository implements an in-memory AuditRepository
+type AuditRepository struct {
+	mu      sync.RWMutex
+	records []domain.AuditRecord
+}
+
+// NewAuditRepository creates a new instance of AuditRepository
+func NewAuditRepository() domain.AuditRepository {
+	return &AuditRepository{}
+}
+
+// Append stores the record
+func (r *AuditRepository) Append(ctx context.Context, record *domain.AuditRecord) error {
+	if record == nil || record.ID == "" {
+		return fmt.Errorf("audit record must have an ID")
+	}
+	r.mu.Lock()
+	defer r.mu.Unlock()
+
+	r.records = append(r.records, *record)
+	return nil
+}
+
+// List returns the most recent records first
+func (r *AuditRepository) List(ctx context.Context, limit int) ([]domain.AuditRecord, error) {
+	r.mu.RLock()
+	defer r.mu.RUnlock()
+
+	records := make([]domain.AuditRecord, 0, len(r.records))
+	for i := len(r.records) - 1; i >= 0; i-- {
+		if limit > 0 && len(records) == limit {
+			break
+		}
+		records = append(records, r.records[i])
+	}
+	return records, nil
+}
@@ -0,0 +1,25 @@
+package infrastructure
+
+import (
+	"anyzzapp/pkg/domain"
+	"context"
+	"testing"
+
+	"github.com/stretchr/testify/assert"
+)
+
+func TestAuditRepository(t *testing.T) {
+	repo := NewAuditRepository()
+	ctx := context.Background()
+
+	assert.Error(t, repo.Append(ctx, nil))
+	_ = repo.Append(ctx, &domain.AuditRecord{ID: "a"})
+	_ = repo.Append(ctx, &domain.AuditRecord{ID: "b"})
+	_ = repo.Append(ctx, &domain.AuditRecord{ID: "c"})
+
+	records, err := repo.List(ctx, 2)
+	assert.NoError(t, err)
+	assert.Len(t, records, 2)
+	assert.Equal(t, "c", records[0].ID)
+	assert.Equal(t, "b", records[1].ID)
+}
@@ -0,0 +1,65 @@
+package boltdb
+
+import (
+	"anyzzapp/pkg/domain"
+	"context"
+	"encoding/json"
+	"fmt"
+
+	bolt "go.etcd.io/bbolt"
+)
+
+// AuditRepository implements an AuditRepository persisted in the database
+type AuditRepository struct {
+	db *bolt.DB
+}
+
+// NewAuditRepository creates a new instance of AuditRepository
+func NewAuditRepository(db *DB) domain.AuditRepository {
+	return &AuditRepository{db: db.bolt}
+}
+
+// Append stores the record
+func (r *AuditRepository) Append(ctx context.Context, record *domain.AuditRecord) error {
+	if record == nil || record.ID == "" {
+		return fmt.Errorf("audit record must have an ID")
+	}
+	data, err := json.Marshal(record)
+	if err != nil {
+		return fmt.Errorf("failed to encode audit record: %w", err)
+	}
+	return r.db.Update(func(tx *bolt.Tx) error {
+		if err := tx.Bucket(auditBucket).Put(auditKey(record), data); err != nil {
+			return fmt.Errorf("failed to write audit record: %w", err)
+		}
+		return nil
+	})
+}
+
+// List returns the most recent records first
+func (r *AuditRepository) List(ctx context.Context, limit int) ([]domain.AuditRecord, error) {
+	records := make([]domain.AuditRecord, 0)
+	err := r.db.View(func(tx *bolt.Tx) error {
+		cursor := tx.Bucket(auditBucket).Cursor()
+		for key, value := cursor.Last(); key != nil; key, value = cursor.Prev() {
+			if limit > 0 && len(records) == limit {
+				break
+			}
+			var record domain.AuditRecord
+			if err := json.Unmarshal(value, &record); err != nil {
+				return fmt.Errorf("failed to decode audit record %s: %w", key[8:], err)
+			}
+			records = append(records, record)
+		}
+		return nil
+	})
+	if err != nil {
+		return nil, err
+	}
+	return records, nil
+}
+
+// auditKey is the time and the ID of the record
+func auditKey(record *domain.AuditRecord) []byte {
+	return append(encodeTime(record.At), record.ID...)
+}
@@ -0,0 +1,42 @@
+package boltdb
+
+import (
+	"anyzzapp/pkg/domain"
+	"context"
+	"testing"
+	"time"
+
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+func TestAuditRepository(t *testing.T) {
+	db, path := openTestDB(t)
+	repo := NewAuditRepository(db)
+	ctx := context.Background()
+	start := time.Date(2024, 1, 1, 0, 0, 0, 0, time.UTC)
+
+	assert.Error(t, repo.Append(ctx, &domain.AuditRecord{}))
+	for i, id := range []string{"a", "b", "c"} {
+		require.NoError(t, repo.Append(ctx, &domain.AuditRecord{
+			ID:     id,
+			Action: domain.AuditActionContactErased,
+			At:     start.Add(time.Duration(i) * time.Hour),
+		}))
+	}
+
+	records, err := repo.List(ctx, 2)
+	assert.NoError(t, err)
+	require.Len(t, records, 2)
+	assert.Equal(t, "c", records[0].ID)
+	assert.Equal(t, "b", records[1].ID)
+
+	// The audit log outlives the process
+	require.NoError(t, db.Close())
+	reopened, err := Open(path)
+	require.NoError(t, err)
+	defer reopened.Close()
+	records, err = NewAuditRepository(reopened).List(ctx, 0)
+	assert.NoError(t, err)
+	assert.Len(t, records, 3)
+}
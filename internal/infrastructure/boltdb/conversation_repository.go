@@ -0,0 +1,130 @@
+package boltdb
+
+import (
+	"anyzzapp/pkg/domain"
+	"encoding/json"
+	"fmt"
+	"sort"
+	"time"
+
+	bolt "go.etcd.io/bbolt"
+)
+
+// ConversationRepository implements a ConversationRepository persisted in the database, so that the
+// conversations handed over to an agent stay with the agent after a restart
+type ConversationRepository struct {
+	db *bolt.DB
+}
+
+// NewConversationRepository creates a new instance of ConversationRepository
+func NewConversationRepository(db *DB) domain.ConversationRepository {
+	return &ConversationRepository{db: db.bolt}
+}
+
+// Get returns the stored conversation, or nil if there is none
+func (r *ConversationRepository) Get(key domain.ConversationKey) (*domain.Conversation, error) {
+	var conversation *domain.Conversation
+	err := r.db.View(func(tx *bolt.Tx) error {
+		var err error
+		conversation, err = getConversation(tx, key)
+		return err
+	})
+	if err != nil {
+		return nil, err
+	}
+	return conversation, nil
+}
+
+// Save stores the conversation, replacing any previous state for the same key
+func (r *ConversationRepository) Save(conversation *domain.Conversation) error {
+	if conversation == nil {
+		return fmt.Errorf("conversation cannot be nil")
+	}
+	return r.db.Update(func(tx *bolt.Tx) error {
+		return putConversation(tx, *conversation)
+	})
+}
+
+// Update runs fn on the stored conversation and saves it in the same transaction
+func (r *ConversationRepository) Update(key domain.ConversationKey, fn func(conversation *domain.Conversation) error) error {
+	return r.db.Update(func(tx *bolt.Tx) error {
+		conversation, err := getConversation(tx, key)
+		if err != nil {
+			return err
+		}
+		if conversation == nil {
+			conversation = &domain.Conversation{Key: key, State: domain.ConversationStateBot}
+		}
+		if err := fn(conversation); err != nil {
+			return err
+		}
+		conversation.Key = key
+		return putConversation(tx, *conversation)
+	})
+}
+
+// Delete forgets everything stored for the conversation
+func (r *ConversationRepository) Delete(key domain.ConversationKey) error {
+	return r.db.Update(func(tx *bolt.Tx) error {
+		if err := tx.Bucket(conversationsBucket).Delete(conversationStateKey(key)); err != nil {
+			return fmt.Errorf("failed to delete conversation: %w", err)
+		}
+		return nil
+	})
+}
+
+// List returns the conversations in the given state, the ones waiting the longest first
+func (r *ConversationRepository) List(state domain.ConversationState) ([]domain.Conversation, error) {
+	conversations := make([]domain.Conversation, 0)
+	err := r.db.View(func(tx *bolt.Tx) error {
+		return tx.Bucket(conversationsBucket).ForEach(func(_, data []byte) error {
+			var conversation domain.Conversation
+			if err := json.Unmarshal(data, &conversation); err != nil {
+				return fmt.Errorf("failed to decode conversation: %w", err)
+			}
+			if state == "" || conversation.State == state {
+				conversations = append(conversations, conversation)
+			}
+			return nil
+		})
+	})
+	if err != nil {
+		return nil, err
+	}
+	sort.Slice(conversations, func(i, j int) bool {
+		return conversations[i].UpdatedAt.Before(conversations[j].UpdatedAt)
+	})
+	return conversations, nil
+}
+
+// getConversation reads the conversation of the key, nil if there is none
+func getConversation(tx *bolt.Tx, key domain.ConversationKey) (*domain.Conversation, error) {
+	data := tx.Bucket(conversationsBucket).Get(conversationStateKey(key))
+	if data == nil {
+		return nil, nil
+	}
+	conversation := &domain.Conversation{}
+	if err := json.Unmarshal(data, conversation); err != nil {
+		return nil, fmt.Errorf("failed to decode conversation: %w", err)
+	}
+	return conversation, nil
+}
+
+// putConversation writes the conversation with its ID and the time of the change
+func putConversation(tx *bolt.Tx, conversation domain.Conversation) error {
+	conversation.ID = conversation.Key.ID()
+	conversation.UpdatedAt = time.Now()
+	data, err := json.Marshal(conversation)
+	if err != nil {
+		return fmt.Errorf("failed to encode conversation: %w", err)
+	}
+	if err := tx.Bucket(conversationsBucket).Put(conversationStateKey(conversation.Key), data); err != nil {
+		return fmt.Errorf("failed to write conversation: %w", err)
+	}
+	return nil
+}
+
+// conversationStateKey is the phone number ID and the wa_id of the conversation, separated by a zero byte
+func conversationStateKey(key domain.ConversationKey) []byte {
+	return []byte(key.PhoneNumberID + "\x00" + key.WaID)
+}
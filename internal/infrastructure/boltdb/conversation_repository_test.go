@@ -0,0 +1,58 @@
+package boltdb
+
+import (
+	"anyzzapp/pkg/domain"
+	"errors"
+	"testing"
+
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+func TestConversationRepository(t *testing.T) {
+	db, path := openTestDB(t)
+	repo := NewConversationRepository(db)
+	human := domain.ConversationKey{PhoneNumberID: "123", WaID: "456"}
+	bot := domain.ConversationKey{PhoneNumberID: "123", WaID: "789"}
+
+	missing, err := repo.Get(human)
+	assert.NoError(t, err)
+	assert.Nil(t, missing)
+
+	require.NoError(t, repo.Save(&domain.Conversation{Key: bot, State: domain.ConversationStateBot}))
+	require.NoError(t, repo.Update(human, func(conversation *domain.Conversation) error {
+		conversation.State = domain.ConversationStateHuman
+		conversation.Messages = append(conversation.Messages, domain.ConversationMessage{ID: "wamid.1", Content: "hola"})
+		return nil
+	}))
+	assert.Error(t, repo.Update(human, func(conversation *domain.Conversation) error {
+		conversation.State = domain.ConversationStateBot
+		return errors.New("rejected")
+	}))
+	assert.Error(t, repo.Save(nil))
+
+	// Handovers survive restarts
+	require.NoError(t, db.Close())
+	reopened, err := Open(path)
+	require.NoError(t, err)
+	defer reopened.Close()
+	repo = NewConversationRepository(reopened)
+
+	conversation, err := repo.Get(human)
+	require.NoError(t, err)
+	assert.Equal(t, "123-456", conversation.ID)
+	assert.Equal(t, domain.ConversationStateHuman, conversation.State, "a failed update saves nothing")
+	assert.Equal(t, "hola", conversation.Messages[0].Content)
+	assert.False(t, conversation.UpdatedAt.IsZero())
+
+	humans, err := repo.List(domain.ConversationStateHuman)
+	require.NoError(t, err)
+	assert.Len(t, humans, 1)
+	all, err := repo.List("")
+	require.NoError(t, err)
+	assert.Equal(t, []string{"123-789", "123-456"}, []string{all[0].ID, all[1].ID}, "the ones waiting the longest first")
+
+	require.NoError(t, repo.Delete(human))
+	deleted, _ := repo.Get(human)
+	assert.Nil(t, deleted)
+}
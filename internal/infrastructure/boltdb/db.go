@@ -0,0 +1,71 @@
+// Package boltdb stores the messages in an embedded bbolt database, a single file that needs no server
+package boltdb
+
+import (
+	"context"
+	"fmt"
+	"os"
+	"time"
+
+	bolt "go.etcd.io/bbolt"
+)
+
+// openTimeout bounds the wait for the file lock held by another process using the same database
+const openTimeout = 5 * time.Second
+
+// DB is an open bbolt database with its schema up to date
+type DB struct {
+	bolt *bolt.DB
+}
+
+// Open opens or creates the database file at path and applies the pending migrations
+func Open(path string) (*DB, error) {
+	db, err := bolt.Open(path, 0o600, &bolt.Options{Timeout: openTimeout})
+	if err != nil {
+		return nil, fmt.Errorf("failed to open database %s: %w", path, err)
+	}
+	if err := migrate(db, migrations); err != nil {
+		db.Close()
+		return nil, err
+	}
+	return &DB{bolt: db}, nil
+}
+
+// OpenReadOnly opens an existing database without writing to it, for tools reading the messages.
+// It fails while a server holds the database, and when its schema is not the current one.
+func OpenReadOnly(path string) (*DB, error) {
+	if _, err := os.Stat(path); err != nil {
+		return nil, fmt.Errorf("failed to open database %s: %w", path, err)
+	}
+	db, err := bolt.Open(path, 0o600, &bolt.Options{Timeout: openTimeout, ReadOnly: true})
+	if err != nil {
+		return nil, fmt.Errorf("failed to open database %s: %w", path, err)
+	}
+	var version int
+	_ = db.View(func(tx *bolt.Tx) error {
+		if meta := tx.Bucket(metaBucket); meta != nil {
+			version = schemaVersion(meta)
+		}
+		return nil
+	})
+	if version != len(migrations) {
+		db.Close()
+		return nil, fmt.Errorf("database schema version %d is not the supported version %d, start the server to migrate it", version, len(migrations))
+	}
+	return &DB{bolt: db}, nil
+}
+
+// Close closes the database file
+func (db *DB) Close() error {
+	return db.bolt.Close()
+}
+
+// CheckWritable writes a probe to check that the database accepts writes
+func (db *DB) CheckWritable(ctx context.Context) error {
+	return db.bolt.Update(func(tx *bolt.Tx) error {
+		if err := ctx.Err(); err != nil {
+			return err
+		}
+		return tx.Bucket(metaBucket).Put([]byte("healthcheck"), []byte(time.Now().UTC().Format(time.RFC3339)))
+	})
+}
@@ -0,0 +1,93 @@
+package boltdb
+
+import (
+	"anyzzapp/pkg/domain"
+	"context"
+	"encoding/json"
+	"fmt"
+	"sort"
+
+	bolt "go.etcd.io/bbolt"
+)
+
+// DeadLetterRepository implements a DeadLetterRepository persisted in the database
+type DeadLetterRepository struct {
+	db *bolt.DB
+}
+
+// NewDeadLetterRepository creates a new instance of DeadLetterRepository
+func NewDeadLetterRepository(db *DB) domain.DeadLetterRepository {
+	return &DeadLetterRepository{db: db.bolt}
+}
+
+// Save stores the dead letter, replacing any previous one with the same ID
+func (r *DeadLetterRepository) Save(ctx context.Context, letter *domain.DeadLetter) error {
+	if letter == nil || letter.ID == "" {
+		return fmt.Errorf("dead letter must have an ID")
+	}
+	data, err := json.Marshal(letter)
+	if err != nil {
+		return fmt.Errorf("failed to encode dead letter: %w", err)
+	}
+	return r.db.Update(func(tx *bolt.Tx) error {
+		if err := tx.Bucket(deadLettersBucket).Put([]byte(letter.ID), data); err != nil {
+			return fmt.Errorf("failed to write dead letter: %w", err)
+		}
+		return nil
+	})
+}
+
+// Get returns the dead letter with the ID, or nil if there is none
+func (r *DeadLetterRepository) Get(ctx context.Context, id string) (*domain.DeadLetter, error) {
+	var letter *domain.DeadLetter
+	err := r.db.View(func(tx *bolt.Tx) error {
+		data := tx.Bucket(deadLettersBucket).Get([]byte(id))
+		if data == nil {
+			return nil
+		}
+		letter = &domain.DeadLetter{}
+		if err := json.Unmarshal(data, letter); err != nil {
+			return fmt.Errorf("failed to decode dead letter: %w", err)
+		}
+		return nil
+	})
+	if err != nil {
+		return nil, err
+	}
+	return letter, nil
+}
+
+// List returns every dead letter, the oldest failure first
+func (r *DeadLetterRepository) List(ctx context.Context) ([]domain.DeadLetter, error) {
+	var letters []domain.DeadLetter
+	err := r.db.View(func(tx *bolt.Tx) error {
+		return tx.Bucket(deadLettersBucket).ForEach(func(_, data []byte) error {
+			var letter domain.DeadLetter
+			if err := json.Unmarshal(data, &letter); err != nil {
+				return fmt.Errorf("failed to decode dead letter: %w", err)
+			}
+			letters = append(letters, letter)
+			return nil
+		})
+	})
+	if err != nil {
+		return nil, err
+	}
+	sort.Slice(letters, func(i, j int) bool {
+		if !letters[i].FirstFailedAt.Equal(letters[j].FirstFailedAt) {
+			return letters[i].FirstFailedAt.Before(letters[j].FirstFailedAt)
+		}
+		return letters[i].ID < letters[j].ID
+	})
+	return letters, nil
+}
+
+// Delete removes the dead letter with the ID, if any
+func (r *DeadLetterRepository) Delete(ctx context.Context, id string) error {
+	return r.db.Update(func(tx *bolt.Tx) error {
+		if err := tx.Bucket(deadLettersBucket).Delete([]byte(id)); err != nil {
+			return fmt.Errorf("failed to delete dead letter: %w", err)
+		}
+		return nil
+	})
+}
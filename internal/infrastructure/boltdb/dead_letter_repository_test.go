@@ -0,0 +1,56 @@
+package boltdb
+
+import (
+	"anyzzapp/pkg/domain"
+	"context"
+	"testing"
+	"time"
+
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+func TestDeadLetterRepository(t *testing.T) {
+	db, path := openTestDB(t)
+	repo := NewDeadLetterRepository(db)
+	ctx := context.Background()
+	at := time.Date(2024, 1, 1, 10, 0, 0, 0, time.UTC)
+
+	missing, err := repo.Get(ctx, "msg_1")
+	assert.NoError(t, err)
+	assert.Nil(t, missing)
+
+	require.NoError(t, repo.Save(ctx, &domain.DeadLetter{
+		ID:            "msg_2",
+		PhoneNumberID: "123",
+		Message:       domain.WebhookMessage{ID: "msg_2", From: "456", Type: "text", Text: &domain.WebhookText{Body: "Hello"}},
+		Error:         "llm: timeout",
+		Attempts:      1,
+		FirstFailedAt: at.Add(time.Minute),
+		NextAttemptAt: at.Add(2 * time.Minute),
+	}))
+	require.NoError(t, repo.Save(ctx, &domain.DeadLetter{ID: "msg_1", Attempts: 3, FirstFailedAt: at}))
+	require.NoError(t, repo.Save(ctx, &domain.DeadLetter{ID: "msg_3", FirstFailedAt: at}))
+	assert.Error(t, repo.Save(ctx, &domain.DeadLetter{}))
+	require.NoError(t, repo.Delete(ctx, "msg_3"))
+
+	// Dead letters survive restarts
+	require.NoError(t, db.Close())
+	reopened, err := Open(path)
+	require.NoError(t, err)
+	defer reopened.Close()
+	repo = NewDeadLetterRepository(reopened)
+
+	letter, err := repo.Get(ctx, "msg_2")
+	assert.NoError(t, err)
+	assert.Equal(t, "Hello", letter.Message.Text.Body)
+	assert.Equal(t, "llm: timeout", letter.Error)
+	assert.True(t, at.Add(2*time.Minute).Equal(letter.NextAttemptAt))
+
+	letters, err := repo.List(ctx)
+	assert.NoError(t, err)
+	if assert.Len(t, letters, 2) {
+		assert.Equal(t, "msg_1", letters[0].ID)
+		assert.Equal(t, "msg_2", letters[1].ID)
+	}
+}
@@ -0,0 +1,381 @@
+package boltdb
+
+import (
+	"anyzzapp/pkg/domain"
+	"bytes"
+	"context"
+	"encoding/binary"
+	"encoding/json"
+	"fmt"
+	"math"
+	"time"
+
+	bolt "go.etcd.io/bbolt"
+)
+
+// MessageRepository implements a MessageRepository persisted in the database
+type MessageRepository struct {
+	db *bolt.DB
+}
+
+// NewMessageRepository creates a new instance of MessageRepository
+func NewMessageRepository(db *DB) domain.MessageRepository {
+	return &MessageRepository{db: db.bolt}
+}
+
+// Save stores the message, replacing any previous one with the same ID
+func (r *MessageRepository) Save(ctx context.Context, message *domain.StoredMessage) error {
+	if message == nil || message.ID == "" {
+		return fmt.Errorf("message must have an ID")
+	}
+	return r.db.Update(func(tx *bolt.Tx) error {
+		previous, err := getMessage(tx, message.ID)
+		if err != nil {
+			return err
+		}
+		if previous != nil {
+			if err := unindex(tx, previous); err != nil {
+				return err
+			}
+		}
+		return putMessage(tx, message)
+	})
+}
+
+// Get returns the message with the ID, or nil if there is none
+func (r *MessageRepository) Get(ctx context.Context, id string) (*domain.StoredMessage, error) {
+	var message *domain.StoredMessage
+	err := r.db.View(func(tx *bolt.Tx) error {
+		var err error
+		message, err = getMessage(tx, id)
+		return err
+	})
+	return message, err
+}
+
+// UpdateStatus applies a status notified by WhatsApp
+func (r *MessageRepository) UpdateStatus(ctx context.Context, id, status, errorMessage string, at time.Time) error {
+	return r.db.Update(func(tx *bolt.Tx) error {
+		message, err := getMessage(tx, id)
+		if err != nil {
+			return err
+		}
+		if message == nil {
+			return domain.ErrMessageNotFound
+		}
+		if !message.ApplyStatus(status, errorMessage, at) {
+			return nil
+		}
+		// The timestamp doesn't change, the index entries stay valid
+		return putJSON(tx, message)
+	})
+}
+
+// List returns the messages matching the filter, oldest first.
+// A filter on a conversation scans only its messages, any other filter scans the messages by time.
+func (r *MessageRepository) List(ctx context.Context, filter domain.MessageFilter) ([]domain.StoredMessage, error) {
+	messages := make([]domain.StoredMessage, 0)
+	err := r.db.View(func(tx *bolt.Tx) error {
+		var index *bolt.Bucket
+		var prefix []byte
+		if filter.PhoneNumberID != "" && filter.WaID != "" {
+			index = tx.Bucket(conversationIndexBucket)
+			prefix = conversationPrefix(filter.PhoneNumberID, filter.WaID)
+		} else {
+			index = tx.Bucket(timeIndexBucket)
+		}
+
+		// A resumed listing starts at the last message of the previous page
+		start := prefix
+		if !filter.Since.IsZero() {
+			start = append(append([]byte(nil), prefix...), encodeTime(filter.Since)...)
+			start = append(start, filter.AfterID...)
+		}
+		cursor := index.Cursor()
+		for key, _ := cursor.Seek(start); key != nil && bytes.HasPrefix(key, prefix); key, _ = cursor.Next() {
+			if err := ctx.Err(); err != nil {
+				return err
+			}
+			message, err := getMessage(tx, string(key[len(prefix)+8:]))
+			if err != nil {
+				return err
+			}
+			if message == nil {
+				continue
+			}
+			if !filter.Until.IsZero() && !message.Timestamp.Before(filter.Until) {
+				break
+			}
+			if !filter.Matches(*message) {
+				continue
+			}
+			messages = append(messages, *message)
+			if filter.Limit > 0 && len(messages) == filter.Limit {
+				break
+			}
+		}
+		return nil
+	})
+	if err != nil {
+		return nil, err
+	}
+	return messages, nil
+}
+
+// ListConversations returns the summaries of the conversations with messages matching the filter, the most recently
+// active first. It scans the messages by time backwards from where the filter resumes, until the page is full, and
+// counts the messages of the conversations of the page only.
+func (r *MessageRepository) ListConversations(ctx context.Context, filter domain.ConversationFilter) ([]domain.ConversationSummary, error) {
+	summaries := make([]domain.ConversationSummary, 0)
+	err := r.db.View(func(tx *bolt.Tx) error {
+		var index *bolt.Bucket
+		var prefix []byte
+		if filter.PhoneNumberID != "" && filter.WaID != "" {
+			index = tx.Bucket(conversationIndexBucket)
+			prefix = conversationPrefix(filter.PhoneNumberID, filter.WaID)
+		} else {
+			index = tx.Bucket(timeIndexBucket)
+		}
+
+		// The conversations active from end on are out of the range or on the previous pages
+		end := filter.Until
+		if !filter.Before.IsZero() {
+			if next := filter.Before.Add(time.Nanosecond); end.IsZero() || next.Before(end) {
+				end = next
+			}
+		}
+		matching := domain.MessageFilter{PhoneNumberID: filter.PhoneNumberID, WaID: filter.WaID, Since: filter.Since, Until: filter.Until}
+		seen := make(map[domain.ConversationKey]bool)
+		cursor := index.Cursor()
+		for key := seekBefore(cursor, prefix, end); key != nil && bytes.HasPrefix(key, prefix); key, _ = cursor.Prev() {
+			if err := ctx.Err(); err != nil {
+				return err
+			}
+			message, err := getMessage(tx, string(key[len(prefix)+8:]))
+			if err != nil {
+				return err
+			}
+			if message == nil {
+				continue
+			}
+			if !filter.Since.IsZero() && message.Timestamp.Before(filter.Since) {
+				break
+			}
+			// The conversations tied with the last one of a full page are collected, the sort decides between them
+			if filter.Limit > 0 && len(summaries) >= filter.Limit &&
+				message.Timestamp.Before(summaries[len(summaries)-1].LastMessageAt) {
+				break
+			}
+			if !matching.Matches(*message) || seen[message.Key()] {
+				continue
+			}
+			seen[message.Key()] = true
+			// The first message found is the last one of the conversation before end
+			if !end.Equal(filter.Until) && hasMessages(tx, message.Key(), end, filter.Until) {
+				continue
+			}
+			summary, err := summarizeConversation(tx, message, filter.Since, filter.Until)
+			if err != nil {
+				return err
+			}
+			if filter.Follows(summary) {
+				summaries = append(summaries, summary)
+			}
+		}
+		return nil
+	})
+	if err != nil {
+		return nil, err
+	}
+	domain.SortConversations(summaries)
+	if filter.Limit > 0 && len(summaries) > filter.Limit {
+		summaries = summaries[:filter.Limit]
+	}
+	return summaries, nil
+}
+
+// Delete removes the messages with the IDs and their index entries, it returns how many existed
+func (r *MessageRepository) Delete(ctx context.Context, ids ...string) (int, error) {
+	deleted := 0
+	err := r.db.Update(func(tx *bolt.Tx) error {
+		deleted = 0
+		for _, id := range ids {
+			message, err := getMessage(tx, id)
+			if err != nil {
+				return err
+			}
+			if message == nil {
+				continue
+			}
+			if err := unindex(tx, message); err != nil {
+				return err
+			}
+			if err := tx.Bucket(messagesBucket).Delete([]byte(id)); err != nil {
+				return fmt.Errorf("failed to delete message: %w", err)
+			}
+			deleted++
+		}
+		return nil
+	})
+	if err != nil {
+		return 0, err
+	}
+	return deleted, nil
+}
+
+// seekBefore moves the cursor to the last key with the prefix whose time is before end, any time when end is zero
+func seekBefore(cursor *bolt.Cursor, prefix []byte, end time.Time) []byte {
+	var bound []byte
+	if end.IsZero() {
+		bound = prefixEnd(prefix)
+	} else {
+		bound = append(append([]byte(nil), prefix...), encodeTime(end)...)
+	}
+	if bound == nil {
+		key, _ := cursor.Last()
+		return key
+	}
+	if key, _ := cursor.Seek(bound); key == nil {
+		key, _ = cursor.Last()
+		return key
+	}
+	key, _ := cursor.Prev()
+	return key
+}
+
+// prefixEnd returns the first key after every key with the prefix, nil when there is none
+func prefixEnd(prefix []byte) []byte {
+	end := append([]byte(nil), prefix...)
+	for i := len(end) - 1; i >= 0; i-- {
+		if end[i] < 0xff {
+			end[i]++
+			return end[:i+1]
+		}
+	}
+	return nil
+}
+
+// hasMessages reports whether the conversation has messages from since to until, until excluded unless zero
+func hasMessages(tx *bolt.Tx, key domain.ConversationKey, since, until time.Time) bool {
+	prefix := conversationPrefix(key.PhoneNumberID, key.WaID)
+	found, _ := tx.Bucket(conversationIndexBucket).Cursor().Seek(append(append([]byte(nil), prefix...), encodeTime(since)...))
+	if found == nil || !bytes.HasPrefix(found, prefix) {
+		return false
+	}
+	return until.IsZero() || bytes.Compare(found[len(prefix):len(prefix)+8], encodeTime(until)) < 0
+}
+
+// summarizeConversation summarizes the messages from since to until of the conversation of last, its last message
+// in the range
+func summarizeConversation(tx *bolt.Tx, last *domain.StoredMessage, since, until time.Time) (domain.ConversationSummary, error) {
+	summary := domain.ConversationSummary{
+		ID:             last.Key().ID(),
+		PhoneNumberID:  last.PhoneNumberID,
+		WaID:           last.WaID,
+		FirstMessageAt: last.Timestamp,
+		LastMessageAt:  last.Timestamp,
+		LastMessage:    last,
+	}
+	prefix := conversationPrefix(last.PhoneNumberID, last.WaID)
+	var first []byte
+	cursor := tx.Bucket(conversationIndexBucket).Cursor()
+	for key, _ := cursor.Seek(append(append([]byte(nil), prefix...), encodeTime(since)...)); key != nil && bytes.HasPrefix(key, prefix); key, _ = cursor.Next() {
+		if !until.IsZero() && bytes.Compare(key[len(prefix):len(prefix)+8], encodeTime(until)) >= 0 {
+			break
+		}
+		if first == nil {
+			first = key
+		}
+		summary.MessageCount++
+	}
+	if first != nil {
+		message, err := getMessage(tx, string(first[len(prefix)+8:]))
+		if err != nil {
+			return summary, err
+		}
+		if message != nil {
+			summary.FirstMessageAt = message.Timestamp
+		}
+	}
+	return summary, nil
+}
+
+// getMessage reads the message with the ID, nil if there is none
+func getMessage(tx *bolt.Tx, id string) (*domain.StoredMessage, error) {
+	data := tx.Bucket(messagesBucket).Get([]byte(id))
+	if data == nil {
+		return nil, nil
+	}
+	var message domain.StoredMessage
+	if err := json.Unmarshal(data, &message); err != nil {
+		return nil, fmt.Errorf("failed to decode message %s: %w", id, err)
+	}
+	return &message, nil
+}
+
+// putMessage writes the message and its index entries
+func putMessage(tx *bolt.Tx, message *domain.StoredMessage) error {
+	if err := putJSON(tx, message); err != nil {
+		return err
+	}
+	if err := tx.Bucket(conversationIndexBucket).Put(conversationKey(message), nil); err != nil {
+		return fmt.Errorf("failed to index message: %w", err)
+	}
+	if err := tx.Bucket(timeIndexBucket).Put(timeKey(message), nil); err != nil {
+		return fmt.Errorf("failed to index message: %w", err)
+	}
+	return nil
+}
+
+// putJSON writes the message without touching the indexes
+func putJSON(tx *bolt.Tx, message *domain.StoredMessage) error {
+	data, err := json.Marshal(message)
+	if err != nil {
+		return fmt.Errorf("failed to encode message: %w", err)
+	}
+	if err := tx.Bucket(messagesBucket).Put([]byte(message.ID), data); err != nil {
+		return fmt.Errorf("failed to write message: %w", err)
+	}
+	return nil
+}
+
+// unindex removes the index entries of the message
+func unindex(tx *bolt.Tx, message *domain.StoredMessage) error {
+	if err := tx.Bucket(conversationIndexBucket).Delete(conversationKey(message)); err != nil {
+		return fmt.Errorf("failed to unindex message: %w", err)
+	}
+	if err := tx.Bucket(timeIndexBucket).Delete(timeKey(message)); err != nil {
+		return fmt.Errorf("failed to unindex message: %w", err)
+	}
+	return nil
+}
+
+// conversationPrefix starts the index keys of the messages of a conversation
+func conversationPrefix(phoneNumberID, waID string) []byte {
+	return []byte(phoneNumberID + "\x00" + waID + "\x00")
+}
+
+// conversationKey is the conversation prefix, the timestamp and the ID of the message
+func conversationKey(message *domain.StoredMessage) []byte {
+	key := conversationPrefix(message.PhoneNumberID, message.WaID)
+	key = append(key, encodeTime(message.Timestamp)...)
+	return append(key, message.ID...)
+}
+
+// timeKey is the timestamp and the ID of the message
+func timeKey(message *domain.StoredMessage) []byte {
+	return append(encodeTime(message.Timestamp), message.ID...)
+}
+
+// encodeTime encodes the time so that the keys sort in chronological order.
+// Times outside of the range of UnixNano (1970 to 2262) are clamped.
+func encodeTime(t time.Time) []byte {
+	value := make([]byte, 8)
+	switch {
+	case t.Year() >= 2262:
+		binary.BigEndian.PutUint64(value, math.MaxUint64)
+	case t.UnixNano() > 0:
+		binary.BigEndian.PutUint64(value, uint64(t.UnixNano()))
+	}
+	return value
+}
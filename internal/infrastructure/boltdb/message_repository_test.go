@@ -0,0 +1,228 @@
+package boltdb
+
+import (
+	"anyzzapp/pkg/domain"
+	"context"
+	"path/filepath"
+	"testing"
+	"time"
+
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+func openTestDB(t *testing.T) (*DB, string) {
+	t.Helper()
+	path := filepath.Join(t.TempDir(), "test.db")
+	db, err := Open(path)
+	require.NoError(t, err)
+	t.Cleanup(func() { db.Close() })
+	return db, path
+}
+
+func TestMessageRepository_SaveGet(t *testing.T) {
+	db, _ := openTestDB(t)
+	repo := NewMessageRepository(db)
+	ctx := context.Background()
+	timestamp := time.Date(2024, 1, 1, 10, 0, 0, 0, time.UTC)
+
+	missing, err := repo.Get(ctx, "wamid.1")
+	assert.NoError(t, err)
+	assert.Nil(t, missing)
+
+	err = repo.Save(ctx, &domain.StoredMessage{
+		ID:            "wamid.1",
+		PhoneNumberID: "123",
+		WaID:          "456",
+		Direction:     domain.MessageDirectionInbound,
+		Type:          "image",
+		Content:       "a caption",
+		Media:         &domain.MediaReference{ID: "media-1", MimeType: "image/jpeg"},
+		Status:        domain.MessageStatusReceived,
+		Timestamp:     timestamp,
+	})
+	assert.NoError(t, err)
+
+	message, err := repo.Get(ctx, "wamid.1")
+	assert.NoError(t, err)
+	assert.Equal(t, "a caption", message.Content)
+	assert.Equal(t, "image/jpeg", message.Media.MimeType)
+	assert.True(t, timestamp.Equal(message.Timestamp))
+
+	assert.Error(t, repo.Save(ctx, nil))
+}
+
+func TestMessageRepository_SaveReplacesIndexes(t *testing.T) {
+	db, _ := openTestDB(t)
+	repo := NewMessageRepository(db)
+	ctx := context.Background()
+	start := time.Date(2024, 1, 1, 0, 0, 0, 0, time.UTC)
+
+	_ = repo.Save(ctx, &domain.StoredMessage{ID: "1", PhoneNumberID: "1", WaID: "a", Timestamp: start})
+	_ = repo.Save(ctx, &domain.StoredMessage{ID: "1", PhoneNumberID: "1", WaID: "b", Timestamp: start.Add(time.Hour)})
+
+	all, _ := repo.List(ctx, domain.MessageFilter{})
+	assert.Len(t, all, 1)
+	old, _ := repo.List(ctx, domain.MessageFilter{PhoneNumberID: "1", WaID: "a"})
+	assert.Empty(t, old)
+}
+
+func TestMessageRepository_UpdateStatus(t *testing.T) {
+	db, _ := openTestDB(t)
+	repo := NewMessageRepository(db)
+	ctx := context.Background()
+	now := time.Now()
+	_ = repo.Save(ctx, &domain.StoredMessage{ID: "wamid.1", Status: domain.MessageStatusSent, Timestamp: now})
+
+	assert.NoError(t, repo.UpdateStatus(ctx, "wamid.1", domain.MessageStatusFailed, "re-engagement window closed", now))
+	assert.NoError(t, repo.UpdateStatus(ctx, "wamid.1", domain.MessageStatusDelivered, "", now))
+
+	message, _ := repo.Get(ctx, "wamid.1")
+	assert.Equal(t, domain.MessageStatusFailed, message.Status)
+	assert.Equal(t, "re-engagement window closed", message.Error)
+	assert.ErrorIs(t, repo.UpdateStatus(ctx, "wamid.2", domain.MessageStatusRead, "", now), domain.ErrMessageNotFound)
+
+	// The status update keeps the message listed
+	listed, _ := repo.List(ctx, domain.MessageFilter{})
+	assert.Len(t, listed, 1)
+}
+
+func TestMessageRepository_List(t *testing.T) {
+	db, _ := openTestDB(t)
+	repo := NewMessageRepository(db)
+	ctx := context.Background()
+	start := time.Date(2024, 1, 1, 0, 0, 0, 0, time.UTC)
+	_ = repo.Save(ctx, &domain.StoredMessage{ID: "c", PhoneNumberID: "1", WaID: "a", Timestamp: start.Add(2 * time.Minute)})
+	_ = repo.Save(ctx, &domain.StoredMessage{ID: "a", PhoneNumberID: "1", WaID: "a", Timestamp: start})
+	_ = repo.Save(ctx, &domain.StoredMessage{ID: "b", PhoneNumberID: "2", WaID: "a", Timestamp: start.Add(time.Minute)})
+
+	tests := []struct {
+		name     string
+		filter   domain.MessageFilter
+		expected []string
+	}{
+		{"all", domain.MessageFilter{}, []string{"a", "b", "c"}},
+		{"conversation", domain.MessageFilter{PhoneNumberID: "1", WaID: "a"}, []string{"a", "c"}},
+		{"tenant", domain.MessageFilter{PhoneNumberID: "1"}, []string{"a", "c"}},
+		{"contact", domain.MessageFilter{WaID: "a"}, []string{"a", "b", "c"}},
+		{"window", domain.MessageFilter{Since: start.Add(time.Minute), Until: start.Add(2 * time.Minute)}, []string{"b"}},
+		{"conversation since", domain.MessageFilter{PhoneNumberID: "1", WaID: "a", Since: start.Add(time.Second)}, []string{"c"}},
+		{"limit", domain.MessageFilter{Limit: 2}, []string{"a", "b"}},
+		{"resume", domain.MessageFilter{Since: start.Add(time.Minute), AfterID: "b"}, []string{"c"}},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			messages, err := repo.List(ctx, tt.filter)
+			assert.NoError(t, err)
+			ids := make([]string, 0, len(messages))
+			for _, message := range messages {
+				ids = append(ids, message.ID)
+			}
+			assert.Equal(t, tt.expected, ids)
+		})
+	}
+}
+
+func TestMessageRepository_Delete(t *testing.T) {
+	db, _ := openTestDB(t)
+	repo := NewMessageRepository(db)
+	ctx := context.Background()
+	start := time.Date(2024, 1, 1, 0, 0, 0, 0, time.UTC)
+	_ = repo.Save(ctx, &domain.StoredMessage{ID: "a", PhoneNumberID: "1", WaID: "a", Timestamp: start})
+	_ = repo.Save(ctx, &domain.StoredMessage{ID: "b", PhoneNumberID: "1", WaID: "a", Timestamp: start.Add(time.Minute)})
+
+	deleted, err := repo.Delete(ctx, "a", "missing")
+	assert.NoError(t, err)
+	assert.Equal(t, 1, deleted)
+
+	message, _ := repo.Get(ctx, "a")
+	assert.Nil(t, message)
+	// The indexes no longer point to the deleted message
+	conversation, _ := repo.List(ctx, domain.MessageFilter{PhoneNumberID: "1", WaID: "a"})
+	assert.Len(t, conversation, 1)
+	all, _ := repo.List(ctx, domain.MessageFilter{})
+	assert.Len(t, all, 1)
+}
+
+func TestMessageRepository_PersistsAcrossReopen(t *testing.T) {
+	db, path := openTestDB(t)
+	_ = NewMessageRepository(db).Save(context.Background(), &domain.StoredMessage{ID: "wamid.1", Timestamp: time.Now()})
+	require.NoError(t, db.Close())
+
+	reopened, err := Open(path)
+	require.NoError(t, err)
+	defer reopened.Close()
+	message, err := NewMessageRepository(reopened).Get(context.Background(), "wamid.1")
+	assert.NoError(t, err)
+	assert.NotNil(t, message)
+}
+
+func TestMessageRepository_ListConversations(t *testing.T) {
+	db, _ := openTestDB(t)
+	repo := NewMessageRepository(db)
+	ctx := context.Background()
+	start := time.Date(2024, 1, 1, 0, 0, 0, 0, time.UTC)
+	at := func(minutes int) time.Time { return start.Add(time.Duration(minutes) * time.Minute) }
+	for _, message := range []domain.StoredMessage{
+		{ID: "a1", PhoneNumberID: "1", WaID: "a", Timestamp: at(0)},
+		{ID: "a2", PhoneNumberID: "1", WaID: "a", Timestamp: at(3)},
+		{ID: "b1", PhoneNumberID: "1", WaID: "b", Timestamp: at(1)},
+		{ID: "b2", PhoneNumberID: "1", WaID: "b", Timestamp: at(3)},
+		{ID: "c1", PhoneNumberID: "2", WaID: "a", Timestamp: at(2)},
+		{ID: "d1", PhoneNumberID: "1", WaID: "c", Timestamp: at(4)},
+		{ID: "d2", PhoneNumberID: "1", WaID: "c", Timestamp: at(5)},
+	} {
+		require.NoError(t, repo.Save(ctx, &message))
+	}
+
+	tests := []struct {
+		name     string
+		filter   domain.ConversationFilter
+		expected []string
+	}{
+		{"all", domain.ConversationFilter{}, []string{"1-c", "1-a", "1-b", "2-a"}},
+		{"tenant", domain.ConversationFilter{PhoneNumberID: "1"}, []string{"1-c", "1-a", "1-b"}},
+		{"conversation", domain.ConversationFilter{PhoneNumberID: "1", WaID: "a"}, []string{"1-a"}},
+		{"until", domain.ConversationFilter{Until: at(4)}, []string{"1-a", "1-b", "2-a"}},
+		{"limit", domain.ConversationFilter{Limit: 2}, []string{"1-c", "1-a"}},
+		{"resume", domain.ConversationFilter{Before: at(3), BeforeID: "1-a"}, []string{"1-b", "2-a"}},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			summaries, err := repo.ListConversations(ctx, tt.filter)
+			require.NoError(t, err)
+			ids := make([]string, 0, len(summaries))
+			for _, summary := range summaries {
+				ids = append(ids, summary.ID)
+			}
+			assert.Equal(t, tt.expected, ids)
+		})
+	}
+
+	t.Run("counts", func(t *testing.T) {
+		summaries, err := repo.ListConversations(ctx, domain.ConversationFilter{Since: at(1), Limit: 2})
+		require.NoError(t, err)
+		require.Len(t, summaries, 2)
+		assert.Equal(t, 2, summaries[0].MessageCount)
+		assert.True(t, at(4).Equal(summaries[0].FirstMessageAt))
+		assert.Equal(t, "d2", summaries[0].LastMessage.ID)
+		assert.Equal(t, 1, summaries[1].MessageCount)
+		assert.True(t, at(3).Equal(summaries[1].FirstMessageAt))
+	})
+
+	t.Run("pages", func(t *testing.T) {
+		var ids []string
+		filter := domain.ConversationFilter{Limit: 1}
+		for {
+			summaries, err := repo.ListConversations(ctx, filter)
+			require.NoError(t, err)
+			if len(summaries) == 0 {
+				break
+			}
+			ids = append(ids, summaries[0].ID)
+			filter.Before = summaries[0].LastMessageAt
+			filter.BeforeID = summaries[0].ID
+		}
+		assert.Equal(t, []string{"1-c", "1-a", "1-b", "2-a"}, ids)
+	})
+}
@@ -0,0 +1,145 @@
+package boltdb
+
+import (
+	"encoding/binary"
+	"fmt"
+
+	"github.com/rs/zerolog/log"
+	bolt "go.etcd.io/bbolt"
+)
+
+var (
+	// metaBucket holds the schema version and the health probe
+	metaBucket = []byte("meta")
+	// messagesBucket maps the message IDs to the JSON of the messages
+	messagesBucket = []byte("messages")
+	// conversationIndexBucket orders the messages of each conversation by time, see conversationKey
+	conversationIndexBucket = []byte("messages_by_conversation")
+	// timeIndexBucket orders every message by time, see timeKey
+	timeIndexBucket = []byte("messages_by_time")
+	// auditBucket holds the audit records ordered by time, see auditKey
+	auditBucket = []byte("audit")
+	// suppressionsBucket maps the contacts that opted out to their suppression, see suppressionKey
+	suppressionsBucket = []byte("suppressions")
+	// deadLettersBucket maps the IDs of the inbound messages that failed to be answered to their dead letter
+	deadLettersBucket = []byte("dead_letters")
+	// outboxBucket maps the IDs of the outbound messages waiting to be sent, or recently sent, to the messages
+	outboxBucket = []byte("outbox")
+	// conversationsBucket maps the conversation keys to the conversations, see conversationStateKey
+	conversationsBucket = []byte("conversations")
+
+	schemaVersionKey = []byte("schema_version")
+)
+
+// migration changes the schema from the previous version, it runs in the transaction recording its version
+type migration func(tx *bolt.Tx) error
+
+// migrations are applied in order, migration i brings the schema to version i+1.
+// Existing migrations must never change, add a new one instead.
+var migrations = []migration{
+	createMessageBuckets,
+	createAuditBucket,
+	createSuppressionBucket,
+	createDeadLetterBucket,
+	createOutboxBucket,
+	createConversationBucket,
+}
+
+// createMessageBuckets creates the messages and their indexes
+func createMessageBuckets(tx *bolt.Tx) error {
+	for _, name := range [][]byte{messagesBucket, conversationIndexBucket, timeIndexBucket} {
+		if _, err := tx.CreateBucket(name); err != nil {
+			return fmt.Errorf("failed to create bucket %s: %w", name, err)
+		}
+	}
+	return nil
+}
+
+// createAuditBucket creates the audit log
+func createAuditBucket(tx *bolt.Tx) error {
+	if _, err := tx.CreateBucket(auditBucket); err != nil {
+		return fmt.Errorf("failed to create bucket %s: %w", auditBucket, err)
+	}
+	return nil
+}
+
+// createSuppressionBucket creates the list of contacts that opted out
+func createSuppressionBucket(tx *bolt.Tx) error {
+	if _, err := tx.CreateBucket(suppressionsBucket); err != nil {
+		return fmt.Errorf("failed to create bucket %s: %w", suppressionsBucket, err)
+	}
+	return nil
+}
+
+// createDeadLetterBucket creates the inbound messages that failed to be answered
+func createDeadLetterBucket(tx *bolt.Tx) error {
+	if _, err := tx.CreateBucket(deadLettersBucket); err != nil {
+		return fmt.Errorf("failed to create bucket %s: %w", deadLettersBucket, err)
+	}
+	return nil
+}
+
+// createOutboxBucket creates the outbound messages waiting to be sent
+func createOutboxBucket(tx *bolt.Tx) error {
+	if _, err := tx.CreateBucket(outboxBucket); err != nil {
+		return fmt.Errorf("failed to create bucket %s: %w", outboxBucket, err)
+	}
+	return nil
+}
+
+// createConversationBucket creates the conversations with their state and history
+func createConversationBucket(tx *bolt.Tx) error {
+	if _, err := tx.CreateBucket(conversationsBucket); err != nil {
+		return fmt.Errorf("failed to create bucket %s: %w", conversationsBucket, err)
+	}
+	return nil
+}
+
+// migrate applies the migrations newer than the schema version of the database, each in its own transaction
+func migrate(db *bolt.DB, migrations []migration) error {
+	var version int
+	err := db.Update(func(tx *bolt.Tx) error {
+		meta, err := tx.CreateBucketIfNotExists(metaBucket)
+		if err != nil {
+			return fmt.Errorf("failed to create bucket %s: %w", metaBucket, err)
+		}
+		version = schemaVersion(meta)
+		return nil
+	})
+	if err != nil {
+		return err
+	}
+	if version > len(migrations) {
+		return fmt.Errorf("database schema version %d is newer than the supported version %d", version, len(migrations))
+	}
+
+	for i := version; i < len(migrations); i++ {
+		err := db.Update(func(tx *bolt.Tx) error {
+			if err := migrations[i](tx); err != nil {
+				return err
+			}
+			return setSchemaVersion(tx.Bucket(metaBucket), i+1)
+		})
+		if err != nil {
+			return fmt.Errorf("failed to migrate database to version %d: %w", i+1, err)
+		}
+		log.Info().Int("version", i+1).Msg("Database migrated")
+	}
+	return nil
+}
+
+// schemaVersion returns the version stored in the meta bucket, 0 for a new database
+func schemaVersion(meta *bolt.Bucket) int {
+	value := meta.Get(schemaVersionKey)
+	if len(value) != 8 {
+		return 0
+	}
+	return int(binary.BigEndian.Uint64(value))
+}
+
+// setSchemaVersion stores the version in the meta bucket
+func setSchemaVersion(meta *bolt.Bucket, version int) error {
+	value := make([]byte, 8)
+	binary.BigEndian.PutUint64(value, uint64(version))
+	return meta.Put(schemaVersionKey, value)
+}
@@ -0,0 +1,65 @@
+package boltdb
+
+import (
+	"context"
+	"errors"
+	"path/filepath"
+	"testing"
+
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+	bolt "go.etcd.io/bbolt"
+)
+
+func TestMigrate(t *testing.T) {
+	db, err := bolt.Open(filepath.Join(t.TempDir(), "test.db"), 0o600, nil)
+	require.NoError(t, err)
+	defer db.Close()
+
+	var applied []int
+	steps := []migration{
+		func(tx *bolt.Tx) error { applied = append(applied, 1); return nil },
+		func(tx *bolt.Tx) error { applied = append(applied, 2); return nil },
+	}
+	require.NoError(t, migrate(db, steps[:1]))
+	require.NoError(t, migrate(db, steps))
+	// Nothing left to apply
+	require.NoError(t, migrate(db, steps))
+	assert.Equal(t, []int{1, 2}, applied)
+
+	// A database migrated by a newer version is not opened
+	assert.Error(t, migrate(db, steps[:1]))
+}
+
+func TestMigrate_FailureKeepsVersion(t *testing.T) {
+	db, err := bolt.Open(filepath.Join(t.TempDir(), "test.db"), 0o600, nil)
+	require.NoError(t, err)
+	defer db.Close()
+
+	failing := []migration{func(tx *bolt.Tx) error { return errors.New("boom") }}
+	assert.Error(t, migrate(db, failing))
+
+	_ = db.View(func(tx *bolt.Tx) error {
+		assert.Equal(t, 0, schemaVersion(tx.Bucket(metaBucket)))
+		return nil
+	})
+}
+
+func TestDB_CheckWritable(t *testing.T) {
+	db, _ := openTestDB(t)
+
+	assert.NoError(t, db.CheckWritable(context.Background()))
+}
+
+func TestOpenReadOnly(t *testing.T) {
+	db, path := openTestDB(t)
+	require.NoError(t, db.Close())
+
+	readOnly, err := OpenReadOnly(path)
+	require.NoError(t, err)
+	defer readOnly.Close()
+	assert.Error(t, readOnly.CheckWritable(context.Background()))
+
+	_, err = OpenReadOnly(filepath.Join(t.TempDir(), "missing.db"))
+	assert.Error(t, err)
+}
@@ -0,0 +1,122 @@
+package boltdb
+
+import (
+	"anyzzapp/pkg/domain"
+	"context"
+	"encoding/json"
+	"fmt"
+	"sort"
+
+	bolt "go.etcd.io/bbolt"
+)
+
+// OutboxRepository implements an OutboxRepository persisted in the database
+type OutboxRepository struct {
+	db *bolt.DB
+}
+
+// NewOutboxRepository creates a new instance of OutboxRepository
+func NewOutboxRepository(db *DB) domain.OutboxRepository {
+	return &OutboxRepository{db: db.bolt}
+}
+
+// Add stores the message unless there is one with the same ID, both in the same transaction
+func (r *OutboxRepository) Add(ctx context.Context, message *domain.OutboxMessage) (bool, error) {
+	if message == nil || message.ID == "" {
+		return false, fmt.Errorf("outbox message must have an ID")
+	}
+	data, err := json.Marshal(message)
+	if err != nil {
+		return false, fmt.Errorf("failed to encode outbox message: %w", err)
+	}
+	added := false
+	err = r.db.Update(func(tx *bolt.Tx) error {
+		bucket := tx.Bucket(outboxBucket)
+		if bucket.Get([]byte(message.ID)) != nil {
+			return nil
+		}
+		if err := bucket.Put([]byte(message.ID), data); err != nil {
+			return fmt.Errorf("failed to write outbox message: %w", err)
+		}
+		added = true
+		return nil
+	})
+	return added, err
+}
+
+// Get returns the message with the ID, or nil if there is none
+func (r *OutboxRepository) Get(ctx context.Context, id string) (*domain.OutboxMessage, error) {
+	var message *domain.OutboxMessage
+	err := r.db.View(func(tx *bolt.Tx) error {
+		data := tx.Bucket(outboxBucket).Get([]byte(id))
+		if data == nil {
+			return nil
+		}
+		message = &domain.OutboxMessage{}
+		if err := json.Unmarshal(data, message); err != nil {
+			return fmt.Errorf("failed to decode outbox message: %w", err)
+		}
+		return nil
+	})
+	if err != nil {
+		return nil, err
+	}
+	return message, nil
+}
+
+// Save replaces the message with the same ID
+func (r *OutboxRepository) Save(ctx context.Context, message *domain.OutboxMessage) error {
+	if message == nil || message.ID == "" {
+		return fmt.Errorf("outbox message must have an ID")
+	}
+	data, err := json.Marshal(message)
+	if err != nil {
+		return fmt.Errorf("failed to encode outbox message: %w", err)
+	}
+	return r.db.Update(func(tx *bolt.Tx) error {
+		if err := tx.Bucket(outboxBucket).Put([]byte(message.ID), data); err != nil {
+			return fmt.Errorf("failed to write outbox message: %w", err)
+		}
+		return nil
+	})
+}
+
+// List returns the messages in the status, the oldest first
+func (r *OutboxRepository) List(ctx context.Context, status domain.OutboxStatus) ([]domain.OutboxMessage, error) {
+	var messages []domain.OutboxMessage
+	err := r.db.View(func(tx *bolt.Tx) error {
+		return tx.Bucket(outboxBucket).ForEach(func(_, data []byte) error {
+			var message domain.OutboxMessage
+			if err := json.Unmarshal(data, &message); err != nil {
+				return fmt.Errorf("failed to decode outbox message: %w", err)
+			}
+			if message.Status == status {
+				messages = append(messages, message)
+			}
+			return nil
+		})
+	})
+	if err != nil {
+		return nil, err
+	}
+	sort.Slice(messages, func(i, j int) bool {
+		if !messages[i].CreatedAt.Equal(messages[j].CreatedAt) {
+			return messages[i].CreatedAt.Before(messages[j].CreatedAt)
+		}
+		return messages[i].ID < messages[j].ID
+	})
+	return messages, nil
+}
+
+// Delete removes the messages with the IDs
+func (r *OutboxRepository) Delete(ctx context.Context, ids ...string) error {
+	return r.db.Update(func(tx *bolt.Tx) error {
+		bucket := tx.Bucket(outboxBucket)
+		for _, id := range ids {
+			if err := bucket.Delete([]byte(id)); err != nil {
+				return fmt.Errorf("failed to delete outbox message: %w", err)
+			}
+		}
+		return nil
+	})
+}
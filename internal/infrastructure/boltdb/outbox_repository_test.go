@@ -0,0 +1,68 @@
+package boltdb
+
+import (
+	"anyzzapp/pkg/domain"
+	"context"
+	"testing"
+	"time"
+
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+func TestOutboxRepository(t *testing.T) {
+	db, path := openTestDB(t)
+	repo := NewOutboxRepository(db)
+	ctx := context.Background()
+	at := time.Date(2024, 1, 1, 10, 0, 0, 0, time.UTC)
+
+	missing, err := repo.Get(ctx, "reply:msg_1")
+	assert.NoError(t, err)
+	assert.Nil(t, missing)
+
+	added, err := repo.Add(ctx, &domain.OutboxMessage{
+		ID:            "reply:msg_1",
+		Message:       domain.Message{PhoneNumberID: "123", To: "456", Content: "Hi", MessageType: "text"},
+		WaID:          "456",
+		Author:        domain.OutboxAuthorBot,
+		Status:        domain.OutboxStatusPending,
+		CreatedAt:     at,
+		NextAttemptAt: at,
+	})
+	require.NoError(t, err)
+	assert.True(t, added)
+	added, err = repo.Add(ctx, &domain.OutboxMessage{ID: "reply:msg_1", Status: domain.OutboxStatusFailed})
+	require.NoError(t, err)
+	assert.False(t, added, "a message is only added once")
+	_, err = repo.Add(ctx, &domain.OutboxMessage{ID: "api:1", Status: domain.OutboxStatusPending, CreatedAt: at.Add(-time.Second)})
+	require.NoError(t, err)
+	require.NoError(t, repo.Save(ctx, &domain.OutboxMessage{ID: "api:2", Status: domain.OutboxStatusSent, MessageID: "wamid.2"}))
+	_, err = repo.Add(ctx, nil)
+	assert.Error(t, err)
+
+	// Pending messages survive restarts
+	require.NoError(t, db.Close())
+	reopened, err := Open(path)
+	require.NoError(t, err)
+	defer reopened.Close()
+	repo = NewOutboxRepository(reopened)
+
+	message, err := repo.Get(ctx, "reply:msg_1")
+	assert.NoError(t, err)
+	assert.Equal(t, "Hi", message.Message.Content)
+	assert.Equal(t, domain.OutboxStatusPending, message.Status)
+	assert.True(t, at.Equal(message.NextAttemptAt))
+
+	pending, err := repo.List(ctx, domain.OutboxStatusPending)
+	assert.NoError(t, err)
+	if assert.Len(t, pending, 2) {
+		assert.Equal(t, "api:1", pending[0].ID)
+		assert.Equal(t, "reply:msg_1", pending[1].ID)
+	}
+	sent, _ := repo.List(ctx, domain.OutboxStatusSent)
+	assert.Len(t, sent, 1)
+
+	require.NoError(t, repo.Delete(ctx, "api:1", "api:2"))
+	sent, _ = repo.List(ctx, domain.OutboxStatusSent)
+	assert.Empty(t, sent)
+}
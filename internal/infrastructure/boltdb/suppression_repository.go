@@ -0,0 +1,72 @@
+package boltdb
+
+import (
+	"anyzzapp/pkg/domain"
+	"context"
+	"encoding/json"
+	"fmt"
+
+	bolt "go.etcd.io/bbolt"
+)
+
+// SuppressionRepository implements a SuppressionRepository persisted in the database
+type SuppressionRepository struct {
+	db *bolt.DB
+}
+
+// NewSuppressionRepository creates a new instance of SuppressionRepository
+func NewSuppressionRepository(db *DB) domain.SuppressionRepository {
+	return &SuppressionRepository{db: db.bolt}
+}
+
+// Get returns the suppression of the contact, or nil if there is none
+func (r *SuppressionRepository) Get(ctx context.Context, phoneNumberID, contact string) (*domain.Suppression, error) {
+	var suppression *domain.Suppression
+	err := r.db.View(func(tx *bolt.Tx) error {
+		data := tx.Bucket(suppressionsBucket).Get(suppressionKey(phoneNumberID, contact))
+		if data == nil {
+			return nil
+		}
+		suppression = &domain.Suppression{}
+		if err := json.Unmarshal(data, suppression); err != nil {
+			return fmt.Errorf("failed to decode suppression: %w", err)
+		}
+		return nil
+	})
+	if err != nil {
+		return nil, err
+	}
+	return suppression, nil
+}
+
+// Add stores the suppression, replacing any previous one of the contact
+func (r *SuppressionRepository) Add(ctx context.Context, suppression *domain.Suppression) error {
+	if suppression == nil || suppression.PhoneNumberID == "" || suppression.Contact == "" {
+		return fmt.Errorf("suppression must have a phone number ID and a contact")
+	}
+	data, err := json.Marshal(suppression)
+	if err != nil {
+		return fmt.Errorf("failed to encode suppression: %w", err)
+	}
+	return r.db.Update(func(tx *bolt.Tx) error {
+		if err := tx.Bucket(suppressionsBucket).Put(suppressionKey(suppression.PhoneNumberID, suppression.Contact), data); err != nil {
+			return fmt.Errorf("failed to write suppression: %w", err)
+		}
+		return nil
+	})
+}
+
+// Remove deletes the suppression of the contact, if any
+func (r *SuppressionRepository) Remove(ctx context.Context, phoneNumberID, contact string) error {
+	return r.db.Update(func(tx *bolt.Tx) error {
+		if err := tx.Bucket(suppressionsBucket).Delete(suppressionKey(phoneNumberID, contact)); err != nil {
+			return fmt.Errorf("failed to delete suppression: %w", err)
+		}
+		return nil
+	})
+}
+
+// suppressionKey is the phone number ID and the contact, separated by a zero byte
+func suppressionKey(phoneNumberID, contact string) []byte {
+	return []byte(phoneNumberID + "\x00" + contact)
+}
@@ -0,0 +1,41 @@
+package boltdb
+
+import (
+	"anyzzapp/pkg/domain"
+	"context"
+	"testing"
+	"time"
+
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+func TestSuppressionRepository(t *testing.T) {
+	db, path := openTestDB(t)
+	repo := NewSuppressionRepository(db)
+	ctx := context.Background()
+	at := time.Date(2024, 1, 1, 10, 0, 0, 0, time.UTC)
+
+	missing, err := repo.Get(ctx, "123", "456")
+	assert.NoError(t, err)
+	assert.Nil(t, missing)
+
+	require.NoError(t, repo.Add(ctx, &domain.Suppression{PhoneNumberID: "123", Contact: "456", Keyword: "BAJA", At: at}))
+	require.NoError(t, repo.Add(ctx, &domain.Suppression{PhoneNumberID: "123", Contact: "789", Keyword: "STOP", At: at}))
+	assert.Error(t, repo.Add(ctx, nil))
+	require.NoError(t, repo.Remove(ctx, "123", "789"))
+
+	// Opt-outs survive restarts
+	require.NoError(t, db.Close())
+	reopened, err := Open(path)
+	require.NoError(t, err)
+	defer reopened.Close()
+	repo = NewSuppressionRepository(reopened)
+
+	suppression, err := repo.Get(ctx, "123", "456")
+	assert.NoError(t, err)
+	assert.Equal(t, "BAJA", suppression.Keyword)
+	assert.True(t, at.Equal(suppression.At))
+	removed, _ := repo.Get(ctx, "123", "789")
+	assert.Nil(t, removed)
+}
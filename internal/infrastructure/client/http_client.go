@@ -1,10 +1,14 @@
 package client
 
 import (
+	"anyzzapp/pkg/logging"
 	"bytes"
+	"context"
 	"encoding/json"
 	"fmt"
 	"github.com/rs/zerolog/log"
+	"go.opentelemetry.io/otel"
+	"go.opentelemetry.io/otel/propagation"
 	"net/http"
 )
 
@@ -14,7 +18,7 @@ type HttpClientImpl struct {
 }
 
 type HttpClient interface {
-	Post(payload interface{}, url string) (*http.Response, error)
+	Post(ctx context.Context, payload interface{}, url string) (*http.Response, error)
 }
 
 func NewHttpClient(client *http.Client, bearerToken string) HttpClient {
@@ -24,18 +28,15 @@ func NewHttpClient(client *http.Client, bearerToken string) HttpClient {
 	}
 }
 
-func (c *HttpClientImpl) Post(payload interface{}, url string) (*http.Response, error) {
-	// TODO add context
-
+func (c *HttpClientImpl) Post(ctx context.Context, payload interface{}, url string) (*http.Response, error) {
 	// Convert payload to JSON
 	jsonPayload, err := json.Marshal(payload)
 	if err != nil {
 		return nil, fmt.Errorf("failed to marshal payload: %w", err)
 	}
-	log.Debug().Msgf("payload to send: %s", string(jsonPayload))
-	log.Debug().Msgf("url %s", url)
+	log.Ctx(ctx).Debug().Str("url", url).Str("payload", logging.JSON(jsonPayload)).Msg("sending request")
 
-	req, err := http.NewRequest("POST", url, bytes.NewBuffer(jsonPayload))
+	req, err := http.NewRequestWithContext(ctx, "POST", url, bytes.NewBuffer(jsonPayload))
 	if err != nil {
 		return nil, fmt.Errorf("failed to create request: %w", err)
 	}
@@ -43,6 +44,12 @@ func (c *HttpClientImpl) Post(payload interface{}, url string) (*http.Response,
 	// Set headers
 	req.Header.Set("Authorization", "Bearer "+c.bearerToken)
 	req.Header.Set("Content-Type", "application/json")
+	// Correlate the call with the request that triggered it
+	if requestID := logging.RequestID(ctx); requestID != "" {
+		req.Header.Set(logging.RequestIDHeader, requestID)
+	}
+	// Propagate the trace context to the called service
+	otel.GetTextMapPropagator().Inject(ctx, propagation.HeaderCarrier(req.Header))
 
 	// Execute request
 	resp, err := c.client.Do(req)
@@ -0,0 +1,65 @@
+package client
+
+import (
+	"anyzzapp/pkg/logging"
+	"context"
+	"net/http"
+	"net/http/httptest"
+	"testing"
+
+	"github.com/stretchr/testify/assert"
+	"go.opentelemetry.io/otel"
+	"go.opentelemetry.io/otel/propagation"
+	sdktrace "go.opentelemetry.io/otel/sdk/trace"
+)
+
+func TestHttpClientImpl_Post(t *testing.T) {
+	otel.SetTextMapPropagator(propagation.TraceContext{})
+	provider := sdktrace.NewTracerProvider()
+	ctx, span := provider.Tracer("test").Start(context.Background(), "parent")
+	defer span.End()
+
+	var received *http.Request
+	server := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		received = r
+		w.WriteHeader(http.StatusOK)
+	}))
+	defer server.Close()
+
+	client := NewHttpClient(server.Client(), "secret-token")
+	resp, err := client.Post(ctx, map[string]string{"prompt": "hi"}, server.URL)
+
+	assert.NoError(t, err)
+	defer resp.Body.Close()
+	assert.Equal(t, "Bearer secret-token", received.Header.Get("Authorization"))
+	assert.Equal(t, "application/json", received.Header.Get("Content-Type"))
+	assert.Contains(t, received.Header.Get("traceparent"), span.SpanContext().TraceID().String())
+}
+
+func TestHttpClientImpl_Post_CanceledContext(t *testing.T) {
+	server := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {}))
+	defer server.Close()
+
+	ctx, cancel := context.WithCancel(context.Background())
+	cancel()
+
+	_, err := NewHttpClient(server.Client(), "").Post(ctx, nil, server.URL)
+
+	assert.Error(t, err)
+}
+
+func TestHttpClientImpl_Post_RequestID(t *testing.T) {
+	var received *http.Request
+	server := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		received = r
+		w.WriteHeader(http.StatusOK)
+	}))
+	defer server.Close()
+
+	ctx := logging.WithRequestID(context.Background(), "req-123")
+	resp, err := NewHttpClient(server.Client(), "").Post(ctx, nil, server.URL)
+
+	assert.NoError(t, err)
+	defer resp.Body.Close()
+	assert.Equal(t, "req-123", received.Header.Get(logging.RequestIDHeader))
+}
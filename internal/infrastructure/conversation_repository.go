@@ -0,0 +1,102 @@
+package infrastructure
+
+import (
+	"anyzzapp/pkg/domain"
+	"fmt"
+	"sort"
+	"sync"
+	"time"
+)
+
+// ConversationRepository implements an in-memory ConversationRepository
+type ConversationRepository struct {
+	mu            sync.RWMutex
+	conversations map[domain.ConversationKey]domain.Conversation
+}
+
+// NewConversationRepository creates a new instance of ConversationRepository
+func NewConversationRepository() domain.ConversationRepository {
+	return &ConversationRepository{
+		conversations: make(map[domain.ConversationKey]domain.Conversation),
+	}
+}
+
+// Get returns a copy of the stored conversation, or nil if there is none
+func (r *ConversationRepository) Get(key domain.ConversationKey) (*domain.Conversation, error) {
+	r.mu.RLock()
+	defer r.mu.RUnlock()
+
+	conversation, ok := r.conversations[key]
+	if !ok {
+		return nil, nil
+	}
+	conversation.Messages = append([]domain.ConversationMessage(nil), conversation.Messages...)
+	return &conversation, nil
+}
+
+// Save stores the conversation, replacing any previous state for the same key
+func (r *ConversationRepository) Save(conversation *domain.Conversation) error {
+	if conversation == nil {
+		return fmt.Errorf("conversation cannot be nil")
+	}
+	r.mu.Lock()
+	defer r.mu.Unlock()
+
+	r.save(conversation)
+	return nil
+}
+
+// Update runs fn on a copy of the stored conversation and saves it, holding the lock in between
+func (r *ConversationRepository) Update(key domain.ConversationKey, fn func(conversation *domain.Conversation) error) error {
+	r.mu.Lock()
+	defer r.mu.Unlock()
+
+	conversation, ok := r.conversations[key]
+	if !ok {
+		conversation = domain.Conversation{Key: key, State: domain.ConversationStateBot}
+	}
+	conversation.Messages = append([]domain.ConversationMessage(nil), conversation.Messages...)
+	if err := fn(&conversation); err != nil {
+		return err
+	}
+	conversation.Key = key
+	r.save(&conversation)
+	return nil
+}
+
+// save stores a copy of the conversation, the caller holds the lock
+func (r *ConversationRepository) save(conversation *domain.Conversation) {
+	stored := *conversation
+	stored.ID = conversation.Key.ID()
+	stored.Messages = append([]domain.ConversationMessage(nil), conversation.Messages...)
+	stored.UpdatedAt = time.Now()
+	r.conversations[conversation.Key] = stored
+}
+
+// Delete forgets everything stored for the conversation
+func (r *ConversationRepository) Delete(key domain.ConversationKey) error {
+	r.mu.Lock()
+	defer r.mu.Unlock()
+
+	delete(r.conversations, key)
+	return nil
+}
+
+// List returns the conversations in the given state, the ones waiting the longest first
+func (r *ConversationRepository) List(state domain.ConversationState) ([]domain.Conversation, error) {
+	r.mu.RLock()
+	defer r.mu.RUnlock()
+
+	conversations := make([]domain.Conversation, 0)
+	for _, conversation := range r.conversations {
+		if state != "" && conversation.State != state {
+			continue
+		}
+		conversation.Messages = append([]domain.ConversationMessage(nil), conversation.Messages...)
+		conversations = append(conversations, conversation)
+	}
+	sort.Slice(conversations, func(i, j int) bool {
+		return conversations[i].UpdatedAt.Before(conversations[j].UpdatedAt)
+	})
+	return conversations, nil
+}
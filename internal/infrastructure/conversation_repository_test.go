@@ -0,0 +1,108 @@
+package infrastructure
+
+import (
+	"anyzzapp/pkg/domain"
+	"errors"
+	"sync"
+	"testing"
+
+	"github.com/stretchr/testify/assert"
+)
+
+func TestConversationRepository_GetMissing(t *testing.T) {
+	repo := NewConversationRepository()
+
+	conversation, err := repo.Get(domain.ConversationKey{PhoneNumberID: "123", WaID: "456"})
+
+	assert.NoError(t, err)
+	assert.Nil(t, conversation)
+}
+
+func TestConversationRepository_SaveGetDelete(t *testing.T) {
+	repo := NewConversationRepository()
+	key := domain.ConversationKey{PhoneNumberID: "123", WaID: "456"}
+
+	err := repo.Save(&domain.Conversation{Key: key, Language: "es"})
+	assert.NoError(t, err)
+
+	conversation, err := repo.Get(key)
+	assert.NoError(t, err)
+	assert.Equal(t, "es", conversation.Language)
+	assert.False(t, conversation.UpdatedAt.IsZero())
+
+	// Changing the returned copy must not change the stored conversation
+	conversation.Language = "pt"
+	stored, _ := repo.Get(key)
+	assert.Equal(t, "es", stored.Language)
+
+	assert.NoError(t, repo.Delete(key))
+	conversation, err = repo.Get(key)
+	assert.NoError(t, err)
+	assert.Nil(t, conversation)
+}
+
+func TestConversationRepository_SaveNil(t *testing.T) {
+	repo := NewConversationRepository()
+
+	assert.Error(t, repo.Save(nil))
+}
+
+func TestConversationRepository_List(t *testing.T) {
+	repo := NewConversationRepository()
+	human := domain.ConversationKey{PhoneNumberID: "123", WaID: "1"}
+	bot := domain.ConversationKey{PhoneNumberID: "123", WaID: "2"}
+
+	assert.NoError(t, repo.Save(&domain.Conversation{Key: human, State: domain.ConversationStateHuman}))
+	assert.NoError(t, repo.Save(&domain.Conversation{Key: bot, State: domain.ConversationStateBot}))
+
+	conversations, err := repo.List(domain.ConversationStateHuman)
+	assert.NoError(t, err)
+	assert.Len(t, conversations, 1)
+	assert.Equal(t, "123-1", conversations[0].ID)
+
+	conversations, err = repo.List("")
+	assert.NoError(t, err)
+	assert.Len(t, conversations, 2)
+}
+
+func TestConversationRepository_Update(t *testing.T) {
+	repo := NewConversationRepository()
+	key := domain.ConversationKey{PhoneNumberID: "123", WaID: "456"}
+
+	// A missing conversation starts with the bot
+	assert.NoError(t, repo.Update(key, func(conversation *domain.Conversation) error {
+		assert.Equal(t, domain.ConversationStateBot, conversation.State)
+		conversation.Language = "es"
+		return nil
+	}))
+	assert.Error(t, repo.Update(key, func(conversation *domain.Conversation) error {
+		conversation.Language = "pt"
+		return errors.New("rejected")
+	}))
+
+	conversation, err := repo.Get(key)
+	assert.NoError(t, err)
+	assert.Equal(t, "es", conversation.Language, "a failed update saves nothing")
+	assert.Equal(t, "123-456", conversation.ID)
+}
+
+func TestConversationRepository_ConcurrentUpdates(t *testing.T) {
+	repo := NewConversationRepository()
+	key := domain.ConversationKey{PhoneNumberID: "123", WaID: "456"}
+
+	var wg sync.WaitGroup
+	for i := 0; i < 50; i++ {
+		wg.Add(1)
+		go func() {
+			defer wg.Done()
+			assert.NoError(t, repo.Update(key, func(conversation *domain.Conversation) error {
+				conversation.Messages = append(conversation.Messages, domain.ConversationMessage{Content: "hi"})
+				return nil
+			}))
+		}()
+	}
+	wg.Wait()
+
+	conversation, _ := repo.Get(key)
+	assert.Len(t, conversation.Messages, 50, "no update is lost")
+}
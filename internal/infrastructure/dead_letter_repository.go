@@ -0,0 +1,78 @@
+package infrastructure
+
+import (
+	"anyzzapp/pkg/domain"
+	"context"
+	"fmt"
+	"sort"
+	"sync"
+)
+
+// DeadLetterRepository implements an in-memory DeadLetterRepository
+type DeadLetterRepository struct {
+	mu      sync.RWMutex
+	letters map[string]domain.DeadLetter
+}
+
+// NewDeadLetterRepository creates a new instance of DeadLetterRepository
+func NewDeadLetterRepository() domain.DeadLetterRepository {
+	return &DeadLetterRepository{
+		letters: make(map[string]domain.DeadLetter),
+	}
+}
+
+// Save stores a copy of the dead letter
+func (r *DeadLetterRepository) Save(ctx context.Context, letter *domain.DeadLetter) error {
+	if letter == nil || letter.ID == "" {
+		return fmt.Errorf("dead letter must have an ID")
+	}
+	r.mu.Lock()
+	defer r.mu.Unlock()
+
+	r.letters[letter.ID] = *letter
+	return nil
+}
+
+// Get returns a copy of the dead letter, or nil if there is none
+func (r *DeadLetterRepository) Get(ctx context.Context, id string) (*domain.DeadLetter, error) {
+	r.mu.RLock()
+	defer r.mu.RUnlock()
+
+	letter, ok := r.letters[id]
+	if !ok {
+		return nil, nil
+	}
+	return &letter, nil
+}
+
+// List returns copies of every dead letter, the oldest failure first
+func (r *DeadLetterRepository) List(ctx context.Context) ([]domain.DeadLetter, error) {
+	r.mu.RLock()
+	defer r.mu.RUnlock()
+
+	letters := make([]domain.DeadLetter, 0, len(r.letters))
+	for _, letter := range r.letters {
+		letters = append(letters, letter)
+	}
+	sortDeadLetters(letters)
+	return letters, nil
+}
+
+// Delete removes the dead letter, if any
+func (r *DeadLetterRepository) Delete(ctx context.Context, id string) error {
+	r.mu.Lock()
+	defer r.mu.Unlock()
+
+	delete(r.letters, id)
+	return nil
+}
+
+// sortDeadLetters orders the dead letters by their first failure, then by ID
+func sortDeadLetters(letters []domain.DeadLetter) {
+	sort.Slice(letters, func(i, j int) bool {
+		if !letters[i].FirstFailedAt.Equal(letters[j].FirstFailedAt) {
+			return letters[i].FirstFailedAt.Before(letters[j].FirstFailedAt)
+		}
+		return letters[i].ID < letters[j].ID
+	})
+}
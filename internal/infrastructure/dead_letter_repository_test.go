@@ -0,0 +1,40 @@
+package infrastructure
+
+import (
+	"anyzzapp/pkg/domain"
+	"context"
+	"testing"
+	"time"
+
+	"github.com/stretchr/testify/assert"
+)
+
+func TestDeadLetterRepository(t *testing.T) {
+	repo := NewDeadLetterRepository()
+	ctx := context.Background()
+	at := time.Date(2024, 1, 1, 10, 0, 0, 0, time.UTC)
+
+	missing, err := repo.Get(ctx, "msg_1")
+	assert.NoError(t, err)
+	assert.Nil(t, missing)
+
+	assert.NoError(t, repo.Save(ctx, &domain.DeadLetter{ID: "msg_2", Attempts: 1, FirstFailedAt: at.Add(time.Minute)}))
+	assert.NoError(t, repo.Save(ctx, &domain.DeadLetter{ID: "msg_1", Attempts: 1, FirstFailedAt: at}))
+	assert.NoError(t, repo.Save(ctx, &domain.DeadLetter{ID: "msg_1", Attempts: 2, FirstFailedAt: at}))
+	assert.Error(t, repo.Save(ctx, &domain.DeadLetter{}))
+
+	letter, err := repo.Get(ctx, "msg_1")
+	assert.NoError(t, err)
+	assert.Equal(t, 2, letter.Attempts)
+
+	letters, err := repo.List(ctx)
+	assert.NoError(t, err)
+	if assert.Len(t, letters, 2) {
+		assert.Equal(t, "msg_1", letters[0].ID)
+		assert.Equal(t, "msg_2", letters[1].ID)
+	}
+
+	assert.NoError(t, repo.Delete(ctx, "msg_1"))
+	deleted, _ := repo.Get(ctx, "msg_1")
+	assert.Nil(t, deleted)
+}
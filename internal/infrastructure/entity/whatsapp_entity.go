@@ -2,11 +2,12 @@ package entity
 
 // SendWhatsAppMessagePayload represents the payload structure for sending messages
 type SendWhatsAppMessagePayload struct {
-	MessagingProduct string `json:"messaging_product"`
-	RecipientType    string `json:"recipient_type"`
-	To               string `json:"to"`
-	Type             string `json:"type"`
-	Text             *Text  `json:"text,omitempty"`
+	MessagingProduct string    `json:"messaging_product"`
+	RecipientType    string    `json:"recipient_type"`
+	To               string    `json:"to"`
+	Type             string    `json:"type"`
+	Text             *Text     `json:"text,omitempty"`
+	Template         *Template `json:"template,omitempty"`
 }
 
 type Text struct {
@@ -14,6 +15,16 @@ type Text struct {
 	Body       string `json:"body"`
 }
 
+// Template is a message template approved by Meta, the only messages allowed outside the customer service window
+type Template struct {
+	Name     string           `json:"name"`
+	Language TemplateLanguage `json:"language"`
+}
+
+type TemplateLanguage struct {
+	Code string `json:"code"`
+}
+
 // Result message response
 type Result struct {
 	Messages []struct {
@@ -0,0 +1,86 @@
+// Package health runs the readiness checks of the dependencies needed to answer messages
+package health
+
+import (
+	"anyzzapp/pkg/domain"
+	"context"
+	"sync"
+	"time"
+)
+
+// Check is a readiness check, it returns an error when the dependency is not usable
+type Check struct {
+	Name string
+	Run  func(ctx context.Context) error
+}
+
+// Checker runs the checks concurrently and caches the report so probes don't hammer the dependencies
+type Checker struct {
+	checks  []Check
+	ttl     time.Duration
+	timeout time.Duration
+	now     func() time.Time
+
+	mu     sync.Mutex
+	report *domain.HealthReport
+}
+
+// NewChecker creates a new instance of Checker keeping reports for ttl, each check is given timeout to complete
+func NewChecker(ttl, timeout time.Duration, checks ...Check) *Checker {
+	return &Checker{
+		checks:  checks,
+		ttl:     ttl,
+		timeout: timeout,
+		now:     time.Now,
+	}
+}
+
+// Ready implements domain.ReadinessChecker, concurrent callers wait for the same run
+func (c *Checker) Ready(ctx context.Context) domain.HealthReport {
+	c.mu.Lock()
+	defer c.mu.Unlock()
+
+	if c.report != nil && c.now().Sub(c.report.CheckedAt) < c.ttl {
+		return *c.report
+	}
+	// A probe giving up must not cache failed checks for the next ones
+	report := c.run(context.WithoutCancel(ctx))
+	c.report = &report
+	return report
+}
+
+// run runs every check with its own timeout
+func (c *Checker) run(ctx context.Context) domain.HealthReport {
+	results := make([]domain.HealthCheckResult, len(c.checks))
+	var wg sync.WaitGroup
+	for i, check := range c.checks {
+		wg.Add(1)
+		go func(i int, check Check) {
+			defer wg.Done()
+			checkCtx, cancel := context.WithTimeout(ctx, c.timeout)
+			defer cancel()
+
+			start := time.Now()
+			result := domain.HealthCheckResult{Name: check.Name, Status: domain.HealthStatusOK}
+			if err := check.Run(checkCtx); err != nil {
+				result.Status = domain.HealthStatusUnavailable
+				result.Error = err.Error()
+			}
+			result.DurationMs = time.Since(start).Milliseconds()
+			results[i] = result
+		}(i, check)
+	}
+	wg.Wait()
+
+	report := domain.HealthReport{
+		Status:    domain.HealthStatusOK,
+		CheckedAt: c.now(),
+		Checks:    results,
+	}
+	for _, result := range results {
+		if result.Status != domain.HealthStatusOK {
+			report.Status = domain.HealthStatusUnavailable
+		}
+	}
+	return report
+}
@@ -0,0 +1,69 @@
+package health
+
+import (
+	"anyzzapp/pkg/domain"
+	"context"
+	"errors"
+	"testing"
+	"time"
+
+	"github.com/stretchr/testify/assert"
+)
+
+func TestChecker_Ready(t *testing.T) {
+	checker := NewChecker(time.Second, time.Second,
+		Check{Name: "ok", Run: func(ctx context.Context) error { return nil }},
+		Check{Name: "down", Run: func(ctx context.Context) error { return errors.New("connection refused") }},
+	)
+
+	report := checker.Ready(context.Background())
+
+	assert.Equal(t, domain.HealthStatusUnavailable, report.Status)
+	assert.Len(t, report.Checks, 2)
+	assert.Equal(t, "ok", report.Checks[0].Name)
+	assert.Equal(t, domain.HealthStatusOK, report.Checks[0].Status)
+	assert.Equal(t, domain.HealthStatusUnavailable, report.Checks[1].Status)
+	assert.Equal(t, "connection refused", report.Checks[1].Error)
+}
+
+func TestChecker_Ready_Cached(t *testing.T) {
+	runs := 0
+	checker := NewChecker(10*time.Second, time.Second,
+		Check{Name: "counted", Run: func(ctx context.Context) error { runs++; return nil }},
+	)
+	now := time.Now()
+	checker.now = func() time.Time { return now }
+
+	checker.Ready(context.Background())
+	checker.Ready(context.Background())
+	assert.Equal(t, 1, runs)
+
+	now = now.Add(11 * time.Second)
+	report := checker.Ready(context.Background())
+	assert.Equal(t, 2, runs)
+	assert.Equal(t, domain.HealthStatusOK, report.Status)
+}
+
+func TestChecker_Ready_Timeout(t *testing.T) {
+	checker := NewChecker(time.Second, 10*time.Millisecond,
+		Check{Name: "slow", Run: func(ctx context.Context) error {
+			<-ctx.Done()
+			return ctx.Err()
+		}},
+	)
+
+	report := checker.Ready(context.Background())
+
+	assert.Equal(t, domain.HealthStatusUnavailable, report.Status)
+	assert.Contains(t, report.Checks[0].Error, "deadline exceeded")
+}
+
+func TestChecker_Ready_IgnoresCallerCancellation(t *testing.T) {
+	checker := NewChecker(time.Second, time.Second,
+		Check{Name: "ctx", Run: func(ctx context.Context) error { return ctx.Err() }},
+	)
+	ctx, cancel := context.WithCancel(context.Background())
+	cancel()
+
+	assert.Equal(t, domain.HealthStatusOK, checker.Ready(ctx).Status)
+}
@@ -0,0 +1,111 @@
+package health
+
+import (
+	"anyzzapp/internal/infrastructure/entity"
+	"anyzzapp/pkg/domain"
+	"context"
+	"encoding/json"
+	"fmt"
+	"net/http"
+	"time"
+)
+
+// probeKey is the conversation written and deleted to check the storage
+var probeKey = domain.ConversationKey{PhoneNumberID: "healthcheck", WaID: "probe"}
+
+// LLMReachable checks that the LLM backend answers HTTP requests.
+// Any status below 500 counts as reachable since the endpoint only accepts POSTs with a prompt.
+func LLMReachable(name string, client *http.Client, llmURL string) Check {
+	return Check{
+		Name: "llm:" + name,
+		Run: func(ctx context.Context) error {
+			req, err := http.NewRequestWithContext(ctx, http.MethodHead, llmURL, nil)
+			if err != nil {
+				return fmt.Errorf("failed to create request: %w", err)
+			}
+			resp, err := client.Do(req)
+			if err != nil {
+				return fmt.Errorf("LLM backend unreachable: %w", err)
+			}
+			defer resp.Body.Close()
+			if resp.StatusCode >= http.StatusInternalServerError {
+				return fmt.Errorf("LLM backend answered with status %d", resp.StatusCode)
+			}
+			return nil
+		},
+	}
+}
+
+// GraphToken checks that the Graph API accepts the WhatsApp access token
+func GraphToken(client *http.Client, baseURL, token string) Check {
+	return Check{
+		Name: "whatsapp:token",
+		Run: func(ctx context.Context) error {
+			req, err := http.NewRequestWithContext(ctx, http.MethodGet, baseURL+"/me?fields=id", nil)
+			if err != nil {
+				return fmt.Errorf("failed to create request: %w", err)
+			}
+			req.Header.Set("Authorization", "Bearer "+token)
+			resp, err := client.Do(req)
+			if err != nil {
+				return fmt.Errorf("graph API unreachable: %w", err)
+			}
+			defer resp.Body.Close()
+			if resp.StatusCode == http.StatusOK {
+				return nil
+			}
+			result := entity.Result{}
+			if err := json.NewDecoder(resp.Body).Decode(&result); err != nil || result.Error.Message == "" {
+				return fmt.Errorf("graph API rejected the token with status %d", resp.StatusCode)
+			}
+			return fmt.Errorf("graph API rejected the token: %s (code: %d)", result.Error.Message, result.Error.Code)
+		},
+	}
+}
+
+// ConversationStorage checks that conversations can be written, read back and deleted
+func ConversationStorage(conversations domain.ConversationRepository) Check {
+	return Check{
+		Name: "storage:conversations",
+		Run: func(ctx context.Context) error {
+			probe := &domain.Conversation{Key: probeKey, State: domain.ConversationStateClosed, UpdatedAt: time.Now()}
+			if err := conversations.Save(probe); err != nil {
+				return fmt.Errorf("failed to write: %w", err)
+			}
+			if _, err := conversations.Get(probeKey); err != nil {
+				return fmt.Errorf("failed to read: %w", err)
+			}
+			if err := conversations.Delete(probeKey); err != nil {
+				return fmt.Errorf("failed to delete: %w", err)
+			}
+			return nil
+		},
+	}
+}
+
+// QueueSaturation fails when the queue is filled up to the threshold, a fraction of its capacity
+func QueueSaturation(name string, depth func() int, capacity int, threshold float64) Check {
+	return Check{
+		Name: "queue:" + name,
+		Run: func(ctx context.Context) error {
+			current := depth()
+			if capacity > 0 && float64(current) >= threshold*float64(capacity) {
+				return fmt.Errorf("queue is saturated: %d of %d", current, capacity)
+			}
+			return nil
+		},
+	}
+}
+
+// Writable checks that a storage accepts writes, write probes a write
+func Writable(name string, write func(ctx context.Context) error) Check {
+	return Check{
+		Name: "storage:" + name,
+		Run: func(ctx context.Context) error {
+			if err := write(ctx); err != nil {
+				return fmt.Errorf("failed to write: %w", err)
+			}
+			return nil
+		},
+	}
+}
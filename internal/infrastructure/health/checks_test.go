@@ -0,0 +1,98 @@
+package health
+
+import (
+	"anyzzapp/internal/infrastructure"
+	"context"
+	"errors"
+	"net/http"
+	"net/http/httptest"
+	"testing"
+
+	"github.com/stretchr/testify/assert"
+)
+
+func TestLLMReachable(t *testing.T) {
+	tests := []struct {
+		name    string
+		status  int
+		wantErr bool
+	}{
+		{name: "Method not allowed is reachable", status: http.StatusMethodNotAllowed, wantErr: false},
+		{name: "OK", status: http.StatusOK, wantErr: false},
+		{name: "Server error", status: http.StatusBadGateway, wantErr: true},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			server := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+				w.WriteHeader(tt.status)
+			}))
+			defer server.Close()
+
+			err := LLMReachable("default", server.Client(), server.URL).Run(context.Background())
+
+			assert.Equal(t, tt.wantErr, err != nil)
+		})
+	}
+}
+
+func TestLLMReachable_Down(t *testing.T) {
+	server := httptest.NewServer(http.NotFoundHandler())
+	server.Close()
+
+	check := LLMReachable("default", server.Client(), server.URL)
+
+	assert.Equal(t, "llm:default", check.Name)
+	assert.Error(t, check.Run(context.Background()))
+}
+
+func TestGraphToken(t *testing.T) {
+	server := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		assert.Equal(t, "/me", r.URL.Path)
+		if r.Header.Get("Authorization") != "Bearer valid" {
+			w.WriteHeader(http.StatusUnauthorized)
+			_, _ = w.Write([]byte(`{"error":{"message":"Error validating access token: Session has expired","code":190}}`))
+			return
+		}
+		_, _ = w.Write([]byte(`{"id":"123"}`))
+	}))
+	defer server.Close()
+
+	assert.NoError(t, GraphToken(server.Client(), server.URL, "valid").Run(context.Background()))
+
+	err := GraphToken(server.Client(), server.URL, "expired").Run(context.Background())
+	assert.Error(t, err)
+	assert.Contains(t, err.Error(), "Session has expired (code: 190)")
+}
+
+func TestConversationStorage(t *testing.T) {
+	conversations := infrastructure.NewConversationRepository()
+
+	assert.NoError(t, ConversationStorage(conversations).Run(context.Background()))
+
+	// The probe doesn't stay in the storage
+	stored, err := conversations.List("")
+	assert.NoError(t, err)
+	assert.Empty(t, stored)
+}
+
+func TestQueueSaturation(t *testing.T) {
+	depth := 0
+	check := QueueSaturation("webhooks", func() int { return depth }, 100, 0.9)
+
+	depth = 89
+	assert.NoError(t, check.Run(context.Background()))
+
+	depth = 90
+	err := check.Run(context.Background())
+	assert.Error(t, err)
+	assert.Equal(t, "queue is saturated: 90 of 100", err.Error())
+}
+
+func TestWritable(t *testing.T) {
+	check := Writable("messages", func(ctx context.Context) error { return errors.New("read-only file system") })
+
+	assert.Equal(t, "storage:messages", check.Name)
+	assert.EqualError(t, check.Run(context.Background()), "failed to write: read-only file system")
+	assert.NoError(t, Writable("messages", func(ctx context.Context) error { return nil }).Run(context.Background()))
+}
@@ -5,10 +5,13 @@ import (
 	client2 "anyzzapp/internal/infrastructure/client"
 	"anyzzapp/internal/infrastructure/entity"
 	"anyzzapp/pkg/domain"
+	"anyzzapp/pkg/logging"
+	"context"
 	"encoding/json"
 	"fmt"
 	"github.com/rs/zerolog/log"
 	"io/ioutil"
+	"net/http"
 )
 
 type LLMRepository struct {
@@ -23,25 +26,27 @@ func NewLLMRepository(config config.Config, client client2.HttpClient) domain.LL
 	}
 }
 
-func (r *LLMRepository) SendMessage(prompt string) (string, error) {
+func (r *LLMRepository) SendMessage(ctx context.Context, prompt string) (string, error) {
 	payload := entity.Request{
 		Prompt: prompt,
 	}
 	// Execute POST
-	resp, err := r.client.Post(payload, r.config.LLMUrl)
+	resp, err := r.client.Post(ctx, payload, r.config.LLMUrl)
 	if err != nil {
 		return "", err
 	}
+	defer resp.Body.Close()
 	body, _ := ioutil.ReadAll(resp.Body)
-
-	fmt.Printf("body: %s\n", string(body))
+	log.Ctx(ctx).Debug().Int("status", resp.StatusCode).Str("body", logging.JSON(body)).Msg("llm response")
+	// An error body would otherwise be read as an empty reply
+	if resp.StatusCode != http.StatusOK {
+		return "", fmt.Errorf("LLM answered with status %d", resp.StatusCode)
+	}
 
 	var response entity.Response
 	if err := json.Unmarshal(body, &response); err != nil {
 		return "", err
 	}
-	defer resp.Body.Close()
-	log.Debug().Msgf("llm response: %v", response)
 
 	return response.Response, nil
 }
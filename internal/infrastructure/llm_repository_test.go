@@ -4,8 +4,10 @@ import (
 	"anyzzapp/internal/config"
 	"anyzzapp/internal/infrastructure/entity"
 	"bytes"
+	"context"
 	"encoding/json"
 	"errors"
+	"fmt"
 	"io"
 	"net/http"
 	"testing"
@@ -54,7 +56,7 @@ func TestLLMRepository_SendMessage_Success(t *testing.T) {
 
 	mockClient.On("Post", expectedPayload, cfg.LLMUrl).Return(mockResponse, nil)
 
-	result, err := repo.SendMessage(prompt)
+	result, err := repo.SendMessage(context.Background(), prompt)
 
 	assert.NoError(t, err)
 	assert.Equal(t, "I'm doing well, thank you!", result)
@@ -77,7 +79,7 @@ func TestLLMRepository_SendMessage_HttpClientError(t *testing.T) {
 
 	mockClient.On("Post", mock.Anything, mock.Anything).Return((*http.Response)(nil), expectedError)
 
-	result, err := repo.SendMessage(prompt)
+	result, err := repo.SendMessage(context.Background(), prompt)
 
 	assert.Error(t, err)
 	assert.Empty(t, result)
@@ -106,13 +108,46 @@ func TestLLMRepository_SendMessage_InvalidJSON(t *testing.T) {
 
 	mockClient.On("Post", mock.Anything, mock.Anything).Return(mockResponse, nil)
 
-	result, err := repo.SendMessage(prompt)
+	result, err := repo.SendMessage(context.Background(), prompt)
 
 	assert.Error(t, err)
 	assert.Empty(t, result)
 	mockClient.AssertExpectations(t)
 }
 
+func TestLLMRepository_SendMessage_ErrorStatus(t *testing.T) {
+	tests := []struct {
+		name   string
+		status int
+		body   string
+	}{
+		{"error body", http.StatusServiceUnavailable, `{"error": "model is overloaded"}`},
+		{"rate limited", http.StatusTooManyRequests, `{"error": "rate limit exceeded"}`},
+		// A reply in an error body is not trusted either
+		{"reply body", http.StatusInternalServerError, `{"response": "partial reply"}`},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			mockClient := &MockHttpClient{}
+			repo := &LLMRepository{
+				config: config.Config{LLMUrl: "https://api.llm.example.com/chat"},
+				client: mockClient,
+			}
+			mockResponse := &http.Response{
+				StatusCode: tt.status,
+				Body:       io.NopCloser(bytes.NewReader([]byte(tt.body))),
+			}
+			mockClient.On("Post", mock.Anything, mock.Anything).Return(mockResponse, nil)
+
+			result, err := repo.SendMessage(context.Background(), "Hello")
+
+			assert.ErrorContains(t, err, fmt.Sprintf("status %d", tt.status))
+			assert.Empty(t, result)
+			mockClient.AssertExpectations(t)
+		})
+	}
+}
+
 func TestLLMRepository_SendMessage_EmptyPrompt(t *testing.T) {
 	cfg := config.Config{
 		LLMUrl:         "https://api.llm.example.com/chat",
@@ -140,7 +175,7 @@ func TestLLMRepository_SendMessage_EmptyPrompt(t *testing.T) {
 
 	mockClient.On("Post", expectedPayload, cfg.LLMUrl).Return(mockResponse, nil)
 
-	result, err := repo.SendMessage(prompt)
+	result, err := repo.SendMessage(context.Background(), prompt)
 
 	assert.NoError(t, err)
 	assert.Equal(t, "Please provide a valid prompt", result)
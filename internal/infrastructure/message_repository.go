@@ -0,0 +1,125 @@
+package infrastructure
+
+import (
+	"anyzzapp/pkg/domain"
+	"context"
+	"fmt"
+	"sort"
+	"sync"
+	"time"
+)
+
+// MessageRepository implements an in-memory MessageRepository
+type MessageRepository struct {
+	mu       sync.RWMutex
+	messages map[string]domain.StoredMessage
+}
+
+// NewMessageRepository creates a new instance of MessageRepository
+func NewMessageRepository() domain.MessageRepository {
+	return &MessageRepository{
+		messages: make(map[string]domain.StoredMessage),
+	}
+}
+
+// Save stores a copy of the message
+func (r *MessageRepository) Save(ctx context.Context, message *domain.StoredMessage) error {
+	if message == nil || message.ID == "" {
+		return fmt.Errorf("message must have an ID")
+	}
+	r.mu.Lock()
+	defer r.mu.Unlock()
+
+	r.messages[message.ID] = copyMessage(*message)
+	return nil
+}
+
+// Get returns a copy of the stored message, or nil if there is none
+func (r *MessageRepository) Get(ctx context.Context, id string) (*domain.StoredMessage, error) {
+	r.mu.RLock()
+	defer r.mu.RUnlock()
+
+	message, ok := r.messages[id]
+	if !ok {
+		return nil, nil
+	}
+	message = copyMessage(message)
+	return &message, nil
+}
+
+// UpdateStatus applies a status notified by WhatsApp
+func (r *MessageRepository) UpdateStatus(ctx context.Context, id, status, errorMessage string, at time.Time) error {
+	r.mu.Lock()
+	defer r.mu.Unlock()
+
+	message, ok := r.messages[id]
+	if !ok {
+		return domain.ErrMessageNotFound
+	}
+	if message.ApplyStatus(status, errorMessage, at) {
+		r.messages[id] = message
+	}
+	return nil
+}
+
+// List returns the messages matching the filter, oldest first
+func (r *MessageRepository) List(ctx context.Context, filter domain.MessageFilter) ([]domain.StoredMessage, error) {
+	r.mu.RLock()
+	defer r.mu.RUnlock()
+
+	messages := make([]domain.StoredMessage, 0)
+	for _, message := range r.messages {
+		if filter.Matches(message) {
+			messages = append(messages, copyMessage(message))
+		}
+	}
+	sort.Slice(messages, func(i, j int) bool {
+		if messages[i].Timestamp.Equal(messages[j].Timestamp) {
+			return messages[i].ID < messages[j].ID
+		}
+		return messages[i].Timestamp.Before(messages[j].Timestamp)
+	})
+	if filter.Limit > 0 && len(messages) > filter.Limit {
+		messages = messages[:filter.Limit]
+	}
+	return messages, nil
+}
+
+// ListConversations returns the summaries of the conversations with messages matching the filter, the most recently
+// active first
+func (r *MessageRepository) ListConversations(ctx context.Context, filter domain.ConversationFilter) ([]domain.ConversationSummary, error) {
+	messages, err := r.List(ctx, domain.MessageFilter{
+		PhoneNumberID: filter.PhoneNumberID,
+		WaID:          filter.WaID,
+		Since:         filter.Since,
+		Until:         filter.Until,
+	})
+	if err != nil {
+		return nil, err
+	}
+	return domain.SummarizeConversations(messages, filter), nil
+}
+
+// Delete removes the messages with the IDs and returns how many existed
+func (r *MessageRepository) Delete(ctx context.Context, ids ...string) (int, error) {
+	r.mu.Lock()
+	defer r.mu.Unlock()
+
+	deleted := 0
+	for _, id := range ids {
+		if _, ok := r.messages[id]; ok {
+			delete(r.messages, id)
+			deleted++
+		}
+	}
+	return deleted, nil
+}
+
+// copyMessage copies the message so the stored one can't be changed through a pointer
+func copyMessage(message domain.StoredMessage) domain.StoredMessage {
+	if message.Media != nil {
+		media := *message.Media
+		message.Media = &media
+	}
+	return message
+}
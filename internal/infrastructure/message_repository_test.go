@@ -0,0 +1,118 @@
+package infrastructure
+
+import (
+	"anyzzapp/pkg/domain"
+	"context"
+	"testing"
+	"time"
+
+	"github.com/stretchr/testify/assert"
+)
+
+func TestMessageRepository_SaveGet(t *testing.T) {
+	repo := NewMessageRepository()
+	ctx := context.Background()
+
+	missing, err := repo.Get(ctx, "wamid.1")
+	assert.NoError(t, err)
+	assert.Nil(t, missing)
+
+	err = repo.Save(ctx, &domain.StoredMessage{
+		ID:    "wamid.1",
+		Type:  "image",
+		Media: &domain.MediaReference{ID: "media-1"},
+	})
+	assert.NoError(t, err)
+
+	message, err := repo.Get(ctx, "wamid.1")
+	assert.NoError(t, err)
+	assert.Equal(t, "media-1", message.Media.ID)
+
+	// Changing the returned copy must not change the stored message
+	message.Media.ID = "changed"
+	stored, _ := repo.Get(ctx, "wamid.1")
+	assert.Equal(t, "media-1", stored.Media.ID)
+
+	assert.Error(t, repo.Save(ctx, &domain.StoredMessage{}))
+}
+
+func TestMessageRepository_UpdateStatus(t *testing.T) {
+	repo := NewMessageRepository()
+	ctx := context.Background()
+	now := time.Now()
+	_ = repo.Save(ctx, &domain.StoredMessage{ID: "wamid.1", Status: domain.MessageStatusSent})
+
+	assert.NoError(t, repo.UpdateStatus(ctx, "wamid.1", domain.MessageStatusRead, "", now))
+	// A late delivered status must not move the message back
+	assert.NoError(t, repo.UpdateStatus(ctx, "wamid.1", domain.MessageStatusDelivered, "", now))
+
+	message, _ := repo.Get(ctx, "wamid.1")
+	assert.Equal(t, domain.MessageStatusRead, message.Status)
+	assert.ErrorIs(t, repo.UpdateStatus(ctx, "wamid.2", domain.MessageStatusRead, "", now), domain.ErrMessageNotFound)
+}
+
+func TestMessageRepository_List(t *testing.T) {
+	repo := NewMessageRepository()
+	ctx := context.Background()
+	start := time.Date(2024, 1, 1, 0, 0, 0, 0, time.UTC)
+	_ = repo.Save(ctx, &domain.StoredMessage{ID: "c", PhoneNumberID: "1", WaID: "a", Timestamp: start.Add(2 * time.Minute)})
+	_ = repo.Save(ctx, &domain.StoredMessage{ID: "a", PhoneNumberID: "1", WaID: "a", Timestamp: start})
+	_ = repo.Save(ctx, &domain.StoredMessage{ID: "b", PhoneNumberID: "1", WaID: "b", Timestamp: start.Add(time.Minute)})
+
+	all, err := repo.List(ctx, domain.MessageFilter{})
+	assert.NoError(t, err)
+	assert.Equal(t, []string{"a", "b", "c"}, messageIDs(all))
+
+	conversation, _ := repo.List(ctx, domain.MessageFilter{PhoneNumberID: "1", WaID: "a"})
+	assert.Equal(t, []string{"a", "c"}, messageIDs(conversation))
+
+	window, _ := repo.List(ctx, domain.MessageFilter{Since: start.Add(time.Minute), Until: start.Add(2 * time.Minute)})
+	assert.Equal(t, []string{"b"}, messageIDs(window))
+
+	limited, _ := repo.List(ctx, domain.MessageFilter{Limit: 2})
+	assert.Equal(t, []string{"a", "b"}, messageIDs(limited))
+}
+
+func TestMessageRepository_ListConversations(t *testing.T) {
+	repo := NewMessageRepository()
+	ctx := context.Background()
+	start := time.Date(2024, 1, 1, 0, 0, 0, 0, time.UTC)
+	_ = repo.Save(ctx, &domain.StoredMessage{ID: "a1", PhoneNumberID: "1", WaID: "a", Timestamp: start})
+	_ = repo.Save(ctx, &domain.StoredMessage{ID: "b1", PhoneNumberID: "1", WaID: "b", Timestamp: start.Add(time.Minute)})
+	_ = repo.Save(ctx, &domain.StoredMessage{ID: "a2", PhoneNumberID: "1", WaID: "a", Timestamp: start.Add(2 * time.Minute)})
+
+	summaries, err := repo.ListConversations(ctx, domain.ConversationFilter{Limit: 1})
+	assert.NoError(t, err)
+	if assert.Len(t, summaries, 1) {
+		assert.Equal(t, "1-a", summaries[0].ID)
+		assert.Equal(t, 2, summaries[0].MessageCount)
+	}
+
+	summaries, err = repo.ListConversations(ctx, domain.ConversationFilter{Before: summaries[0].LastMessageAt, BeforeID: "1-a"})
+	assert.NoError(t, err)
+	if assert.Len(t, summaries, 1) {
+		assert.Equal(t, "1-b", summaries[0].ID)
+	}
+}
+
+func TestMessageRepository_Delete(t *testing.T) {
+	repo := NewMessageRepository()
+	ctx := context.Background()
+	_ = repo.Save(ctx, &domain.StoredMessage{ID: "a"})
+	_ = repo.Save(ctx, &domain.StoredMessage{ID: "b"})
+
+	deleted, err := repo.Delete(ctx, "a", "missing")
+	assert.NoError(t, err)
+	assert.Equal(t, 1, deleted)
+
+	remaining, _ := repo.List(ctx, domain.MessageFilter{})
+	assert.Equal(t, []string{"b"}, messageIDs(remaining))
+}
+
+func messageIDs(messages []domain.StoredMessage) []string {
+	ids := make([]string, 0, len(messages))
+	for _, message := range messages {
+		ids = append(ids, message.ID)
+	}
+	return ids
+}
@@ -0,0 +1,83 @@
+package metrics
+
+import (
+	"anyzzapp/pkg/domain"
+	"context"
+	"errors"
+	"strconv"
+	"time"
+)
+
+// LLMRepository records the latency of every request of the wrapped LLMRepository
+type LLMRepository struct {
+	next    domain.LLMRepository
+	metrics domain.Metrics
+	backend string
+}
+
+// InstrumentLLMRepository wraps an LLMRepository to record its latency under the backend label
+func InstrumentLLMRepository(next domain.LLMRepository, metrics domain.Metrics, backend string) domain.LLMRepository {
+	return &LLMRepository{
+		next:    next,
+		metrics: metrics,
+		backend: backend,
+	}
+}
+
+// SendMessage sends the prompt through the wrapped repository
+func (r *LLMRepository) SendMessage(ctx context.Context, prompt string) (string, error) {
+	start := time.Now()
+	response, err := r.next.SendMessage(ctx, prompt)
+	r.metrics.ObserveLLMLatency(r.backend, time.Since(start))
+	return response, err
+}
+
+// WhatsAppRepository records latency and error codes of the wrapped WhatsAppRepository
+type WhatsAppRepository struct {
+	next    domain.WhatsAppRepository
+	metrics domain.Metrics
+}
+
+// InstrumentWhatsAppRepository wraps a WhatsAppRepository to record its Graph API requests
+func InstrumentWhatsAppRepository(next domain.WhatsAppRepository, metrics domain.Metrics) domain.WhatsAppRepository {
+	return &WhatsAppRepository{
+		next:    next,
+		metrics: metrics,
+	}
+}
+
+// SendMessage sends a message through the wrapped repository
+func (r *WhatsAppRepository) SendMessage(ctx context.Context, message domain.Message) (*domain.SendMessageResponse, error) {
+	start := time.Now()
+	response, err := r.next.SendMessage(ctx, message)
+	r.observe("send_message", start, err)
+	return response, err
+}
+
+// MarkAsRead marks a message as read through the wrapped repository
+func (r *WhatsAppRepository) MarkAsRead(ctx context.Context, phoneNumberID, messageID string) error {
+	start := time.Now()
+	err := r.next.MarkAsRead(ctx, phoneNumberID, messageID)
+	r.observe("mark_as_read", start, err)
+	return err
+}
+
+// observe records the latency of an operation and its error code if it failed
+func (r *WhatsAppRepository) observe(operation string, start time.Time, err error) {
+	r.metrics.ObserveGraphAPILatency(operation, time.Since(start))
+	if err != nil {
+		r.metrics.IncGraphAPIError(operation, errorCode(err))
+	}
+}
+
+// errorCode returns the Graph API error code, the HTTP status if there is no code, or "network"
+func errorCode(err error) string {
+	var apiErr *domain.APIError
+	if !errors.As(err, &apiErr) {
+		return "network"
+	}
+	if apiErr.Code != 0 {
+		return strconv.Itoa(apiErr.Code)
+	}
+	return "http_" + strconv.Itoa(apiErr.StatusCode)
+}
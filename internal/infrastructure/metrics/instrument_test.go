@@ -0,0 +1,128 @@
+package metrics
+
+import (
+	"anyzzapp/pkg/domain"
+	"context"
+	"errors"
+	"fmt"
+	"sync"
+	"testing"
+	"time"
+
+	"github.com/stretchr/testify/assert"
+)
+
+// recordingMetrics records the calls made to Metrics
+type recordingMetrics struct {
+	mu       sync.Mutex
+	counters map[string]int
+}
+
+func newRecordingMetrics() *recordingMetrics {
+	return &recordingMetrics{counters: make(map[string]int)}
+}
+
+func (m *recordingMetrics) inc(name string) {
+	m.mu.Lock()
+	defer m.mu.Unlock()
+	m.counters[name]++
+}
+
+func (m *recordingMetrics) IncInboundMessage(messageType string) { m.inc("inbound:" + messageType) }
+func (m *recordingMetrics) IncAutoReply(result string)           { m.inc("auto_reply:" + result) }
+func (m *recordingMetrics) ObserveLLMLatency(backend string, _ time.Duration) {
+	m.inc("llm:" + backend)
+}
+func (m *recordingMetrics) ObserveGraphAPILatency(operation string, _ time.Duration) {
+	m.inc("graph:" + operation)
+}
+func (m *recordingMetrics) IncGraphAPIError(operation, code string) {
+	m.inc("graph_error:" + operation + ":" + code)
+}
+func (m *recordingMetrics) ObserveHTTPRequest(method, route string, status int, _ time.Duration) {
+	m.inc(fmt.Sprintf("http:%s %s %d", method, route, status))
+}
+func (m *recordingMetrics) SetQueueDepth(queue string, depth int) {
+	m.inc(fmt.Sprintf("queue:%s:%d", queue, depth))
+}
+
+type stubLLMRepository struct {
+	err error
+}
+
+func (r stubLLMRepository) SendMessage(ctx context.Context, prompt string) (string, error) {
+	return "reply to " + prompt, r.err
+}
+
+type stubWhatsAppRepository struct {
+	err error
+}
+
+func (r stubWhatsAppRepository) SendMessage(ctx context.Context, message domain.Message) (*domain.SendMessageResponse, error) {
+	return &domain.SendMessageResponse{MessageID: "msg_1"}, r.err
+}
+
+func (r stubWhatsAppRepository) MarkAsRead(ctx context.Context, phoneNumberID, messageID string) error {
+	return r.err
+}
+
+func TestInstrumentLLMRepository(t *testing.T) {
+	m := newRecordingMetrics()
+	repo := InstrumentLLMRepository(stubLLMRepository{}, m, "anyprompt")
+
+	response, err := repo.SendMessage(context.Background(), "hi")
+
+	assert.NoError(t, err)
+	assert.Equal(t, "reply to hi", response)
+	assert.Equal(t, 1, m.counters["llm:anyprompt"])
+}
+
+func TestInstrumentWhatsAppRepository_Success(t *testing.T) {
+	m := newRecordingMetrics()
+	repo := InstrumentWhatsAppRepository(stubWhatsAppRepository{}, m)
+
+	response, err := repo.SendMessage(context.Background(), domain.Message{})
+	assert.NoError(t, err)
+	assert.Equal(t, "msg_1", response.MessageID)
+	assert.NoError(t, repo.MarkAsRead(context.Background(), "123", "msg_1"))
+
+	assert.Equal(t, 1, m.counters["graph:send_message"])
+	assert.Equal(t, 1, m.counters["graph:mark_as_read"])
+	assert.Len(t, m.counters, 2)
+}
+
+func TestInstrumentWhatsAppRepository_ErrorCodes(t *testing.T) {
+	tests := []struct {
+		name     string
+		err      error
+		expected string
+	}{
+		{
+			name:     "Graph API error code",
+			err:      &domain.APIError{StatusCode: 400, Code: 131026, Message: "Message undeliverable"},
+			expected: "graph_error:send_message:131026",
+		},
+		{
+			name:     "HTTP status without code",
+			err:      fmt.Errorf("wrapped: %w", &domain.APIError{StatusCode: 502}),
+			expected: "graph_error:send_message:http_502",
+		},
+		{
+			name:     "Network error",
+			err:      errors.New("connection refused"),
+			expected: "graph_error:send_message:network",
+		},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			m := newRecordingMetrics()
+			repo := InstrumentWhatsAppRepository(stubWhatsAppRepository{err: tt.err}, m)
+
+			_, err := repo.SendMessage(context.Background(), domain.Message{})
+
+			assert.ErrorIs(t, err, tt.err)
+			assert.Equal(t, 1, m.counters[tt.expected])
+		})
+	}
+}
@@ -0,0 +1,133 @@
+package metrics
+
+import (
+	"anyzzapp/pkg/domain"
+	"net/http"
+	"strconv"
+	"time"
+
+	"github.com/prometheus/client_golang/prometheus"
+	"github.com/prometheus/client_golang/prometheus/collectors"
+	"github.com/prometheus/client_golang/prometheus/promhttp"
+)
+
+const namespace = "anyzzapp"
+
+// PrometheusMetrics implements Metrics on a Prometheus registry
+type PrometheusMetrics struct {
+	registry           *prometheus.Registry
+	inboundMessages    *prometheus.CounterVec
+	autoReplies        *prometheus.CounterVec
+	llmLatency         *prometheus.HistogramVec
+	graphAPILatency    *prometheus.HistogramVec
+	graphAPIErrors     *prometheus.CounterVec
+	httpRequests       *prometheus.CounterVec
+	httpRequestLatency *prometheus.HistogramVec
+	queueDepth         *prometheus.GaugeVec
+}
+
+// NewPrometheusMetrics creates a new instance of PrometheusMetrics with its own registry
+func NewPrometheusMetrics() *PrometheusMetrics {
+	m := &PrometheusMetrics{
+		registry: prometheus.NewRegistry(),
+		inboundMessages: prometheus.NewCounterVec(prometheus.CounterOpts{
+			Namespace: namespace,
+			Name:      "inbound_messages_total",
+			Help:      "Messages received through the webhook by type.",
+		}, []string{"type"}),
+		autoReplies: prometheus.NewCounterVec(prometheus.CounterOpts{
+			Namespace: namespace,
+			Name:      "auto_replies_total",
+			Help:      "Auto-replies by result (sent or failed).",
+		}, []string{"result"}),
+		llmLatency: prometheus.NewHistogramVec(prometheus.HistogramOpts{
+			Namespace: namespace,
+			Name:      "llm_request_duration_seconds",
+			Help:      "Latency of the LLM requests by backend.",
+			Buckets:   []float64{0.1, 0.25, 0.5, 1, 2.5, 5, 10, 20, 30, 60},
+		}, []string{"backend"}),
+		graphAPILatency: prometheus.NewHistogramVec(prometheus.HistogramOpts{
+			Namespace: namespace,
+			Name:      "graph_api_request_duration_seconds",
+			Help:      "Latency of the WhatsApp Graph API requests by operation.",
+			Buckets:   prometheus.DefBuckets,
+		}, []string{"operation"}),
+		graphAPIErrors: prometheus.NewCounterVec(prometheus.CounterOpts{
+			Namespace: namespace,
+			Name:      "graph_api_errors_total",
+			Help:      "WhatsApp Graph API errors by operation and error code.",
+		}, []string{"operation", "code"}),
+		httpRequests: prometheus.NewCounterVec(prometheus.CounterOpts{
+			Namespace: namespace,
+			Name:      "http_requests_total",
+			Help:      "HTTP requests by method, route and status.",
+		}, []string{"method", "route", "status"}),
+		httpRequestLatency: prometheus.NewHistogramVec(prometheus.HistogramOpts{
+			Namespace: namespace,
+			Name:      "http_request_duration_seconds",
+			Help:      "Latency of the HTTP requests by method and route.",
+			Buckets:   prometheus.DefBuckets,
+		}, []string{"method", "route"}),
+		queueDepth: prometheus.NewGaugeVec(prometheus.GaugeOpts{
+			Namespace: namespace,
+			Name:      "queue_depth",
+			Help:      "Jobs waiting in each queue.",
+		}, []string{"queue"}),
+	}
+	m.registry.MustRegister(
+		collectors.NewGoCollector(),
+		collectors.NewProcessCollector(collectors.ProcessCollectorOpts{}),
+		m.inboundMessages,
+		m.autoReplies,
+		m.llmLatency,
+		m.graphAPILatency,
+		m.graphAPIErrors,
+		m.httpRequests,
+		m.httpRequestLatency,
+		m.queueDepth,
+	)
+	return m
+}
+
+// Handler returns the handler that exposes the metrics in Prometheus text format
+func (m *PrometheusMetrics) Handler() http.Handler {
+	return promhttp.HandlerFor(m.registry, promhttp.HandlerOpts{})
+}
+
+// IncInboundMessage counts a message received through the webhook
+func (m *PrometheusMetrics) IncInboundMessage(messageType string) {
+	m.inboundMessages.WithLabelValues(messageType).Inc()
+}
+
+// IncAutoReply counts an auto-reply by result
+func (m *PrometheusMetrics) IncAutoReply(result string) {
+	m.autoReplies.WithLabelValues(result).Inc()
+}
+
+// ObserveLLMLatency records the duration of an LLM request
+func (m *PrometheusMetrics) ObserveLLMLatency(backend string, duration time.Duration) {
+	m.llmLatency.WithLabelValues(backend).Observe(duration.Seconds())
+}
+
+// ObserveGraphAPILatency records the duration of a Graph API request
+func (m *PrometheusMetrics) ObserveGraphAPILatency(operation string, duration time.Duration) {
+	m.graphAPILatency.WithLabelValues(operation).Observe(duration.Seconds())
+}
+
+// IncGraphAPIError counts a failed Graph API request
+func (m *PrometheusMetrics) IncGraphAPIError(operation, code string) {
+	m.graphAPIErrors.WithLabelValues(operation, code).Inc()
+}
+
+// ObserveHTTPRequest records a served HTTP request
+func (m *PrometheusMetrics) ObserveHTTPRequest(method, route string, status int, duration time.Duration) {
+	m.httpRequests.WithLabelValues(method, route, strconv.Itoa(status)).Inc()
+	m.httpRequestLatency.WithLabelValues(method, route).Observe(duration.Seconds())
+}
+
+// SetQueueDepth records the number of jobs waiting in a queue
+func (m *PrometheusMetrics) SetQueueDepth(queue string, depth int) {
+	m.queueDepth.WithLabelValues(queue).Set(float64(depth))
+}
+
+var _ domain.Metrics = (*PrometheusMetrics)(nil)
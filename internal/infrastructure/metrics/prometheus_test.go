@@ -0,0 +1,50 @@
+package metrics
+
+import (
+	"errors"
+	"net/http"
+	"net/http/httptest"
+	"testing"
+	"time"
+
+	"github.com/prometheus/client_golang/prometheus/testutil"
+	"github.com/stretchr/testify/assert"
+)
+
+func TestPrometheusMetrics_Counters(t *testing.T) {
+	m := NewPrometheusMetrics()
+
+	m.IncInboundMessage("text")
+	m.IncInboundMessage("text")
+	m.IncInboundMessage("image")
+	m.IncAutoReply("sent")
+	m.IncGraphAPIError("send_message", "131026")
+	m.ObserveHTTPRequest("POST", "/api/v1/whatsapp/webhook", 200, 10*time.Millisecond)
+	m.SetQueueDepth("outbox", 3)
+
+	assert.Equal(t, 2.0, testutil.ToFloat64(m.inboundMessages.WithLabelValues("text")))
+	assert.Equal(t, 1.0, testutil.ToFloat64(m.inboundMessages.WithLabelValues("image")))
+	assert.Equal(t, 1.0, testutil.ToFloat64(m.autoReplies.WithLabelValues("sent")))
+	assert.Equal(t, 1.0, testutil.ToFloat64(m.graphAPIErrors.WithLabelValues("send_message", "131026")))
+	assert.Equal(t, 1.0, testutil.ToFloat64(m.httpRequests.WithLabelValues("POST", "/api/v1/whatsapp/webhook", "200")))
+	assert.Equal(t, 3.0, testutil.ToFloat64(m.queueDepth.WithLabelValues("outbox")))
+}
+
+func TestPrometheusMetrics_Handler(t *testing.T) {
+	m := NewPrometheusMetrics()
+	m.ObserveLLMLatency("localhost:8081", 1500*time.Millisecond)
+	m.ObserveGraphAPILatency("mark_as_read", 80*time.Millisecond)
+
+	w := httptest.NewRecorder()
+	m.Handler().ServeHTTP(w, httptest.NewRequest("GET", "/metrics", nil))
+
+	assert.Equal(t, http.StatusOK, w.Code)
+	body := w.Body.String()
+	assert.Contains(t, body, `anyzzapp_llm_request_duration_seconds_count{backend="localhost:8081"} 1`)
+	assert.Contains(t, body, `anyzzapp_graph_api_request_duration_seconds_count{operation="mark_as_read"} 1`)
+	assert.Contains(t, body, "go_goroutines")
+}
+
+func TestErrorCode(t *testing.T) {
+	assert.Equal(t, "network", errorCode(errors.New("connection refused")))
+}
@@ -0,0 +1,97 @@
+package infrastructure
+
+import (
+	"anyzzapp/pkg/domain"
+	"context"
+	"fmt"
+	"sort"
+	"sync"
+)
+
+// OutboxRepository implements an in-memory OutboxRepository
+type OutboxRepository struct {
+	mu       sync.RWMutex
+	messages map[string]domain.OutboxMessage
+}
+
+// NewOutboxRepository creates a new instance of OutboxRepository
+func NewOutboxRepository() domain.OutboxRepository {
+	return &OutboxRepository{
+		messages: make(map[string]domain.OutboxMessage),
+	}
+}
+
+// Add stores a copy of the message unless there is one with the same ID
+func (r *OutboxRepository) Add(ctx context.Context, message *domain.OutboxMessage) (bool, error) {
+	if message == nil || message.ID == "" {
+		return false, fmt.Errorf("outbox message must have an ID")
+	}
+	r.mu.Lock()
+	defer r.mu.Unlock()
+
+	if _, exists := r.messages[message.ID]; exists {
+		return false, nil
+	}
+	r.messages[message.ID] = *message
+	return true, nil
+}
+
+// Get returns a copy of the message, or nil if there is none
+func (r *OutboxRepository) Get(ctx context.Context, id string) (*domain.OutboxMessage, error) {
+	r.mu.RLock()
+	defer r.mu.RUnlock()
+
+	message, ok := r.messages[id]
+	if !ok {
+		return nil, nil
+	}
+	return &message, nil
+}
+
+// Save stores a copy of the message, replacing the one with the same ID
+func (r *OutboxRepository) Save(ctx context.Context, message *domain.OutboxMessage) error {
+	if message == nil || message.ID == "" {
+		return fmt.Errorf("outbox message must have an ID")
+	}
+	r.mu.Lock()
+	defer r.mu.Unlock()
+
+	r.messages[message.ID] = *message
+	return nil
+}
+
+// List returns copies of the messages in the status, the oldest first
+func (r *OutboxRepository) List(ctx context.Context, status domain.OutboxStatus) ([]domain.OutboxMessage, error) {
+	r.mu.RLock()
+	defer r.mu.RUnlock()
+
+	var messages []domain.OutboxMessage
+	for _, message := range r.messages {
+		if message.Status == status {
+			messages = append(messages, message)
+		}
+	}
+	sortOutbox(messages)
+	return messages, nil
+}
+
+// Delete removes the messages, if any
+func (r *OutboxRepository) Delete(ctx context.Context, ids ...string) error {
+	r.mu.Lock()
+	defer r.mu.Unlock()
+
+	for _, id := range ids {
+		delete(r.messages, id)
+	}
+	return nil
+}
+
+// sortOutbox orders the messages by creation, then by ID
+func sortOutbox(messages []domain.OutboxMessage) {
+	sort.Slice(messages, func(i, j int) bool {
+		if !messages[i].CreatedAt.Equal(messages[j].CreatedAt) {
+			return messages[i].CreatedAt.Before(messages[j].CreatedAt)
+		}
+		return messages[i].ID < messages[j].ID
+	})
+}
@@ -0,0 +1,49 @@
+package infrastructure
+
+import (
+	"anyzzapp/pkg/domain"
+	"context"
+	"testing"
+	"time"
+
+	"github.com/stretchr/testify/assert"
+)
+
+func TestOutboxRepository(t *testing.T) {
+	repo := NewOutboxRepository()
+	ctx := context.Background()
+	at := time.Date(2024, 1, 1, 10, 0, 0, 0, time.UTC)
+
+	missing, err := repo.Get(ctx, "reply:msg_1")
+	assert.NoError(t, err)
+	assert.Nil(t, missing)
+
+	added, err := repo.Add(ctx, &domain.OutboxMessage{ID: "reply:msg_2", Status: domain.OutboxStatusPending, CreatedAt: at.Add(time.Second)})
+	assert.NoError(t, err)
+	assert.True(t, added)
+	added, _ = repo.Add(ctx, &domain.OutboxMessage{ID: "reply:msg_1", Status: domain.OutboxStatusPending, CreatedAt: at})
+	assert.True(t, added)
+	// A message is only added once
+	added, err = repo.Add(ctx, &domain.OutboxMessage{ID: "reply:msg_1", Status: domain.OutboxStatusFailed})
+	assert.NoError(t, err)
+	assert.False(t, added)
+	_, err = repo.Add(ctx, &domain.OutboxMessage{})
+	assert.Error(t, err)
+
+	pending, err := repo.List(ctx, domain.OutboxStatusPending)
+	assert.NoError(t, err)
+	if assert.Len(t, pending, 2) {
+		assert.Equal(t, "reply:msg_1", pending[0].ID)
+		assert.Equal(t, "reply:msg_2", pending[1].ID)
+	}
+
+	assert.NoError(t, repo.Save(ctx, &domain.OutboxMessage{ID: "reply:msg_1", Status: domain.OutboxStatusSent, MessageID: "wamid.1"}))
+	message, _ := repo.Get(ctx, "reply:msg_1")
+	assert.Equal(t, "wamid.1", message.MessageID)
+	sent, _ := repo.List(ctx, domain.OutboxStatusSent)
+	assert.Len(t, sent, 1)
+
+	assert.NoError(t, repo.Delete(ctx, "reply:msg_1", "reply:msg_2"))
+	pending, _ = repo.List(ctx, domain.OutboxStatusPending)
+	assert.Empty(t, pending)
+}
@@ -0,0 +1,155 @@
+// Package recording writes the webhooks received to rotating JSONL files and reads them back to replay them
+package recording
+
+import (
+	"anyzzapp/pkg/domain"
+	"anyzzapp/pkg/logging"
+	"bufio"
+	"context"
+	"encoding/json"
+	"fmt"
+	"net/http"
+	"os"
+	"path/filepath"
+	"sort"
+	"sync"
+	"time"
+)
+
+// filePrefix and fileSuffix frame the name of the recording files, the creation time in between sorts them
+const (
+	filePrefix = "webhooks-"
+	fileSuffix = ".jsonl"
+)
+
+// maxLineSize bounds the recordings read back, webhooks are far smaller
+const maxLineSize = 16 << 20
+
+// secretHeaders are never written, a replay doesn't need them
+var secretHeaders = []string{"Authorization", "Cookie", "Proxy-Authorization"}
+
+// Recorder appends the webhooks to JSONL files in a directory, one recording per line.
+// A new file is started when the current one would grow past the size limit.
+type Recorder struct {
+	dir     string
+	maxSize int64
+	maskPII bool
+
+	mu   sync.Mutex
+	file *os.File
+	size int64
+}
+
+// NewRecorder creates a Recorder writing to dir, creating it if needed.
+// With maskPII the phone numbers and message content of the bodies are masked like in the logs.
+func NewRecorder(dir string, maxSize int64, maskPII bool) (*Recorder, error) {
+	if err := os.MkdirAll(dir, 0o750); err != nil {
+		return nil, fmt.Errorf("failed to create the recording directory: %w", err)
+	}
+	return &Recorder{dir: dir, maxSize: maxSize, maskPII: maskPII}, nil
+}
+
+// Record appends the recording to the current file
+func (r *Recorder) Record(ctx context.Context, recording domain.WebhookRecording) error {
+	recording.Header = recording.Header.Clone()
+	for _, name := range secretHeaders {
+		recording.Header.Del(name)
+	}
+	if r.maskPII {
+		recording.Body = logging.MaskJSON([]byte(recording.Body))
+	}
+	line, err := json.Marshal(recording)
+	if err != nil {
+		return fmt.Errorf("failed to encode the recording: %w", err)
+	}
+	line = append(line, '\n')
+
+	r.mu.Lock()
+	defer r.mu.Unlock()
+	if r.file != nil && r.size > 0 && r.size+int64(len(line)) > r.maxSize {
+		if err := r.file.Close(); err != nil {
+			return fmt.Errorf("failed to close the recording file: %w", err)
+		}
+		r.file = nil
+	}
+	if r.file == nil {
+		if err := r.rotate(recording.At); err != nil {
+			return err
+		}
+	}
+	n, err := r.file.Write(line)
+	r.size += int64(n)
+	if err != nil {
+		return fmt.Errorf("failed to write the recording: %w", err)
+	}
+	return nil
+}
+
+// rotate opens a new file named after the time of its first recording, the caller holds the lock
+func (r *Recorder) rotate(at time.Time) error {
+	name := filePrefix + at.UTC().Format("20060102T150405.000000000Z") + fileSuffix
+	file, err := os.OpenFile(filepath.Join(r.dir, name), os.O_CREATE|os.O_WRONLY|os.O_APPEND, 0o640)
+	if err != nil {
+		return fmt.Errorf("failed to open the recording file: %w", err)
+	}
+	info, err := file.Stat()
+	if err != nil {
+		_ = file.Close()
+		return fmt.Errorf("failed to open the recording file: %w", err)
+	}
+	r.file = file
+	r.size = info.Size()
+	return nil
+}
+
+// Close closes the current file
+func (r *Recorder) Close() error {
+	r.mu.Lock()
+	defer r.mu.Unlock()
+	if r.file == nil {
+		return nil
+	}
+	err := r.file.Close()
+	r.file = nil
+	return err
+}
+
+// Files returns the recording files of a directory, oldest first
+func Files(dir string) ([]string, error) {
+	files, err := filepath.Glob(filepath.Join(dir, filePrefix+"*"+fileSuffix))
+	if err != nil {
+		return nil, err
+	}
+	sort.Strings(files)
+	return files, nil
+}
+
+// ReadFile reads the recordings of a file, in the order they were written
+func ReadFile(path string) ([]domain.WebhookRecording, error) {
+	file, err := os.Open(path)
+	if err != nil {
+		return nil, err
+	}
+	defer file.Close()
+
+	var recordings []domain.WebhookRecording
+	scanner := bufio.NewScanner(file)
+	scanner.Buffer(make([]byte, 0, 64*1024), maxLineSize)
+	for line := 1; scanner.Scan(); line++ {
+		if len(scanner.Bytes()) == 0 {
+			continue
+		}
+		var recording domain.WebhookRecording
+		if err := json.Unmarshal(scanner.Bytes(), &recording); err != nil {
+			return nil, fmt.Errorf("%s:%d: invalid recording: %w", path, line, err)
+		}
+		if recording.Header == nil {
+			recording.Header = http.Header{}
+		}
+		recordings = append(recordings, recording)
+	}
+	if err := scanner.Err(); err != nil {
+		return nil, fmt.Errorf("failed to read %s: %w", path, err)
+	}
+	return recordings, nil
+}
@@ -0,0 +1,97 @@
+package recording
+
+import (
+	"anyzzapp/pkg/domain"
+	"context"
+	"net/http"
+	"os"
+	"path/filepath"
+	"testing"
+	"time"
+
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+// webhook is a recording of a text message received at the time
+func webhook(at time.Time) domain.WebhookRecording {
+	return domain.WebhookRecording{
+		At:     at,
+		Method: "POST",
+		Path:   "/api/v1/whatsapp/webhook",
+		Header: http.Header{"Content-Type": {"application/json"}, "Authorization": {"Bearer secret"}},
+		Body:   `{"entry":[{"changes":[{"value":{"messages":[{"from":"5491112345678","text":{"body":"Hello"}}]}}]}]}`,
+	}
+}
+
+func TestRecorder_RecordAndRead(t *testing.T) {
+	dir := filepath.Join(t.TempDir(), "recordings")
+	recorder, err := NewRecorder(dir, 1<<20, false)
+	require.NoError(t, err)
+	at := time.Date(2024, 1, 1, 10, 0, 0, 0, time.UTC)
+
+	require.NoError(t, recorder.Record(context.Background(), webhook(at)))
+	require.NoError(t, recorder.Record(context.Background(), webhook(at.Add(time.Second))))
+	require.NoError(t, recorder.Close())
+
+	files, err := Files(dir)
+	require.NoError(t, err)
+	require.Len(t, files, 1)
+	recordings, err := ReadFile(files[0])
+	require.NoError(t, err)
+	require.Len(t, recordings, 2)
+	assert.True(t, at.Equal(recordings[0].At))
+	assert.Equal(t, webhook(at).Body, recordings[0].Body, "the body is kept byte for byte")
+	assert.Equal(t, "application/json", recordings[0].Header.Get("Content-Type"))
+	assert.Empty(t, recordings[0].Header.Get("Authorization"), "secrets are never recorded")
+}
+
+func TestRecorder_MasksPII(t *testing.T) {
+	dir := t.TempDir()
+	recorder, err := NewRecorder(dir, 1<<20, true)
+	require.NoError(t, err)
+
+	require.NoError(t, recorder.Record(context.Background(), webhook(time.Now())))
+	require.NoError(t, recorder.Close())
+
+	files, err := Files(dir)
+	require.NoError(t, err)
+	recordings, err := ReadFile(files[0])
+	require.NoError(t, err)
+	assert.Contains(t, recordings[0].Body, `"from":"*********5678"`)
+	assert.Contains(t, recordings[0].Body, `"body":"[redacted 5 chars]"`)
+	assert.NotContains(t, recordings[0].Body, "Hello")
+}
+
+func TestRecorder_Rotates(t *testing.T) {
+	dir := t.TempDir()
+	// Every recording is bigger than the limit so each one gets its own file
+	recorder, err := NewRecorder(dir, 10, false)
+	require.NoError(t, err)
+	at := time.Date(2024, 1, 1, 10, 0, 0, 0, time.UTC)
+
+	for i := 0; i < 3; i++ {
+		require.NoError(t, recorder.Record(context.Background(), webhook(at.Add(time.Duration(i)*time.Second))))
+	}
+	require.NoError(t, recorder.Close())
+
+	files, err := Files(dir)
+	require.NoError(t, err)
+	require.Len(t, files, 3)
+	assert.Equal(t, "webhooks-20240101T100000.000000000Z.jsonl", filepath.Base(files[0]))
+	for i, file := range files {
+		recordings, err := ReadFile(file)
+		require.NoError(t, err)
+		require.Len(t, recordings, 1)
+		assert.True(t, at.Add(time.Duration(i)*time.Second).Equal(recordings[0].At))
+	}
+}
+
+func TestReadFile_Invalid(t *testing.T) {
+	path := filepath.Join(t.TempDir(), "webhooks.jsonl")
+	require.NoError(t, os.WriteFile(path, []byte("{\"method\":\"POST\"}\n\nnot json\n"), 0o600))
+
+	_, err := ReadFile(path)
+
+	assert.ErrorContains(t, err, "webhooks.jsonl:3: invalid recording")
+}
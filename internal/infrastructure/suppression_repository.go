@@ -0,0 +1,54 @@
+package infrastructure
+
+import (
+	"anyzzapp/pkg/domain"
+	"context"
+	"fmt"
+	"sync"
+)
+
+// SuppressionRepository implements an in-memory SuppressionRepository
+type SuppressionRepository struct {
+	mu           sync.RWMutex
+	suppressions map[domain.ConversationKey]domain.Suppression
+}
+
+// NewSuppressionRepository creates a new instance of SuppressionRepository
+func NewSuppressionRepository() domain.SuppressionRepository {
+	return &SuppressionRepository{
+		suppressions: make(map[domain.ConversationKey]domain.Suppression),
+	}
+}
+
+// Get returns a copy of the suppression of the contact, or nil if there is none
+func (r *SuppressionRepository) Get(ctx context.Context, phoneNumberID, contact string) (*domain.Suppression, error) {
+	r.mu.RLock()
+	defer r.mu.RUnlock()
+
+	suppression, ok := r.suppressions[domain.ConversationKey{PhoneNumberID: phoneNumberID, WaID: contact}]
+	if !ok {
+		return nil, nil
+	}
+	return &suppression, nil
+}
+
+// Add stores a copy of the suppression
+func (r *SuppressionRepository) Add(ctx context.Context, suppression *domain.Suppression) error {
+	if suppression == nil || suppression.PhoneNumberID == "" || suppression.Contact == "" {
+		return fmt.Errorf("suppression must have a phone number ID and a contact")
+	}
+	r.mu.Lock()
+	defer r.mu.Unlock()
+
+	r.suppressions[domain.ConversationKey{PhoneNumberID: suppression.PhoneNumberID, WaID: suppression.Contact}] = *suppression
+	return nil
+}
+
+// Remove deletes the suppression of the contact, if any
+func (r *SuppressionRepository) Remove(ctx context.Context, phoneNumberID, contact string) error {
+	r.mu.Lock()
+	defer r.mu.Unlock()
+
+	delete(r.suppressions, domain.ConversationKey{PhoneNumberID: phoneNumberID, WaID: contact})
+	return nil
+}
@@ -0,0 +1,32 @@
+package infrastructure
+
+import (
+	"anyzzapp/pkg/domain"
+	"context"
+	"testing"
+
+	"github.com/stretchr/testify/assert"
+)
+
+func TestSuppressionRepository(t *testing.T) {
+	repo := NewSuppressionRepository()
+	ctx := context.Background()
+
+	missing, err := repo.Get(ctx, "123", "456")
+	assert.NoError(t, err)
+	assert.Nil(t, missing)
+
+	assert.NoError(t, repo.Add(ctx, &domain.Suppression{PhoneNumberID: "123", Contact: "456", Keyword: "STOP"}))
+	assert.Error(t, repo.Add(ctx, &domain.Suppression{PhoneNumberID: "123"}))
+
+	suppression, err := repo.Get(ctx, "123", "456")
+	assert.NoError(t, err)
+	assert.Equal(t, "STOP", suppression.Keyword)
+	// The suppression only applies to the number the contact opted out of
+	other, _ := repo.Get(ctx, "789", "456")
+	assert.Nil(t, other)
+
+	assert.NoError(t, repo.Remove(ctx, "123", "456"))
+	removed, _ := repo.Get(ctx, "123", "456")
+	assert.Nil(t, removed)
+}
@@ -0,0 +1,94 @@
+package tracing
+
+import (
+	"anyzzapp/pkg/domain"
+	"context"
+
+	"go.opentelemetry.io/otel"
+	"go.opentelemetry.io/otel/attribute"
+	"go.opentelemetry.io/otel/codes"
+	"go.opentelemetry.io/otel/trace"
+)
+
+const instrumentationName = "anyzzapp/internal/infrastructure/tracing"
+
+// LLMRepository creates a span for every request of the wrapped LLMRepository
+type LLMRepository struct {
+	next    domain.LLMRepository
+	backend string
+}
+
+// InstrumentLLMRepository wraps an LLMRepository to trace its requests
+func InstrumentLLMRepository(next domain.LLMRepository, backend string) domain.LLMRepository {
+	return &LLMRepository{
+		next:    next,
+		backend: backend,
+	}
+}
+
+// SendMessage sends the prompt through the wrapped repository
+func (r *LLMRepository) SendMessage(ctx context.Context, prompt string) (string, error) {
+	ctx, span := start(ctx, "llm.SendMessage", attribute.String("anyzzapp.llm.backend", r.backend))
+	defer span.End()
+
+	response, err := r.next.SendMessage(ctx, prompt)
+	recordError(span, err)
+	return response, err
+}
+
+// WhatsAppRepository creates a span for every Graph API request of the wrapped WhatsAppRepository
+type WhatsAppRepository struct {
+	next domain.WhatsAppRepository
+}
+
+// InstrumentWhatsAppRepository wraps a WhatsAppRepository to trace its requests
+func InstrumentWhatsAppRepository(next domain.WhatsAppRepository) domain.WhatsAppRepository {
+	return &WhatsAppRepository{
+		next: next,
+	}
+}
+
+// SendMessage sends a message through the wrapped repository
+func (r *WhatsAppRepository) SendMessage(ctx context.Context, message domain.Message) (*domain.SendMessageResponse, error) {
+	ctx, span := start(ctx, "whatsapp.SendMessage",
+		attribute.String("anyzzapp.tenant", message.PhoneNumberID),
+		attribute.String("anyzzapp.message.type", message.MessageType),
+	)
+	defer span.End()
+
+	response, err := r.next.SendMessage(ctx, message)
+	if response != nil && response.MessageID != "" {
+		span.SetAttributes(attribute.String("messaging.message.id", response.MessageID))
+	}
+	recordError(span, err)
+	return response, err
+}
+
+// MarkAsRead marks a message as read through the wrapped repository
+func (r *WhatsAppRepository) MarkAsRead(ctx context.Context, phoneNumberID, messageID string) error {
+	ctx, span := start(ctx, "whatsapp.MarkAsRead",
+		attribute.String("anyzzapp.tenant", phoneNumberID),
+		attribute.String("messaging.message.id", messageID),
+	)
+	defer span.End()
+
+	err := r.next.MarkAsRead(ctx, phoneNumberID, messageID)
+	recordError(span, err)
+	return err
+}
+
+// start starts a client span with the global tracer provider
+func start(ctx context.Context, name string, attributes ...attribute.KeyValue) (context.Context, trace.Span) {
+	return otel.Tracer(instrumentationName).Start(ctx, name,
+		trace.WithSpanKind(trace.SpanKindClient),
+		trace.WithAttributes(attributes...),
+	)
+}
+
+// recordError marks the span as failed if there is an error
+func recordError(span trace.Span, err error) {
+	if err != nil {
+		span.RecordError(err)
+		span.SetStatus(codes.Error, err.Error())
+	}
+}
@@ -0,0 +1,62 @@
+package tracing
+
+import (
+	"anyzzapp/pkg/domain"
+	"context"
+	"errors"
+	"testing"
+
+	"github.com/stretchr/testify/assert"
+	"go.opentelemetry.io/otel"
+	"go.opentelemetry.io/otel/attribute"
+	"go.opentelemetry.io/otel/codes"
+	sdktrace "go.opentelemetry.io/otel/sdk/trace"
+	"go.opentelemetry.io/otel/sdk/trace/tracetest"
+)
+
+type stubLLMRepository struct {
+	err error
+}
+
+func (r stubLLMRepository) SendMessage(ctx context.Context, prompt string) (string, error) {
+	return "reply", r.err
+}
+
+type stubWhatsAppRepository struct{}
+
+func (stubWhatsAppRepository) SendMessage(ctx context.Context, message domain.Message) (*domain.SendMessageResponse, error) {
+	return &domain.SendMessageResponse{MessageID: "wamid.1"}, nil
+}
+
+func (stubWhatsAppRepository) MarkAsRead(ctx context.Context, phoneNumberID, messageID string) error {
+	return nil
+}
+
+func TestInstrumentedRepositories(t *testing.T) {
+	recorder := tracetest.NewSpanRecorder()
+	otel.SetTracerProvider(sdktrace.NewTracerProvider(sdktrace.WithSpanProcessor(recorder)))
+
+	llmRepo := InstrumentLLMRepository(stubLLMRepository{err: errors.New("timeout")}, "anyprompt")
+	_, err := llmRepo.SendMessage(context.Background(), "hi")
+	assert.Error(t, err)
+
+	whatsappRepo := InstrumentWhatsAppRepository(stubWhatsAppRepository{})
+	_, err = whatsappRepo.SendMessage(context.Background(), domain.Message{PhoneNumberID: "123", MessageType: "text"})
+	assert.NoError(t, err)
+	assert.NoError(t, whatsappRepo.MarkAsRead(context.Background(), "123", "wamid.0"))
+
+	spans := recorder.Ended()
+	assert.Len(t, spans, 3)
+
+	assert.Equal(t, "llm.SendMessage", spans[0].Name())
+	assert.Equal(t, codes.Error, spans[0].Status().Code)
+	assert.Contains(t, spans[0].Attributes(), attribute.String("anyzzapp.llm.backend", "anyprompt"))
+
+	assert.Equal(t, "whatsapp.SendMessage", spans[1].Name())
+	assert.Contains(t, spans[1].Attributes(), attribute.String("anyzzapp.tenant", "123"))
+	assert.Contains(t, spans[1].Attributes(), attribute.String("anyzzapp.message.type", "text"))
+	assert.Contains(t, spans[1].Attributes(), attribute.String("messaging.message.id", "wamid.1"))
+
+	assert.Equal(t, "whatsapp.MarkAsRead", spans[2].Name())
+	assert.Contains(t, spans[2].Attributes(), attribute.String("messaging.message.id", "wamid.0"))
+}
@@ -0,0 +1,38 @@
+package tracing
+
+import (
+	"anyzzapp/internal/config"
+	"bytes"
+	"context"
+	"testing"
+
+	"github.com/stretchr/testify/assert"
+)
+
+func TestSetup_None(t *testing.T) {
+	shutdown, err := Setup(context.Background(), config.Config{TracingExporter: ExporterNone})
+
+	assert.NoError(t, err)
+	assert.NoError(t, shutdown(context.Background()))
+}
+
+func TestSetup_UnknownExporter(t *testing.T) {
+	_, err := Setup(context.Background(), config.Config{TracingExporter: "zipkin"})
+
+	assert.Error(t, err)
+	assert.Contains(t, err.Error(), "unknown tracing exporter")
+}
+
+func TestNewExporter_Stdout(t *testing.T) {
+	var out bytes.Buffer
+	exporter, err := newExporter(context.Background(), ExporterStdout, &out)
+	assert.NoError(t, err)
+
+	provider := NewTracerProvider(exporter)
+	_, span := provider.Tracer("test").Start(context.Background(), "offline-span")
+	span.End()
+	assert.NoError(t, provider.Shutdown(context.Background()))
+
+	assert.Contains(t, out.String(), `"Name":"offline-span"`)
+	assert.Contains(t, out.String(), "anyzzapp")
+}
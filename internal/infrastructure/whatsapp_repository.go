@@ -5,12 +5,16 @@ import (
 	client2 "anyzzapp/internal/infrastructure/client"
 	"anyzzapp/internal/infrastructure/entity"
 	"anyzzapp/pkg/domain"
+	"context"
 	"encoding/json"
 	"fmt"
 	"github.com/rs/zerolog/log"
 	"net/http"
 )
 
+// defaultTemplateLanguage is the language of the template messages that set none
+const defaultTemplateLanguage = "en_US"
+
 // WhatsAppRepository implements WhatsAppRepository
 type WhatsAppRepository struct {
 	apiKey  string
@@ -28,7 +32,7 @@ func NewWhatsAppRepository(config config.Config, client client2.HttpClient) doma
 }
 
 // SendMessage sends a message through WhatsApp API
-func (r *WhatsAppRepository) SendMessage(message domain.Message) (*domain.SendMessageResponse, error) {
+func (r *WhatsAppRepository) SendMessage(ctx context.Context, message domain.Message) (*domain.SendMessageResponse, error) {
 	// Default message type to text if not specified
 	if message.MessageType == "" {
 		message.MessageType = "text"
@@ -41,16 +45,26 @@ func (r *WhatsAppRepository) SendMessage(message domain.Message) (*domain.SendMe
 		To:               message.To,
 		Type:             message.MessageType,
 	}
-	// Currently only supporting text messages //TODO - add audio type
-	if message.MessageType == "text" {
+	// Currently only supporting text and template messages //TODO - add audio type
+	switch message.MessageType {
+	case "text":
 		payload.Text = &entity.Text{
 			PreviewURL: false,
 			Body:       message.Content,
 		}
+	case "template":
+		language := message.Language
+		if language == "" {
+			language = defaultTemplateLanguage
+		}
+		payload.Template = &entity.Template{
+			Name:     message.Content,
+			Language: entity.TemplateLanguage{Code: language},
+		}
 	}
 	url := fmt.Sprintf("%s/%s/messages", r.baseURL, message.PhoneNumberID)
 	// Execute POST
-	resp, err := r.client.Post(payload, url)
+	resp, err := r.client.Post(ctx, payload, url)
 	if err != nil {
 		return nil, err
 	}
@@ -59,14 +73,18 @@ func (r *WhatsAppRepository) SendMessage(message domain.Message) (*domain.SendMe
 	if err := json.NewDecoder(resp.Body).Decode(&result); err != nil {
 		return nil, fmt.Errorf("failed to decode entity: %w", err)
 	}
-	log.Debug().Msgf("whatsapp response: %v", resp)
+	log.Ctx(ctx).Debug().Int("status", resp.StatusCode).Msg("whatsapp response")
 
 	// Check for errors
 	if resp.StatusCode != http.StatusOK {
 		return &domain.SendMessageResponse{
 			Status:  "failed",
 			Message: result.Error.Message,
-		}, fmt.Errorf("API error: %s (code: %d)", result.Error.Message, result.Error.Code)
+		}, &domain.APIError{
+			StatusCode: resp.StatusCode,
+			Code:       result.Error.Code,
+			Message:    result.Error.Message,
+		}
 	}
 	defer resp.Body.Close()
 
@@ -91,7 +109,7 @@ type markAsReadPayload struct {
 }
 
 // MarkAsRead marks a message as read
-func (r *WhatsAppRepository) MarkAsRead(phoneNumberID, messageID string) error {
+func (r *WhatsAppRepository) MarkAsRead(ctx context.Context, phoneNumberID, messageID string) error {
 	payload := markAsReadPayload{
 		MessagingProduct: "whatsapp",
 		Status:           "read",
@@ -99,13 +117,13 @@ func (r *WhatsAppRepository) MarkAsRead(phoneNumberID, messageID string) error {
 	}
 	url := fmt.Sprintf("%s/%s/messages", r.baseURL, phoneNumberID)
 	// Execute POST
-	resp, err := r.client.Post(payload, url)
+	resp, err := r.client.Post(ctx, payload, url)
 	if err != nil {
 		return err
 	}
 	defer resp.Body.Close()
 	if resp.StatusCode != http.StatusOK {
-		return fmt.Errorf("API error: status code %d", resp.StatusCode)
+		return &domain.APIError{StatusCode: resp.StatusCode}
 	}
 	return nil
 }
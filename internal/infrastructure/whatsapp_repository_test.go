@@ -5,6 +5,7 @@ import (
 	"anyzzapp/internal/infrastructure/entity"
 	"anyzzapp/pkg/domain"
 	"bytes"
+	"context"
 	"encoding/json"
 	"errors"
 	"io"
@@ -20,7 +21,7 @@ type MockHttpClient struct {
 	mock.Mock
 }
 
-func (m *MockHttpClient) Post(payload interface{}, url string) (*http.Response, error) {
+func (m *MockHttpClient) Post(ctx context.Context, payload interface{}, url string) (*http.Response, error) {
 	args := m.Called(payload, url)
 	return args.Get(0).(*http.Response), args.Error(1)
 }
@@ -84,7 +85,7 @@ func TestWhatsAppRepository_SendMessage_Success(t *testing.T) {
 	expectedURL := "https://graph.facebook.com/v18.0/123456789/messages"
 	mockClient.On("Post", expectedPayload, expectedURL).Return(mockResponse, nil)
 
-	result, err := repo.SendMessage(message)
+	result, err := repo.SendMessage(context.Background(), message)
 
 	assert.NoError(t, err)
 	assert.NotNil(t, result)
@@ -139,7 +140,7 @@ func TestWhatsAppRepository_SendMessage_DefaultMessageType(t *testing.T) {
 	expectedURL := "https://graph.facebook.com/v18.0/123456789/messages"
 	mockClient.On("Post", expectedPayload, expectedURL).Return(mockResponse, nil)
 
-	result, err := repo.SendMessage(message)
+	result, err := repo.SendMessage(context.Background(), message)
 
 	assert.NoError(t, err)
 	assert.NotNil(t, result)
@@ -148,6 +149,39 @@ func TestWhatsAppRepository_SendMessage_DefaultMessageType(t *testing.T) {
 	mockClient.AssertExpectations(t)
 }
 
+func TestWhatsAppRepository_SendMessage_Template(t *testing.T) {
+	mockClient := &MockHttpClient{}
+	repo := &WhatsAppRepository{baseURL: "https://graph.facebook.com/v18.0", client: mockClient}
+
+	expectedPayload := entity.SendWhatsAppMessagePayload{
+		MessagingProduct: "whatsapp",
+		RecipientType:    "individual",
+		To:               "5491112345678",
+		Type:             "template",
+		Template: &entity.Template{
+			Name:     "order_shipped",
+			Language: entity.TemplateLanguage{Code: "en_US"}, // Defaulted when empty
+		},
+	}
+	responseJSON := []byte(`{"messages":[{"id":"msg_123"}]}`)
+	mockResponse := &http.Response{
+		StatusCode: http.StatusOK,
+		Body:       io.NopCloser(bytes.NewReader(responseJSON)),
+	}
+	mockClient.On("Post", expectedPayload, "https://graph.facebook.com/v18.0/123456789/messages").Return(mockResponse, nil)
+
+	result, err := repo.SendMessage(context.Background(), domain.Message{
+		PhoneNumberID: "123456789",
+		To:            "5491112345678",
+		Content:       "order_shipped",
+		MessageType:   "template",
+	})
+
+	assert.NoError(t, err)
+	assert.Equal(t, "msg_123", result.MessageID)
+	mockClient.AssertExpectations(t)
+}
+
 func TestWhatsAppRepository_SendMessage_HttpClientError(t *testing.T) {
 	cfg := config.Config{
 		WhatsAppAPIKey:  "test-api-key",
@@ -170,7 +204,7 @@ func TestWhatsAppRepository_SendMessage_HttpClientError(t *testing.T) {
 	expectedError := errors.New("network error")
 	mockClient.On("Post", mock.Anything, mock.Anything).Return((*http.Response)(nil), expectedError)
 
-	result, err := repo.SendMessage(message)
+	result, err := repo.SendMessage(context.Background(), message)
 
 	assert.Error(t, err)
 	assert.Nil(t, result)
@@ -214,7 +248,7 @@ func TestWhatsAppRepository_SendMessage_APIError(t *testing.T) {
 
 	mockClient.On("Post", mock.Anything, mock.Anything).Return(mockResponse, nil)
 
-	result, err := repo.SendMessage(message)
+	result, err := repo.SendMessage(context.Background(), message)
 
 	assert.Error(t, err)
 	assert.NotNil(t, result)
@@ -256,7 +290,7 @@ func TestWhatsAppRepository_SendMessage_NoMessageID(t *testing.T) {
 
 	mockClient.On("Post", mock.Anything, mock.Anything).Return(mockResponse, nil)
 
-	result, err := repo.SendMessage(message)
+	result, err := repo.SendMessage(context.Background(), message)
 
 	assert.Error(t, err)
 	assert.NotNil(t, result)
@@ -294,7 +328,7 @@ func TestWhatsAppRepository_MarkAsRead_Success(t *testing.T) {
 	expectedURL := "https://graph.facebook.com/v18.0/123456789/messages"
 	mockClient.On("Post", expectedPayload, expectedURL).Return(mockResponse, nil)
 
-	err := repo.MarkAsRead(phoneNumberID, messageID)
+	err := repo.MarkAsRead(context.Background(), phoneNumberID, messageID)
 
 	assert.NoError(t, err)
 	mockClient.AssertExpectations(t)
@@ -318,7 +352,7 @@ func TestWhatsAppRepository_MarkAsRead_HttpClientError(t *testing.T) {
 	expectedError := errors.New("network error")
 	mockClient.On("Post", mock.Anything, mock.Anything).Return((*http.Response)(nil), expectedError)
 
-	err := repo.MarkAsRead(phoneNumberID, messageID)
+	err := repo.MarkAsRead(context.Background(), phoneNumberID, messageID)
 
 	assert.Error(t, err)
 	assert.Equal(t, expectedError, err)
@@ -347,7 +381,7 @@ func TestWhatsAppRepository_MarkAsRead_APIError(t *testing.T) {
 
 	mockClient.On("Post", mock.Anything, mock.Anything).Return(mockResponse, nil)
 
-	err := repo.MarkAsRead(phoneNumberID, messageID)
+	err := repo.MarkAsRead(context.Background(), phoneNumberID, messageID)
 
 	assert.Error(t, err)
 	assert.Contains(t, err.Error(), "API error")
@@ -0,0 +1,55 @@
+package handler
+
+import (
+	"anyzzapp/internal/config"
+	"errors"
+	"github.com/gin-gonic/gin"
+	"net/http"
+)
+
+// ConfigReloader reloads the configuration and returns the applied changes
+type ConfigReloader interface {
+	Reload() ([]string, error)
+}
+
+// AdminHandler handles HTTP requests of operators
+type AdminHandler struct {
+	reloader ConfigReloader
+}
+
+// NewAdminHandler creates a new instance of AdminHandler
+func NewAdminHandler(reloader ConfigReloader) *AdminHandler {
+	return &AdminHandler{
+		reloader: reloader,
+	}
+}
+
+// ReloadConfig handles POST /admin/config/reload
+func (h *AdminHandler) ReloadConfig(c *gin.Context) {
+	changes, err := h.reloader.Reload()
+	if err != nil {
+		var invalid *config.ValidationError
+		if errors.As(err, &invalid) {
+			c.JSON(http.StatusUnprocessableEntity, gin.H{
+				"error":    "invalid_configuration",
+				"message":  "The configuration was not applied",
+				"code":     http.StatusUnprocessableEntity,
+				"problems": invalid.Problems,
+			})
+			return
+		}
+		c.JSON(http.StatusInternalServerError, gin.H{
+			"error":   "reload_failed",
+			"message": err.Error(),
+			"code":    http.StatusInternalServerError,
+		})
+		return
+	}
+	if changes == nil {
+		changes = []string{}
+	}
+	c.JSON(http.StatusOK, gin.H{
+		"status":  "reloaded",
+		"changes": changes,
+	})
+}
@@ -0,0 +1,73 @@
+package handler
+
+import (
+	"anyzzapp/internal/config"
+	"encoding/json"
+	"errors"
+	"net/http"
+	"net/http/httptest"
+	"testing"
+
+	"github.com/gin-gonic/gin"
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/mock"
+)
+
+// MockConfigReloader is a mock implementation of ConfigReloader
+type MockConfigReloader struct {
+	mock.Mock
+}
+
+func (m *MockConfigReloader) Reload() ([]string, error) {
+	args := m.Called()
+	return args.Get(0).([]string), args.Error(1)
+}
+
+func reloadConfig(reloader ConfigReloader) *httptest.ResponseRecorder {
+	gin.SetMode(gin.TestMode)
+	router := gin.New()
+	router.POST("/admin/config/reload", NewAdminHandler(reloader).ReloadConfig)
+
+	w := httptest.NewRecorder()
+	router.ServeHTTP(w, httptest.NewRequest("POST", "/admin/config/reload", nil))
+	return w
+}
+
+func TestAdminHandler_ReloadConfig(t *testing.T) {
+	reloader := &MockConfigReloader{}
+	reloader.On("Reload").Return([]string{`limits.rate_limit_per_minute: "0" -> "10"`}, nil)
+
+	w := reloadConfig(reloader)
+
+	assert.Equal(t, http.StatusOK, w.Code)
+	var response struct {
+		Status  string   `json:"status"`
+		Changes []string `json:"changes"`
+	}
+	assert.NoError(t, json.Unmarshal(w.Body.Bytes(), &response))
+	assert.Equal(t, "reloaded", response.Status)
+	assert.Equal(t, []string{`limits.rate_limit_per_minute: "0" -> "10"`}, response.Changes)
+}
+
+func TestAdminHandler_ReloadConfig_Invalid(t *testing.T) {
+	reloader := &MockConfigReloader{}
+	reloader.On("Reload").Return([]string(nil), &config.ValidationError{Problems: []string{"personas[0]: prompt is required"}})
+
+	w := reloadConfig(reloader)
+
+	assert.Equal(t, http.StatusUnprocessableEntity, w.Code)
+	var response map[string]interface{}
+	assert.NoError(t, json.Unmarshal(w.Body.Bytes(), &response))
+	assert.Equal(t, "invalid_configuration", response["error"])
+	assert.Equal(t, []interface{}{"personas[0]: prompt is required"}, response["problems"])
+}
+
+func TestAdminHandler_ReloadConfig_Error(t *testing.T) {
+	reloader := &MockConfigReloader{}
+	reloader.On("Reload").Return([]string(nil), errors.New("failed to read config file"))
+
+	w := reloadConfig(reloader)
+
+	assert.Equal(t, http.StatusInternalServerError, w.Code)
+	assert.Contains(t, w.Body.String(), "reload_failed")
+}
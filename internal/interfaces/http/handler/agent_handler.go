@@ -0,0 +1,133 @@
+package handler
+
+import (
+	"anyzzapp/pkg/domain"
+	"errors"
+	"github.com/gin-gonic/gin"
+	"net/http"
+)
+
+// AgentHandler handles HTTP requests of human agents taking over conversations
+type AgentHandler struct {
+	agentUseCase domain.AgentUseCaseInterface
+}
+
+// NewAgentHandler creates a new instance of AgentHandler
+func NewAgentHandler(agentUseCase domain.AgentUseCaseInterface) *AgentHandler {
+	return &AgentHandler{
+		agentUseCase: agentUseCase,
+	}
+}
+
+// ListConversations handles GET /api/v1/agent/conversations
+func (h *AgentHandler) ListConversations(c *gin.Context) {
+	// Waiting conversations by default
+	state := domain.ConversationState(c.DefaultQuery("state", string(domain.ConversationStateHuman)))
+	if state == "all" {
+		state = ""
+	}
+	if state != "" && !state.IsValid() {
+		c.JSON(http.StatusBadRequest, domain.ErrorResponse{
+			Error:   "invalid_request",
+			Message: "state must be one of bot, human, closed or all",
+			Code:    http.StatusBadRequest,
+		})
+		return
+	}
+
+	conversations, err := h.agentUseCase.ListConversations(state)
+	if err != nil {
+		respondAgentError(c, err)
+		return
+	}
+	// The list doesn't include the history, it is read per conversation
+	for i := range conversations {
+		conversations[i].Messages = nil
+	}
+	c.JSON(http.StatusOK, gin.H{"conversations": conversations})
+}
+
+// GetConversation handles GET /api/v1/agent/conversations/:id
+func (h *AgentHandler) GetConversation(c *gin.Context) {
+	conversation, err := h.agentUseCase.GetConversation(c.Param("id"))
+	if err != nil {
+		respondAgentError(c, err)
+		return
+	}
+	c.JSON(http.StatusOK, conversation)
+}
+
+// Reply handles POST /api/v1/agent/conversations/:id/reply
+func (h *AgentHandler) Reply(c *gin.Context) {
+	var req domain.AgentReplyRequest
+
+	// Bind JSON request to struct
+	if err := c.ShouldBindJSON(&req); err != nil {
+		c.JSON(http.StatusBadRequest, domain.ErrorResponse{
+			Error:   "invalid_request",
+			Message: err.Error(),
+			Code:    http.StatusBadRequest,
+		})
+		return
+	}
+
+	response, err := h.agentUseCase.Reply(c.Request.Context(), c.Param("id"), req)
+	if err != nil {
+		respondAgentError(c, err)
+		return
+	}
+	// The outbox sends it later
+	if response != nil && response.Status == domain.SendStatusQueued {
+		c.JSON(http.StatusAccepted, response)
+		return
+	}
+	c.JSON(http.StatusOK, response)
+}
+
+// HandBack handles POST /api/v1/agent/conversations/:id/handback
+func (h *AgentHandler) HandBack(c *gin.Context) {
+	if err := h.agentUseCase.HandBack(c.Param("id")); err != nil {
+		respondAgentError(c, err)
+		return
+	}
+	c.JSON(http.StatusOK, gin.H{"status": "ok", "state": domain.ConversationStateBot})
+}
+
+// Close handles POST /api/v1/agent/conversations/:id/close
+func (h *AgentHandler) Close(c *gin.Context) {
+	if err := h.agentUseCase.Close(c.Param("id")); err != nil {
+		respondAgentError(c, err)
+		return
+	}
+	c.JSON(http.StatusOK, gin.H{"status": "ok", "state": domain.ConversationStateClosed})
+}
+
+// respondAgentError maps agent use case errors to HTTP responses
+func respondAgentError(c *gin.Context, err error) {
+	switch {
+	case errors.Is(err, domain.ErrConversationNotFound):
+		c.JSON(http.StatusNotFound, domain.ErrorResponse{
+			Error:   "conversation_not_found",
+			Message: err.Error(),
+			Code:    http.StatusNotFound,
+		})
+	case errors.Is(err, domain.ErrConversationNotInHumanMode):
+		c.JSON(http.StatusConflict, domain.ErrorResponse{
+			Error:   "conversation_not_in_human_mode",
+			Message: err.Error(),
+			Code:    http.StatusConflict,
+		})
+	case errors.Is(err, domain.ErrContactOptedOut):
+		c.JSON(http.StatusForbidden, domain.ErrorResponse{
+			Error:   "contact_opted_out",
+			Message: "The contact opted out, only transactional messages can be sent",
+			Code:    http.StatusForbidden,
+		})
+	default:
+		c.JSON(http.StatusInternalServerError, domain.ErrorResponse{
+			Error:   "agent_operation_failed",
+			Message: err.Error(),
+			Code:    http.StatusInternalServerError,
+		})
+	}
+}
@@ -0,0 +1,199 @@
+package handler
+
+import (
+	"anyzzapp/pkg/domain"
+	"bytes"
+	"context"
+	"encoding/json"
+	"errors"
+	"net/http"
+	"net/http/httptest"
+	"testing"
+
+	"github.com/gin-gonic/gin"
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/mock"
+)
+
+// MockAgentUseCase is a mock implementation of AgentUseCaseInterface
+type MockAgentUseCase struct {
+	mock.Mock
+}
+
+func (m *MockAgentUseCase) ListConversations(state domain.ConversationState) ([]domain.Conversation, error) {
+	args := m.Called(state)
+	return args.Get(0).([]domain.Conversation), args.Error(1)
+}
+
+func (m *MockAgentUseCase) GetConversation(id string) (*domain.Conversation, error) {
+	args := m.Called(id)
+	return args.Get(0).(*domain.Conversation), args.Error(1)
+}
+
+func (m *MockAgentUseCase) Reply(ctx context.Context, id string, reply domain.AgentReplyRequest) (*domain.SendMessageResponse, error) {
+	args := m.Called(id, reply)
+	return args.Get(0).(*domain.SendMessageResponse), args.Error(1)
+}
+
+func (m *MockAgentUseCase) HandBack(id string) error {
+	args := m.Called(id)
+	return args.Error(0)
+}
+
+func (m *MockAgentUseCase) Close(id string) error {
+	args := m.Called(id)
+	return args.Error(0)
+}
+
+func setupAgentRouter(useCase domain.AgentUseCaseInterface) *gin.Engine {
+	gin.SetMode(gin.TestMode)
+	router := gin.New()
+	agentHandler := NewAgentHandler(useCase)
+	router.GET("/conversations", agentHandler.ListConversations)
+	router.GET("/conversations/:id", agentHandler.GetConversation)
+	router.POST("/conversations/:id/reply", agentHandler.Reply)
+	router.POST("/conversations/:id/handback", agentHandler.HandBack)
+	router.POST("/conversations/:id/close", agentHandler.Close)
+	return router
+}
+
+func TestAgentHandler_ListConversations_DefaultsToHuman(t *testing.T) {
+	mockUseCase := &MockAgentUseCase{}
+	router := setupAgentRouter(mockUseCase)
+
+	mockUseCase.On("ListConversations", domain.ConversationStateHuman).Return([]domain.Conversation{
+		{
+			ID:       "123-456",
+			State:    domain.ConversationStateHuman,
+			Messages: []domain.ConversationMessage{{Content: "help"}},
+		},
+	}, nil)
+
+	w := httptest.NewRecorder()
+	router.ServeHTTP(w, httptest.NewRequest("GET", "/conversations", nil))
+
+	assert.Equal(t, http.StatusOK, w.Code)
+	var response struct {
+		Conversations []domain.Conversation `json:"conversations"`
+	}
+	assert.NoError(t, json.Unmarshal(w.Body.Bytes(), &response))
+	assert.Len(t, response.Conversations, 1)
+	assert.Equal(t, "123-456", response.Conversations[0].ID)
+	assert.Empty(t, response.Conversations[0].Messages)
+	mockUseCase.AssertExpectations(t)
+}
+
+func TestAgentHandler_ListConversations_InvalidState(t *testing.T) {
+	router := setupAgentRouter(&MockAgentUseCase{})
+
+	w := httptest.NewRecorder()
+	router.ServeHTTP(w, httptest.NewRequest("GET", "/conversations?state=waiting", nil))
+
+	assert.Equal(t, http.StatusBadRequest, w.Code)
+}
+
+func TestAgentHandler_GetConversation_NotFound(t *testing.T) {
+	mockUseCase := &MockAgentUseCase{}
+	router := setupAgentRouter(mockUseCase)
+
+	mockUseCase.On("GetConversation", "123-456").Return((*domain.Conversation)(nil), domain.ErrConversationNotFound)
+
+	w := httptest.NewRecorder()
+	router.ServeHTTP(w, httptest.NewRequest("GET", "/conversations/123-456", nil))
+
+	assert.Equal(t, http.StatusNotFound, w.Code)
+	var errorResponse domain.ErrorResponse
+	assert.NoError(t, json.Unmarshal(w.Body.Bytes(), &errorResponse))
+	assert.Equal(t, "conversation_not_found", errorResponse.Error)
+}
+
+func TestAgentHandler_Reply_Success(t *testing.T) {
+	mockUseCase := &MockAgentUseCase{}
+	router := setupAgentRouter(mockUseCase)
+
+	mockUseCase.On("Reply", "123-456", domain.AgentReplyRequest{Content: "On my way"}).Return(&domain.SendMessageResponse{MessageID: "msg_1", Status: "sent"}, nil)
+
+	body, _ := json.Marshal(domain.AgentReplyRequest{Content: "On my way"})
+	w := httptest.NewRecorder()
+	req := httptest.NewRequest("POST", "/conversations/123-456/reply", bytes.NewReader(body))
+	req.Header.Set("Content-Type", "application/json")
+	router.ServeHTTP(w, req)
+
+	assert.Equal(t, http.StatusOK, w.Code)
+	var response domain.SendMessageResponse
+	assert.NoError(t, json.Unmarshal(w.Body.Bytes(), &response))
+	assert.Equal(t, "msg_1", response.MessageID)
+	mockUseCase.AssertExpectations(t)
+}
+
+func TestAgentHandler_Reply_InvalidJSON(t *testing.T) {
+	router := setupAgentRouter(&MockAgentUseCase{})
+
+	w := httptest.NewRecorder()
+	req := httptest.NewRequest("POST", "/conversations/123-456/reply", bytes.NewReader([]byte("{}")))
+	req.Header.Set("Content-Type", "application/json")
+	router.ServeHTTP(w, req)
+
+	assert.Equal(t, http.StatusBadRequest, w.Code)
+}
+
+func TestAgentHandler_Reply_NotInHumanMode(t *testing.T) {
+	mockUseCase := &MockAgentUseCase{}
+	router := setupAgentRouter(mockUseCase)
+
+	mockUseCase.On("Reply", "123-456", domain.AgentReplyRequest{Content: "Hi"}).Return((*domain.SendMessageResponse)(nil), domain.ErrConversationNotInHumanMode)
+
+	body, _ := json.Marshal(domain.AgentReplyRequest{Content: "Hi"})
+	w := httptest.NewRecorder()
+	req := httptest.NewRequest("POST", "/conversations/123-456/reply", bytes.NewReader(body))
+	req.Header.Set("Content-Type", "application/json")
+	router.ServeHTTP(w, req)
+
+	assert.Equal(t, http.StatusConflict, w.Code)
+}
+
+func TestAgentHandler_Reply_Outcomes(t *testing.T) {
+	tests := []struct {
+		name     string
+		response *domain.SendMessageResponse
+		err      error
+		expected int
+	}{
+		{name: "Queued by the outbox", response: &domain.SendMessageResponse{Status: domain.SendStatusQueued}, expected: http.StatusAccepted},
+		{name: "Contact opted out", response: nil, err: domain.ErrContactOptedOut, expected: http.StatusForbidden},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			mockUseCase := &MockAgentUseCase{}
+			router := setupAgentRouter(mockUseCase)
+
+			mockUseCase.On("Reply", "123-456", domain.AgentReplyRequest{Content: "Hi"}).Return(tt.response, tt.err)
+
+			body, _ := json.Marshal(domain.AgentReplyRequest{Content: "Hi"})
+			w := httptest.NewRecorder()
+			req := httptest.NewRequest("POST", "/conversations/123-456/reply", bytes.NewReader(body))
+			req.Header.Set("Content-Type", "application/json")
+			router.ServeHTTP(w, req)
+
+			assert.Equal(t, tt.expected, w.Code)
+		})
+	}
+}
+
+func TestAgentHandler_HandBackAndClose(t *testing.T) {
+	mockUseCase := &MockAgentUseCase{}
+	router := setupAgentRouter(mockUseCase)
+
+	mockUseCase.On("HandBack", "123-456").Return(nil)
+	mockUseCase.On("Close", "123-789").Return(errors.New("store down"))
+
+	w := httptest.NewRecorder()
+	router.ServeHTTP(w, httptest.NewRequest("POST", "/conversations/123-456/handback", nil))
+	assert.Equal(t, http.StatusOK, w.Code)
+
+	w = httptest.NewRecorder()
+	router.ServeHTTP(w, httptest.NewRequest("POST", "/conversations/123-789/close", nil))
+	assert.Equal(t, http.StatusInternalServerError, w.Code)
+	mockUseCase.AssertExpectations(t)
+}
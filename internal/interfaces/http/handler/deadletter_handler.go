@@ -0,0 +1,91 @@
+package handler
+
+import (
+	"anyzzapp/pkg/domain"
+	"errors"
+	"github.com/gin-gonic/gin"
+	"net/http"
+)
+
+// DeadLetterHandler handles HTTP requests of operators on the messages that failed to be answered
+type DeadLetterHandler struct {
+	deadLetterUseCase domain.DeadLetterUseCaseInterface
+}
+
+// NewDeadLetterHandler creates a new instance of DeadLetterHandler
+func NewDeadLetterHandler(deadLetterUseCase domain.DeadLetterUseCaseInterface) *DeadLetterHandler {
+	return &DeadLetterHandler{
+		deadLetterUseCase: deadLetterUseCase,
+	}
+}
+
+// ListDeadLetters handles GET /api/v1/admin/dead-letters
+func (h *DeadLetterHandler) ListDeadLetters(c *gin.Context) {
+	letters, err := h.deadLetterUseCase.ListDeadLetters(c.Request.Context())
+	if err != nil {
+		respondDeadLetterError(c, err)
+		return
+	}
+	if letters == nil {
+		letters = []domain.DeadLetter{}
+	}
+	c.JSON(http.StatusOK, gin.H{"dead_letters": letters})
+}
+
+// GetDeadLetter handles GET /api/v1/admin/dead-letters/:id
+func (h *DeadLetterHandler) GetDeadLetter(c *gin.Context) {
+	letter, err := h.deadLetterUseCase.GetDeadLetter(c.Request.Context(), c.Param("id"))
+	if err != nil {
+		respondDeadLetterError(c, err)
+		return
+	}
+	c.JSON(http.StatusOK, letter)
+}
+
+// RetryDeadLetter handles POST /api/v1/admin/dead-letters/:id/retry
+func (h *DeadLetterHandler) RetryDeadLetter(c *gin.Context) {
+	if err := h.deadLetterUseCase.RetryDeadLetter(c.Request.Context(), c.Param("id")); err != nil {
+		respondDeadLetterError(c, err)
+		return
+	}
+	c.JSON(http.StatusOK, gin.H{"status": "answered"})
+}
+
+// DiscardDeadLetter handles DELETE /api/v1/admin/dead-letters/:id
+func (h *DeadLetterHandler) DiscardDeadLetter(c *gin.Context) {
+	if err := h.deadLetterUseCase.DiscardDeadLetter(c.Request.Context(), c.Param("id")); err != nil {
+		respondDeadLetterError(c, err)
+		return
+	}
+	c.JSON(http.StatusOK, gin.H{"status": "discarded"})
+}
+
+// respondDeadLetterError maps dead letter use case errors to HTTP responses
+func respondDeadLetterError(c *gin.Context, err error) {
+	switch {
+	case errors.Is(err, domain.ErrDeadLetterNotFound):
+		c.JSON(http.StatusNotFound, domain.ErrorResponse{
+			Error:   "dead_letter_not_found",
+			Message: err.Error(),
+			Code:    http.StatusNotFound,
+		})
+	case errors.Is(err, domain.ErrDeadLetterInProgress):
+		c.JSON(http.StatusConflict, domain.ErrorResponse{
+			Error:   "dead_letter_in_progress",
+			Message: err.Error(),
+			Code:    http.StatusConflict,
+		})
+	case domain.IsDownstream(err):
+		c.JSON(http.StatusBadGateway, domain.ErrorResponse{
+			Error:   "dead_letter_retry_failed",
+			Message: err.Error(),
+			Code:    http.StatusBadGateway,
+		})
+	default:
+		c.JSON(http.StatusInternalServerError, domain.ErrorResponse{
+			Error:   "dead_letter_operation_failed",
+			Message: err.Error(),
+			Code:    http.StatusInternalServerError,
+		})
+	}
+}
@@ -0,0 +1,129 @@
+package handler
+
+import (
+	"anyzzapp/pkg/domain"
+	"context"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"net/http"
+	"net/http/httptest"
+	"testing"
+
+	"github.com/gin-gonic/gin"
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/mock"
+)
+
+// MockDeadLetterUseCase is a mock implementation of DeadLetterUseCaseInterface
+type MockDeadLetterUseCase struct {
+	mock.Mock
+}
+
+func (m *MockDeadLetterUseCase) ListDeadLetters(ctx context.Context) ([]domain.DeadLetter, error) {
+	args := m.Called()
+	return args.Get(0).([]domain.DeadLetter), args.Error(1)
+}
+
+func (m *MockDeadLetterUseCase) GetDeadLetter(ctx context.Context, id string) (*domain.DeadLetter, error) {
+	args := m.Called(id)
+	return args.Get(0).(*domain.DeadLetter), args.Error(1)
+}
+
+func (m *MockDeadLetterUseCase) RetryDeadLetter(ctx context.Context, id string) error {
+	args := m.Called(id)
+	return args.Error(0)
+}
+
+func (m *MockDeadLetterUseCase) DiscardDeadLetter(ctx context.Context, id string) error {
+	args := m.Called(id)
+	return args.Error(0)
+}
+
+func setupDeadLetterRouter(useCase domain.DeadLetterUseCaseInterface) *gin.Engine {
+	gin.SetMode(gin.TestMode)
+	router := gin.New()
+	deadLetterHandler := NewDeadLetterHandler(useCase)
+	router.GET("/dead-letters", deadLetterHandler.ListDeadLetters)
+	router.GET("/dead-letters/:id", deadLetterHandler.GetDeadLetter)
+	router.POST("/dead-letters/:id/retry", deadLetterHandler.RetryDeadLetter)
+	router.DELETE("/dead-letters/:id", deadLetterHandler.DiscardDeadLetter)
+	return router
+}
+
+func TestDeadLetterHandler_ListAndGet(t *testing.T) {
+	useCase := &MockDeadLetterUseCase{}
+	router := setupDeadLetterRouter(useCase)
+
+	letter := domain.DeadLetter{ID: "msg_1", PhoneNumberID: "123", Error: "llm: timeout", Attempts: 2}
+	useCase.On("ListDeadLetters").Return([]domain.DeadLetter{letter}, nil)
+	useCase.On("GetDeadLetter", "msg_1").Return(&letter, nil)
+	useCase.On("GetDeadLetter", "msg_2").Return((*domain.DeadLetter)(nil), domain.ErrDeadLetterNotFound)
+
+	w := httptest.NewRecorder()
+	router.ServeHTTP(w, httptest.NewRequest("GET", "/dead-letters", nil))
+	assert.Equal(t, http.StatusOK, w.Code)
+	var list struct {
+		DeadLetters []domain.DeadLetter `json:"dead_letters"`
+	}
+	assert.NoError(t, json.Unmarshal(w.Body.Bytes(), &list))
+	assert.Equal(t, []domain.DeadLetter{letter}, list.DeadLetters)
+
+	w = httptest.NewRecorder()
+	router.ServeHTTP(w, httptest.NewRequest("GET", "/dead-letters/msg_1", nil))
+	assert.Equal(t, http.StatusOK, w.Code)
+	assert.Contains(t, w.Body.String(), `"attempts":2`)
+
+	w = httptest.NewRecorder()
+	router.ServeHTTP(w, httptest.NewRequest("GET", "/dead-letters/msg_2", nil))
+	assert.Equal(t, http.StatusNotFound, w.Code)
+	assert.Contains(t, w.Body.String(), "dead_letter_not_found")
+	useCase.AssertExpectations(t)
+}
+
+func TestDeadLetterHandler_Retry(t *testing.T) {
+	useCase := &MockDeadLetterUseCase{}
+	router := setupDeadLetterRouter(useCase)
+
+	useCase.On("RetryDeadLetter", "msg_1").Return(nil)
+	useCase.On("RetryDeadLetter", "msg_2").
+		Return(fmt.Errorf("failed to answer dead letter: %w", &domain.DownstreamError{Service: "llm", Err: errors.New("timeout")}))
+	useCase.On("RetryDeadLetter", "msg_3").Return(errors.New("disk failure"))
+	useCase.On("RetryDeadLetter", "msg_4").Return(domain.ErrDeadLetterInProgress)
+
+	tests := []struct {
+		id     string
+		status int
+		body   string
+	}{
+		{"msg_1", http.StatusOK, "answered"},
+		{"msg_2", http.StatusBadGateway, "dead_letter_retry_failed"},
+		{"msg_3", http.StatusInternalServerError, "dead_letter_operation_failed"},
+		{"msg_4", http.StatusConflict, "dead_letter_in_progress"},
+	}
+	for _, tt := range tests {
+		w := httptest.NewRecorder()
+		router.ServeHTTP(w, httptest.NewRequest("POST", "/dead-letters/"+tt.id+"/retry", nil))
+		assert.Equal(t, tt.status, w.Code, tt.id)
+		assert.Contains(t, w.Body.String(), tt.body, tt.id)
+	}
+	useCase.AssertExpectations(t)
+}
+
+func TestDeadLetterHandler_Discard(t *testing.T) {
+	useCase := &MockDeadLetterUseCase{}
+	router := setupDeadLetterRouter(useCase)
+
+	useCase.On("DiscardDeadLetter", "msg_1").Return(nil)
+	useCase.On("DiscardDeadLetter", "msg_2").Return(domain.ErrDeadLetterNotFound)
+
+	w := httptest.NewRecorder()
+	router.ServeHTTP(w, httptest.NewRequest("DELETE", "/dead-letters/msg_1", nil))
+	assert.Equal(t, http.StatusOK, w.Code)
+	assert.Contains(t, w.Body.String(), "discarded")
+
+	w = httptest.NewRecorder()
+	router.ServeHTTP(w, httptest.NewRequest("DELETE", "/dead-letters/msg_2", nil))
+	assert.Equal(t, http.StatusNotFound, w.Code)
+	useCase.AssertExpectations(t)
+}
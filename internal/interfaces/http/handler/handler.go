@@ -3,6 +3,8 @@ package handler
 import (
 	"anyzzapp/internal/config"
 	"anyzzapp/pkg/domain"
+	"anyzzapp/pkg/domain/phone"
+	"errors"
 	"github.com/gin-gonic/gin"
 	"net/http"
 )
@@ -37,13 +39,33 @@ func (h *WhatsAppHandler) SendMessage(c *gin.Context) {
 		return
 	}
 
+	// Validate the recipient
+	number, err := phone.Parse(req.To)
+	if err != nil {
+		c.JSON(http.StatusBadRequest, domain.ErrorResponse{
+			Error:   "invalid_phone_number",
+			Message: err.Error(),
+			Code:    http.StatusBadRequest,
+		})
+		return
+	}
+	req.To = number.String()
+
 	// Default message type to text if not provided
 	if req.MessageType == "" {
 		req.MessageType = "text"
 	}
 
 	// Call use case
-	response, err := h.whatsappUseCase.SendMessage(req)
+	response, err := h.whatsappUseCase.SendMessage(c.Request.Context(), req)
+	if errors.Is(err, domain.ErrContactOptedOut) {
+		c.JSON(http.StatusForbidden, domain.ErrorResponse{
+			Error:   "contact_opted_out",
+			Message: "The contact opted out, only transactional messages can be sent",
+			Code:    http.StatusForbidden,
+		})
+		return
+	}
 	if err != nil {
 		c.JSON(http.StatusInternalServerError, domain.ErrorResponse{
 			Error:   "send_failed",
@@ -52,6 +74,11 @@ func (h *WhatsAppHandler) SendMessage(c *gin.Context) {
 		})
 		return
 	}
+	// The outbox sends it later
+	if response != nil && response.Status == domain.SendStatusQueued {
+		c.JSON(http.StatusAccepted, response)
+		return
+	}
 
 	c.JSON(http.StatusOK, response)
 }
@@ -71,7 +98,14 @@ func (h *WhatsAppHandler) ReceiveWebhook(c *gin.Context) {
 	}
 
 	// Process the webhook
-	if err := h.whatsappUseCase.ProcessIncomingWebhook(&webhook); err != nil {
+	if err := h.whatsappUseCase.ProcessIncomingWebhook(c.Request.Context(), &webhook); err != nil {
+		_ = c.Error(err)
+		// Meta would redeliver the whole batch, answering again the messages that did not fail, when only
+		// the LLM or the WhatsApp API failed
+		if domain.IsDownstream(err) {
+			c.JSON(http.StatusOK, gin.H{"status": "ok"})
+			return
+		}
 		c.JSON(http.StatusInternalServerError, domain.ErrorResponse{
 			Error:   "webhook_processing_failed",
 			Message: err.Error(),
@@ -94,7 +128,8 @@ func (h *WhatsAppHandler) VerifyWebhook(c *gin.Context) {
 	// Verify token
 	expectedToken := h.config.WebhookVerifyToken
 
-	if mode == "subscribe" && token == expectedToken {
+	// An unset token never matches, otherwise an empty hub.verify_token would pass
+	if mode == "subscribe" && expectedToken != "" && token == expectedToken {
 		// Return the challenge to verify the webhook
 		c.String(http.StatusOK, challenge)
 		return
@@ -4,8 +4,10 @@ import (
 	"anyzzapp/internal/config"
 	"anyzzapp/pkg/domain"
 	"bytes"
+	"context"
 	"encoding/json"
 	"errors"
+	"fmt"
 	"net/http"
 	"net/http/httptest"
 	"testing"
@@ -20,16 +22,21 @@ type MockWhatsAppUseCase struct {
 	mock.Mock
 }
 
-func (m *MockWhatsAppUseCase) SendMessage(message domain.Message) (*domain.SendMessageResponse, error) {
+func (m *MockWhatsAppUseCase) SendMessage(ctx context.Context, message domain.Message) (*domain.SendMessageResponse, error) {
 	args := m.Called(message)
 	return args.Get(0).(*domain.SendMessageResponse), args.Error(1)
 }
 
-func (m *MockWhatsAppUseCase) ProcessIncomingWebhook(webhook *domain.WebhookRequest) error {
+func (m *MockWhatsAppUseCase) ProcessIncomingWebhook(ctx context.Context, webhook *domain.WebhookRequest) error {
 	args := m.Called(webhook)
 	return args.Error(0)
 }
 
+func (m *MockWhatsAppUseCase) ReprocessMessage(ctx context.Context, phoneNumberID string, msg domain.WebhookMessage) error {
+	args := m.Called(phoneNumberID, msg)
+	return args.Error(0)
+}
+
 func TestNewWhatsAppHandler(t *testing.T) {
 	cfg := config.Config{
 		WebhookVerifyToken: "test-verify-token",
@@ -114,6 +121,77 @@ func TestWhatsAppHandler_SendMessage_InvalidJSON(t *testing.T) {
 	assert.Equal(t, http.StatusBadRequest, errorResponse.Code)
 }
 
+func TestWhatsAppHandler_SendMessage_InvalidPhoneNumber(t *testing.T) {
+	cfg := config.Config{}
+	mockUseCase := &MockWhatsAppUseCase{}
+	handler := &WhatsAppHandler{
+		whatsappUseCase: mockUseCase,
+		config:          cfg,
+	}
+
+	message := domain.Message{
+		PhoneNumberID: "123456789",
+		To:            "+54 11 1234-5678",
+		Content:       "Hello, World!",
+	}
+
+	// Setup Gin
+	gin.SetMode(gin.TestMode)
+	w := httptest.NewRecorder()
+	c, _ := gin.CreateTestContext(w)
+
+	// Prepare request body
+	jsonBody, _ := json.Marshal(message)
+	c.Request = httptest.NewRequest("POST", "/api/v1/whatsapp/send", bytes.NewReader(jsonBody))
+	c.Request.Header.Set("Content-Type", "application/json")
+
+	handler.SendMessage(c)
+
+	assert.Equal(t, http.StatusBadRequest, w.Code)
+
+	var errorResponse domain.ErrorResponse
+	err := json.Unmarshal(w.Body.Bytes(), &errorResponse)
+	assert.NoError(t, err)
+	assert.Equal(t, "invalid_phone_number", errorResponse.Error)
+	assert.Contains(t, errorResponse.Message, "must contain only digits")
+	mockUseCase.AssertNotCalled(t, "SendMessage", mock.Anything)
+}
+
+func TestWhatsAppHandler_SendMessage_StripsPlus(t *testing.T) {
+	cfg := config.Config{}
+	mockUseCase := &MockWhatsAppUseCase{}
+	handler := &WhatsAppHandler{
+		whatsappUseCase: mockUseCase,
+		config:          cfg,
+	}
+
+	message := domain.Message{
+		PhoneNumberID: "123456789",
+		To:            "+541112345678",
+		Content:       "Hello, World!",
+		MessageType:   "text",
+	}
+	expectedMessage := message
+	expectedMessage.To = "541112345678"
+
+	mockUseCase.On("SendMessage", expectedMessage).Return(&domain.SendMessageResponse{MessageID: "msg_123"}, nil)
+
+	// Setup Gin
+	gin.SetMode(gin.TestMode)
+	w := httptest.NewRecorder()
+	c, _ := gin.CreateTestContext(w)
+
+	// Prepare request body
+	jsonBody, _ := json.Marshal(message)
+	c.Request = httptest.NewRequest("POST", "/api/v1/whatsapp/send", bytes.NewReader(jsonBody))
+	c.Request.Header.Set("Content-Type", "application/json")
+
+	handler.SendMessage(c)
+
+	assert.Equal(t, http.StatusOK, w.Code)
+	mockUseCase.AssertExpectations(t)
+}
+
 func TestWhatsAppHandler_SendMessage_DefaultMessageType(t *testing.T) {
 	cfg := config.Config{}
 	mockUseCase := &MockWhatsAppUseCase{}
@@ -199,6 +277,63 @@ func TestWhatsAppHandler_SendMessage_UseCaseError(t *testing.T) {
 	mockUseCase.AssertExpectations(t)
 }
 
+func TestWhatsAppHandler_SendMessage_OptedOut(t *testing.T) {
+	mockUseCase := &MockWhatsAppUseCase{}
+	handler := &WhatsAppHandler{whatsappUseCase: mockUseCase}
+
+	message := domain.Message{
+		PhoneNumberID: "123456789",
+		To:            "5491112345678",
+		Content:       "Our sale starts today!",
+		MessageType:   "text",
+	}
+	mockUseCase.On("SendMessage", message).Return((*domain.SendMessageResponse)(nil), domain.ErrContactOptedOut)
+
+	gin.SetMode(gin.TestMode)
+	w := httptest.NewRecorder()
+	c, _ := gin.CreateTestContext(w)
+	jsonBody, _ := json.Marshal(message)
+	c.Request = httptest.NewRequest("POST", "/api/v1/whatsapp/send", bytes.NewReader(jsonBody))
+	c.Request.Header.Set("Content-Type", "application/json")
+
+	handler.SendMessage(c)
+
+	assert.Equal(t, http.StatusForbidden, w.Code)
+	var errorResponse domain.ErrorResponse
+	assert.NoError(t, json.Unmarshal(w.Body.Bytes(), &errorResponse))
+	assert.Equal(t, "contact_opted_out", errorResponse.Error)
+	mockUseCase.AssertExpectations(t)
+}
+
+func TestWhatsAppHandler_SendMessage_Queued(t *testing.T) {
+	mockUseCase := &MockWhatsAppUseCase{}
+	handler := &WhatsAppHandler{whatsappUseCase: mockUseCase}
+
+	message := domain.Message{
+		PhoneNumberID: "123456789",
+		To:            "5491112345678",
+		Content:       "Hello, World!",
+		MessageType:   "text",
+	}
+	mockUseCase.On("SendMessage", message).Return(&domain.SendMessageResponse{Status: domain.SendStatusQueued}, nil)
+
+	gin.SetMode(gin.TestMode)
+	w := httptest.NewRecorder()
+	c, _ := gin.CreateTestContext(w)
+	jsonBody, _ := json.Marshal(message)
+	c.Request = httptest.NewRequest("POST", "/api/v1/whatsapp/send", bytes.NewReader(jsonBody))
+	c.Request.Header.Set("Content-Type", "application/json")
+
+	handler.SendMessage(c)
+
+	assert.Equal(t, http.StatusAccepted, w.Code)
+	var response domain.SendMessageResponse
+	assert.NoError(t, json.Unmarshal(w.Body.Bytes(), &response))
+	assert.Equal(t, "queued", response.Status)
+	assert.Empty(t, response.MessageID)
+	mockUseCase.AssertExpectations(t)
+}
+
 func TestWhatsAppHandler_ReceiveWebhook_Success(t *testing.T) {
 	cfg := config.Config{}
 	mockUseCase := &MockWhatsAppUseCase{}
@@ -317,6 +452,32 @@ func TestWhatsAppHandler_ReceiveWebhook_ProcessingError(t *testing.T) {
 	mockUseCase.AssertExpectations(t)
 }
 
+func TestWhatsAppHandler_ReceiveWebhook_DownstreamError(t *testing.T) {
+	mockUseCase := &MockWhatsAppUseCase{}
+	handler := &WhatsAppHandler{whatsappUseCase: mockUseCase}
+
+	webhook := domain.WebhookRequest{Object: "whatsapp_business_account", Entry: []domain.WebhookEntry{}}
+	downstream := errors.Join(
+		&domain.DownstreamError{Service: "llm", Err: errors.New("timeout")},
+		fmt.Errorf("message msg_2: %w", &domain.DownstreamError{Service: "whatsapp", Err: errors.New("API down")}))
+	mockUseCase.On("ProcessIncomingWebhook", &webhook).Return(fmt.Errorf("failed to process messages: %w", downstream))
+
+	gin.SetMode(gin.TestMode)
+	w := httptest.NewRecorder()
+	c, _ := gin.CreateTestContext(w)
+	jsonBody, _ := json.Marshal(webhook)
+	c.Request = httptest.NewRequest("POST", "/api/v1/whatsapp/webhook", bytes.NewReader(jsonBody))
+	c.Request.Header.Set("Content-Type", "application/json")
+
+	handler.ReceiveWebhook(c)
+
+	// Only the LLM and the WhatsApp API failed, redelivering the batch would not help
+	assert.Equal(t, http.StatusOK, w.Code)
+	assert.Contains(t, c.Errors.String(), "llm: timeout")
+	assert.Contains(t, c.Errors.String(), "whatsapp: API down")
+	mockUseCase.AssertExpectations(t)
+}
+
 func TestWhatsAppHandler_VerifyWebhook_Success(t *testing.T) {
 	cfg := config.Config{
 		WebhookVerifyToken: "test-verify-token",
@@ -398,3 +559,19 @@ func TestWhatsAppHandler_VerifyWebhook_WrongMode(t *testing.T) {
 	assert.Equal(t, "verification_failed", errorResponse.Error)
 	assert.Equal(t, http.StatusForbidden, errorResponse.Code)
 }
+
+func TestWhatsAppHandler_VerifyWebhook_EmptyConfiguredToken(t *testing.T) {
+	handler := &WhatsAppHandler{
+		whatsappUseCase: &MockWhatsAppUseCase{},
+		config:          config.Config{},
+	}
+
+	gin.SetMode(gin.TestMode)
+	w := httptest.NewRecorder()
+	c, _ := gin.CreateTestContext(w)
+	c.Request = httptest.NewRequest("GET", "/api/v1/whatsapp/webhook?hub.mode=subscribe&hub.verify_token=&hub.challenge=test-challenge", nil)
+
+	handler.VerifyWebhook(c)
+
+	assert.Equal(t, http.StatusForbidden, w.Code)
+}
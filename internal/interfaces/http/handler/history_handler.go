@@ -0,0 +1,208 @@
+package handler
+
+import (
+	"anyzzapp/pkg/domain"
+	"errors"
+	"fmt"
+	"github.com/gin-gonic/gin"
+	"net/http"
+	"strconv"
+	"strings"
+	"time"
+)
+
+// HistoryHandler handles HTTP requests of support staff reading the stored conversations
+type HistoryHandler struct {
+	historyUseCase domain.HistoryUseCaseInterface
+}
+
+// NewHistoryHandler creates a new instance of HistoryHandler
+func NewHistoryHandler(historyUseCase domain.HistoryUseCaseInterface) *HistoryHandler {
+	return &HistoryHandler{
+		historyUseCase: historyUseCase,
+	}
+}
+
+// ListConversations handles GET /api/v1/admin/conversations
+func (h *HistoryHandler) ListConversations(c *gin.Context) {
+	since, until, limit, err := rangeParams(c)
+	if err != nil {
+		respondInvalidRequest(c, err.Error())
+		return
+	}
+	state := domain.ConversationState(c.Query("state"))
+	if state != "" && !state.IsValid() {
+		respondInvalidRequest(c, "state must be one of bot, human or closed")
+		return
+	}
+
+	page, err := h.historyUseCase.ListConversations(c.Request.Context(), domain.ConversationQuery{
+		PhoneNumberID: c.Query("tenant"),
+		WaID:          c.Query("contact"),
+		Since:         since,
+		Until:         until,
+		State:         state,
+		Cursor:        c.Query("cursor"),
+		Limit:         limit,
+	})
+	if err != nil {
+		respondHistoryError(c, err)
+		return
+	}
+	c.JSON(http.StatusOK, page)
+}
+
+// Transcript handles GET /api/v1/admin/conversations/:id/messages
+func (h *HistoryHandler) Transcript(c *gin.Context) {
+	since, until, limit, err := rangeParams(c)
+	if err != nil {
+		respondInvalidRequest(c, err.Error())
+		return
+	}
+
+	page, err := h.historyUseCase.Transcript(c.Request.Context(), c.Param("id"), domain.MessageQuery{
+		Since:  since,
+		Until:  until,
+		Cursor: c.Query("cursor"),
+		Limit:  limit,
+	})
+	if err != nil {
+		respondHistoryError(c, err)
+		return
+	}
+	c.JSON(http.StatusOK, page)
+}
+
+// SearchMessages handles GET /api/v1/admin/messages/search
+func (h *HistoryHandler) SearchMessages(c *gin.Context) {
+	since, until, limit, err := rangeParams(c)
+	if err != nil {
+		respondInvalidRequest(c, err.Error())
+		return
+	}
+	text := c.Query("q")
+	if text == "" {
+		respondInvalidRequest(c, "q is required")
+		return
+	}
+
+	page, err := h.historyUseCase.SearchMessages(c.Request.Context(), domain.MessageQuery{
+		PhoneNumberID: c.Query("tenant"),
+		WaID:          c.Query("contact"),
+		Text:          text,
+		Since:         since,
+		Until:         until,
+		Cursor:        c.Query("cursor"),
+		Limit:         limit,
+	})
+	if err != nil {
+		respondHistoryError(c, err)
+		return
+	}
+	c.JSON(http.StatusOK, page)
+}
+
+// exportContentTypes are the content types and file extensions of the export formats
+var exportContentTypes = map[string][2]string{
+	domain.ExportFormatJSONL:      {"application/x-ndjson", "jsonl"},
+	domain.ExportFormatCSV:        {"text/csv; charset=utf-8", "csv"},
+	domain.ExportFormatTranscript: {"text/plain; charset=utf-8", "txt"},
+}
+
+// Export handles GET /api/v1/admin/export, the export is streamed as it is read
+func (h *HistoryHandler) Export(c *gin.Context) {
+	since, until, _, err := rangeParams(c)
+	if err != nil {
+		respondInvalidRequest(c, err.Error())
+		return
+	}
+	query := domain.ExportQuery{
+		PhoneNumberID: c.Query("tenant"),
+		Since:         since,
+		Until:         until,
+		Format:        c.DefaultQuery("format", domain.ExportFormatJSONL),
+	}
+	if mask := c.Query("mask"); mask != "" {
+		query.Mask = strings.Split(mask, ",")
+	}
+	if err := query.Validate(); err != nil {
+		respondInvalidRequest(c, err.Error())
+		return
+	}
+
+	contentType := exportContentTypes[query.Format]
+	c.Header("Content-Type", contentType[0])
+	c.Header("Content-Disposition", fmt.Sprintf(`attachment; filename="anyzzapp-export.%s"`, contentType[1]))
+	c.Status(http.StatusOK)
+	if err := h.historyUseCase.Export(c.Request.Context(), query, c.Writer); err != nil {
+		if !c.Writer.Written() {
+			c.Writer.Header().Del("Content-Type")
+			c.Writer.Header().Del("Content-Disposition")
+			respondHistoryError(c, err)
+			return
+		}
+		// The status was sent with the first conversation, the export is cut short
+		_ = c.Error(err)
+	}
+}
+
+// rangeParams parses the since, until and limit query parameters
+func rangeParams(c *gin.Context) (since, until time.Time, limit int, err error) {
+	if since, err = timeParam(c, "since"); err != nil {
+		return
+	}
+	if until, err = timeParam(c, "until"); err != nil {
+		return
+	}
+	if value := c.Query("limit"); value != "" {
+		if limit, err = strconv.Atoi(value); err != nil || limit < 1 {
+			err = fmt.Errorf("limit must be a positive number")
+			return
+		}
+	}
+	return
+}
+
+// timeParam parses a query parameter holding an RFC 3339 time or a date, zero when missing
+func timeParam(c *gin.Context, name string) (time.Time, error) {
+	value := c.Query(name)
+	if value == "" {
+		return time.Time{}, nil
+	}
+	if t, err := time.Parse(time.RFC3339, value); err == nil {
+		return t, nil
+	}
+	if t, err := time.Parse(time.DateOnly, value); err == nil {
+		return t, nil
+	}
+	return time.Time{}, fmt.Errorf("%s must be an RFC 3339 time or a YYYY-MM-DD date", name)
+}
+
+// respondInvalidRequest answers 400 with the problem of the request
+func respondInvalidRequest(c *gin.Context, message string) {
+	c.JSON(http.StatusBadRequest, domain.ErrorResponse{
+		Error:   "invalid_request",
+		Message: message,
+		Code:    http.StatusBadRequest,
+	})
+}
+
+// respondHistoryError maps history use case errors to HTTP responses
+func respondHistoryError(c *gin.Context, err error) {
+	switch {
+	case errors.Is(err, domain.ErrInvalidCursor):
+		respondInvalidRequest(c, err.Error())
+	case errors.Is(err, domain.ErrConversationNotFound):
+		c.JSON(http.StatusNotFound, domain.ErrorResponse{
+			Error:   "conversation_not_found",
+			Message: err.Error(),
+			Code:    http.StatusNotFound,
+		})
+	default:
+		c.JSON(http.StatusInternalServerError, domain.ErrorResponse{
+			Error:   "history_query_failed",
+			Message: err.Error(),
+			Code:    http.StatusInternalServerError,
+		})
+	}
+}
@@ -0,0 +1,188 @@
+package handler
+
+import (
+	"anyzzapp/pkg/domain"
+	"context"
+	"encoding/json"
+	"errors"
+	"io"
+	"net/http"
+	"net/http/httptest"
+	"testing"
+	"time"
+
+	"github.com/gin-gonic/gin"
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/mock"
+)
+
+// MockHistoryUseCase is a mock implementation of HistoryUseCaseInterface
+type MockHistoryUseCase struct {
+	mock.Mock
+}
+
+func (m *MockHistoryUseCase) ListConversations(ctx context.Context, query domain.ConversationQuery) (*domain.ConversationPage, error) {
+	args := m.Called(query)
+	return args.Get(0).(*domain.ConversationPage), args.Error(1)
+}
+
+func (m *MockHistoryUseCase) Transcript(ctx context.Context, id string, query domain.MessageQuery) (*domain.MessagePage, error) {
+	args := m.Called(id, query)
+	return args.Get(0).(*domain.MessagePage), args.Error(1)
+}
+
+func (m *MockHistoryUseCase) SearchMessages(ctx context.Context, query domain.MessageQuery) (*domain.MessagePage, error) {
+	args := m.Called(query)
+	return args.Get(0).(*domain.MessagePage), args.Error(1)
+}
+
+func (m *MockHistoryUseCase) Export(ctx context.Context, query domain.ExportQuery, w io.Writer) error {
+	args := m.Called(query)
+	if content := args.String(0); content != "" {
+		_, _ = io.WriteString(w, content)
+	}
+	return args.Error(1)
+}
+
+func setupHistoryRouter(useCase domain.HistoryUseCaseInterface) *gin.Engine {
+	gin.SetMode(gin.TestMode)
+	router := gin.New()
+	historyHandler := NewHistoryHandler(useCase)
+	router.GET("/conversations", historyHandler.ListConversations)
+	router.GET("/conversations/:id/messages", historyHandler.Transcript)
+	router.GET("/messages/search", historyHandler.SearchMessages)
+	router.GET("/export", historyHandler.Export)
+	return router
+}
+
+func TestHistoryHandler_ListConversations(t *testing.T) {
+	useCase := &MockHistoryUseCase{}
+	router := setupHistoryRouter(useCase)
+
+	useCase.On("ListConversations", domain.ConversationQuery{
+		PhoneNumberID: "123",
+		WaID:          "456",
+		Since:         time.Date(2024, 1, 1, 0, 0, 0, 0, time.UTC),
+		Until:         time.Date(2024, 1, 31, 12, 0, 0, 0, time.UTC),
+		State:         domain.ConversationStateHuman,
+		Cursor:        "abc",
+		Limit:         10,
+	}).Return(&domain.ConversationPage{
+		Conversations: []domain.ConversationSummary{{ID: "123-456", MessageCount: 3}},
+		NextCursor:    "def",
+	}, nil)
+
+	w := httptest.NewRecorder()
+	router.ServeHTTP(w, httptest.NewRequest("GET",
+		"/conversations?tenant=123&contact=456&since=2024-01-01&until=2024-01-31T12:00:00Z&state=human&cursor=abc&limit=10", nil))
+
+	assert.Equal(t, http.StatusOK, w.Code)
+	var page domain.ConversationPage
+	assert.NoError(t, json.Unmarshal(w.Body.Bytes(), &page))
+	assert.Equal(t, "def", page.NextCursor)
+	assert.Equal(t, 3, page.Conversations[0].MessageCount)
+	useCase.AssertExpectations(t)
+}
+
+func TestHistoryHandler_InvalidParameters(t *testing.T) {
+	router := setupHistoryRouter(&MockHistoryUseCase{})
+
+	for _, path := range []string{
+		"/conversations?since=yesterday",
+		"/conversations?limit=0",
+		"/conversations?state=waiting",
+		"/conversations/123-456/messages?until=31/01/2024",
+		"/messages/search",
+	} {
+		w := httptest.NewRecorder()
+		router.ServeHTTP(w, httptest.NewRequest("GET", path, nil))
+		assert.Equal(t, http.StatusBadRequest, w.Code, path)
+	}
+}
+
+func TestHistoryHandler_Transcript(t *testing.T) {
+	useCase := &MockHistoryUseCase{}
+	router := setupHistoryRouter(useCase)
+
+	useCase.On("Transcript", "123-456", domain.MessageQuery{Limit: 2}).
+		Return(&domain.MessagePage{Messages: []domain.StoredMessage{{ID: "m1"}, {ID: "m2"}}}, nil)
+	useCase.On("Transcript", "malformed", domain.MessageQuery{}).
+		Return((*domain.MessagePage)(nil), domain.ErrConversationNotFound)
+	useCase.On("Transcript", "123-456", domain.MessageQuery{Cursor: "bad"}).
+		Return((*domain.MessagePage)(nil), domain.ErrInvalidCursor)
+
+	w := httptest.NewRecorder()
+	router.ServeHTTP(w, httptest.NewRequest("GET", "/conversations/123-456/messages?limit=2", nil))
+	assert.Equal(t, http.StatusOK, w.Code)
+	assert.Contains(t, w.Body.String(), `"id":"m2"`)
+
+	w = httptest.NewRecorder()
+	router.ServeHTTP(w, httptest.NewRequest("GET", "/conversations/malformed/messages", nil))
+	assert.Equal(t, http.StatusNotFound, w.Code)
+
+	w = httptest.NewRecorder()
+	router.ServeHTTP(w, httptest.NewRequest("GET", "/conversations/123-456/messages?cursor=bad", nil))
+	assert.Equal(t, http.StatusBadRequest, w.Code)
+}
+
+func TestHistoryHandler_SearchMessages(t *testing.T) {
+	useCase := &MockHistoryUseCase{}
+	router := setupHistoryRouter(useCase)
+
+	useCase.On("SearchMessages", domain.MessageQuery{PhoneNumberID: "123", Text: "refund"}).
+		Return(&domain.MessagePage{Messages: []domain.StoredMessage{{ID: "m1", Content: "I want a refund"}}}, nil)
+	useCase.On("SearchMessages", domain.MessageQuery{Text: "broken"}).
+		Return((*domain.MessagePage)(nil), errors.New("database closed"))
+
+	w := httptest.NewRecorder()
+	router.ServeHTTP(w, httptest.NewRequest("GET", "/messages/search?q=refund&tenant=123", nil))
+	assert.Equal(t, http.StatusOK, w.Code)
+	assert.Contains(t, w.Body.String(), "I want a refund")
+
+	w = httptest.NewRecorder()
+	router.ServeHTTP(w, httptest.NewRequest("GET", "/messages/search?q=broken", nil))
+	assert.Equal(t, http.StatusInternalServerError, w.Code)
+}
+
+func TestHistoryHandler_Export(t *testing.T) {
+	useCase := &MockHistoryUseCase{}
+	router := setupHistoryRouter(useCase)
+
+	useCase.On("Export", domain.ExportQuery{
+		PhoneNumberID: "123",
+		Since:         time.Date(2024, 1, 1, 0, 0, 0, 0, time.UTC),
+		Format:        domain.ExportFormatCSV,
+		Mask:          []string{"wa_id", "content"},
+	}).Return("id,phone_number_id\n", nil)
+
+	w := httptest.NewRecorder()
+	router.ServeHTTP(w, httptest.NewRequest("GET", "/export?tenant=123&since=2024-01-01&format=csv&mask=wa_id,content", nil))
+
+	assert.Equal(t, http.StatusOK, w.Code)
+	assert.Equal(t, "text/csv; charset=utf-8", w.Header().Get("Content-Type"))
+	assert.Equal(t, `attachment; filename="anyzzapp-export.csv"`, w.Header().Get("Content-Disposition"))
+	assert.Equal(t, "id,phone_number_id\n", w.Body.String())
+}
+
+func TestHistoryHandler_Export_Invalid(t *testing.T) {
+	router := setupHistoryRouter(&MockHistoryUseCase{})
+
+	for _, path := range []string{"/export?format=xml", "/export?mask=name", "/export?since=monday"} {
+		w := httptest.NewRecorder()
+		router.ServeHTTP(w, httptest.NewRequest("GET", path, nil))
+		assert.Equal(t, http.StatusBadRequest, w.Code, path)
+	}
+}
+
+func TestHistoryHandler_Export_FailureBeforeStreaming(t *testing.T) {
+	useCase := &MockHistoryUseCase{}
+	router := setupHistoryRouter(useCase)
+	useCase.On("Export", domain.ExportQuery{Format: domain.ExportFormatJSONL}).Return("", errors.New("database closed"))
+
+	w := httptest.NewRecorder()
+	router.ServeHTTP(w, httptest.NewRequest("GET", "/export", nil))
+
+	assert.Equal(t, http.StatusInternalServerError, w.Code)
+	assert.Contains(t, w.Header().Get("Content-Type"), "application/json")
+	assert.Empty(t, w.Header().Get("Content-Disposition"))
+}
@@ -0,0 +1,99 @@
+package handler
+
+import (
+	"anyzzapp/pkg/domain"
+	"github.com/gin-gonic/gin"
+	"net/http"
+	"strconv"
+	"strings"
+)
+
+// PrivacyHandler handles HTTP requests deleting personal data
+type PrivacyHandler struct {
+	privacyUseCase domain.PrivacyUseCaseInterface
+}
+
+// NewPrivacyHandler creates a new instance of PrivacyHandler
+func NewPrivacyHandler(privacyUseCase domain.PrivacyUseCaseInterface) *PrivacyHandler {
+	return &PrivacyHandler{
+		privacyUseCase: privacyUseCase,
+	}
+}
+
+// EraseContact handles DELETE /api/v1/admin/contacts/:wa_id
+func (h *PrivacyHandler) EraseContact(c *gin.Context) {
+	dryRun, err := strconv.ParseBool(c.DefaultQuery("dry_run", "false"))
+	if err != nil {
+		respondInvalidRequest(c, "dry_run must be true or false")
+		return
+	}
+	waID := strings.TrimPrefix(c.Param("wa_id"), "+")
+	if !isDigits(waID) {
+		respondInvalidRequest(c, "wa_id must be a phone number with only digits")
+		return
+	}
+
+	report, err := h.privacyUseCase.EraseContact(c.Request.Context(), waID, c.Query("tenant"), dryRun)
+	if err != nil {
+		respondPrivacyError(c, err)
+		return
+	}
+	c.JSON(http.StatusOK, report)
+}
+
+// ApplyRetention handles POST /api/v1/admin/retention/run
+func (h *PrivacyHandler) ApplyRetention(c *gin.Context) {
+	dryRun, err := strconv.ParseBool(c.DefaultQuery("dry_run", "false"))
+	if err != nil {
+		respondInvalidRequest(c, "dry_run must be true or false")
+		return
+	}
+
+	report, err := h.privacyUseCase.ApplyRetention(c.Request.Context(), dryRun)
+	if err != nil {
+		respondPrivacyError(c, err)
+		return
+	}
+	c.JSON(http.StatusOK, report)
+}
+
+// AuditLog handles GET /api/v1/admin/audit
+func (h *PrivacyHandler) AuditLog(c *gin.Context) {
+	limit := 0
+	if value := c.Query("limit"); value != "" {
+		var err error
+		if limit, err = strconv.Atoi(value); err != nil || limit < 1 {
+			respondInvalidRequest(c, "limit must be a positive number")
+			return
+		}
+	}
+
+	records, err := h.privacyUseCase.AuditLog(c.Request.Context(), limit)
+	if err != nil {
+		respondPrivacyError(c, err)
+		return
+	}
+	c.JSON(http.StatusOK, gin.H{"records": records})
+}
+
+// respondPrivacyError answers 500, the requests are validated before calling the use case
+func respondPrivacyError(c *gin.Context, err error) {
+	c.JSON(http.StatusInternalServerError, domain.ErrorResponse{
+		Error:   "privacy_operation_failed",
+		Message: err.Error(),
+		Code:    http.StatusInternalServerError,
+	})
+}
+
+// isDigits reports whether value is a non-empty string of digits
+func isDigits(value string) bool {
+	if value == "" {
+		return false
+	}
+	for _, r := range value {
+		if r < '0' || r > '9' {
+			return false
+		}
+	}
+	return true
+}
@@ -0,0 +1,117 @@
+package handler
+
+import (
+	"anyzzapp/pkg/domain"
+	"context"
+	"encoding/json"
+	"errors"
+	"net/http"
+	"net/http/httptest"
+	"testing"
+
+	"github.com/gin-gonic/gin"
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/mock"
+)
+
+// MockPrivacyUseCase is a mock implementation of PrivacyUseCaseInterface
+type MockPrivacyUseCase struct {
+	mock.Mock
+}
+
+func (m *MockPrivacyUseCase) EraseContact(ctx context.Context, waID, phoneNumberID string, dryRun bool) (*domain.ErasureReport, error) {
+	args := m.Called(waID, phoneNumberID, dryRun)
+	return args.Get(0).(*domain.ErasureReport), args.Error(1)
+}
+
+func (m *MockPrivacyUseCase) ApplyRetention(ctx context.Context, dryRun bool) (*domain.RetentionReport, error) {
+	args := m.Called(dryRun)
+	return args.Get(0).(*domain.RetentionReport), args.Error(1)
+}
+
+func (m *MockPrivacyUseCase) AuditLog(ctx context.Context, limit int) ([]domain.AuditRecord, error) {
+	args := m.Called(limit)
+	return args.Get(0).([]domain.AuditRecord), args.Error(1)
+}
+
+func setupPrivacyRouter(useCase domain.PrivacyUseCaseInterface) *gin.Engine {
+	gin.SetMode(gin.TestMode)
+	router := gin.New()
+	privacyHandler := NewPrivacyHandler(useCase)
+	router.DELETE("/contacts/:wa_id", privacyHandler.EraseContact)
+	router.POST("/retention/run", privacyHandler.ApplyRetention)
+	router.GET("/audit", privacyHandler.AuditLog)
+	return router
+}
+
+func TestPrivacyHandler_EraseContact(t *testing.T) {
+	useCase := &MockPrivacyUseCase{}
+	router := setupPrivacyRouter(useCase)
+
+	useCase.On("EraseContact", "456", "123", true).Return(&domain.ErasureReport{DryRun: true, Messages: 4}, nil)
+
+	w := httptest.NewRecorder()
+	router.ServeHTTP(w, httptest.NewRequest("DELETE", "/contacts/+456?tenant=123&dry_run=true", nil))
+
+	assert.Equal(t, http.StatusOK, w.Code)
+	var report domain.ErasureReport
+	assert.NoError(t, json.Unmarshal(w.Body.Bytes(), &report))
+	assert.True(t, report.DryRun)
+	assert.Equal(t, 4, report.Messages)
+	useCase.AssertExpectations(t)
+}
+
+func TestPrivacyHandler_EraseContact_InvalidRequest(t *testing.T) {
+	useCase := &MockPrivacyUseCase{}
+	router := setupPrivacyRouter(useCase)
+
+	for _, path := range []string{"/contacts/john", "/contacts/456?dry_run=maybe"} {
+		w := httptest.NewRecorder()
+		router.ServeHTTP(w, httptest.NewRequest("DELETE", path, nil))
+		assert.Equal(t, http.StatusBadRequest, w.Code, path)
+	}
+	useCase.AssertNotCalled(t, "EraseContact", mock.Anything, mock.Anything, mock.Anything)
+}
+
+func TestPrivacyHandler_EraseContact_Error(t *testing.T) {
+	useCase := &MockPrivacyUseCase{}
+	router := setupPrivacyRouter(useCase)
+
+	useCase.On("EraseContact", "456", "", false).Return((*domain.ErasureReport)(nil), errors.New("disk full"))
+
+	w := httptest.NewRecorder()
+	router.ServeHTTP(w, httptest.NewRequest("DELETE", "/contacts/456", nil))
+
+	assert.Equal(t, http.StatusInternalServerError, w.Code)
+	assert.Contains(t, w.Body.String(), "privacy_operation_failed")
+}
+
+func TestPrivacyHandler_ApplyRetention(t *testing.T) {
+	useCase := &MockPrivacyUseCase{}
+	router := setupPrivacyRouter(useCase)
+
+	useCase.On("ApplyRetention", false).Return(&domain.RetentionReport{Messages: 2}, nil)
+
+	w := httptest.NewRecorder()
+	router.ServeHTTP(w, httptest.NewRequest("POST", "/retention/run", nil))
+
+	assert.Equal(t, http.StatusOK, w.Code)
+	assert.Contains(t, w.Body.String(), `"messages":2`)
+	useCase.AssertExpectations(t)
+}
+
+func TestPrivacyHandler_AuditLog(t *testing.T) {
+	useCase := &MockPrivacyUseCase{}
+	router := setupPrivacyRouter(useCase)
+
+	useCase.On("AuditLog", 5).Return([]domain.AuditRecord{{ID: "a1", Action: domain.AuditActionContactErased}}, nil)
+
+	w := httptest.NewRecorder()
+	router.ServeHTTP(w, httptest.NewRequest("GET", "/audit?limit=5", nil))
+	assert.Equal(t, http.StatusOK, w.Code)
+	assert.Contains(t, w.Body.String(), `"id":"a1"`)
+
+	w = httptest.NewRecorder()
+	router.ServeHTTP(w, httptest.NewRequest("GET", "/audit?limit=0", nil))
+	assert.Equal(t, http.StatusBadRequest, w.Code)
+}
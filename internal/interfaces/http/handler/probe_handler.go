@@ -0,0 +1,45 @@
+package handler
+
+import (
+	"anyzzapp/internal/interfaces/http/middleware"
+	"anyzzapp/pkg/domain"
+	"github.com/gin-gonic/gin"
+	"net/http"
+)
+
+// ProbeHandler handles the liveness and readiness probes
+type ProbeHandler struct {
+	readiness  domain.ReadinessChecker
+	adminToken string
+}
+
+// NewProbeHandler creates a new instance of ProbeHandler, readiness may be nil when there is nothing to check
+func NewProbeHandler(readiness domain.ReadinessChecker, adminToken string) *ProbeHandler {
+	return &ProbeHandler{
+		readiness:  readiness,
+		adminToken: adminToken,
+	}
+}
+
+// Livez handles GET /livez, it only tells the process is serving requests
+func (h *ProbeHandler) Livez(c *gin.Context) {
+	c.JSON(http.StatusOK, gin.H{"status": domain.HealthStatusOK})
+}
+
+// Readyz handles GET /readyz, answering 503 while a dependency is not usable.
+// The result of every check is only shown to admin callers as it reveals the dependencies.
+func (h *ProbeHandler) Readyz(c *gin.Context) {
+	report := domain.HealthReport{Status: domain.HealthStatusOK, Checks: []domain.HealthCheckResult{}}
+	if h.readiness != nil {
+		report = h.readiness.Ready(c.Request.Context())
+	}
+	status := http.StatusOK
+	if report.Status != domain.HealthStatusOK {
+		status = http.StatusServiceUnavailable
+	}
+	if middleware.HasAdminToken(c, h.adminToken) {
+		c.JSON(status, report)
+		return
+	}
+	c.JSON(status, gin.H{"status": report.Status})
+}
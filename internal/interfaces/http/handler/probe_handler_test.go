@@ -0,0 +1,73 @@
+package handler
+
+import (
+	"anyzzapp/pkg/domain"
+	"context"
+	"encoding/json"
+	"net/http"
+	"net/http/httptest"
+	"testing"
+
+	"github.com/gin-gonic/gin"
+	"github.com/stretchr/testify/assert"
+)
+
+// staticReadiness always returns the same report
+type staticReadiness domain.HealthReport
+
+func (r staticReadiness) Ready(ctx context.Context) domain.HealthReport {
+	return domain.HealthReport(r)
+}
+
+var failingReport = staticReadiness{
+	Status: domain.HealthStatusUnavailable,
+	Checks: []domain.HealthCheckResult{
+		{Name: "whatsapp:token", Status: domain.HealthStatusUnavailable, Error: "Session has expired"},
+	},
+}
+
+func probe(probeHandler *ProbeHandler, path, authorization string) *httptest.ResponseRecorder {
+	gin.SetMode(gin.TestMode)
+	router := gin.New()
+	router.GET("/livez", probeHandler.Livez)
+	router.GET("/readyz", probeHandler.Readyz)
+
+	w := httptest.NewRecorder()
+	req := httptest.NewRequest("GET", path, nil)
+	if authorization != "" {
+		req.Header.Set("Authorization", authorization)
+	}
+	router.ServeHTTP(w, req)
+	return w
+}
+
+func TestProbeHandler_Livez(t *testing.T) {
+	w := probe(NewProbeHandler(failingReport, ""), "/livez", "")
+
+	assert.Equal(t, http.StatusOK, w.Code)
+	assert.JSONEq(t, `{"status":"ok"}`, w.Body.String())
+}
+
+func TestProbeHandler_Readyz_NoChecks(t *testing.T) {
+	w := probe(NewProbeHandler(nil, ""), "/readyz", "")
+
+	assert.Equal(t, http.StatusOK, w.Code)
+	assert.JSONEq(t, `{"status":"ok"}`, w.Body.String())
+}
+
+func TestProbeHandler_Readyz_HidesDetails(t *testing.T) {
+	w := probe(NewProbeHandler(failingReport, "admin-token-0123456789"), "/readyz", "Bearer wrong")
+
+	assert.Equal(t, http.StatusServiceUnavailable, w.Code)
+	assert.JSONEq(t, `{"status":"unavailable"}`, w.Body.String())
+}
+
+func TestProbeHandler_Readyz_AdminDetails(t *testing.T) {
+	w := probe(NewProbeHandler(failingReport, "admin-token-0123456789"), "/readyz", "Bearer admin-token-0123456789")
+
+	assert.Equal(t, http.StatusServiceUnavailable, w.Code)
+	var report domain.HealthReport
+	assert.NoError(t, json.Unmarshal(w.Body.Bytes(), &report))
+	assert.Len(t, report.Checks, 1)
+	assert.Equal(t, "Session has expired", report.Checks[0].Error)
+}
@@ -2,10 +2,22 @@ package middleware
 
 import (
 	"anyzzapp/pkg/domain"
+	"anyzzapp/pkg/logging"
+	"bytes"
+	"crypto/subtle"
+	"io"
+	"net/http"
 	"time"
 
 	"github.com/gin-contrib/cors"
 	"github.com/gin-gonic/gin"
+	"github.com/rs/zerolog"
+	"github.com/rs/zerolog/log"
+	"go.opentelemetry.io/otel"
+	"go.opentelemetry.io/otel/attribute"
+	"go.opentelemetry.io/otel/codes"
+	"go.opentelemetry.io/otel/propagation"
+	"go.opentelemetry.io/otel/trace"
 )
 
 // CORS middleware for handling Cross-Origin Resource Sharing
@@ -13,8 +25,8 @@ func CORS() gin.HandlerFunc {
 	config := cors.DefaultConfig()
 	config.AllowAllOrigins = true
 	config.AllowMethods = []string{"GET", "POST", "PUT", "PATCH", "DELETE", "HEAD", "OPTIONS"}
-	config.AllowHeaders = []string{"Origin", "Content-Length", "Content-Type", "Authorization", "X-Phone-Number-ID"}
-	config.ExposeHeaders = []string{"Content-Length"}
+	config.AllowHeaders = []string{"Origin", "Content-Length", "Content-Type", "Authorization", "X-Phone-Number-ID", logging.RequestIDHeader}
+	config.ExposeHeaders = []string{"Content-Length", logging.RequestIDHeader}
 	config.AllowCredentials = true
 	config.MaxAge = 12 * time.Hour
 
@@ -23,7 +35,9 @@ func CORS() gin.HandlerFunc {
 
 // ErrorHandler middleware for handling panics and errors
 func ErrorHandler() gin.HandlerFunc {
-	return gin.CustomRecovery(func(c *gin.Context, recovered interface{}) {
+	// Panics are logged through zerolog instead of gin's plain text writer
+	return gin.CustomRecoveryWithWriter(io.Discard, func(c *gin.Context, recovered interface{}) {
+		log.Ctx(c.Request.Context()).Error().Interface("panic", recovered).Str("route", c.FullPath()).Msg("recovered from panic")
 		if err, ok := recovered.(string); ok {
 			c.JSON(500, domain.ErrorResponse{
 				Error:   "internal_server_error",
@@ -39,3 +53,150 @@ func ErrorHandler() gin.HandlerFunc {
 		}
 	})
 }
+
+// Metrics middleware records method, route, status and latency of every request
+func Metrics(metrics domain.Metrics) gin.HandlerFunc {
+	return func(c *gin.Context) {
+		start := time.Now()
+		c.Next()
+
+		route := c.FullPath()
+		if route == "" {
+			route = "unmatched"
+		}
+		metrics.ObserveHTTPRequest(c.Request.Method, route, c.Writer.Status(), time.Since(start))
+	}
+}
+
+// Tracing middleware starts a server span for every request, continuing the trace of the caller if any
+func Tracing() gin.HandlerFunc {
+	tracer := otel.Tracer("anyzzapp/internal/interfaces/http")
+	return func(c *gin.Context) {
+		ctx := otel.GetTextMapPropagator().Extract(c.Request.Context(), propagation.HeaderCarrier(c.Request.Header))
+
+		route := c.FullPath()
+		if route == "" {
+			route = "unmatched"
+		}
+		ctx, span := tracer.Start(ctx, c.Request.Method+" "+route,
+			trace.WithSpanKind(trace.SpanKindServer),
+			trace.WithAttributes(
+				attribute.String("http.request.method", c.Request.Method),
+				attribute.String("http.route", route),
+			),
+		)
+		defer span.End()
+
+		c.Request = c.Request.WithContext(ctx)
+		c.Next()
+
+		status := c.Writer.Status()
+		span.SetAttributes(attribute.Int("http.response.status_code", status))
+		if status >= 500 {
+			span.SetStatus(codes.Error, http.StatusText(status))
+		}
+	}
+}
+
+// AdminAuth middleware only lets through the requests carrying the admin token as a bearer token
+func AdminAuth(token string) gin.HandlerFunc {
+	return func(c *gin.Context) {
+		if !HasAdminToken(c, token) {
+			c.AbortWithStatusJSON(http.StatusUnauthorized, domain.ErrorResponse{
+				Error:   "unauthorized",
+				Message: "A valid admin token is required",
+				Code:    http.StatusUnauthorized,
+			})
+			return
+		}
+		c.Next()
+	}
+}
+
+// HasAdminToken reports whether the request carries the admin token as a bearer token.
+// No request has it when the token is empty.
+func HasAdminToken(c *gin.Context, token string) bool {
+	if token == "" {
+		return false
+	}
+	provided := []byte(c.GetHeader("Authorization"))
+	return subtle.ConstantTimeCompare(provided, []byte("Bearer "+token)) == 1
+}
+
+// RequestID middleware reuses the X-Request-ID of the caller or generates one, returns it in the response
+// and stores it in the request context so every log entry and outbound call carries it
+func RequestID() gin.HandlerFunc {
+	return func(c *gin.Context) {
+		id := c.GetHeader(logging.RequestIDHeader)
+		if !logging.ValidRequestID(id) {
+			id = logging.NewRequestID()
+		}
+		c.Header(logging.RequestIDHeader, id)
+		c.Request = c.Request.WithContext(logging.WithRequestID(c.Request.Context(), id))
+		c.Next()
+	}
+}
+
+// AccessLog middleware writes a JSON log entry for every request.
+// The query string is never logged as it may carry the webhook verify token.
+func AccessLog() gin.HandlerFunc {
+	return func(c *gin.Context) {
+		start := time.Now()
+		c.Next()
+
+		status := c.Writer.Status()
+		logger := log.Ctx(c.Request.Context())
+		var event *zerolog.Event
+		switch {
+		case status >= 500:
+			event = logger.Error()
+		case status >= 400:
+			event = logger.Warn()
+		default:
+			event = logger.Info()
+		}
+		route := c.FullPath()
+		if route == "" {
+			route = "unmatched"
+		}
+		if len(c.Errors) > 0 {
+			event = event.Str("errors", c.Errors.String())
+		}
+		event.
+			Str("method", c.Request.Method).
+			Str("route", route).
+			Int("status", status).
+			Int("size", c.Writer.Size()).
+			Dur("latency", time.Since(start)).
+			Str("client_ip", c.ClientIP()).
+			Str("user_agent", c.Request.UserAgent()).
+			Msg("request handled")
+	}
+}
+
+// RecordWebhooks middleware saves the headers and raw body of every request before it is handled, to replay it later.
+// A failure to record is only logged, the request is handled anyway.
+func RecordWebhooks(recorder domain.WebhookRecorder) gin.HandlerFunc {
+	return func(c *gin.Context) {
+		body, err := io.ReadAll(c.Request.Body)
+		// The handler reads the body again, even when it could only be read partially
+		c.Request.Body = io.NopCloser(bytes.NewReader(body))
+		if err != nil {
+			log.Ctx(c.Request.Context()).Warn().Err(err).Msg("failed to read the webhook to record it")
+			c.Next()
+			return
+		}
+
+		recording := domain.WebhookRecording{
+			At:     time.Now(),
+			Method: c.Request.Method,
+			Path:   c.Request.URL.Path,
+			Header: c.Request.Header,
+			Body:   string(body),
+		}
+		if err := recorder.Record(c.Request.Context(), recording); err != nil {
+			log.Ctx(c.Request.Context()).Error().Err(err).Msg("failed to record the webhook")
+		}
+		c.Next()
+	}
+}
@@ -5,15 +5,100 @@ import (
 	"anyzzapp/internal/interfaces/http/handler"
 	"anyzzapp/internal/interfaces/http/middleware"
 	"anyzzapp/pkg/domain"
+	"net/http"
+
 	"github.com/gin-gonic/gin"
 )
 
+// options holds the optional dependencies of the router
+type options struct {
+	agentUseCase   domain.AgentUseCaseInterface
+	metrics        domain.Metrics
+	metricsHandler http.Handler
+	configReloader handler.ConfigReloader
+	readiness      domain.ReadinessChecker
+	historyUseCase domain.HistoryUseCaseInterface
+	privacyUseCase domain.PrivacyUseCaseInterface
+	recorder       domain.WebhookRecorder
+	deadLetters    domain.DeadLetterUseCaseInterface
+}
+
+// Option configures an optional part of the router
+type Option func(*options)
+
+// WithAgentUseCase registers the /api/v1/agent endpoints used by human agents, protected by the admin token
+func WithAgentUseCase(agentUseCase domain.AgentUseCaseInterface) Option {
+	return func(o *options) {
+		o.agentUseCase = agentUseCase
+	}
+}
+
+// WithMetrics records the HTTP requests and exposes the handler on /metrics
+func WithMetrics(metrics domain.Metrics, metricsHandler http.Handler) Option {
+	return func(o *options) {
+		o.metrics = metrics
+		o.metricsHandler = metricsHandler
+	}
+}
+
+// WithConfigReloader registers POST /admin/config/reload, protected by the admin token of the configuration
+func WithConfigReloader(reloader handler.ConfigReloader) Option {
+	return func(o *options) {
+		o.configReloader = reloader
+	}
+}
+
+// WithReadiness sets the checks run by /readyz
+func WithReadiness(readiness domain.ReadinessChecker) Option {
+	return func(o *options) {
+		o.readiness = readiness
+	}
+}
+
+// WithHistoryUseCase registers the /api/v1/admin conversation history endpoints, protected by the admin token
+func WithHistoryUseCase(historyUseCase domain.HistoryUseCaseInterface) Option {
+	return func(o *options) {
+		o.historyUseCase = historyUseCase
+	}
+}
+
+// WithPrivacyUseCase registers the /api/v1/admin data deletion and audit endpoints, protected by the admin token
+func WithPrivacyUseCase(privacyUseCase domain.PrivacyUseCaseInterface) Option {
+	return func(o *options) {
+		o.privacyUseCase = privacyUseCase
+	}
+}
+
+// WithWebhookRecorder saves every webhook received with the recorder, to replay it later
+func WithWebhookRecorder(recorder domain.WebhookRecorder) Option {
+	return func(o *options) {
+		o.recorder = recorder
+	}
+}
+
+// WithDeadLetterUseCase registers the /api/v1/admin/dead-letters endpoints, protected by the admin token
+func WithDeadLetterUseCase(deadLetters domain.DeadLetterUseCaseInterface) Option {
+	return func(o *options) {
+		o.deadLetters = deadLetters
+	}
+}
+
 // NewRouter creates and configures the HTTP router
 func NewRouter(config config.Config,
-	whatsappUseCase domain.WhatsAppUseCaseInterface) *gin.Engine {
-	router := gin.Default()
+	whatsappUseCase domain.WhatsAppUseCaseInterface, opts ...Option) *gin.Engine {
+	o := &options{}
+	for _, opt := range opts {
+		opt(o)
+	}
+	router := gin.New()
 
 	// Add middlewares
+	router.Use(middleware.RequestID())
+	router.Use(middleware.AccessLog())
+	router.Use(middleware.Tracing())
+	if o.metrics != nil {
+		router.Use(middleware.Metrics(o.metrics))
+	}
 	router.Use(middleware.CORS())
 	router.Use(middleware.ErrorHandler())
 
@@ -28,12 +113,70 @@ func NewRouter(config config.Config,
 		})
 	})
 
+	// Kubernetes style probes
+	probeHandler := handler.NewProbeHandler(o.readiness, config.AdminToken)
+	router.GET("/livez", probeHandler.Livez)
+	router.GET("/readyz", probeHandler.Readyz)
+
+	// Prometheus metrics endpoint
+	if o.metricsHandler != nil {
+		router.GET("/metrics", gin.WrapH(o.metricsHandler))
+	}
+
 	v1 := router.Group("/api/v1")
 
 	whatsapp := v1.Group("/whatsapp")
 	whatsapp.POST("/send", whatsappHandler.SendMessage)
-	whatsapp.POST("/webhook", whatsappHandler.ReceiveWebhook)
+	receiveWebhook := []gin.HandlerFunc{whatsappHandler.ReceiveWebhook}
+	if o.recorder != nil {
+		receiveWebhook = append([]gin.HandlerFunc{middleware.RecordWebhooks(o.recorder)}, receiveWebhook...)
+	}
+	whatsapp.POST("/webhook", receiveWebhook...)
 	whatsapp.GET("/webhook", whatsappHandler.VerifyWebhook)
 
+	// Human agent, support staff and compliance endpoints, disabled without an admin token
+	if config.AdminToken != "" {
+		if o.agentUseCase != nil {
+			agentHandler := handler.NewAgentHandler(o.agentUseCase)
+
+			conversations := v1.Group("/agent/conversations", middleware.AdminAuth(config.AdminToken))
+			conversations.GET("", agentHandler.ListConversations)
+			conversations.GET("/:id", agentHandler.GetConversation)
+			conversations.POST("/:id/reply", agentHandler.Reply)
+			conversations.POST("/:id/handback", agentHandler.HandBack)
+			conversations.POST("/:id/close", agentHandler.Close)
+		}
+
+		admin := v1.Group("/admin", middleware.AdminAuth(config.AdminToken))
+		if o.historyUseCase != nil {
+			historyHandler := handler.NewHistoryHandler(o.historyUseCase)
+			admin.GET("/conversations", historyHandler.ListConversations)
+			admin.GET("/conversations/:id/messages", historyHandler.Transcript)
+			admin.GET("/messages/search", historyHandler.SearchMessages)
+			admin.GET("/export", historyHandler.Export)
+		}
+		if o.privacyUseCase != nil {
+			privacyHandler := handler.NewPrivacyHandler(o.privacyUseCase)
+			admin.DELETE("/contacts/:wa_id", privacyHandler.EraseContact)
+			admin.POST("/retention/run", privacyHandler.ApplyRetention)
+			admin.GET("/audit", privacyHandler.AuditLog)
+		}
+		if o.deadLetters != nil {
+			deadLetterHandler := handler.NewDeadLetterHandler(o.deadLetters)
+			admin.GET("/dead-letters", deadLetterHandler.ListDeadLetters)
+			admin.GET("/dead-letters/:id", deadLetterHandler.GetDeadLetter)
+			admin.POST("/dead-letters/:id/retry", deadLetterHandler.RetryDeadLetter)
+			admin.DELETE("/dead-letters/:id", deadLetterHandler.DiscardDeadLetter)
+		}
+	}
+
+	// Operator endpoints, disabled without an admin token
+	if config.AdminToken != "" && o.configReloader != nil {
+		adminHandler := handler.NewAdminHandler(o.configReloader)
+
+		admin := router.Group("/admin", middleware.AdminAuth(config.AdminToken))
+		admin.POST("/config/reload", adminHandler.ReloadConfig)
+	}
+
 	return router
 }
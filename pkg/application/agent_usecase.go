@@ -0,0 +1,178 @@
+package application
+
+import (
+	"anyzzapp/pkg/domain"
+	"context"
+	"errors"
+	"fmt"
+	"time"
+)
+
+// AgentUseCase implements AgentUseCaseInterface
+type AgentUseCase struct {
+	whatsappRepo  domain.WhatsAppRepository
+	conversations domain.ConversationRepository
+	messages      domain.MessageRepository
+	suppressions  domain.SuppressionRepository
+	outbox        *Outbox
+}
+
+// AgentOption configures an optional collaborator of AgentUseCase
+type AgentOption func(*AgentUseCase)
+
+// WithAgentMessages sets the store recording the messages sent by the agents
+func WithAgentMessages(messages domain.MessageRepository) AgentOption {
+	return func(uc *AgentUseCase) {
+		uc.messages = messages
+	}
+}
+
+// WithAgentOutbox stores the agent replies in the outbox before they are sent, so that a temporary failure of the
+// WhatsApp API does not lose them. The use case the outbox reports to records them once they are sent.
+func WithAgentOutbox(outbox *Outbox) AgentOption {
+	return func(uc *AgentUseCase) {
+		uc.outbox = outbox
+	}
+}
+
+// WithAgentSuppressions refuses the replies to the contacts that opted out, unless they are transactional
+func WithAgentSuppressions(suppressions domain.SuppressionRepository) AgentOption {
+	return func(uc *AgentUseCase) {
+		uc.suppressions = suppressions
+	}
+}
+
+// NewAgentUseCase creates a new instance of AgentUseCase
+func NewAgentUseCase(whatsappRepo domain.WhatsAppRepository,
+	conversations domain.ConversationRepository, opts ...AgentOption) domain.AgentUseCaseInterface {
+	uc := &AgentUseCase{
+		whatsappRepo:  whatsappRepo,
+		conversations: conversations,
+	}
+	for _, opt := range opts {
+		opt(uc)
+	}
+	return uc
+}
+
+// ListConversations returns the conversations in the given state, every conversation if the state is empty
+func (uc *AgentUseCase) ListConversations(state domain.ConversationState) ([]domain.Conversation, error) {
+	if state != "" && !state.IsValid() {
+		return nil, fmt.Errorf("invalid conversation state: %q", state)
+	}
+	conversations, err := uc.conversations.List(state)
+	if err != nil {
+		return nil, fmt.Errorf("failed to list conversations: %w", err)
+	}
+	return conversations, nil
+}
+
+// GetConversation returns a conversation with its history
+func (uc *AgentUseCase) GetConversation(id string) (*domain.Conversation, error) {
+	key, err := domain.ParseConversationID(id)
+	if err != nil {
+		return nil, fmt.Errorf("%w: %v", domain.ErrConversationNotFound, err)
+	}
+	conversation, err := uc.conversations.Get(key)
+	if err != nil {
+		return nil, fmt.Errorf("failed to get conversation: %w", err)
+	}
+	if conversation == nil {
+		return nil, domain.ErrConversationNotFound
+	}
+	return conversation, nil
+}
+
+// Reply sends an agent message to the contact of a conversation in human mode. Contacts that opted out only get
+// transactional replies.
+func (uc *AgentUseCase) Reply(ctx context.Context, id string, reply domain.AgentReplyRequest) (*domain.SendMessageResponse, error) {
+	if reply.Content == "" {
+		return nil, fmt.Errorf("message content is required")
+	}
+	conversation, err := uc.GetConversation(id)
+	if err != nil {
+		return nil, err
+	}
+	if conversation.State != domain.ConversationStateHuman {
+		return nil, domain.ErrConversationNotInHumanMode
+	}
+	if !reply.Transactional && uc.suppressions != nil {
+		suppression, err := uc.suppressions.Get(ctx, conversation.Key.PhoneNumberID, suppressionContact(conversation.Key.WaID))
+		if err != nil {
+			return nil, fmt.Errorf("failed to read the suppression list: %w", err)
+		}
+		if suppression != nil {
+			return nil, domain.ErrContactOptedOut
+		}
+	}
+	message := domain.Message{
+		PhoneNumberID: conversation.Key.PhoneNumberID,
+		To:            sendAddress(ctx, conversation.Key.WaID),
+		Content:       reply.Content,
+		MessageType:   "text",
+		Transactional: reply.Transactional,
+	}
+	if uc.outbox != nil {
+		response, err := uc.outbox.Send(ctx, &domain.OutboxMessage{
+			ID:      newOutboxID(domain.OutboxAuthorAgent),
+			Message: message,
+			WaID:    conversation.Key.WaID,
+			Author:  domain.OutboxAuthorAgent,
+		})
+		if err != nil {
+			return response, fmt.Errorf("failed to send message: %w", err)
+		}
+		return response, nil
+	}
+	response, err := uc.whatsappRepo.SendMessage(ctx, message)
+	if err != nil {
+		return response, fmt.Errorf("failed to send message: %w", err)
+	}
+	if response == nil {
+		return &domain.SendMessageResponse{Status: domain.SendStatusSent}, nil
+	}
+	storeMessage(ctx, uc.messages, outboundMessage(message, conversation.Key.WaID, domain.OutboxAuthorAgent, response.MessageID))
+	if err := recordMessage(uc.conversations, conversation.Key, domain.ConversationMessage{
+		ID:        response.MessageID,
+		Direction: "outbound",
+		Author:    "agent",
+		Content:   reply.Content,
+		Timestamp: time.Now(),
+	}, 0); err != nil {
+		return response, err
+	}
+	return response, nil
+}
+
+// HandBack returns a conversation in human mode to the bot
+func (uc *AgentUseCase) HandBack(id string) error {
+	return uc.transition(id, domain.ConversationStateBot)
+}
+
+// Close closes a conversation, the next message of the contact reopens it with the bot
+func (uc *AgentUseCase) Close(id string) error {
+	return uc.transition(id, domain.ConversationStateClosed)
+}
+
+// transition moves a conversation in human mode to the given state, checking the state in the same update
+// so that the messages recorded meanwhile are kept
+func (uc *AgentUseCase) transition(id string, state domain.ConversationState) error {
+	conversation, err := uc.GetConversation(id)
+	if err != nil {
+		return err
+	}
+	err = uc.conversations.Update(conversation.Key, func(conversation *domain.Conversation) error {
+		if conversation.State != domain.ConversationStateHuman {
+			return domain.ErrConversationNotInHumanMode
+		}
+		conversation.State = state
+		return nil
+	})
+	if errors.Is(err, domain.ErrConversationNotInHumanMode) {
+		return err
+	}
+	if err != nil {
+		return fmt.Errorf("failed to save conversation: %w", err)
+	}
+	return nil
+}
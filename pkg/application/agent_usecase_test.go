@@ -0,0 +1,247 @@
+package application
+
+import (
+	"anyzzapp/pkg/domain"
+	"context"
+	"errors"
+	"testing"
+	"time"
+
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/mock"
+)
+
+func TestNewAgentUseCase(t *testing.T) {
+	useCase := NewAgentUseCase(&MockWhatsAppRepository{}, &MockConversationRepository{})
+
+	assert.NotNil(t, useCase)
+	assert.IsType(t, &AgentUseCase{}, useCase)
+}
+
+func TestAgentUseCase_ListConversations(t *testing.T) {
+	conversations := &MockConversationRepository{}
+	useCase := NewAgentUseCase(&MockWhatsAppRepository{}, conversations)
+
+	expected := []domain.Conversation{{Key: testKey, State: domain.ConversationStateHuman}}
+	conversations.On("List", domain.ConversationStateHuman).Return(expected, nil)
+
+	result, err := useCase.ListConversations(domain.ConversationStateHuman)
+
+	assert.NoError(t, err)
+	assert.Equal(t, expected, result)
+	conversations.AssertExpectations(t)
+}
+
+func TestAgentUseCase_ListConversations_InvalidState(t *testing.T) {
+	useCase := NewAgentUseCase(&MockWhatsAppRepository{}, &MockConversationRepository{})
+
+	_, err := useCase.ListConversations("waiting")
+
+	assert.Error(t, err)
+	assert.Contains(t, err.Error(), "invalid conversation state")
+}
+
+func TestAgentUseCase_GetConversation_NotFound(t *testing.T) {
+	conversations := &MockConversationRepository{}
+	useCase := NewAgentUseCase(&MockWhatsAppRepository{}, conversations)
+
+	conversations.On("Get", testKey).Return((*domain.Conversation)(nil), nil)
+
+	_, err := useCase.GetConversation(testKey.ID())
+	assert.ErrorIs(t, err, domain.ErrConversationNotFound)
+
+	_, err = useCase.GetConversation("malformed")
+	assert.ErrorIs(t, err, domain.ErrConversationNotFound)
+}
+
+func TestAgentUseCase_Reply_Success(t *testing.T) {
+	mockWhatsAppRepo := &MockWhatsAppRepository{}
+	conversations := &MockConversationRepository{}
+	useCase := NewAgentUseCase(mockWhatsAppRepo, conversations)
+
+	conversations.On("Get", testKey).Return(&domain.Conversation{Key: testKey, State: domain.ConversationStateHuman}, nil)
+	mockWhatsAppRepo.On("SendMessage", domain.Message{
+		PhoneNumberID: "123456789",
+		To:            "541112345678",
+		Content:       "Hi, I'm Ana from support",
+		MessageType:   "text",
+	}).Return(&domain.SendMessageResponse{MessageID: "agent_msg_1", Status: "sent"}, nil)
+	conversations.On("Save", mock.MatchedBy(func(conversation *domain.Conversation) bool {
+		last := conversation.Messages[len(conversation.Messages)-1]
+		return last.Author == "agent" && last.ID == "agent_msg_1" && last.Direction == "outbound"
+	})).Return(nil)
+
+	response, err := useCase.Reply(context.Background(), testKey.ID(), domain.AgentReplyRequest{Content: "Hi, I'm Ana from support"})
+
+	assert.NoError(t, err)
+	assert.Equal(t, "agent_msg_1", response.MessageID)
+	mockWhatsAppRepo.AssertExpectations(t)
+	conversations.AssertExpectations(t)
+}
+
+func TestAgentUseCase_Reply_NotInHumanMode(t *testing.T) {
+	mockWhatsAppRepo := &MockWhatsAppRepository{}
+	conversations := &MockConversationRepository{}
+	useCase := NewAgentUseCase(mockWhatsAppRepo, conversations)
+
+	conversations.On("Get", testKey).Return(&domain.Conversation{Key: testKey, State: domain.ConversationStateBot}, nil)
+
+	_, err := useCase.Reply(context.Background(), testKey.ID(), domain.AgentReplyRequest{Content: "Hello"})
+
+	assert.ErrorIs(t, err, domain.ErrConversationNotInHumanMode)
+	mockWhatsAppRepo.AssertNotCalled(t, "SendMessage", mock.Anything)
+}
+
+func TestAgentUseCase_Reply_SendError(t *testing.T) {
+	mockWhatsAppRepo := &MockWhatsAppRepository{}
+	conversations := &MockConversationRepository{}
+	useCase := NewAgentUseCase(mockWhatsAppRepo, conversations)
+
+	conversations.On("Get", testKey).Return(&domain.Conversation{Key: testKey, State: domain.ConversationStateHuman}, nil)
+	mockWhatsAppRepo.On("SendMessage", mock.Anything).Return((*domain.SendMessageResponse)(nil), errors.New("API down"))
+
+	_, err := useCase.Reply(context.Background(), testKey.ID(), domain.AgentReplyRequest{Content: "Hello"})
+
+	assert.Error(t, err)
+	assert.Contains(t, err.Error(), "failed to send message")
+	conversations.AssertNotCalled(t, "Save", mock.Anything)
+}
+
+func TestAgentUseCase_HandBackAndClose(t *testing.T) {
+	tests := []struct {
+		name     string
+		action   func(uc domain.AgentUseCaseInterface, id string) error
+		expected domain.ConversationState
+	}{
+		{
+			name:     "Hand back to the bot",
+			action:   func(uc domain.AgentUseCaseInterface, id string) error { return uc.HandBack(id) },
+			expected: domain.ConversationStateBot,
+		},
+		{
+			name:     "Close",
+			action:   func(uc domain.AgentUseCaseInterface, id string) error { return uc.Close(id) },
+			expected: domain.ConversationStateClosed,
+		},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			conversations := &MockConversationRepository{}
+			useCase := NewAgentUseCase(&MockWhatsAppRepository{}, conversations)
+
+			conversations.On("Get", testKey).Return(&domain.Conversation{Key: testKey, State: domain.ConversationStateHuman}, nil)
+			conversations.On("Save", &domain.Conversation{Key: testKey, State: tt.expected}).Return(nil)
+
+			err := tt.action(useCase, testKey.ID())
+
+			assert.NoError(t, err)
+			conversations.AssertExpectations(t)
+		})
+	}
+}
+
+func TestAgentUseCase_HandBack_NotInHumanMode(t *testing.T) {
+	conversations := &MockConversationRepository{}
+	useCase := NewAgentUseCase(&MockWhatsAppRepository{}, conversations)
+
+	conversations.On("Get", testKey).Return(&domain.Conversation{Key: testKey, State: domain.ConversationStateBot}, nil)
+
+	err := useCase.HandBack(testKey.ID())
+
+	assert.ErrorIs(t, err, domain.ErrConversationNotInHumanMode)
+}
+
+func TestAgentUseCase_Reply_StoresMessage(t *testing.T) {
+	mockWhatsAppRepo := &MockWhatsAppRepository{}
+	conversations := &MockConversationRepository{}
+	messages := &MockMessageRepository{}
+	useCase := NewAgentUseCase(mockWhatsAppRepo, conversations, WithAgentMessages(messages))
+
+	conversations.On("Get", testKey).Return(&domain.Conversation{Key: testKey, State: domain.ConversationStateHuman}, nil)
+	conversations.On("Save", mock.Anything).Return(nil)
+	mockWhatsAppRepo.On("SendMessage", mock.Anything).Return(&domain.SendMessageResponse{MessageID: "agent_msg_1"}, nil)
+	messages.On("Save", mock.MatchedBy(func(message *domain.StoredMessage) bool {
+		return message.ID == "agent_msg_1" && message.Author == "agent" && message.WaID == testKey.WaID
+	})).Return(nil)
+
+	_, err := useCase.Reply(context.Background(), testKey.ID(), domain.AgentReplyRequest{Content: "Hi"})
+
+	assert.NoError(t, err)
+	messages.AssertExpectations(t)
+}
+
+func TestAgentUseCase_Reply_NoResponse(t *testing.T) {
+	mockWhatsAppRepo := &MockWhatsAppRepository{}
+	conversations := &MockConversationRepository{}
+	useCase := NewAgentUseCase(mockWhatsAppRepo, conversations)
+
+	conversations.On("Get", testKey).Return(&domain.Conversation{Key: testKey, State: domain.ConversationStateHuman}, nil)
+	mockWhatsAppRepo.On("SendMessage", mock.Anything).Return((*domain.SendMessageResponse)(nil), nil)
+
+	response, err := useCase.Reply(context.Background(), testKey.ID(), domain.AgentReplyRequest{Content: "Hi"})
+
+	assert.NoError(t, err)
+	assert.Equal(t, domain.SendStatusSent, response.Status)
+}
+
+func TestAgentUseCase_Reply_OptedOut(t *testing.T) {
+	mockWhatsAppRepo := &MockWhatsAppRepository{}
+	conversations := &MockConversationRepository{}
+	suppressions := suppressionList{"123456789-541112345678": {PhoneNumberID: "123456789", Contact: "541112345678"}}
+	useCase := NewAgentUseCase(mockWhatsAppRepo, conversations, WithAgentSuppressions(suppressions))
+
+	conversations.On("Get", testKey).Return(&domain.Conversation{Key: testKey, State: domain.ConversationStateHuman}, nil)
+	conversations.On("Save", mock.Anything).Return(nil)
+	mockWhatsAppRepo.On("SendMessage", mock.MatchedBy(func(message domain.Message) bool {
+		return message.Transactional
+	})).Return(&domain.SendMessageResponse{MessageID: "agent_msg_1"}, nil).Once()
+
+	_, err := useCase.Reply(context.Background(), testKey.ID(), domain.AgentReplyRequest{Content: "Our sale starts today"})
+	assert.ErrorIs(t, err, domain.ErrContactOptedOut)
+
+	// Transactional replies are still sent
+	response, err := useCase.Reply(context.Background(), testKey.ID(), domain.AgentReplyRequest{Content: "Your refund was issued", Transactional: true})
+	assert.NoError(t, err)
+	assert.Equal(t, "agent_msg_1", response.MessageID)
+	mockWhatsAppRepo.AssertExpectations(t)
+}
+
+func TestAgentUseCase_Reply_Outbox(t *testing.T) {
+	mockWhatsAppRepo := &MockWhatsAppRepository{}
+	conversations := &MockConversationRepository{}
+	messages := &MockMessageRepository{}
+	entries := outboxList{}
+	outbox := NewOutbox(entries, mockWhatsAppRepo, testRetrySchedule)
+	now := time.Date(2024, 1, 1, 10, 0, 0, 0, time.UTC)
+	outbox.now = func() time.Time { return now }
+	// The WhatsApp use case records the replies of the outbox once sent
+	NewWhatsAppUseCase(mockWhatsAppRepo, &MockLLMRepository{}, WithConversations(conversations), WithMessages(messages), WithOutbox(outbox))
+	useCase := NewAgentUseCase(mockWhatsAppRepo, conversations, WithAgentMessages(messages), WithAgentOutbox(outbox))
+	ctx := context.Background()
+
+	conversations.On("Get", testKey).Return(&domain.Conversation{Key: testKey, State: domain.ConversationStateHuman}, nil)
+	mockWhatsAppRepo.On("SendMessage", mock.Anything).Return(&domain.SendMessageResponse{Status: "failed"}, rateLimited).Once()
+	mockWhatsAppRepo.On("SendMessage", mock.Anything).Return(&domain.SendMessageResponse{MessageID: "agent_msg_1"}, nil).Once()
+
+	// A reply failing for a temporary reason is queued, not lost
+	response, err := useCase.Reply(ctx, testKey.ID(), domain.AgentReplyRequest{Content: "Hi, I'm Ana"})
+	assert.NoError(t, err)
+	assert.Equal(t, domain.SendStatusQueued, response.Status)
+	conversations.AssertNotCalled(t, "Save", mock.Anything)
+
+	conversations.On("Save", mock.MatchedBy(func(conversation *domain.Conversation) bool {
+		last := conversation.Messages[len(conversation.Messages)-1]
+		return last.Author == "agent" && last.ID == "agent_msg_1" && last.Content == "Hi, I'm Ana"
+	})).Return(nil).Once()
+	messages.On("Save", mock.MatchedBy(func(message *domain.StoredMessage) bool {
+		return message.ID == "agent_msg_1" && message.Author == "agent"
+	})).Return(nil).Once()
+
+	now = now.Add(time.Minute)
+	sent, _, err := outbox.DispatchDue(ctx)
+	assert.NoError(t, err)
+	assert.Equal(t, 1, sent)
+	conversations.AssertExpectations(t)
+	messages.AssertExpectations(t)
+}
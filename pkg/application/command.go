@@ -0,0 +1,217 @@
+package application
+
+import (
+	"anyzzapp/pkg/domain"
+	"context"
+	"fmt"
+	"sort"
+	"strings"
+)
+
+// CommandContext carries what a command handler needs to answer a message
+type CommandContext struct {
+	Key     domain.ConversationKey
+	Message domain.WebhookMessage
+	Prefix  string
+	Args    string
+}
+
+// CommandHandler answers a command and returns the text to reply with
+type CommandHandler func(ctx context.Context, cmd CommandContext) (string, error)
+
+// Command represents an in-chat command such as /help
+type Command struct {
+	Name string
+	// Usage describes the arguments of the command in the help, e.g. <language>
+	Usage       string
+	Description string
+	Handler     CommandHandler
+}
+
+// CommandRouter recognizes command prefixes in incoming texts and dispatches them to registered handlers
+type CommandRouter struct {
+	prefixes   []string
+	helpPrefix string
+	commands   map[string]Command
+}
+
+// NewCommandRouter creates a new instance of CommandRouter, "/" is used when no prefix is given
+func NewCommandRouter(prefixes ...string) *CommandRouter {
+	var valid []string
+	for _, prefix := range prefixes {
+		if prefix = strings.TrimSpace(prefix); prefix != "" {
+			valid = append(valid, prefix)
+		}
+	}
+	if len(valid) == 0 {
+		valid = []string{"/"}
+	}
+	helpPrefix := valid[0]
+	// Longest prefixes first so "!!" wins over "!"
+	sort.SliceStable(valid, func(i, j int) bool { return len(valid[i]) > len(valid[j]) })
+
+	return &CommandRouter{
+		prefixes:   valid,
+		helpPrefix: helpPrefix,
+		commands:   make(map[string]Command),
+	}
+}
+
+// Register adds a command to the router
+func (r *CommandRouter) Register(command Command) error {
+	name := strings.ToLower(strings.TrimSpace(command.Name))
+	if name == "" || strings.ContainsAny(name, " \t\n") {
+		return fmt.Errorf("invalid command name: %q", command.Name)
+	}
+	if command.Handler == nil {
+		return fmt.Errorf("command %s has no handler", name)
+	}
+	if _, exists := r.commands[name]; exists {
+		return fmt.Errorf("command %s is already registered", name)
+	}
+	command.Name = name
+	r.commands[name] = command
+	return nil
+}
+
+// Commands returns the registered commands sorted by name
+func (r *CommandRouter) Commands() []Command {
+	commands := make([]Command, 0, len(r.commands))
+	for _, command := range r.commands {
+		commands = append(commands, command)
+	}
+	sort.Slice(commands, func(i, j int) bool { return commands[i].Name < commands[j].Name })
+	return commands
+}
+
+// Help returns a text listing every registered command
+func (r *CommandRouter) Help() string {
+	var b strings.Builder
+	b.WriteString("Available commands:")
+	for _, command := range r.Commands() {
+		fmt.Fprintf(&b, "\n%s%s", r.helpPrefix, command.Name)
+		if command.Usage != "" {
+			fmt.Fprintf(&b, " %s", command.Usage)
+		}
+		fmt.Fprintf(&b, " - %s", command.Description)
+	}
+	return b.String()
+}
+
+// IsCommand reports whether the text starts with one of the command prefixes
+func (r *CommandRouter) IsCommand(text string) bool {
+	_, _, ok := r.parse(text)
+	return ok
+}
+
+// Dispatch runs the command contained in the message text and returns the reply
+func (r *CommandRouter) Dispatch(ctx context.Context, key domain.ConversationKey, msg domain.WebhookMessage) (string, error) {
+	if msg.Text == nil {
+		return "", fmt.Errorf("message %s has no text", msg.ID)
+	}
+	prefix, rest, ok := r.parse(msg.Text.Body)
+	if !ok {
+		return "", fmt.Errorf("message %s is not a command", msg.ID)
+	}
+	name, args, _ := strings.Cut(rest, " ")
+	name = strings.ToLower(name)
+
+	command, exists := r.commands[name]
+	if !exists {
+		return fmt.Sprintf("Unknown command %s%s. Send %shelp to see the available commands.", prefix, name, prefix), nil
+	}
+	return command.Handler(ctx, CommandContext{
+		Key:     key,
+		Message: msg,
+		Prefix:  prefix,
+		Args:    strings.TrimSpace(args),
+	})
+}
+
+// parse splits a command text into its prefix and the rest of the text
+func (r *CommandRouter) parse(text string) (string, string, bool) {
+	text = strings.TrimSpace(text)
+	for _, prefix := range r.prefixes {
+		if rest, found := strings.CutPrefix(text, prefix); found {
+			// A lone prefix or a prefix followed by a space is a regular message
+			if rest == "" || rest[0] == ' ' {
+				return "", "", false
+			}
+			return prefix, rest, true
+		}
+	}
+	return "", "", false
+}
+
+// RegisterBuiltinCommands registers /help, /reset, /lang and /human backed by the conversation memory
+func RegisterBuiltinCommands(router *CommandRouter, conversations domain.ConversationRepository) error {
+	builtins := []Command{
+		{
+			Name:        "help",
+			Description: "Show this help",
+			Handler: func(ctx context.Context, cmd CommandContext) (string, error) {
+				return router.Help(), nil
+			},
+		},
+		{
+			Name:        "reset",
+			Description: "Clear the conversation history",
+			Handler: func(ctx context.Context, cmd CommandContext) (string, error) {
+				// Only the history, a conversation handed over to an agent stays with the agent
+				err := conversations.Update(cmd.Key, func(conversation *domain.Conversation) error {
+					conversation.Messages = nil
+					return nil
+				})
+				if err != nil {
+					return "", fmt.Errorf("failed to reset conversation: %w", err)
+				}
+				return "Conversation history cleared.", nil
+			},
+		},
+		{
+			Name:        "lang",
+			Usage:       "<language>",
+			Description: "Switch the reply language, or show the current one without a language",
+			Handler: func(ctx context.Context, cmd CommandContext) (string, error) {
+				conversation, err := loadConversation(conversations, cmd.Key)
+				if err != nil {
+					return "", err
+				}
+				if cmd.Args == "" {
+					if conversation.Language == "" {
+						return fmt.Sprintf("No reply language set. Use %slang <language> to choose one.", cmd.Prefix), nil
+					}
+					return fmt.Sprintf("Current reply language: %s", conversation.Language), nil
+				}
+				err = conversations.Update(cmd.Key, func(conversation *domain.Conversation) error {
+					conversation.Language = cmd.Args
+					return nil
+				})
+				if err != nil {
+					return "", fmt.Errorf("failed to save conversation: %w", err)
+				}
+				return fmt.Sprintf("Reply language set to %s.", cmd.Args), nil
+			},
+		},
+		{
+			Name:        "human",
+			Description: "Ask for a human agent",
+			Handler: func(ctx context.Context, cmd CommandContext) (string, error) {
+				err := conversations.Update(cmd.Key, func(conversation *domain.Conversation) error {
+					conversation.State = domain.ConversationStateHuman
+					return nil
+				})
+				if err != nil {
+					return "", fmt.Errorf("failed to save conversation: %w", err)
+				}
+				return "A human agent has been requested and will reply to you soon.", nil
+			},
+		},
+	}
+	for _, command := range builtins {
+		if err := router.Register(command); err != nil {
+			return err
+		}
+	}
+	return nil
+}
@@ -0,0 +1,254 @@
+package application
+
+import (
+	"anyzzapp/pkg/domain"
+	"context"
+	"errors"
+	"testing"
+
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/mock"
+)
+
+// MockConversationRepository is a mock implementation of ConversationRepository
+type MockConversationRepository struct {
+	mock.Mock
+}
+
+func (m *MockConversationRepository) Get(key domain.ConversationKey) (*domain.Conversation, error) {
+	args := m.Called(key)
+	return args.Get(0).(*domain.Conversation), args.Error(1)
+}
+
+func (m *MockConversationRepository) Save(conversation *domain.Conversation) error {
+	args := m.Called(conversation)
+	return args.Error(0)
+}
+
+// Update runs fn between the mocked Get and Save
+func (m *MockConversationRepository) Update(key domain.ConversationKey, fn func(conversation *domain.Conversation) error) error {
+	conversation, err := m.Get(key)
+	if err != nil {
+		return err
+	}
+	if conversation == nil {
+		conversation = &domain.Conversation{Key: key, State: domain.ConversationStateBot}
+	}
+	if err := fn(conversation); err != nil {
+		return err
+	}
+	return m.Save(conversation)
+}
+
+func (m *MockConversationRepository) Delete(key domain.ConversationKey) error {
+	args := m.Called(key)
+	return args.Error(0)
+}
+
+func (m *MockConversationRepository) List(state domain.ConversationState) ([]domain.Conversation, error) {
+	args := m.Called(state)
+	return args.Get(0).([]domain.Conversation), args.Error(1)
+}
+
+func textMessage(body string) domain.WebhookMessage {
+	return domain.WebhookMessage{
+		From: "5491112345678",
+		ID:   "msg_123",
+		Type: "text",
+		Text: &domain.WebhookText{Body: body},
+	}
+}
+
+var testKey = domain.ConversationKey{PhoneNumberID: "123456789", WaID: "5491112345678"}
+
+func TestNewCommandRouter_DefaultPrefix(t *testing.T) {
+	router := NewCommandRouter()
+
+	assert.True(t, router.IsCommand("/help"))
+	assert.False(t, router.IsCommand("!help"))
+}
+
+func TestCommandRouter_IsCommand(t *testing.T) {
+	router := NewCommandRouter("/", "!")
+
+	tests := []struct {
+		name     string
+		text     string
+		expected bool
+	}{
+		{name: "Slash prefix", text: "/help", expected: true},
+		{name: "Bang prefix", text: "!reset", expected: true},
+		{name: "Leading spaces", text: "  /lang es", expected: true},
+		{name: "Regular text", text: "hello", expected: false},
+		{name: "Lone prefix", text: "/", expected: false},
+		{name: "Prefix followed by a space", text: "/ not a command", expected: false},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			assert.Equal(t, tt.expected, router.IsCommand(tt.text))
+		})
+	}
+}
+
+func TestCommandRouter_Register_Errors(t *testing.T) {
+	router := NewCommandRouter()
+	handler := func(ctx context.Context, cmd CommandContext) (string, error) { return "", nil }
+
+	assert.NoError(t, router.Register(Command{Name: "ping", Handler: handler}))
+	assert.Error(t, router.Register(Command{Name: "PING", Handler: handler}))
+	assert.Error(t, router.Register(Command{Name: "", Handler: handler}))
+	assert.Error(t, router.Register(Command{Name: "two words", Handler: handler}))
+	assert.Error(t, router.Register(Command{Name: "nohandler"}))
+}
+
+func TestCommandRouter_Dispatch_CustomCommand(t *testing.T) {
+	router := NewCommandRouter("/")
+	var received CommandContext
+	err := router.Register(Command{
+		Name:        "Echo",
+		Description: "Echo the arguments",
+		Handler: func(ctx context.Context, cmd CommandContext) (string, error) {
+			received = cmd
+			return cmd.Args, nil
+		},
+	})
+	assert.NoError(t, err)
+
+	reply, err := router.Dispatch(context.Background(), testKey, textMessage("/ECHO  hello there "))
+
+	assert.NoError(t, err)
+	assert.Equal(t, "hello there", reply)
+	assert.Equal(t, testKey, received.Key)
+	assert.Equal(t, "/", received.Prefix)
+	assert.Equal(t, "msg_123", received.Message.ID)
+}
+
+func TestCommandRouter_Dispatch_UnknownCommand(t *testing.T) {
+	router := NewCommandRouter("!")
+
+	reply, err := router.Dispatch(context.Background(), testKey, textMessage("!nope"))
+
+	assert.NoError(t, err)
+	assert.Contains(t, reply, "Unknown command !nope")
+	assert.Contains(t, reply, "!help")
+}
+
+func TestCommandRouter_Dispatch_NotACommand(t *testing.T) {
+	router := NewCommandRouter()
+
+	_, err := router.Dispatch(context.Background(), testKey, textMessage("hello"))
+
+	assert.Error(t, err)
+}
+
+func TestBuiltinCommands_Help(t *testing.T) {
+	router := NewCommandRouter("/")
+	assert.NoError(t, RegisterBuiltinCommands(router, &MockConversationRepository{}))
+
+	reply, err := router.Dispatch(context.Background(), testKey, textMessage("/help"))
+
+	assert.NoError(t, err)
+	assert.Contains(t, reply, "/help - Show this help")
+	assert.Contains(t, reply, "/reset")
+	assert.Contains(t, reply, "/lang <language> - ")
+	assert.Contains(t, reply, "/human")
+}
+
+func TestBuiltinCommands_Reset(t *testing.T) {
+	conversations := &MockConversationRepository{}
+	router := NewCommandRouter("/")
+	assert.NoError(t, RegisterBuiltinCommands(router, conversations))
+
+	conversations.On("Get", testKey).Return(&domain.Conversation{
+		Key:      testKey,
+		State:    domain.ConversationStateBot,
+		Language: "es",
+		Messages: []domain.ConversationMessage{{ID: "msg_1", Content: "Hello"}},
+	}, nil)
+	conversations.On("Save", &domain.Conversation{Key: testKey, State: domain.ConversationStateBot, Language: "es"}).Return(nil)
+
+	reply, err := router.Dispatch(context.Background(), testKey, textMessage("/reset"))
+
+	assert.NoError(t, err)
+	assert.Equal(t, "Conversation history cleared.", reply)
+	conversations.AssertExpectations(t)
+}
+
+func TestBuiltinCommands_Reset_KeepsHandover(t *testing.T) {
+	conversations := &MockConversationRepository{}
+	router := NewCommandRouter("/")
+	assert.NoError(t, RegisterBuiltinCommands(router, conversations))
+
+	conversations.On("Get", testKey).Return(&domain.Conversation{
+		Key:      testKey,
+		State:    domain.ConversationStateHuman,
+		Messages: []domain.ConversationMessage{{ID: "msg_1", Content: "I want to talk to someone"}},
+	}, nil)
+	// The conversation stays with the agent
+	conversations.On("Save", &domain.Conversation{Key: testKey, State: domain.ConversationStateHuman}).Return(nil)
+
+	_, err := router.Dispatch(context.Background(), testKey, textMessage("/reset"))
+
+	assert.NoError(t, err)
+	conversations.AssertExpectations(t)
+	conversations.AssertNotCalled(t, "Delete", mock.Anything)
+}
+
+func TestBuiltinCommands_Reset_Error(t *testing.T) {
+	conversations := &MockConversationRepository{}
+	router := NewCommandRouter("/")
+	assert.NoError(t, RegisterBuiltinCommands(router, conversations))
+
+	conversations.On("Get", testKey).Return((*domain.Conversation)(nil), nil)
+	conversations.On("Save", mock.Anything).Return(errors.New("store down"))
+
+	_, err := router.Dispatch(context.Background(), testKey, textMessage("/reset"))
+
+	assert.Error(t, err)
+	assert.Contains(t, err.Error(), "failed to reset conversation")
+}
+
+func TestBuiltinCommands_Lang(t *testing.T) {
+	conversations := &MockConversationRepository{}
+	router := NewCommandRouter("/")
+	assert.NoError(t, RegisterBuiltinCommands(router, conversations))
+
+	conversations.On("Get", testKey).Return((*domain.Conversation)(nil), nil)
+	conversations.On("Save", &domain.Conversation{Key: testKey, State: domain.ConversationStateBot, Language: "es"}).Return(nil)
+
+	reply, err := router.Dispatch(context.Background(), testKey, textMessage("/lang es"))
+
+	assert.NoError(t, err)
+	assert.Equal(t, "Reply language set to es.", reply)
+	conversations.AssertExpectations(t)
+}
+
+func TestBuiltinCommands_Lang_ShowCurrent(t *testing.T) {
+	conversations := &MockConversationRepository{}
+	router := NewCommandRouter("/")
+	assert.NoError(t, RegisterBuiltinCommands(router, conversations))
+
+	conversations.On("Get", testKey).Return(&domain.Conversation{Key: testKey, Language: "pt"}, nil)
+
+	reply, err := router.Dispatch(context.Background(), testKey, textMessage("/lang"))
+
+	assert.NoError(t, err)
+	assert.Equal(t, "Current reply language: pt", reply)
+	conversations.AssertNotCalled(t, "Save", mock.Anything)
+}
+
+func TestBuiltinCommands_Human(t *testing.T) {
+	conversations := &MockConversationRepository{}
+	router := NewCommandRouter("/")
+	assert.NoError(t, RegisterBuiltinCommands(router, conversations))
+
+	conversations.On("Get", testKey).Return(&domain.Conversation{Key: testKey, State: domain.ConversationStateBot, Language: "es"}, nil)
+	conversations.On("Save", &domain.Conversation{Key: testKey, State: domain.ConversationStateHuman, Language: "es"}).Return(nil)
+
+	reply, err := router.Dispatch(context.Background(), testKey, textMessage("/human"))
+
+	assert.NoError(t, err)
+	assert.Contains(t, reply, "human agent")
+	conversations.AssertExpectations(t)
+}
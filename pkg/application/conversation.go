@@ -0,0 +1,56 @@
+package application
+
+import (
+	"anyzzapp/pkg/domain"
+	"fmt"
+	"strconv"
+	"time"
+)
+
+// maxConversationHistory is the number of messages kept per conversation
+const maxConversationHistory = 100
+
+// loadConversation returns the stored conversation or a new one for the key
+func loadConversation(conversations domain.ConversationRepository, key domain.ConversationKey) (*domain.Conversation, error) {
+	conversation, err := conversations.Get(key)
+	if err != nil {
+		return nil, fmt.Errorf("failed to get conversation: %w", err)
+	}
+	if conversation == nil {
+		conversation = &domain.Conversation{Key: key, State: domain.ConversationStateBot}
+	}
+	return conversation, nil
+}
+
+// appendHistory adds a message to the conversation history, dropping the oldest ones past the limit,
+// maxConversationHistory when limit is 0
+func appendHistory(conversation *domain.Conversation, message domain.ConversationMessage, limit int) {
+	if limit <= 0 {
+		limit = maxConversationHistory
+	}
+	conversation.Messages = append(conversation.Messages, message)
+	if excess := len(conversation.Messages) - limit; excess > 0 {
+		conversation.Messages = conversation.Messages[excess:]
+	}
+}
+
+// recordMessage appends a message to the stored conversation history
+func recordMessage(conversations domain.ConversationRepository, key domain.ConversationKey, message domain.ConversationMessage, limit int) error {
+	err := conversations.Update(key, func(conversation *domain.Conversation) error {
+		appendHistory(conversation, message, limit)
+		return nil
+	})
+	if err != nil {
+		return fmt.Errorf("failed to save conversation: %w", err)
+	}
+	return nil
+}
+
+// messageTime returns the time of a webhook message, or now if the timestamp can't be parsed
+func messageTime(msg domain.WebhookMessage) time.Time {
+	seconds, err := strconv.ParseInt(msg.Timestamp, 10, 64)
+	if err != nil {
+		return time.Now()
+	}
+	return time.Unix(seconds, 0)
+}
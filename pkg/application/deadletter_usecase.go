@@ -0,0 +1,297 @@
+package application
+
+import (
+	"anyzzapp/pkg/domain"
+	"context"
+	"fmt"
+	"sync"
+	"time"
+
+	"github.com/rs/zerolog/log"
+)
+
+// deadLetterRetention is how long the expired dead letters are kept for an operator to retry or discard them
+const deadLetterRetention = 7 * 24 * time.Hour
+
+// DeadLetterUseCase implements DeadLetterUseCaseInterface
+type DeadLetterUseCase struct {
+	letters       domain.DeadLetterRepository
+	whatsApp      domain.WhatsAppUseCaseInterface
+	retrySchedule []time.Duration
+	metrics       domain.Metrics
+	now           func() time.Time
+	// inFlight keeps a message from being answered twice by the retry job and an operator at the same time
+	mu       sync.Mutex
+	inFlight map[string]bool
+}
+
+// DeadLetterOption configures an optional collaborator of DeadLetterUseCase
+type DeadLetterOption func(*DeadLetterUseCase)
+
+// WithDeadLetterMetrics sets where the number of dead letters is recorded
+func WithDeadLetterMetrics(metrics domain.Metrics) DeadLetterOption {
+	return func(uc *DeadLetterUseCase) {
+		uc.metrics = metrics
+	}
+}
+
+// NewDeadLetterUseCase creates a new instance of DeadLetterUseCase. A message is retried after each delay of
+// the schedule, then it expires: it waits for an operator to retry or discard it, for deadLetterRetention.
+func NewDeadLetterUseCase(letters domain.DeadLetterRepository, whatsApp domain.WhatsAppUseCaseInterface,
+	retrySchedule []time.Duration, opts ...DeadLetterOption) *DeadLetterUseCase {
+	uc := &DeadLetterUseCase{
+		letters:       letters,
+		whatsApp:      whatsApp,
+		retrySchedule: retrySchedule,
+		metrics:       nopMetrics{},
+		now:           time.Now,
+		inFlight:      make(map[string]bool),
+	}
+	for _, opt := range opts {
+		opt(uc)
+	}
+	return uc
+}
+
+// ListDeadLetters returns every dead letter, the oldest failure first
+func (uc *DeadLetterUseCase) ListDeadLetters(ctx context.Context) ([]domain.DeadLetter, error) {
+	letters, err := uc.letters.List(ctx)
+	if err != nil {
+		return nil, fmt.Errorf("failed to list dead letters: %w", err)
+	}
+	return letters, nil
+}
+
+// GetDeadLetter returns the dead letter with the ID
+func (uc *DeadLetterUseCase) GetDeadLetter(ctx context.Context, id string) (*domain.DeadLetter, error) {
+	letter, err := uc.letters.Get(ctx, id)
+	if err != nil {
+		return nil, fmt.Errorf("failed to get dead letter: %w", err)
+	}
+	if letter == nil {
+		return nil, domain.ErrDeadLetterNotFound
+	}
+	return letter, nil
+}
+
+// RetryDeadLetter answers the message again now, whatever its schedule
+func (uc *DeadLetterUseCase) RetryDeadLetter(ctx context.Context, id string) error {
+	if !uc.claim(id) {
+		return domain.ErrDeadLetterInProgress
+	}
+	defer uc.release(id)
+
+	letter, err := uc.GetDeadLetter(ctx, id)
+	if err != nil {
+		return err
+	}
+	defer reportDeadLetters(ctx, uc.letters, uc.metrics)
+	return uc.retry(ctx, letter)
+}
+
+// DiscardDeadLetter removes the dead letter without answering the message
+func (uc *DeadLetterUseCase) DiscardDeadLetter(ctx context.Context, id string) error {
+	if !uc.claim(id) {
+		return domain.ErrDeadLetterInProgress
+	}
+	defer uc.release(id)
+
+	if _, err := uc.GetDeadLetter(ctx, id); err != nil {
+		return err
+	}
+	if err := uc.letters.Delete(ctx, id); err != nil {
+		return fmt.Errorf("failed to delete dead letter: %w", err)
+	}
+	log.Ctx(ctx).Info().Str("message_id", id).Msg("dead letter discarded")
+	reportDeadLetters(ctx, uc.letters, uc.metrics)
+	return nil
+}
+
+// RetryDue retries the dead letters whose next attempt is due, and returns how many were retried and failed again.
+// It also removes the expired dead letters older than the retention.
+func (uc *DeadLetterUseCase) RetryDue(ctx context.Context) (retried, failed int, err error) {
+	letters, err := uc.ListDeadLetters(ctx)
+	if err != nil {
+		return 0, 0, err
+	}
+	now := uc.now()
+	for _, listed := range letters {
+		if ctx.Err() != nil {
+			break
+		}
+		// A letter an operator is retrying or discarding is left to them
+		if !listed.Due(now) || !uc.claim(listed.ID) {
+			continue
+		}
+		tried, err := uc.retryDue(ctx, listed.ID, now)
+		uc.release(listed.ID)
+		if tried {
+			retried++
+		}
+		if err != nil {
+			failed++
+		}
+	}
+	if err := uc.purge(ctx, now); err != nil {
+		return retried, failed, err
+	}
+	reportDeadLetters(ctx, uc.letters, uc.metrics)
+	return retried, failed, nil
+}
+
+// retryDue retries the dead letter if it is still due, an operator may have retried or discarded it since it was
+// listed, and reports whether it was retried
+func (uc *DeadLetterUseCase) retryDue(ctx context.Context, id string, now time.Time) (bool, error) {
+	letter, err := uc.letters.Get(ctx, id)
+	if err != nil {
+		return false, fmt.Errorf("failed to get dead letter: %w", err)
+	}
+	if letter == nil || !letter.Due(now) {
+		return false, nil
+	}
+	return true, uc.retry(ctx, letter)
+}
+
+// purge removes the expired dead letters that failed for the last time before the retention
+func (uc *DeadLetterUseCase) purge(ctx context.Context, now time.Time) error {
+	letters, err := uc.ListDeadLetters(ctx)
+	if err != nil {
+		return err
+	}
+	cutoff := now.Add(-deadLetterRetention)
+	for _, letter := range letters {
+		if letter.Status != domain.DeadLetterStatusExpired || !letter.LastFailedAt.Before(cutoff) || !uc.claim(letter.ID) {
+			continue
+		}
+		err := uc.letters.Delete(ctx, letter.ID)
+		uc.release(letter.ID)
+		if err != nil {
+			return fmt.Errorf("failed to delete expired dead letter: %w", err)
+		}
+		log.Ctx(ctx).Info().Str("message_id", letter.ID).Int("attempts", letter.Attempts).Msg("expired dead letter removed")
+	}
+	return nil
+}
+
+// claim marks the dead letter as being retried or discarded, it reports false if it already is
+func (uc *DeadLetterUseCase) claim(id string) bool {
+	uc.mu.Lock()
+	defer uc.mu.Unlock()
+	if uc.inFlight[id] {
+		return false
+	}
+	uc.inFlight[id] = true
+	return true
+}
+
+// release marks the dead letter as no longer being retried or discarded
+func (uc *DeadLetterUseCase) release(id string) {
+	uc.mu.Lock()
+	defer uc.mu.Unlock()
+	delete(uc.inFlight, id)
+}
+
+// retry answers the message of the dead letter, removing it when it succeeds and recording the failure otherwise
+func (uc *DeadLetterUseCase) retry(ctx context.Context, letter *domain.DeadLetter) error {
+	answerErr := uc.whatsApp.ReprocessMessage(ctx, letter.PhoneNumberID, letter.Message)
+	if answerErr == nil {
+		if err := uc.letters.Delete(ctx, letter.ID); err != nil {
+			return fmt.Errorf("message answered but the dead letter was not removed: %w", err)
+		}
+		log.Ctx(ctx).Info().Str("message_id", letter.ID).Int("attempts", letter.Attempts+1).Msg("dead letter answered")
+		return nil
+	}
+
+	recordFailure(letter, answerErr, uc.now(), uc.retrySchedule)
+	if err := uc.letters.Save(ctx, letter); err != nil {
+		log.Ctx(ctx).Error().Err(err).Str("message_id", letter.ID).Msg("failed to update dead letter")
+	}
+	event := log.Ctx(ctx).Warn()
+	if letter.Status == domain.DeadLetterStatusExpired {
+		event = log.Ctx(ctx).Error()
+	}
+	event.Err(answerErr).
+		Str("message_id", letter.ID).
+		Int("attempts", letter.Attempts).
+		Time("next_attempt_at", letter.NextAttemptAt).
+		Msg("failed to answer dead letter")
+	return fmt.Errorf("failed to answer dead letter: %w", answerErr)
+}
+
+// deadLetter keeps a message that failed to be answered for a temporary reason, to retry it later. The ones the
+// WhatsApp API refused for good, such as an invalid recipient or a closed 24-hour window, would fail again.
+func (uc *WhatsAppUseCase) deadLetter(ctx context.Context, phoneNumberID string, msg domain.WebhookMessage, cause error) {
+	if uc.deadLetters == nil {
+		return
+	}
+	if !domain.TemporarySendFailure(cause) {
+		log.Ctx(ctx).Warn().Err(cause).Str("message_id", msg.ID).Msg("message dropped, the failure is permanent")
+		return
+	}
+	letter := &domain.DeadLetter{ID: msg.ID, PhoneNumberID: phoneNumberID, Message: msg}
+	recordFailure(letter, cause, time.Now(), uc.retrySchedule)
+	if err := uc.deadLetters.Save(ctx, letter); err != nil {
+		log.Ctx(ctx).Error().Err(err).Str("message_id", msg.ID).Msg("failed to store dead letter, the message is lost")
+		return
+	}
+	log.Ctx(ctx).Warn().
+		Str("message_id", msg.ID).
+		Time("next_attempt_at", letter.NextAttemptAt).
+		Msg("message sent to the dead-letter queue")
+	reportDeadLetters(ctx, uc.deadLetters, uc.recorder())
+}
+
+// reportDeadLetters records the number of dead letters waiting to be retried, the expired ones are left out
+func reportDeadLetters(ctx context.Context, letters domain.DeadLetterRepository, metrics domain.Metrics) {
+	all, err := letters.List(ctx)
+	if err != nil {
+		log.Ctx(ctx).Warn().Err(err).Msg("failed to count the dead letters")
+		return
+	}
+	pending := 0
+	for _, letter := range all {
+		if letter.Status != domain.DeadLetterStatusExpired {
+			pending++
+		}
+	}
+	metrics.SetQueueDepth(domain.QueueDeadLetters, pending)
+}
+
+// recordFailure records a failed attempt at answering the dead letter and schedules the next one. The letter
+// expires once every delay of the schedule was waited, or at once when the failure is permanent.
+func recordFailure(letter *domain.DeadLetter, err error, now time.Time, retrySchedule []time.Duration) {
+	letter.Attempts++
+	letter.Error = err.Error()
+	letter.LastFailedAt = now
+	if letter.FirstFailedAt.IsZero() {
+		letter.FirstFailedAt = now
+	}
+	letter.NextAttemptAt = time.Time{}
+	if domain.TemporarySendFailure(err) {
+		letter.NextAttemptAt = nextAttempt(letter.Attempts, now, retrySchedule)
+	}
+	letter.Status = domain.DeadLetterStatusPending
+	if letter.NextAttemptAt.IsZero() {
+		letter.Status = domain.DeadLetterStatusExpired
+	}
+}
+
+// RunDeadLetterRetries retries the dead letters that are due every interval, until ctx is done
+func RunDeadLetterRetries(ctx context.Context, deadLetters *DeadLetterUseCase, interval time.Duration) {
+	ticker := time.NewTicker(interval)
+	defer ticker.Stop()
+	for {
+		select {
+		case <-ctx.Done():
+			return
+		case <-ticker.C:
+		}
+		retried, failed, err := deadLetters.RetryDue(ctx)
+		switch {
+		case err != nil:
+			log.Error().Err(err).Msg("Failed to retry the dead letters")
+		case retried > 0:
+			log.Info().Int("retried", retried).Int("failed", failed).Msg("Dead letters retried")
+		}
+	}
+}
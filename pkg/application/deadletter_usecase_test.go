@@ -0,0 +1,301 @@
+package application
+
+import (
+	"anyzzapp/pkg/domain"
+	"context"
+	"errors"
+	"maps"
+	"slices"
+	"sort"
+	"testing"
+	"time"
+
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/mock"
+	"github.com/stretchr/testify/require"
+)
+
+// deadLetterList keeps the dead letters in a map
+type deadLetterList map[string]domain.DeadLetter
+
+func (l deadLetterList) Save(ctx context.Context, letter *domain.DeadLetter) error {
+	l[letter.ID] = *letter
+	return nil
+}
+
+func (l deadLetterList) Get(ctx context.Context, id string) (*domain.DeadLetter, error) {
+	if letter, ok := l[id]; ok {
+		return &letter, nil
+	}
+	return nil, nil
+}
+
+func (l deadLetterList) List(ctx context.Context) ([]domain.DeadLetter, error) {
+	var letters []domain.DeadLetter
+	for _, letter := range l {
+		letters = append(letters, letter)
+	}
+	sort.Slice(letters, func(i, j int) bool { return letters[i].ID < letters[j].ID })
+	return letters, nil
+}
+
+func (l deadLetterList) Delete(ctx context.Context, id string) error {
+	delete(l, id)
+	return nil
+}
+
+// MockWhatsAppUseCase is a mock implementation of WhatsAppUseCaseInterface
+type MockWhatsAppUseCase struct {
+	mock.Mock
+}
+
+func (m *MockWhatsAppUseCase) SendMessage(ctx context.Context, message domain.Message) (*domain.SendMessageResponse, error) {
+	args := m.Called(message)
+	return args.Get(0).(*domain.SendMessageResponse), args.Error(1)
+}
+
+func (m *MockWhatsAppUseCase) ProcessIncomingWebhook(ctx context.Context, webhook *domain.WebhookRequest) error {
+	args := m.Called(webhook)
+	return args.Error(0)
+}
+
+func (m *MockWhatsAppUseCase) ReprocessMessage(ctx context.Context, phoneNumberID string, msg domain.WebhookMessage) error {
+	args := m.Called(phoneNumberID, msg)
+	return args.Error(0)
+}
+
+var testRetrySchedule = []time.Duration{time.Minute, time.Hour}
+
+func TestWhatsAppUseCase_ProcessIncomingWebhook_DeadLetters(t *testing.T) {
+	mockWhatsAppRepo := &MockWhatsAppRepository{}
+	mockLLMRepo := &MockLLMRepository{}
+	letters := deadLetterList{}
+	useCase := NewWhatsAppUseCase(mockWhatsAppRepo, mockLLMRepo, WithDeadLetters(letters, testRetrySchedule))
+
+	mockWhatsAppRepo.On("MarkAsRead", "123456789", "msg_123").Return(nil)
+	mockLLMRepo.On("SendMessage", "Hello").Return("", errors.New("LLM down"))
+
+	before := time.Now()
+	err := useCase.ProcessIncomingWebhook(context.Background(), commandWebhook("Hello"))
+	assert.Error(t, err)
+
+	letter, ok := letters["msg_123"]
+	require.True(t, ok)
+	assert.Equal(t, "123456789", letter.PhoneNumberID)
+	assert.Equal(t, "Hello", letter.Message.Text.Body)
+	assert.Equal(t, "llm: LLM down", letter.Error)
+	assert.Equal(t, 1, letter.Attempts)
+	assert.Equal(t, letter.FirstFailedAt, letter.LastFailedAt)
+	assert.False(t, letter.FirstFailedAt.Before(before))
+	assert.Equal(t, letter.LastFailedAt.Add(time.Minute), letter.NextAttemptAt)
+}
+
+func TestWhatsAppUseCase_ProcessIncomingWebhook_PermanentFailureNotDeadLettered(t *testing.T) {
+	mockWhatsAppRepo := &MockWhatsAppRepository{}
+	mockLLMRepo := &MockLLMRepository{}
+	letters := deadLetterList{}
+	useCase := NewWhatsAppUseCase(mockWhatsAppRepo, mockLLMRepo, WithDeadLetters(letters, testRetrySchedule))
+
+	mockWhatsAppRepo.On("MarkAsRead", "123456789", "msg_123").Return(nil)
+	mockLLMRepo.On("SendMessage", "Hello").Return("Hi!", nil)
+	mockWhatsAppRepo.On("SendMessage", mock.Anything).Return((*domain.SendMessageResponse)(nil), invalidUser)
+
+	err := useCase.ProcessIncomingWebhook(context.Background(), commandWebhook("Hello"))
+
+	// The webhook is acknowledged, but the reply would be refused again
+	assert.ErrorIs(t, err, invalidUser)
+	assert.True(t, domain.IsDownstream(err))
+	assert.Empty(t, letters)
+}
+
+func TestWhatsAppUseCase_ProcessIncomingWebhook_LocalFailureNotDeadLettered(t *testing.T) {
+	mockWhatsAppRepo := &MockWhatsAppRepository{}
+	letters := deadLetterList{}
+	useCase := NewWhatsAppUseCase(mockWhatsAppRepo, &MockLLMRepository{},
+		WithOptOut(failingSuppressions{suppressionList{}}, testOptOut),
+		WithDeadLetters(letters, testRetrySchedule))
+
+	mockWhatsAppRepo.On("MarkAsRead", "123456789", "msg_123").Return(nil)
+
+	err := useCase.ProcessIncomingWebhook(context.Background(), commandWebhook("Hello"))
+
+	// Meta redelivers the webhooks failing for other reasons
+	assert.Error(t, err)
+	assert.Empty(t, letters)
+}
+
+func TestWhatsAppUseCase_ReprocessMessage(t *testing.T) {
+	mockWhatsAppRepo := &MockWhatsAppRepository{}
+	mockLLMRepo := &MockLLMRepository{}
+	conversations := &MockConversationRepository{}
+	useCase := NewWhatsAppUseCase(mockWhatsAppRepo, mockLLMRepo, WithConversations(conversations))
+	key := domain.ConversationKey{PhoneNumberID: "123456789", WaID: "5491112345678"}
+	msg := commandWebhook("Hello").Entry[0].Changes[0].Value.Messages[0]
+
+	conversations.On("Get", key).Return(&domain.Conversation{Key: key, State: domain.ConversationStateBot, Language: "Spanish"}, nil)
+	conversations.On("Save", mock.Anything).Return(nil)
+	mockLLMRepo.On("SendMessage", "Reply in Spanish.\n\nHello").Return("Hola", nil)
+	mockWhatsAppRepo.On("SendMessage", mock.Anything).Return(&domain.SendMessageResponse{MessageID: "reply_1"}, nil)
+
+	err := useCase.ReprocessMessage(context.Background(), "123456789", msg)
+
+	assert.NoError(t, err)
+	// The message was already marked as read and stored the first time
+	mockWhatsAppRepo.AssertNotCalled(t, "MarkAsRead", mock.Anything, mock.Anything)
+	saved := conversations.Calls[len(conversations.Calls)-1].Arguments.Get(0).(*domain.Conversation)
+	require.Len(t, saved.Messages, 1)
+	assert.Equal(t, "outbound", saved.Messages[0].Direction)
+}
+
+func TestDeadLetterUseCase_RetryDue(t *testing.T) {
+	mockWhatsAppUseCase := &MockWhatsAppUseCase{}
+	now := time.Date(2024, 1, 1, 10, 0, 0, 0, time.UTC)
+	letters := deadLetterList{
+		"due":       {ID: "due", PhoneNumberID: "123", Attempts: 1, NextAttemptAt: now},
+		"failing":   {ID: "failing", PhoneNumberID: "123", Attempts: 1, NextAttemptAt: now.Add(-time.Second)},
+		"exhausted": {ID: "exhausted", PhoneNumberID: "123", Attempts: 2, NextAttemptAt: now.Add(-time.Second)},
+		"later":     {ID: "later", PhoneNumberID: "123", Attempts: 1, NextAttemptAt: now.Add(time.Second)},
+		"manual":    {ID: "manual", PhoneNumberID: "123", Attempts: 3},
+	}
+	useCase := NewDeadLetterUseCase(letters, mockWhatsAppUseCase, testRetrySchedule)
+	useCase.now = func() time.Time { return now }
+
+	mockWhatsAppUseCase.On("ReprocessMessage", "123", domain.WebhookMessage{}).Return(nil).Once()
+	mockWhatsAppUseCase.On("ReprocessMessage", "123", domain.WebhookMessage{}).
+		Return(&domain.DownstreamError{Service: "whatsapp", Err: errors.New("API down")}).Twice()
+
+	retried, failed, err := useCase.RetryDue(context.Background())
+
+	assert.NoError(t, err)
+	assert.Equal(t, 3, retried)
+	assert.Equal(t, 2, failed)
+	// The letters are retried in the order they are listed
+	assert.NotContains(t, letters, "due")
+	assert.Equal(t, 2, letters["failing"].Attempts)
+	assert.Equal(t, "whatsapp: API down", letters["failing"].Error)
+	assert.Equal(t, now, letters["failing"].LastFailedAt)
+	assert.Equal(t, now.Add(time.Hour), letters["failing"].NextAttemptAt)
+	assert.Equal(t, domain.DeadLetterStatusPending, letters["failing"].Status)
+	// Past the schedule the letter expires and waits for an operator
+	assert.Equal(t, 3, letters["exhausted"].Attempts)
+	assert.True(t, letters["exhausted"].NextAttemptAt.IsZero())
+	assert.Equal(t, domain.DeadLetterStatusExpired, letters["exhausted"].Status)
+	assert.Equal(t, 1, letters["later"].Attempts)
+	assert.Equal(t, 3, letters["manual"].Attempts)
+	mockWhatsAppUseCase.AssertExpectations(t)
+}
+
+func TestDeadLetterUseCase_RetryDue_PermanentFailure(t *testing.T) {
+	mockWhatsAppUseCase := &MockWhatsAppUseCase{}
+	now := time.Date(2024, 1, 1, 10, 0, 0, 0, time.UTC)
+	letters := deadLetterList{"due": {ID: "due", PhoneNumberID: "123", Attempts: 1, NextAttemptAt: now}}
+	useCase := NewDeadLetterUseCase(letters, mockWhatsAppUseCase, testRetrySchedule)
+	useCase.now = func() time.Time { return now }
+
+	mockWhatsAppUseCase.On("ReprocessMessage", "123", domain.WebhookMessage{}).
+		Return(&domain.DownstreamError{Service: "whatsapp", Err: invalidUser})
+
+	_, failed, err := useCase.RetryDue(context.Background())
+
+	assert.NoError(t, err)
+	assert.Equal(t, 1, failed)
+	// The reply would be refused again, the letter is not scheduled
+	assert.Equal(t, domain.DeadLetterStatusExpired, letters["due"].Status)
+	assert.True(t, letters["due"].NextAttemptAt.IsZero())
+}
+
+func TestDeadLetterUseCase_RetryDue_PurgesExpired(t *testing.T) {
+	now := time.Date(2024, 1, 10, 10, 0, 0, 0, time.UTC)
+	letters := deadLetterList{
+		"stale":  {ID: "stale", Status: domain.DeadLetterStatusExpired, LastFailedAt: now.Add(-deadLetterRetention - time.Second)},
+		"recent": {ID: "recent", Status: domain.DeadLetterStatusExpired, LastFailedAt: now.Add(-time.Hour)},
+		"old":    {ID: "old", Status: domain.DeadLetterStatusPending, LastFailedAt: now.Add(-deadLetterRetention - time.Second), NextAttemptAt: now.Add(time.Hour)},
+	}
+	metrics := newCountingMetrics()
+	useCase := NewDeadLetterUseCase(letters, &MockWhatsAppUseCase{}, testRetrySchedule, WithDeadLetterMetrics(metrics))
+	useCase.now = func() time.Time { return now }
+
+	_, _, err := useCase.RetryDue(context.Background())
+
+	assert.NoError(t, err)
+	assert.Equal(t, []string{"old", "recent"}, slices.Sorted(maps.Keys(letters)))
+	// The expired letters are not waiting to be retried
+	assert.Equal(t, []int{1}, metrics.queueDepths(domain.QueueDeadLetters))
+}
+
+func TestDeadLetterUseCase_Claims(t *testing.T) {
+	mockWhatsAppUseCase := &MockWhatsAppUseCase{}
+	now := time.Date(2024, 1, 1, 10, 0, 0, 0, time.UTC)
+	letters := deadLetterList{
+		"busy": {ID: "busy", PhoneNumberID: "123", Attempts: 1, NextAttemptAt: now},
+		"due":  {ID: "due", PhoneNumberID: "123", Attempts: 1, NextAttemptAt: now},
+	}
+	useCase := NewDeadLetterUseCase(letters, mockWhatsAppUseCase, testRetrySchedule)
+	useCase.now = func() time.Time { return now }
+	ctx := context.Background()
+
+	// An operator is retrying busy: the job retries the other letters and the operator can't act on it twice
+	require.True(t, useCase.claim("busy"))
+	mockWhatsAppUseCase.On("ReprocessMessage", "123", domain.WebhookMessage{}).Return(nil).Once()
+
+	retried, _, err := useCase.RetryDue(ctx)
+
+	assert.NoError(t, err)
+	assert.Equal(t, 1, retried)
+	assert.Contains(t, letters, "busy")
+	assert.NotContains(t, letters, "due")
+	assert.ErrorIs(t, useCase.RetryDeadLetter(ctx, "busy"), domain.ErrDeadLetterInProgress)
+	assert.ErrorIs(t, useCase.DiscardDeadLetter(ctx, "busy"), domain.ErrDeadLetterInProgress)
+	useCase.release("busy")
+	assert.NoError(t, useCase.DiscardDeadLetter(ctx, "busy"))
+	mockWhatsAppUseCase.AssertExpectations(t)
+}
+
+func TestDeadLetterUseCase_QueueDepth(t *testing.T) {
+	mockWhatsAppRepo := &MockWhatsAppRepository{}
+	mockLLMRepo := &MockLLMRepository{}
+	metrics := newCountingMetrics()
+	letters := deadLetterList{}
+	whatsApp := NewWhatsAppUseCase(mockWhatsAppRepo, mockLLMRepo, WithMetrics(metrics), WithDeadLetters(letters, testRetrySchedule))
+	useCase := NewDeadLetterUseCase(letters, whatsApp, testRetrySchedule, WithDeadLetterMetrics(metrics))
+	ctx := context.Background()
+
+	mockWhatsAppRepo.On("MarkAsRead", "123456789", "msg_123").Return(nil)
+	mockLLMRepo.On("SendMessage", "Hello").Return("", errors.New("LLM down")).Once()
+	mockLLMRepo.On("SendMessage", "Hello").Return("Hi!", nil).Once()
+	mockWhatsAppRepo.On("SendMessage", mock.Anything).Return(&domain.SendMessageResponse{MessageID: "reply_1"}, nil)
+
+	assert.Error(t, whatsApp.ProcessIncomingWebhook(ctx, commandWebhook("Hello")))
+	assert.Equal(t, []int{1}, metrics.queueDepths(domain.QueueDeadLetters))
+
+	assert.NoError(t, useCase.RetryDeadLetter(ctx, "msg_123"))
+	assert.Equal(t, []int{1, 0}, metrics.queueDepths(domain.QueueDeadLetters))
+}
+
+func TestDeadLetterUseCase_RetryAndDiscard(t *testing.T) {
+	mockWhatsAppUseCase := &MockWhatsAppUseCase{}
+	msg := domain.WebhookMessage{ID: "msg_1", From: "456", Type: "text", Text: &domain.WebhookText{Body: "Hello"}}
+	letters := deadLetterList{
+		"msg_1": {ID: "msg_1", PhoneNumberID: "123", Message: msg, Attempts: 3},
+		"msg_2": {ID: "msg_2", PhoneNumberID: "123", Attempts: 1},
+	}
+	useCase := NewDeadLetterUseCase(letters, mockWhatsAppUseCase, testRetrySchedule)
+	ctx := context.Background()
+
+	mockWhatsAppUseCase.On("ReprocessMessage", "123", msg).Return(errors.New("LLM down")).Once()
+	mockWhatsAppUseCase.On("ReprocessMessage", "123", msg).Return(nil).Once()
+
+	// Operators retry a letter whatever its schedule
+	assert.ErrorContains(t, useCase.RetryDeadLetter(ctx, "msg_1"), "LLM down")
+	assert.Equal(t, 4, letters["msg_1"].Attempts)
+	assert.NoError(t, useCase.RetryDeadLetter(ctx, "msg_1"))
+	assert.NotContains(t, letters, "msg_1")
+	assert.ErrorIs(t, useCase.RetryDeadLetter(ctx, "msg_1"), domain.ErrDeadLetterNotFound)
+
+	assert.NoError(t, useCase.DiscardDeadLetter(ctx, "msg_2"))
+	assert.Empty(t, letters)
+	assert.ErrorIs(t, useCase.DiscardDeadLetter(ctx, "msg_2"), domain.ErrDeadLetterNotFound)
+	_, err := useCase.GetDeadLetter(ctx, "msg_2")
+	assert.ErrorIs(t, err, domain.ErrDeadLetterNotFound)
+	mockWhatsAppUseCase.AssertExpectations(t)
+}
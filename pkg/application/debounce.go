@@ -0,0 +1,236 @@
+package application
+
+import (
+	"anyzzapp/pkg/domain"
+	"context"
+	"strings"
+	"sync"
+	"time"
+
+	"github.com/rs/zerolog/log"
+	"go.opentelemetry.io/otel/attribute"
+	"go.opentelemetry.io/otel/codes"
+	"go.opentelemetry.io/otel/trace"
+)
+
+// Debouncer holds the text messages a contact sends within the window of each other, so that they are answered
+// at once with a single prompt. The messages are held in memory only: the ones held when the process crashes are
+// not answered, Meta does not redeliver them since their webhook was acknowledged.
+type Debouncer struct {
+	window  time.Duration
+	maxWait time.Duration
+	// flush answers the messages of a burst, it is set by WithDebouncer
+	flush func(ctx context.Context, key domain.ConversationKey, messages []domain.WebhookMessage)
+
+	mu     sync.Mutex
+	bursts map[domain.ConversationKey]*burst
+	// flushing counts the bursts being answered
+	flushing sync.WaitGroup
+}
+
+// burst is the messages of a conversation waiting to be answered
+type burst struct {
+	// ctx is the context of the request of the first message, without its cancellation
+	ctx      context.Context
+	messages []domain.WebhookMessage
+	started  time.Time
+	// deadline is when the messages are answered unless another one arrives before
+	deadline time.Time
+	timer    *time.Timer
+}
+
+// NewDebouncer creates a new instance of Debouncer. The messages of a conversation are answered once the window
+// passes without a new one, and at the latest maxWait after the first one.
+func NewDebouncer(window, maxWait time.Duration) *Debouncer {
+	return &Debouncer{
+		window:  window,
+		maxWait: maxWait,
+		bursts:  make(map[domain.ConversationKey]*burst),
+	}
+}
+
+// add holds the message with the other messages of the conversation, and postpones their answer. They are answered
+// with the values of ctx, its logger and trace, once the request it belongs to is over.
+func (d *Debouncer) add(ctx context.Context, key domain.ConversationKey, msg domain.WebhookMessage) {
+	d.mu.Lock()
+	defer d.mu.Unlock()
+
+	now := time.Now()
+	b, ok := d.bursts[key]
+	if !ok {
+		b = &burst{ctx: context.WithoutCancel(ctx), started: now}
+		d.bursts[key] = b
+	}
+	b.messages = append(b.messages, msg)
+	b.deadline = now.Add(d.window)
+	if latest := b.started.Add(d.maxWait); b.deadline.After(latest) {
+		b.deadline = latest
+	}
+	if b.timer == nil {
+		b.timer = time.AfterFunc(b.deadline.Sub(now), func() { d.expire(key, b) })
+		return
+	}
+	b.timer.Reset(b.deadline.Sub(now))
+}
+
+// take removes the messages of the conversation waiting to be answered, to answer them now
+func (d *Debouncer) take(key domain.ConversationKey) []domain.WebhookMessage {
+	d.mu.Lock()
+	defer d.mu.Unlock()
+
+	b, ok := d.bursts[key]
+	if !ok {
+		return nil
+	}
+	b.timer.Stop()
+	delete(d.bursts, key)
+	return b.messages
+}
+
+// discard removes the messages held of the conversations matching, which are not answered, and returns how many
+// they were. A dry run only counts them.
+func (d *Debouncer) discard(match func(key domain.ConversationKey) bool, dryRun bool) int {
+	d.mu.Lock()
+	defer d.mu.Unlock()
+
+	discarded := 0
+	for key, b := range d.bursts {
+		if !match(key) {
+			continue
+		}
+		discarded += len(b.messages)
+		if !dryRun {
+			b.timer.Stop()
+			delete(d.bursts, key)
+		}
+	}
+	return discarded
+}
+
+// expire answers the messages of the burst once its deadline passed
+func (d *Debouncer) expire(key domain.ConversationKey, b *burst) {
+	d.mu.Lock()
+	// Taken, or postponed by a message added while the timer fired
+	if d.bursts[key] != b {
+		d.mu.Unlock()
+		return
+	}
+	if wait := time.Until(b.deadline); wait > 0 {
+		b.timer.Reset(wait)
+		d.mu.Unlock()
+		return
+	}
+	delete(d.bursts, key)
+	d.flushing.Add(1)
+	d.mu.Unlock()
+
+	defer d.flushing.Done()
+	if d.flush != nil {
+		d.flush(b.ctx, key, b.messages)
+	}
+}
+
+// Flush answers the messages waiting now and waits for the ones being answered, so that none is lost on shutdown.
+// The bursts not answered yet when ctx is done are given up on and logged.
+func (d *Debouncer) Flush(ctx context.Context) error {
+	d.mu.Lock()
+	bursts := d.bursts
+	d.bursts = make(map[domain.ConversationKey]*burst)
+	for _, b := range bursts {
+		b.timer.Stop()
+	}
+	d.mu.Unlock()
+
+	for key, b := range bursts {
+		if ctx.Err() != nil {
+			for _, msg := range b.messages {
+				log.Ctx(b.ctx).Error().Str("message_id", msg.ID).Msg("message not answered before the shutdown timeout")
+			}
+			continue
+		}
+		if d.flush != nil {
+			d.flush(b.ctx, key, b.messages)
+		}
+	}
+	if err := ctx.Err(); err != nil {
+		return err
+	}
+	done := make(chan struct{})
+	go func() {
+		d.flushing.Wait()
+		close(done)
+	}()
+	select {
+	case <-done:
+		return nil
+	case <-ctx.Done():
+		return ctx.Err()
+	}
+}
+
+// debounces reports whether the message waits for the next ones of the contact: text messages other than
+// commands and opt-out keywords, which are answered at once
+func (uc *WhatsAppUseCase) debounces(msg domain.WebhookMessage, content string) bool {
+	if uc.debouncer == nil || msg.Type != "text" || content == "" {
+		return false
+	}
+	if action, _ := domain.MatchOptOut(uc.optOut, content); action != domain.OptOutNone {
+		return false
+	}
+	return uc.commands == nil || !uc.commands.IsCommand(content)
+}
+
+// flushExpired answers a burst whose window passed, with the other messages of the conversation
+func (uc *WhatsAppUseCase) flushExpired(ctx context.Context, key domain.ConversationKey, messages []domain.WebhookMessage) {
+	last := messages[len(messages)-1]
+	ctx, span := tracer.Start(ctx, "flushMessages", trace.WithAttributes(
+		attribute.String("messaging.message.id", last.ID),
+		attribute.String("anyzzapp.tenant", key.PhoneNumberID),
+		attribute.Int("messaging.batch.message_count", len(messages)),
+	))
+	defer span.End()
+
+	err := uc.inOrder(ctx, key, messageTime(last), func(ctx context.Context) error {
+		return uc.answerBurst(ctx, key, messages)
+	})
+	if err != nil {
+		span.RecordError(err)
+		span.SetStatus(codes.Error, err.Error())
+		log.Ctx(ctx).Error().Err(err).Str("message_id", last.ID).Int("messages", len(messages)).Msg("failed to answer messages")
+	}
+}
+
+// answerBurst marks the messages as read and answers them with a single prompt. A failure of the LLM or the
+// WhatsApp API sends the merged message to the dead-letter queue.
+func (uc *WhatsAppUseCase) answerBurst(ctx context.Context, key domain.ConversationKey, messages []domain.WebhookMessage) error {
+	if len(messages) == 0 {
+		return nil
+	}
+	texts := make([]string, 0, len(messages))
+	for _, msg := range messages {
+		if err := uc.whatsappRepo.MarkAsRead(ctx, key.PhoneNumberID, msg.ID); err != nil {
+			log.Ctx(ctx).Warn().Err(err).Str("message_id", msg.ID).Msg("failed to mark message as read")
+		}
+		texts = append(texts, msg.Text.Body)
+	}
+	// The reply is the one to the last message, which stands for the burst
+	merged := messages[len(messages)-1]
+	merged.Text = &domain.WebhookText{Body: strings.Join(texts, "\n")}
+
+	conversation := &domain.Conversation{Key: key, State: domain.ConversationStateBot}
+	if uc.conversations != nil {
+		var err error
+		if conversation, err = loadConversation(uc.conversations, key); err != nil {
+			return err
+		}
+	}
+	err := uc.answer(ctx, key, merged, merged.Text.Body, conversation, uc.tenantSettings(key.PhoneNumberID))
+	if err != nil {
+		if domain.IsDownstream(err) {
+			uc.deadLetter(ctx, key.PhoneNumberID, merged, err)
+		}
+		return err
+	}
+	log.Ctx(ctx).Debug().Int("messages", len(messages)).Str("message_id", merged.ID).Msg("messages answered at once")
+	return nil
+}
@@ -0,0 +1,155 @@
+package application
+
+import (
+	"anyzzapp/pkg/domain"
+	"anyzzapp/pkg/logging"
+	"context"
+	"fmt"
+	"sync"
+	"testing"
+	"time"
+
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/mock"
+	"github.com/stretchr/testify/require"
+)
+
+// burstWebhook is a text message of the contact with the ID
+func burstWebhook(id, body string) *domain.WebhookRequest {
+	webhook := commandWebhook(body)
+	webhook.Entry[0].Changes[0].Value.Messages[0].ID = id
+	return webhook
+}
+
+func TestWhatsAppUseCase_Debounce(t *testing.T) {
+	mockWhatsAppRepo := &MockWhatsAppRepository{}
+	mockLLMRepo := &MockLLMRepository{}
+	useCase := NewWhatsAppUseCase(mockWhatsAppRepo, mockLLMRepo, WithDebouncer(NewDebouncer(50*time.Millisecond, time.Minute)))
+	ctx := context.Background()
+
+	mockWhatsAppRepo.On("MarkAsRead", "123456789", mock.Anything).Return(nil)
+	mockLLMRepo.On("SendMessage", "I have a question\nabout my order\n#1234").Return("It shipped yesterday.", nil)
+	replied := make(chan string, 3)
+	mockWhatsAppRepo.On("SendMessage", mock.Anything).Run(func(args mock.Arguments) {
+		replied <- args.Get(0).(domain.Message).Content
+	}).Return(&domain.SendMessageResponse{MessageID: "reply_1"}, nil)
+
+	for i, text := range []string{"I have a question", "about my order", "#1234"} {
+		require.NoError(t, useCase.ProcessIncomingWebhook(ctx, burstWebhook(fmt.Sprintf("msg_%d", i+1), text)))
+	}
+	// Nothing is answered or marked as read within the window
+	mockWhatsAppRepo.AssertNotCalled(t, "MarkAsRead", mock.Anything, mock.Anything)
+
+	select {
+	case reply := <-replied:
+		assert.Equal(t, "It shipped yesterday.", reply)
+	case <-time.After(time.Second):
+		t.Fatal("the messages were not answered")
+	}
+	mockLLMRepo.AssertNumberOfCalls(t, "SendMessage", 1)
+	mockWhatsAppRepo.AssertNumberOfCalls(t, "SendMessage", 1)
+	for _, id := range []string{"msg_1", "msg_2", "msg_3"} {
+		mockWhatsAppRepo.AssertCalled(t, "MarkAsRead", "123456789", id)
+	}
+}
+
+func TestWhatsAppUseCase_Debounce_AnswersBeforeOptOut(t *testing.T) {
+	mockWhatsAppRepo := &MockWhatsAppRepository{}
+	mockLLMRepo := &MockLLMRepository{}
+	useCase := NewWhatsAppUseCase(mockWhatsAppRepo, mockLLMRepo,
+		WithOptOut(suppressionList{}, testOptOut),
+		WithDebouncer(NewDebouncer(time.Hour, time.Hour)))
+	ctx := context.Background()
+
+	mockWhatsAppRepo.On("MarkAsRead", "123456789", mock.Anything).Return(nil)
+	mockLLMRepo.On("SendMessage", "Hello").Return("Hi!", nil)
+	var mu sync.Mutex
+	var sent []string
+	mockWhatsAppRepo.On("SendMessage", mock.Anything).Run(func(args mock.Arguments) {
+		mu.Lock()
+		defer mu.Unlock()
+		sent = append(sent, args.Get(0).(domain.Message).Content)
+	}).Return(&domain.SendMessageResponse{MessageID: "reply_1"}, nil)
+
+	require.NoError(t, useCase.ProcessIncomingWebhook(ctx, burstWebhook("msg_1", "Hello")))
+	// Opt-out keywords are not merged, the messages held before them are answered first
+	require.NoError(t, useCase.ProcessIncomingWebhook(ctx, burstWebhook("msg_2", "STOP")))
+
+	assert.Equal(t, []string{"Hi!", "Unsubscribed."}, sent)
+}
+
+func TestDebouncer_MaxWait(t *testing.T) {
+	debouncer := NewDebouncer(time.Hour, 30*time.Millisecond)
+	flushed := make(chan []domain.WebhookMessage, 1)
+	debouncer.flush = func(ctx context.Context, key domain.ConversationKey, messages []domain.WebhookMessage) {
+		flushed <- messages
+	}
+
+	debouncer.add(context.Background(), alice, domain.WebhookMessage{ID: "msg_1"})
+	debouncer.add(context.Background(), alice, domain.WebhookMessage{ID: "msg_2"})
+
+	// The window would never pass, the maximum wait does
+	select {
+	case messages := <-flushed:
+		assert.Len(t, messages, 2)
+	case <-time.After(time.Second):
+		t.Fatal("the messages were not answered within the maximum wait")
+	}
+	assert.Nil(t, debouncer.take(alice))
+}
+
+func TestDebouncer_Flush(t *testing.T) {
+	debouncer := NewDebouncer(time.Hour, time.Hour)
+	flushed := map[domain.ConversationKey]int{}
+	debouncer.flush = func(ctx context.Context, key domain.ConversationKey, messages []domain.WebhookMessage) {
+		flushed[key] += len(messages)
+	}
+
+	debouncer.add(context.Background(), alice, domain.WebhookMessage{ID: "msg_1"})
+	debouncer.add(context.Background(), alice, domain.WebhookMessage{ID: "msg_2"})
+	debouncer.add(context.Background(), bob, domain.WebhookMessage{ID: "msg_3"})
+
+	// On shutdown the messages held are answered at once
+	assert.NoError(t, debouncer.Flush(context.Background()))
+	assert.Equal(t, map[domain.ConversationKey]int{alice: 2, bob: 1}, flushed)
+	assert.Nil(t, debouncer.take(alice))
+}
+
+func TestDebouncer_FirstMessageContext(t *testing.T) {
+	debouncer := NewDebouncer(10*time.Millisecond, time.Hour)
+	flushed := make(chan context.Context, 1)
+	debouncer.flush = func(ctx context.Context, key domain.ConversationKey, messages []domain.WebhookMessage) {
+		flushed <- ctx
+	}
+
+	// The request of the first message is over before the window passes
+	ctx, cancel := context.WithCancel(logging.WithRequestID(context.Background(), "req-1"))
+	debouncer.add(ctx, alice, domain.WebhookMessage{ID: "msg_1"})
+	debouncer.add(logging.WithRequestID(context.Background(), "req-2"), alice, domain.WebhookMessage{ID: "msg_2"})
+	cancel()
+
+	select {
+	case ctx := <-flushed:
+		assert.NoError(t, ctx.Err())
+		assert.Equal(t, "req-1", logging.RequestID(ctx))
+	case <-time.After(time.Second):
+		t.Fatal("the messages were not answered")
+	}
+}
+
+func TestDebouncer_Flush_Timeout(t *testing.T) {
+	debouncer := NewDebouncer(time.Hour, time.Hour)
+	ctx, cancel := context.WithCancel(context.Background())
+	flushed := 0
+	// The first burst takes the time left to shut down
+	debouncer.flush = func(context.Context, domain.ConversationKey, []domain.WebhookMessage) {
+		flushed++
+		cancel()
+	}
+
+	debouncer.add(context.Background(), alice, domain.WebhookMessage{ID: "msg_1"})
+	debouncer.add(context.Background(), bob, domain.WebhookMessage{ID: "msg_2"})
+
+	assert.ErrorIs(t, debouncer.Flush(ctx), context.Canceled)
+	assert.Equal(t, 1, flushed)
+}
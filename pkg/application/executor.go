@@ -0,0 +1,170 @@
+package application
+
+import (
+	"anyzzapp/pkg/domain"
+	"container/heap"
+	"context"
+	"fmt"
+	"sync"
+	"time"
+)
+
+// KeyedExecutor runs the tasks of a conversation one at a time, the earliest first, and the tasks of different
+// conversations concurrently. Each conversation with tasks waiting has its own worker, which stops once it has been
+// idle for the idle timeout.
+type KeyedExecutor struct {
+	idleTimeout time.Duration
+	// slots bounds the tasks waiting or running across every conversation
+	slots chan struct{}
+	// depth is told the number of tasks waiting or running when it changes, it is set by WithKeyedExecutor
+	depth func(pending int)
+
+	mu    sync.Mutex
+	lanes map[domain.ConversationKey]*lane
+	seq   uint64
+}
+
+// lane holds the tasks of a conversation waiting for its worker
+type lane struct {
+	tasks taskQueue
+	// wake tells the worker that a task was added
+	wake chan struct{}
+}
+
+// keyedTask is a task waiting for the worker of its conversation
+type keyedTask struct {
+	ctx context.Context
+	at  time.Time
+	// seq keeps the submission order of the tasks at the same time
+	seq  uint64
+	run  func(context.Context) error
+	done chan error
+}
+
+// NewKeyedExecutor creates a new instance of KeyedExecutor. At most maxPending tasks wait or run at a time,
+// Submit waits for one of them to finish past it.
+func NewKeyedExecutor(maxPending int, idleTimeout time.Duration) *KeyedExecutor {
+	return &KeyedExecutor{
+		idleTimeout: idleTimeout,
+		slots:       make(chan struct{}, maxPending),
+		lanes:       make(map[domain.ConversationKey]*lane),
+	}
+}
+
+// Submit queues run for the conversation and returns the channel its error is sent to once it ran. The tasks of a
+// conversation run in the order of at among the ones waiting, the ones submitted while a task runs included.
+// It fails when ctx is done before there is room for the task; a task whose ctx is done before it runs is skipped.
+func (e *KeyedExecutor) Submit(ctx context.Context, key domain.ConversationKey, at time.Time,
+	run func(context.Context) error) (<-chan error, error) {
+	select {
+	case e.slots <- struct{}{}:
+	case <-ctx.Done():
+		return nil, fmt.Errorf("too many messages waiting to be processed: %w", ctx.Err())
+	}
+	e.reportDepth()
+
+	done := make(chan error, 1)
+	e.mu.Lock()
+	defer e.mu.Unlock()
+	l, ok := e.lanes[key]
+	if !ok {
+		l = &lane{wake: make(chan struct{}, 1)}
+		e.lanes[key] = l
+		go e.work(key, l)
+	}
+	e.seq++
+	heap.Push(&l.tasks, &keyedTask{ctx: ctx, at: at, seq: e.seq, run: run, done: done})
+	select {
+	case l.wake <- struct{}{}:
+	default:
+	}
+	return done, nil
+}
+
+// Pending returns the number of tasks waiting or running
+func (e *KeyedExecutor) Pending() int {
+	return len(e.slots)
+}
+
+// reportDepth tells depth the number of tasks waiting or running
+func (e *KeyedExecutor) reportDepth() {
+	if e.depth != nil {
+		e.depth(e.Pending())
+	}
+}
+
+// Keys returns the number of conversations with a worker
+func (e *KeyedExecutor) Keys() int {
+	e.mu.Lock()
+	defer e.mu.Unlock()
+	return len(e.lanes)
+}
+
+// work runs the tasks of the conversation, and removes it once it has been idle for the idle timeout
+func (e *KeyedExecutor) work(key domain.ConversationKey, l *lane) {
+	idle := time.NewTimer(e.idleTimeout)
+	defer idle.Stop()
+	for {
+		e.mu.Lock()
+		if l.tasks.Len() > 0 {
+			task := heap.Pop(&l.tasks).(*keyedTask)
+			e.mu.Unlock()
+			task.done <- runTask(task)
+			<-e.slots
+			e.reportDepth()
+			idle.Reset(e.idleTimeout)
+			continue
+		}
+		e.mu.Unlock()
+
+		select {
+		case <-l.wake:
+		case <-idle.C:
+			e.mu.Lock()
+			if l.tasks.Len() == 0 {
+				delete(e.lanes, key)
+				e.mu.Unlock()
+				return
+			}
+			e.mu.Unlock()
+			idle.Reset(e.idleTimeout)
+		}
+	}
+}
+
+// runTask runs the task unless its ctx is done, a panic is returned as an error so that the worker keeps running
+func runTask(task *keyedTask) (err error) {
+	if err := task.ctx.Err(); err != nil {
+		return err
+	}
+	defer func() {
+		if r := recover(); r != nil {
+			err = fmt.Errorf("task panicked: %v", r)
+		}
+	}()
+	return task.run(task.ctx)
+}
+
+// taskQueue orders the tasks of a conversation by time, then by submission, it implements heap.Interface
+type taskQueue []*keyedTask
+
+func (q taskQueue) Len() int { return len(q) }
+
+func (q taskQueue) Less(i, j int) bool {
+	if !q[i].at.Equal(q[j].at) {
+		return q[i].at.Before(q[j].at)
+	}
+	return q[i].seq < q[j].seq
+}
+
+func (q taskQueue) Swap(i, j int) { q[i], q[j] = q[j], q[i] }
+
+func (q *taskQueue) Push(x any) { *q = append(*q, x.(*keyedTask)) }
+
+func (q *taskQueue) Pop() any {
+	old := *q
+	task := old[len(old)-1]
+	old[len(old)-1] = nil
+	*q = old[:len(old)-1]
+	return task
+}
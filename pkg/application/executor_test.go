@@ -0,0 +1,189 @@
+package application
+
+import (
+	"anyzzapp/pkg/domain"
+	"context"
+	"sync"
+	"testing"
+	"time"
+
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/mock"
+	"github.com/stretchr/testify/require"
+)
+
+var (
+	alice = domain.ConversationKey{PhoneNumberID: "123", WaID: "111"}
+	bob   = domain.ConversationKey{PhoneNumberID: "123", WaID: "222"}
+)
+
+func TestKeyedExecutor_OrdersTasksOfAConversation(t *testing.T) {
+	executor := NewKeyedExecutor(10, time.Minute)
+	ctx := context.Background()
+	at := time.Date(2024, 1, 1, 10, 0, 0, 0, time.UTC)
+	release := make(chan struct{})
+	var mu sync.Mutex
+	var ran []string
+	task := func(name string) func(context.Context) error {
+		return func(context.Context) error {
+			mu.Lock()
+			defer mu.Unlock()
+			ran = append(ran, name)
+			return nil
+		}
+	}
+
+	first, err := executor.Submit(ctx, alice, at, func(context.Context) error {
+		<-release
+		return task("first")(ctx)
+	})
+	require.NoError(t, err)
+	// Submitted while the first one runs, they run by time then by submission
+	var results []<-chan error
+	for _, submit := range []struct {
+		name string
+		at   time.Time
+	}{{"third", at.Add(2 * time.Second)}, {"second", at.Add(time.Second)}, {"fourth", at.Add(2 * time.Second)}} {
+		done, err := executor.Submit(ctx, alice, submit.at, task(submit.name))
+		require.NoError(t, err)
+		results = append(results, done)
+	}
+	close(release)
+
+	assert.NoError(t, <-first)
+	for _, done := range results {
+		assert.NoError(t, <-done)
+	}
+	assert.Equal(t, []string{"first", "second", "third", "fourth"}, ran)
+}
+
+func TestKeyedExecutor_RunsConversationsConcurrently(t *testing.T) {
+	executor := NewKeyedExecutor(10, time.Minute)
+	ctx := context.Background()
+	var started sync.WaitGroup
+	started.Add(2)
+	wait := func(context.Context) error {
+		started.Done()
+		// Only returns once both tasks started
+		started.Wait()
+		return nil
+	}
+
+	aliceDone, err := executor.Submit(ctx, alice, time.Now(), wait)
+	require.NoError(t, err)
+	bobDone, err := executor.Submit(ctx, bob, time.Now(), wait)
+	require.NoError(t, err)
+
+	assert.NoError(t, <-aliceDone)
+	assert.NoError(t, <-bobDone)
+	assert.Equal(t, 2, executor.Keys())
+}
+
+func TestKeyedExecutor_BoundsPendingTasks(t *testing.T) {
+	executor := NewKeyedExecutor(1, time.Minute)
+	release := make(chan struct{})
+
+	done, err := executor.Submit(context.Background(), alice, time.Now(), func(context.Context) error {
+		<-release
+		return nil
+	})
+	require.NoError(t, err)
+
+	ctx, cancel := context.WithTimeout(context.Background(), 10*time.Millisecond)
+	defer cancel()
+	_, err = executor.Submit(ctx, bob, time.Now(), func(context.Context) error { return nil })
+	assert.ErrorIs(t, err, context.DeadlineExceeded)
+
+	close(release)
+	assert.NoError(t, <-done)
+	// There is room again once the task ran
+	assert.Eventually(t, func() bool { return executor.Pending() == 0 }, time.Second, 5*time.Millisecond)
+	done, err = executor.Submit(context.Background(), bob, time.Now(), func(context.Context) error { return nil })
+	require.NoError(t, err)
+	assert.NoError(t, <-done)
+}
+
+func TestKeyedExecutor_RemovesIdleConversations(t *testing.T) {
+	executor := NewKeyedExecutor(10, 10*time.Millisecond)
+
+	done, err := executor.Submit(context.Background(), alice, time.Now(), func(context.Context) error { return nil })
+	require.NoError(t, err)
+	assert.NoError(t, <-done)
+
+	assert.Eventually(t, func() bool { return executor.Keys() == 0 }, time.Second, 5*time.Millisecond)
+	// A conversation gets a new worker once it is removed
+	done, err = executor.Submit(context.Background(), alice, time.Now(), func(context.Context) error { return nil })
+	require.NoError(t, err)
+	assert.NoError(t, <-done)
+}
+
+func TestKeyedExecutor_Failures(t *testing.T) {
+	executor := NewKeyedExecutor(10, time.Minute)
+
+	done, err := executor.Submit(context.Background(), alice, time.Now(), func(context.Context) error { panic("boom") })
+	require.NoError(t, err)
+	assert.ErrorContains(t, <-done, "task panicked: boom")
+
+	// A task whose request is gone while it waits is skipped
+	release := make(chan struct{})
+	blocking, err := executor.Submit(context.Background(), alice, time.Now(), func(context.Context) error {
+		<-release
+		return nil
+	})
+	require.NoError(t, err)
+	ctx, cancel := context.WithCancel(context.Background())
+	ran := false
+	done, err = executor.Submit(ctx, alice, time.Now(), func(context.Context) error {
+		ran = true
+		return nil
+	})
+	require.NoError(t, err)
+	cancel()
+	close(release)
+	assert.NoError(t, <-blocking)
+	assert.ErrorIs(t, <-done, context.Canceled)
+	assert.False(t, ran)
+}
+
+func TestWhatsAppUseCase_ProcessIncomingWebhook_KeyedExecutor(t *testing.T) {
+	mockWhatsAppRepo := &MockWhatsAppRepository{}
+	mockLLMRepo := &MockLLMRepository{}
+	useCase := NewWhatsAppUseCase(mockWhatsAppRepo, mockLLMRepo, WithKeyedExecutor(NewKeyedExecutor(10, time.Minute)))
+	webhook := commandWebhook("Second")
+	first := webhook.Entry[0].Changes[0].Value.Messages[0]
+	first.ID, first.Timestamp, first.Text = "msg_122", "1234567889", &domain.WebhookText{Body: "First"}
+	// Meta does not guarantee the order of the messages
+	webhook.Entry[0].Changes[0].Value.Messages = append(webhook.Entry[0].Changes[0].Value.Messages, first)
+
+	mockWhatsAppRepo.On("MarkAsRead", "123456789", mock.Anything).Return(nil)
+	mockLLMRepo.On("SendMessage", mock.Anything).Return("Reply", nil)
+	mockWhatsAppRepo.On("SendMessage", mock.Anything).Return(&domain.SendMessageResponse{MessageID: "reply_1"}, nil)
+
+	err := useCase.ProcessIncomingWebhook(context.Background(), webhook)
+
+	assert.NoError(t, err)
+	require.Len(t, mockLLMRepo.Calls, 2)
+	assert.Equal(t, "First", mockLLMRepo.Calls[0].Arguments.Get(0))
+	assert.Equal(t, "Second", mockLLMRepo.Calls[1].Arguments.Get(0))
+}
+
+func TestWhatsAppUseCase_KeyedExecutor_QueueDepth(t *testing.T) {
+	mockWhatsAppRepo := &MockWhatsAppRepository{}
+	mockLLMRepo := &MockLLMRepository{}
+	metrics := newCountingMetrics()
+	executor := NewKeyedExecutor(10, time.Minute)
+	useCase := NewWhatsAppUseCase(mockWhatsAppRepo, mockLLMRepo, WithMetrics(metrics), WithKeyedExecutor(executor))
+
+	mockWhatsAppRepo.On("MarkAsRead", "123456789", mock.Anything).Return(nil)
+	mockLLMRepo.On("SendMessage", mock.Anything).Return("Reply", nil)
+	mockWhatsAppRepo.On("SendMessage", mock.Anything).Return(&domain.SendMessageResponse{MessageID: "reply_1"}, nil)
+
+	assert.NoError(t, useCase.ProcessIncomingWebhook(context.Background(), commandWebhook("Hello")))
+
+	// Up while the message is processed, back down once it was
+	assert.Eventually(t, func() bool {
+		depths := metrics.queueDepths(domain.QueueProcessing)
+		return len(depths) == 2 && depths[1] == 0
+	}, time.Second, 5*time.Millisecond)
+	assert.Equal(t, 1, metrics.queueDepths(domain.QueueProcessing)[0])
+}
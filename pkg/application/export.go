@@ -0,0 +1,314 @@
+package application
+
+import (
+	"anyzzapp/pkg/domain"
+	"context"
+	"encoding/csv"
+	"encoding/json"
+	"fmt"
+	"io"
+	"sort"
+	"strconv"
+	"strings"
+	"time"
+)
+
+// exportBatchSize is the number of messages read at once, an export never holds more in memory
+const exportBatchSize = 500
+
+// transcriptTimeFormat is the time format of the transcript lines
+const transcriptTimeFormat = "2006-01-02 15:04:05"
+
+// Export writes the messages selected by the query to w, conversation by conversation and oldest first.
+// The messages are read and written in batches so that large exports are streamed.
+func (uc *HistoryUseCase) Export(ctx context.Context, query domain.ExportQuery, w io.Writer) error {
+	if err := query.Validate(); err != nil {
+		return err
+	}
+	filter := domain.MessageFilter{
+		PhoneNumberID: query.PhoneNumberID,
+		Since:         query.Since,
+		Until:         query.Until,
+	}
+	keys, err := uc.conversationKeys(ctx, filter)
+	if err != nil {
+		return err
+	}
+
+	writer := newExportWriter(query, w)
+	for _, key := range keys {
+		filter.PhoneNumberID = key.PhoneNumberID
+		filter.WaID = key.WaID
+		writer.startConversation(key)
+		if err := eachMessage(ctx, uc.messages, filter, writer.write); err != nil {
+			return err
+		}
+		if err := writer.flush(); err != nil {
+			return fmt.Errorf("failed to write export: %w", err)
+		}
+		// Sends the conversation to HTTP clients right away
+		if flusher, ok := w.(interface{ Flush() }); ok {
+			flusher.Flush()
+		}
+	}
+	return nil
+}
+
+// conversationKeys returns the conversations with messages matching the filter, sorted by tenant and wa_id
+func (uc *HistoryUseCase) conversationKeys(ctx context.Context, filter domain.MessageFilter) ([]domain.ConversationKey, error) {
+	seen := make(map[domain.ConversationKey]bool)
+	var keys []domain.ConversationKey
+	err := eachMessage(ctx, uc.messages, filter, func(message domain.StoredMessage) error {
+		if !seen[message.Key()] {
+			seen[message.Key()] = true
+			keys = append(keys, message.Key())
+		}
+		return nil
+	})
+	if err != nil {
+		return nil, err
+	}
+	sort.Slice(keys, func(i, j int) bool {
+		if keys[i].PhoneNumberID == keys[j].PhoneNumberID {
+			return keys[i].WaID < keys[j].WaID
+		}
+		return keys[i].PhoneNumberID < keys[j].PhoneNumberID
+	})
+	return keys, nil
+}
+
+// eachMessage calls fn with the messages matching the filter, oldest first, reading them in batches
+func eachMessage(ctx context.Context, repo domain.MessageRepository, filter domain.MessageFilter, fn func(domain.StoredMessage) error) error {
+	filter.Limit = exportBatchSize
+	for {
+		messages, err := repo.List(ctx, filter)
+		if err != nil {
+			return fmt.Errorf("failed to list messages: %w", err)
+		}
+		for _, message := range messages {
+			if err := fn(message); err != nil {
+				return fmt.Errorf("failed to write export: %w", err)
+			}
+		}
+		if len(messages) < exportBatchSize {
+			return nil
+		}
+		last := messages[len(messages)-1]
+		filter.Since = last.Timestamp
+		filter.AfterID = last.ID
+	}
+}
+
+// exportWriter writes the messages in one of the export formats
+type exportWriter struct {
+	query   domain.ExportQuery
+	format  exportFormat
+	aliases map[string]string
+	// pending is the conversation announced to the format with its first message
+	pending *domain.ConversationKey
+}
+
+// exportFormat encodes the messages, it gets the wa_id already masked
+type exportFormat interface {
+	startConversation(key domain.ConversationKey, waID string) error
+	write(message domain.StoredMessage) error
+	flush() error
+}
+
+// newExportWriter creates the writer of the format of the query
+func newExportWriter(query domain.ExportQuery, w io.Writer) *exportWriter {
+	writer := &exportWriter{query: query, aliases: make(map[string]string)}
+	switch query.Format {
+	case domain.ExportFormatCSV:
+		writer.format = newCSVExport(w, query)
+	case domain.ExportFormatTranscript:
+		writer.format = &transcriptExport{w: w, query: query}
+	default:
+		writer.format = &jsonlExport{encoder: json.NewEncoder(w)}
+	}
+	return writer
+}
+
+// startConversation announces the conversation of the next messages
+func (e *exportWriter) startConversation(key domain.ConversationKey) {
+	e.pending = &key
+}
+
+// write masks and writes a message
+func (e *exportWriter) write(message domain.StoredMessage) error {
+	if e.pending != nil {
+		if err := e.format.startConversation(*e.pending, e.waID(e.pending.WaID)); err != nil {
+			return err
+		}
+		e.pending = nil
+	}
+	message.WaID = e.waID(message.WaID)
+	if e.query.Masks("content") {
+		message.Content = ""
+		if message.Media != nil {
+			message.Media.Caption = ""
+			message.Media.Filename = ""
+		}
+	}
+	if e.query.Masks("media") {
+		message.Media = nil
+	}
+	return e.format.write(message)
+}
+
+// flush writes the buffered messages
+func (e *exportWriter) flush() error {
+	return e.format.flush()
+}
+
+// waID returns the wa_id, or its pseudonym when the wa_id is masked
+func (e *exportWriter) waID(waID string) string {
+	if !e.query.Masks("wa_id") {
+		return waID
+	}
+	alias, ok := e.aliases[waID]
+	if !ok {
+		alias = "contact-" + strconv.Itoa(len(e.aliases)+1)
+		e.aliases[waID] = alias
+	}
+	return alias
+}
+
+// jsonlExport writes one JSON message per line
+type jsonlExport struct {
+	encoder *json.Encoder
+}
+
+func (e *jsonlExport) startConversation(key domain.ConversationKey, waID string) error { return nil }
+
+func (e *jsonlExport) write(message domain.StoredMessage) error {
+	return e.encoder.Encode(message)
+}
+
+func (e *jsonlExport) flush() error { return nil }
+
+// csvColumns are the columns of the CSV export, with the masked field dropping each of them
+var csvColumns = []struct {
+	name  string
+	field string
+	value func(domain.StoredMessage) string
+}{
+	{"id", "", func(m domain.StoredMessage) string { return m.ID }},
+	{"phone_number_id", "", func(m domain.StoredMessage) string { return m.PhoneNumberID }},
+	{"wa_id", "", func(m domain.StoredMessage) string { return m.WaID }},
+	{"direction", "", func(m domain.StoredMessage) string { return m.Direction }},
+	{"author", "", func(m domain.StoredMessage) string { return m.Author }},
+	{"type", "", func(m domain.StoredMessage) string { return m.Type }},
+	{"content", "content", func(m domain.StoredMessage) string { return m.Content }},
+	{"media_id", "media", func(m domain.StoredMessage) string {
+		if m.Media == nil {
+			return ""
+		}
+		return m.Media.ID
+	}},
+	{"media_mime_type", "media", func(m domain.StoredMessage) string {
+		if m.Media == nil {
+			return ""
+		}
+		return m.Media.MimeType
+	}},
+	{"status", "", func(m domain.StoredMessage) string { return m.Status }},
+	{"error", "", func(m domain.StoredMessage) string { return m.Error }},
+	{"timestamp", "", func(m domain.StoredMessage) string { return m.Timestamp.UTC().Format(time.RFC3339) }},
+	{"updated_at", "", func(m domain.StoredMessage) string { return m.UpdatedAt.UTC().Format(time.RFC3339) }},
+}
+
+// csvExport writes one message per row after a header row
+type csvExport struct {
+	writer  *csv.Writer
+	columns []int
+	header  bool
+}
+
+// newCSVExport creates a CSV export without the columns of the masked fields
+func newCSVExport(w io.Writer, query domain.ExportQuery) *csvExport {
+	e := &csvExport{writer: csv.NewWriter(w)}
+	for i, column := range csvColumns {
+		if column.field == "" || !query.Masks(column.field) {
+			e.columns = append(e.columns, i)
+		}
+	}
+	return e
+}
+
+func (e *csvExport) startConversation(key domain.ConversationKey, waID string) error { return nil }
+
+func (e *csvExport) write(message domain.StoredMessage) error {
+	if !e.header {
+		e.header = true
+		if err := e.writer.Write(e.row(func(i int) string { return csvColumns[i].name })); err != nil {
+			return err
+		}
+	}
+	return e.writer.Write(e.row(func(i int) string { return csvColumns[i].value(message) }))
+}
+
+// row returns the cells of the exported columns
+func (e *csvExport) row(cell func(i int) string) []string {
+	row := make([]string, 0, len(e.columns))
+	for _, i := range e.columns {
+		row = append(row, cell(i))
+	}
+	return row
+}
+
+func (e *csvExport) flush() error {
+	e.writer.Flush()
+	return e.writer.Error()
+}
+
+// transcriptExport writes the conversations like the chats exported by WhatsApp
+type transcriptExport struct {
+	w       io.Writer
+	query   domain.ExportQuery
+	started bool
+}
+
+func (e *transcriptExport) startConversation(key domain.ConversationKey, waID string) error {
+	separator := ""
+	if e.started {
+		separator = "\n"
+	}
+	e.started = true
+	_, err := fmt.Fprintf(e.w, "%s== Conversation of %s with %s ==\n", separator, key.PhoneNumberID, contactName(waID, e.query))
+	return err
+}
+
+func (e *transcriptExport) write(message domain.StoredMessage) error {
+	author := message.Author
+	if author == "contact" || author == "" {
+		author = contactName(message.WaID, e.query)
+	}
+	_, err := fmt.Fprintf(e.w, "%s - %s: %s\n", message.Timestamp.UTC().Format(transcriptTimeFormat), author, transcriptText(message, e.query))
+	return err
+}
+
+func (e *transcriptExport) flush() error { return nil }
+
+// contactName shows the wa_id as a phone number, or the pseudonym of a masked one
+func contactName(waID string, query domain.ExportQuery) string {
+	if query.Masks("wa_id") {
+		return waID
+	}
+	return "+" + waID
+}
+
+// transcriptText is the text of a transcript line, media are omitted like in the chats exported by WhatsApp
+func transcriptText(message domain.StoredMessage, query domain.ExportQuery) string {
+	var parts []string
+	if message.Type != "" && message.Type != "text" {
+		parts = append(parts, fmt.Sprintf("<%s omitted>", message.Type))
+	}
+	switch {
+	case query.Masks("content"):
+		parts = append(parts, "<content redacted>")
+	case message.Content != "":
+		parts = append(parts, message.Content)
+	}
+	return strings.Join(parts, " ")
+}
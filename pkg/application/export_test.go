@@ -0,0 +1,103 @@
+package application
+
+import (
+	"anyzzapp/pkg/domain"
+	"bytes"
+	"context"
+	"encoding/json"
+	"sort"
+	"strconv"
+	"strings"
+	"testing"
+	"time"
+
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+// exportMessages are two conversations, 789 writing before 456
+func exportMessages() storedMessages {
+	return storedMessages{
+		{ID: "m1", PhoneNumberID: "123", WaID: "789", Author: "contact", Type: "text", Content: "Hi", Timestamp: historyStart},
+		{ID: "m2", PhoneNumberID: "123", WaID: "456", Author: "contact", Type: "image", Content: "my receipt",
+			Media: &domain.MediaReference{ID: "media_1", MimeType: "image/jpeg", Caption: "my receipt"}, Timestamp: historyStart.Add(time.Minute)},
+		{ID: "m3", PhoneNumberID: "123", WaID: "456", Author: "bot", Type: "text", Content: "Thanks!", Timestamp: historyStart.Add(2 * time.Minute)},
+	}
+}
+
+func TestHistoryUseCase_Export_JSONL(t *testing.T) {
+	useCase := NewHistoryUseCase(exportMessages(), nil)
+	var out bytes.Buffer
+
+	err := useCase.Export(context.Background(), domain.ExportQuery{Format: domain.ExportFormatJSONL}, &out)
+
+	require.NoError(t, err)
+	lines := strings.Split(strings.TrimSpace(out.String()), "\n")
+	require.Len(t, lines, 3)
+	var first domain.StoredMessage
+	require.NoError(t, json.Unmarshal([]byte(lines[0]), &first))
+	// Conversation by conversation, sorted by wa_id
+	assert.Equal(t, "m2", first.ID)
+	assert.Equal(t, "media_1", first.Media.ID)
+}
+
+func TestHistoryUseCase_Export_CSVMasked(t *testing.T) {
+	useCase := NewHistoryUseCase(exportMessages(), nil)
+	var out bytes.Buffer
+
+	err := useCase.Export(context.Background(), domain.ExportQuery{
+		Format: domain.ExportFormatCSV,
+		Mask:   []string{"wa_id", "content", "media"},
+	}, &out)
+
+	require.NoError(t, err)
+	expected := "id,phone_number_id,wa_id,direction,author,type,status,error,timestamp,updated_at\n" +
+		"m2,123,contact-1,,contact,image,,,2024-01-01T10:01:00Z,0001-01-01T00:00:00Z\n" +
+		"m3,123,contact-1,,bot,text,,,2024-01-01T10:02:00Z,0001-01-01T00:00:00Z\n" +
+		"m1,123,contact-2,,contact,text,,,2024-01-01T10:00:00Z,0001-01-01T00:00:00Z\n"
+	assert.Equal(t, expected, out.String())
+	assert.NotContains(t, out.String(), "receipt")
+}
+
+func TestHistoryUseCase_Export_Transcript(t *testing.T) {
+	useCase := NewHistoryUseCase(exportMessages(), nil)
+	var out bytes.Buffer
+
+	err := useCase.Export(context.Background(), domain.ExportQuery{
+		Format: domain.ExportFormatTranscript,
+		Until:  historyStart.Add(2 * time.Minute),
+	}, &out)
+
+	require.NoError(t, err)
+	expected := "== Conversation of 123 with +456 ==\n" +
+		"2024-01-01 10:01:00 - +456: <image omitted> my receipt\n" +
+		"\n" +
+		"== Conversation of 123 with +789 ==\n" +
+		"2024-01-01 10:00:00 - +789: Hi\n"
+	assert.Equal(t, expected, out.String())
+}
+
+func TestHistoryUseCase_Export_ReadsInBatches(t *testing.T) {
+	var messages storedMessages
+	for i := 0; i < exportBatchSize*2+1; i++ {
+		messages = append(messages, domain.StoredMessage{
+			ID: "m" + strconv.Itoa(i), PhoneNumberID: "123", WaID: "456", Timestamp: historyStart,
+		})
+	}
+	// The IDs sort as text, like the stores do for messages at the same time
+	sort.Slice(messages, func(i, j int) bool { return messages[i].ID < messages[j].ID })
+	useCase := NewHistoryUseCase(messages, nil)
+	var out bytes.Buffer
+
+	err := useCase.Export(context.Background(), domain.ExportQuery{Format: domain.ExportFormatJSONL}, &out)
+
+	require.NoError(t, err)
+	assert.Equal(t, len(messages), strings.Count(out.String(), "\n"))
+}
+
+func TestHistoryUseCase_Export_Invalid(t *testing.T) {
+	useCase := NewHistoryUseCase(exportMessages(), nil)
+
+	assert.Error(t, useCase.Export(context.Background(), domain.ExportQuery{Format: "xml"}, &bytes.Buffer{}))
+	assert.Error(t, useCase.Export(context.Background(), domain.ExportQuery{Format: "csv", Mask: []string{"name"}}, &bytes.Buffer{}))
+}
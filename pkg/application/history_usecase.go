@@ -0,0 +1,196 @@
+package application
+
+import (
+	"anyzzapp/pkg/domain"
+	"context"
+	"encoding/base64"
+	"fmt"
+	"strconv"
+	"strings"
+	"time"
+)
+
+const (
+	// defaultPageSize is the number of items of a page when the query sets no limit
+	defaultPageSize = 50
+	// maxPageSize bounds the size of a page
+	maxPageSize = 200
+)
+
+// HistoryUseCase implements HistoryUseCaseInterface
+type HistoryUseCase struct {
+	messages      domain.MessageRepository
+	conversations domain.ConversationRepository
+}
+
+// NewHistoryUseCase creates a new instance of HistoryUseCase, the conversation states are read from conversations
+func NewHistoryUseCase(messages domain.MessageRepository,
+	conversations domain.ConversationRepository) domain.HistoryUseCaseInterface {
+	return &HistoryUseCase{
+		messages:      messages,
+		conversations: conversations,
+	}
+}
+
+// ListConversations returns a page of the conversations with stored messages, the most recently active first
+func (uc *HistoryUseCase) ListConversations(ctx context.Context, query domain.ConversationQuery) (*domain.ConversationPage, error) {
+	if query.State != "" && !query.State.IsValid() {
+		return nil, fmt.Errorf("invalid conversation state: %q", query.State)
+	}
+	filter := domain.ConversationFilter{
+		PhoneNumberID: query.PhoneNumberID,
+		WaID:          query.WaID,
+		Since:         query.Since,
+		Until:         query.Until,
+	}
+	if query.Cursor != "" {
+		cursor, err := decodeCursor(query.Cursor)
+		if err != nil {
+			return nil, err
+		}
+		filter.Before = cursor.at
+		filter.BeforeID = cursor.id
+	}
+	limit := pageSize(query.Limit)
+	// One more conversation tells whether there is a next page
+	filter.Limit = limit + 1
+
+	// The states are not part of the stored messages, the batches are read until the page is full
+	summaries := make([]domain.ConversationSummary, 0, filter.Limit)
+	for len(summaries) < filter.Limit {
+		batch, err := uc.messages.ListConversations(ctx, filter)
+		if err != nil {
+			return nil, fmt.Errorf("failed to list conversations: %w", err)
+		}
+		for _, summary := range batch {
+			state, err := uc.state(domain.ConversationKey{PhoneNumberID: summary.PhoneNumberID, WaID: summary.WaID})
+			if err != nil {
+				return nil, err
+			}
+			if query.State != "" && state != query.State {
+				continue
+			}
+			summary.State = state
+			summaries = append(summaries, summary)
+		}
+		if len(batch) < filter.Limit {
+			break
+		}
+		last := batch[len(batch)-1]
+		filter.Before = last.LastMessageAt
+		filter.BeforeID = last.ID
+	}
+
+	page := &domain.ConversationPage{Conversations: summaries}
+	if len(summaries) > limit {
+		page.Conversations = summaries[:limit]
+		last := page.Conversations[limit-1]
+		page.NextCursor = encodeCursor(pageCursor{at: last.LastMessageAt, id: last.ID})
+	}
+	return page, nil
+}
+
+// Transcript returns a page of the messages of a conversation, oldest first
+func (uc *HistoryUseCase) Transcript(ctx context.Context, id string, query domain.MessageQuery) (*domain.MessagePage, error) {
+	key, err := domain.ParseConversationID(id)
+	if err != nil {
+		return nil, fmt.Errorf("%w: %v", domain.ErrConversationNotFound, err)
+	}
+	query.PhoneNumberID = key.PhoneNumberID
+	query.WaID = key.WaID
+	return uc.listMessages(ctx, query)
+}
+
+// SearchMessages returns a page of the messages whose content contains every word of the query text, oldest first
+func (uc *HistoryUseCase) SearchMessages(ctx context.Context, query domain.MessageQuery) (*domain.MessagePage, error) {
+	if strings.TrimSpace(query.Text) == "" {
+		return nil, fmt.Errorf("search text is required")
+	}
+	return uc.listMessages(ctx, query)
+}
+
+// listMessages returns a page of the messages matching the query
+func (uc *HistoryUseCase) listMessages(ctx context.Context, query domain.MessageQuery) (*domain.MessagePage, error) {
+	filter := domain.MessageFilter{
+		PhoneNumberID: query.PhoneNumberID,
+		WaID:          query.WaID,
+		Text:          query.Text,
+		Since:         query.Since,
+		Until:         query.Until,
+	}
+	if query.Cursor != "" {
+		cursor, err := decodeCursor(query.Cursor)
+		if err != nil {
+			return nil, err
+		}
+		// The cursor is always after the start of the range
+		filter.Since = cursor.at
+		filter.AfterID = cursor.id
+	}
+	limit := pageSize(query.Limit)
+	// One more message tells whether there is a next page
+	filter.Limit = limit + 1
+
+	messages, err := uc.messages.List(ctx, filter)
+	if err != nil {
+		return nil, fmt.Errorf("failed to list messages: %w", err)
+	}
+	page := &domain.MessagePage{Messages: messages}
+	if len(messages) > limit {
+		page.Messages = messages[:limit]
+		last := page.Messages[limit-1]
+		page.NextCursor = encodeCursor(pageCursor{at: last.Timestamp, id: last.ID})
+	}
+	return page, nil
+}
+
+// state returns the state of the conversation, the bot answers the ones the memory doesn't know
+func (uc *HistoryUseCase) state(key domain.ConversationKey) (domain.ConversationState, error) {
+	if uc.conversations == nil {
+		return domain.ConversationStateBot, nil
+	}
+	conversation, err := loadConversation(uc.conversations, key)
+	if err != nil {
+		return "", err
+	}
+	return conversation.State, nil
+}
+
+// pageSize returns the size of a page for the requested limit
+func pageSize(limit int) int {
+	switch {
+	case limit <= 0:
+		return defaultPageSize
+	case limit > maxPageSize:
+		return maxPageSize
+	}
+	return limit
+}
+
+// pageCursor is the position of the last item of a page
+type pageCursor struct {
+	at time.Time
+	id string
+}
+
+// encodeCursor returns the opaque form of the cursor given to the clients
+func encodeCursor(cursor pageCursor) string {
+	return base64.RawURLEncoding.EncodeToString([]byte(strconv.FormatInt(cursor.at.UnixNano(), 10) + ":" + cursor.id))
+}
+
+// decodeCursor parses a cursor returned by encodeCursor
+func decodeCursor(value string) (pageCursor, error) {
+	data, err := base64.RawURLEncoding.DecodeString(value)
+	if err != nil {
+		return pageCursor{}, domain.ErrInvalidCursor
+	}
+	nanos, id, ok := strings.Cut(string(data), ":")
+	if !ok || id == "" {
+		return pageCursor{}, domain.ErrInvalidCursor
+	}
+	at, err := strconv.ParseInt(nanos, 10, 64)
+	if err != nil {
+		return pageCursor{}, domain.ErrInvalidCursor
+	}
+	return pageCursor{at: time.Unix(0, at), id: id}, nil
+}
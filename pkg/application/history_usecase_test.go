@@ -0,0 +1,192 @@
+package application
+
+import (
+	"anyzzapp/pkg/domain"
+	"context"
+	"testing"
+	"time"
+
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/mock"
+	"github.com/stretchr/testify/require"
+)
+
+// storedMessages is a message repository listing a fixed set of messages sorted oldest first
+type storedMessages []domain.StoredMessage
+
+func (s storedMessages) Save(ctx context.Context, message *domain.StoredMessage) error { return nil }
+
+func (s storedMessages) Get(ctx context.Context, id string) (*domain.StoredMessage, error) {
+	return nil, nil
+}
+
+func (s storedMessages) UpdateStatus(ctx context.Context, id, status, errorMessage string, at time.Time) error {
+	return nil
+}
+
+func (s storedMessages) List(ctx context.Context, filter domain.MessageFilter) ([]domain.StoredMessage, error) {
+	var messages []domain.StoredMessage
+	for _, message := range s {
+		if filter.Matches(message) {
+			messages = append(messages, message)
+		}
+		if filter.Limit > 0 && len(messages) == filter.Limit {
+			break
+		}
+	}
+	return messages, nil
+}
+
+func (s storedMessages) ListConversations(ctx context.Context, filter domain.ConversationFilter) ([]domain.ConversationSummary, error) {
+	return domain.SummarizeConversations(s, filter), nil
+}
+
+func (s storedMessages) Delete(ctx context.Context, ids ...string) (int, error) { return 0, nil }
+
+var historyStart = time.Date(2024, 1, 1, 10, 0, 0, 0, time.UTC)
+
+// historyMessages are two conversations of the same tenant, 456 being the most recently active
+func historyMessages() storedMessages {
+	return storedMessages{
+		{ID: "m1", PhoneNumberID: "123", WaID: "456", Content: "Where is my order?", Timestamp: historyStart},
+		{ID: "m2", PhoneNumberID: "123", WaID: "789", Content: "Do you ship to Chile?", Timestamp: historyStart.Add(time.Minute)},
+		{ID: "m3", PhoneNumberID: "123", WaID: "789", Content: "Yes, we ship to Chile", Timestamp: historyStart.Add(2 * time.Minute)},
+		{ID: "m4", PhoneNumberID: "123", WaID: "456", Content: "Your ORDER shipped", Timestamp: historyStart.Add(3 * time.Minute)},
+	}
+}
+
+func TestHistoryUseCase_ListConversations(t *testing.T) {
+	conversations := &MockConversationRepository{}
+	useCase := NewHistoryUseCase(historyMessages(), conversations)
+
+	conversations.On("Get", domain.ConversationKey{PhoneNumberID: "123", WaID: "456"}).
+		Return(&domain.Conversation{State: domain.ConversationStateHuman}, nil)
+	conversations.On("Get", domain.ConversationKey{PhoneNumberID: "123", WaID: "789"}).
+		Return((*domain.Conversation)(nil), nil)
+
+	page, err := useCase.ListConversations(context.Background(), domain.ConversationQuery{})
+
+	require.NoError(t, err)
+	require.Len(t, page.Conversations, 2)
+	first := page.Conversations[0]
+	assert.Equal(t, "123-456", first.ID)
+	assert.Equal(t, domain.ConversationStateHuman, first.State)
+	assert.Equal(t, 2, first.MessageCount)
+	assert.Equal(t, historyStart, first.FirstMessageAt)
+	assert.Equal(t, "m4", first.LastMessage.ID)
+	assert.Equal(t, domain.ConversationStateBot, page.Conversations[1].State)
+	assert.Empty(t, page.NextCursor)
+
+	human, err := useCase.ListConversations(context.Background(), domain.ConversationQuery{State: domain.ConversationStateHuman})
+	require.NoError(t, err)
+	assert.Len(t, human.Conversations, 1)
+
+	_, err = useCase.ListConversations(context.Background(), domain.ConversationQuery{State: "waiting"})
+	assert.Error(t, err)
+}
+
+func TestHistoryUseCase_ListConversations_Pagination(t *testing.T) {
+	conversations := &MockConversationRepository{}
+	useCase := NewHistoryUseCase(historyMessages(), conversations)
+	conversations.On("Get", mock.Anything).Return((*domain.Conversation)(nil), nil)
+
+	first, err := useCase.ListConversations(context.Background(), domain.ConversationQuery{Limit: 1})
+	require.NoError(t, err)
+	require.Len(t, first.Conversations, 1)
+	assert.Equal(t, "123-456", first.Conversations[0].ID)
+	require.NotEmpty(t, first.NextCursor)
+
+	second, err := useCase.ListConversations(context.Background(), domain.ConversationQuery{Limit: 1, Cursor: first.NextCursor})
+	require.NoError(t, err)
+	require.Len(t, second.Conversations, 1)
+	assert.Equal(t, "123-789", second.Conversations[0].ID)
+	assert.Empty(t, second.NextCursor)
+
+	_, err = useCase.ListConversations(context.Background(), domain.ConversationQuery{Cursor: "not a cursor"})
+	assert.ErrorIs(t, err, domain.ErrInvalidCursor)
+}
+
+func TestHistoryUseCase_ListConversations_DateRange(t *testing.T) {
+	conversations := &MockConversationRepository{}
+	useCase := NewHistoryUseCase(historyMessages(), conversations)
+	conversations.On("Get", mock.Anything).Return((*domain.Conversation)(nil), nil)
+
+	page, err := useCase.ListConversations(context.Background(), domain.ConversationQuery{
+		Since: historyStart.Add(time.Minute),
+		Until: historyStart.Add(3 * time.Minute),
+	})
+
+	require.NoError(t, err)
+	require.Len(t, page.Conversations, 1)
+	assert.Equal(t, "123-789", page.Conversations[0].ID)
+}
+
+func TestHistoryUseCase_ListConversations_StateBatches(t *testing.T) {
+	messages := &MockMessageRepository{}
+	conversations := &MockConversationRepository{}
+	useCase := NewHistoryUseCase(messages, conversations)
+	bot := []domain.ConversationSummary{
+		{ID: "123-1", PhoneNumberID: "123", WaID: "1", LastMessageAt: historyStart.Add(2 * time.Minute)},
+		{ID: "123-2", PhoneNumberID: "123", WaID: "2", LastMessageAt: historyStart.Add(time.Minute)},
+	}
+	human := []domain.ConversationSummary{{ID: "123-3", PhoneNumberID: "123", WaID: "3", LastMessageAt: historyStart}}
+
+	// The repository pages the conversations, the ones in another state are skipped batch after batch
+	messages.On("ListConversations", domain.ConversationFilter{Limit: 2}).Return(bot, nil)
+	messages.On("ListConversations", domain.ConversationFilter{Before: bot[1].LastMessageAt, BeforeID: "123-2", Limit: 2}).
+		Return(human, nil)
+	conversations.On("Get", domain.ConversationKey{PhoneNumberID: "123", WaID: "3"}).
+		Return(&domain.Conversation{State: domain.ConversationStateHuman}, nil)
+	conversations.On("Get", mock.Anything).Return((*domain.Conversation)(nil), nil)
+
+	page, err := useCase.ListConversations(context.Background(), domain.ConversationQuery{State: domain.ConversationStateHuman, Limit: 1})
+
+	require.NoError(t, err)
+	require.Len(t, page.Conversations, 1)
+	assert.Equal(t, "123-3", page.Conversations[0].ID)
+	assert.Empty(t, page.NextCursor)
+	messages.AssertExpectations(t)
+}
+
+func TestHistoryUseCase_Transcript(t *testing.T) {
+	useCase := NewHistoryUseCase(historyMessages(), nil)
+
+	first, err := useCase.Transcript(context.Background(), "123-456", domain.MessageQuery{Limit: 1})
+	require.NoError(t, err)
+	assert.Equal(t, "m1", first.Messages[0].ID)
+	require.NotEmpty(t, first.NextCursor)
+
+	second, err := useCase.Transcript(context.Background(), "123-456", domain.MessageQuery{Limit: 1, Cursor: first.NextCursor})
+	require.NoError(t, err)
+	assert.Equal(t, "m4", second.Messages[0].ID)
+	assert.Empty(t, second.NextCursor)
+
+	_, err = useCase.Transcript(context.Background(), "malformed", domain.MessageQuery{})
+	assert.ErrorIs(t, err, domain.ErrConversationNotFound)
+}
+
+func TestHistoryUseCase_SearchMessages(t *testing.T) {
+	useCase := NewHistoryUseCase(historyMessages(), nil)
+
+	page, err := useCase.SearchMessages(context.Background(), domain.MessageQuery{Text: "order"})
+	require.NoError(t, err)
+	assert.Len(t, page.Messages, 2)
+
+	page, err = useCase.SearchMessages(context.Background(), domain.MessageQuery{Text: "ship chile"})
+	require.NoError(t, err)
+	assert.Len(t, page.Messages, 2)
+	assert.Equal(t, "m2", page.Messages[0].ID)
+
+	_, err = useCase.SearchMessages(context.Background(), domain.MessageQuery{Text: " "})
+	assert.Error(t, err)
+}
+
+func TestCursor_RoundTrip(t *testing.T) {
+	cursor := pageCursor{at: time.Unix(1700000000, 123), id: "wamid.HBgL:1"}
+
+	decoded, err := decodeCursor(encodeCursor(cursor))
+
+	require.NoError(t, err)
+	assert.True(t, cursor.at.Equal(decoded.at))
+	assert.Equal(t, cursor.id, decoded.id)
+}
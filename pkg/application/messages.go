@@ -0,0 +1,111 @@
+package application
+
+import (
+	"anyzzapp/pkg/domain"
+	"context"
+	"errors"
+	"fmt"
+	"strconv"
+	"strings"
+	"time"
+
+	"github.com/rs/zerolog/log"
+)
+
+// inboundMessage builds the record of a message received from a contact, its content is the text or the media caption
+func inboundMessage(phoneNumberID string, msg domain.WebhookMessage) *domain.StoredMessage {
+	stored := &domain.StoredMessage{
+		ID:            msg.ID,
+		PhoneNumberID: phoneNumberID,
+		WaID:          msg.From,
+		Direction:     domain.MessageDirectionInbound,
+		Author:        "contact",
+		Type:          msg.Type,
+		Status:        domain.MessageStatusReceived,
+		Timestamp:     messageTime(msg),
+	}
+	if msg.Text != nil {
+		stored.Content = msg.Text.Body
+	}
+	for _, media := range []*domain.WebhookMedia{msg.Image, msg.Audio, msg.Document} {
+		if media != nil {
+			stored.Media = &domain.MediaReference{
+				ID:       media.ID,
+				MimeType: media.MimeType,
+				Caption:  media.Caption,
+				Filename: media.Filename,
+			}
+			if stored.Content == "" {
+				stored.Content = media.Caption
+			}
+			break
+		}
+	}
+	stored.UpdatedAt = stored.Timestamp
+	return stored
+}
+
+// outboundMessage builds the record of a message accepted by the Graph API
+func outboundMessage(message domain.Message, waID, author, messageID string) *domain.StoredMessage {
+	messageType := message.MessageType
+	if messageType == "" {
+		messageType = "text"
+	}
+	now := time.Now()
+	return &domain.StoredMessage{
+		ID:            messageID,
+		PhoneNumberID: message.PhoneNumberID,
+		WaID:          waID,
+		Direction:     domain.MessageDirectionOutbound,
+		Author:        author,
+		Type:          messageType,
+		Content:       message.Content,
+		Status:        domain.MessageStatusSent,
+		Timestamp:     now,
+		UpdatedAt:     now,
+	}
+}
+
+// storeMessage records the message if there is a message store.
+// A failure is only logged, the message was already received or sent.
+func storeMessage(ctx context.Context, messages domain.MessageRepository, message *domain.StoredMessage) {
+	if messages == nil || message.ID == "" {
+		return
+	}
+	if err := messages.Save(ctx, message); err != nil {
+		log.Ctx(ctx).Warn().Err(err).Str("message_id", message.ID).Str("direction", message.Direction).Msg("failed to store message")
+	}
+}
+
+// processStatuses applies the delivery statuses notified by WhatsApp to the stored messages.
+// Statuses of messages that were not stored, like the ones sent before the store existed, are ignored.
+func (uc *WhatsAppUseCase) processStatuses(ctx context.Context, statuses []domain.WebhookStatus) error {
+	if uc.messages == nil {
+		return nil
+	}
+	var errs []error
+	for _, status := range statuses {
+		at := time.Now()
+		if seconds, err := strconv.ParseInt(status.Timestamp, 10, 64); err == nil {
+			at = time.Unix(seconds, 0)
+		}
+		err := uc.messages.UpdateStatus(ctx, status.ID, status.Status, statusError(status), at)
+		if err != nil && !errors.Is(err, domain.ErrMessageNotFound) {
+			errs = append(errs, fmt.Errorf("failed to update status of message %s: %w", status.ID, err))
+		}
+	}
+	return errors.Join(errs...)
+}
+
+// statusError describes the errors of a failed status
+func statusError(status domain.WebhookStatus) string {
+	var messages []string
+	for _, err := range status.Errors {
+		message := fmt.Sprintf("%s (code: %d)", err.Title, err.Code)
+		if err.Message != "" {
+			message = fmt.Sprintf("%s: %s (code: %d)", err.Title, err.Message, err.Code)
+		}
+		messages = append(messages, message)
+	}
+	return strings.Join(messages, "; ")
+}
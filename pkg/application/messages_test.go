@@ -0,0 +1,165 @@
+package application
+
+import (
+	"anyzzapp/pkg/domain"
+	"context"
+	"errors"
+	"testing"
+	"time"
+
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/mock"
+)
+
+// MockMessageRepository is a mock implementation of MessageRepository
+type MockMessageRepository struct {
+	mock.Mock
+}
+
+func (m *MockMessageRepository) Save(ctx context.Context, message *domain.StoredMessage) error {
+	args := m.Called(message)
+	return args.Error(0)
+}
+
+func (m *MockMessageRepository) Get(ctx context.Context, id string) (*domain.StoredMessage, error) {
+	args := m.Called(id)
+	return args.Get(0).(*domain.StoredMessage), args.Error(1)
+}
+
+func (m *MockMessageRepository) UpdateStatus(ctx context.Context, id, status, errorMessage string, at time.Time) error {
+	args := m.Called(id, status, errorMessage, at)
+	return args.Error(0)
+}
+
+func (m *MockMessageRepository) List(ctx context.Context, filter domain.MessageFilter) ([]domain.StoredMessage, error) {
+	args := m.Called(filter)
+	return args.Get(0).([]domain.StoredMessage), args.Error(1)
+}
+
+func (m *MockMessageRepository) ListConversations(ctx context.Context, filter domain.ConversationFilter) ([]domain.ConversationSummary, error) {
+	args := m.Called(filter)
+	return args.Get(0).([]domain.ConversationSummary), args.Error(1)
+}
+
+func (m *MockMessageRepository) Delete(ctx context.Context, ids ...string) (int, error) {
+	args := m.Called(ids)
+	return args.Int(0), args.Error(1)
+}
+
+func TestInboundMessage(t *testing.T) {
+	stored := inboundMessage("123456789", domain.WebhookMessage{
+		From:      "5491112345678",
+		ID:        "msg_1",
+		Timestamp: "1700000000",
+		Type:      "image",
+		Image:     &domain.WebhookMedia{ID: "media_1", MimeType: "image/jpeg", Caption: "my receipt"},
+	})
+
+	assert.Equal(t, domain.MessageDirectionInbound, stored.Direction)
+	assert.Equal(t, "contact", stored.Author)
+	assert.Equal(t, "my receipt", stored.Content)
+	assert.Equal(t, &domain.MediaReference{ID: "media_1", MimeType: "image/jpeg", Caption: "my receipt"}, stored.Media)
+	assert.Equal(t, domain.MessageStatusReceived, stored.Status)
+	assert.Equal(t, time.Unix(1700000000, 0), stored.Timestamp)
+}
+
+func TestWhatsAppUseCase_ProcessIncomingWebhook_StoresMessages(t *testing.T) {
+	mockWhatsAppRepo := &MockWhatsAppRepository{}
+	mockLLMRepo := &MockLLMRepository{}
+	messages := &MockMessageRepository{}
+	useCase := NewWhatsAppUseCase(mockWhatsAppRepo, mockLLMRepo, WithMessages(messages))
+
+	mockWhatsAppRepo.On("MarkAsRead", "123456789", "msg_123").Return(nil)
+	mockLLMRepo.On("SendMessage", "Hello").Return("Hi", nil)
+	mockWhatsAppRepo.On("SendMessage", mock.Anything).Return(&domain.SendMessageResponse{MessageID: "reply_msg_123"}, nil)
+	messages.On("Save", mock.MatchedBy(func(message *domain.StoredMessage) bool {
+		return message.ID == "msg_123" && message.Direction == domain.MessageDirectionInbound &&
+			message.Content == "Hello" && message.WaID == "5491112345678"
+	})).Return(nil)
+	messages.On("Save", mock.MatchedBy(func(message *domain.StoredMessage) bool {
+		return message.ID == "reply_msg_123" && message.Direction == domain.MessageDirectionOutbound &&
+			message.Author == "bot" && message.Content == "Hi" && message.Status == domain.MessageStatusSent
+	})).Return(nil)
+
+	err := useCase.ProcessIncomingWebhook(context.Background(), commandWebhook("Hello"))
+
+	assert.NoError(t, err)
+	messages.AssertExpectations(t)
+}
+
+func TestWhatsAppUseCase_ProcessIncomingWebhook_StoresNonTextMessages(t *testing.T) {
+	mockWhatsAppRepo := &MockWhatsAppRepository{}
+	messages := &MockMessageRepository{}
+	useCase := NewWhatsAppUseCase(mockWhatsAppRepo, &MockLLMRepository{}, WithMessages(messages))
+
+	webhook := commandWebhook("")
+	webhook.Entry[0].Changes[0].Value.Messages[0].Text = nil
+	webhook.Entry[0].Changes[0].Value.Messages[0].Type = "audio"
+	webhook.Entry[0].Changes[0].Value.Messages[0].Audio = &domain.WebhookMedia{ID: "media_1", MimeType: "audio/ogg"}
+	mockWhatsAppRepo.On("MarkAsRead", "123456789", "msg_123").Return(nil)
+	messages.On("Save", mock.MatchedBy(func(message *domain.StoredMessage) bool {
+		return message.Type == "audio" && message.Media != nil && message.Media.ID == "media_1"
+	})).Return(nil)
+
+	err := useCase.ProcessIncomingWebhook(context.Background(), webhook)
+
+	assert.NoError(t, err)
+	messages.AssertExpectations(t)
+	mockWhatsAppRepo.AssertNotCalled(t, "SendMessage", mock.Anything)
+}
+
+func TestWhatsAppUseCase_ProcessIncomingWebhook_StorageFailureIsNotFatal(t *testing.T) {
+	mockWhatsAppRepo := &MockWhatsAppRepository{}
+	mockLLMRepo := &MockLLMRepository{}
+	messages := &MockMessageRepository{}
+	useCase := NewWhatsAppUseCase(mockWhatsAppRepo, mockLLMRepo, WithMessages(messages))
+
+	mockWhatsAppRepo.On("MarkAsRead", "123456789", "msg_123").Return(nil)
+	mockLLMRepo.On("SendMessage", "Hello").Return("Hi", nil)
+	mockWhatsAppRepo.On("SendMessage", mock.Anything).Return(&domain.SendMessageResponse{MessageID: "reply_msg_123"}, nil)
+	messages.On("Save", mock.Anything).Return(errors.New("disk full"))
+
+	err := useCase.ProcessIncomingWebhook(context.Background(), commandWebhook("Hello"))
+
+	assert.NoError(t, err)
+	mockWhatsAppRepo.AssertExpectations(t)
+}
+
+func TestWhatsAppUseCase_ProcessIncomingWebhook_Statuses(t *testing.T) {
+	messages := &MockMessageRepository{}
+	useCase := NewWhatsAppUseCase(&MockWhatsAppRepository{}, &MockLLMRepository{}, WithMessages(messages))
+
+	webhook := commandWebhook("")
+	webhook.Entry[0].Changes[0].Value.Messages = nil
+	webhook.Entry[0].Changes[0].Value.Statuses = []domain.WebhookStatus{
+		{ID: "wamid.1", Status: "delivered", Timestamp: "1700000000"},
+		{ID: "wamid.2", Status: "failed", Timestamp: "1700000001", Errors: []domain.WebhookError{
+			{Code: 131047, Title: "Re-engagement message", Message: "More than 24 hours have passed"},
+		}},
+	}
+	messages.On("UpdateStatus", "wamid.1", "delivered", "", time.Unix(1700000000, 0)).Return(domain.ErrMessageNotFound)
+	messages.On("UpdateStatus", "wamid.2", "failed",
+		"Re-engagement message: More than 24 hours have passed (code: 131047)", time.Unix(1700000001, 0)).Return(nil)
+
+	err := useCase.ProcessIncomingWebhook(context.Background(), webhook)
+
+	assert.NoError(t, err)
+	messages.AssertExpectations(t)
+}
+
+func TestWhatsAppUseCase_SendMessage_StoresMessage(t *testing.T) {
+	mockWhatsAppRepo := &MockWhatsAppRepository{}
+	messages := &MockMessageRepository{}
+	useCase := NewWhatsAppUseCase(mockWhatsAppRepo, &MockLLMRepository{}, WithMessages(messages))
+
+	message := domain.Message{PhoneNumberID: "123456789", To: "+5491112345678", Content: "Your order shipped"}
+	mockWhatsAppRepo.On("SendMessage", message).Return(&domain.SendMessageResponse{MessageID: "wamid.1"}, nil)
+	messages.On("Save", mock.MatchedBy(func(stored *domain.StoredMessage) bool {
+		return stored.ID == "wamid.1" && stored.Author == "api" && stored.WaID == "5491112345678" && stored.Type == "text"
+	})).Return(nil)
+
+	_, err := useCase.SendMessage(context.Background(), message)
+
+	assert.NoError(t, err)
+	messages.AssertExpectations(t)
+}
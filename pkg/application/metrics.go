@@ -0,0 +1,14 @@
+package application
+
+import "time"
+
+// nopMetrics discards every metric, it is used when no Metrics is configured
+type nopMetrics struct{}
+
+func (nopMetrics) IncInboundMessage(string)                              {}
+func (nopMetrics) IncAutoReply(string)                                   {}
+func (nopMetrics) ObserveLLMLatency(string, time.Duration)               {}
+func (nopMetrics) ObserveGraphAPILatency(string, time.Duration)          {}
+func (nopMetrics) IncGraphAPIError(string, string)                       {}
+func (nopMetrics) ObserveHTTPRequest(string, string, int, time.Duration) {}
+func (nopMetrics) SetQueueDepth(string, int)                             {}
@@ -0,0 +1,69 @@
+package application
+
+import (
+	"anyzzapp/pkg/domain"
+	"anyzzapp/pkg/domain/phone"
+	"anyzzapp/pkg/logging"
+	"context"
+	"fmt"
+	"strings"
+	"time"
+
+	"github.com/rs/zerolog/log"
+)
+
+// changeSubscription adds the contact to the suppression list or removes it, and confirms the change
+func (uc *WhatsAppUseCase) changeSubscription(ctx context.Context, key domain.ConversationKey, inboundID string,
+	action domain.OptOutAction, keywords domain.OptOutKeywords, content string, maxHistory int) error {
+	contact := suppressionContact(key.WaID)
+	reply := keywords.OptInReply
+	var err error
+	if action == domain.OptOutStop {
+		reply = keywords.OptOutReply
+		err = uc.suppressions.Add(ctx, &domain.Suppression{
+			PhoneNumberID: key.PhoneNumberID,
+			Contact:       contact,
+			Keyword:       strings.TrimSpace(content),
+			At:            time.Now(),
+		})
+	} else {
+		err = uc.suppressions.Remove(ctx, key.PhoneNumberID, contact)
+	}
+	if err != nil {
+		log.Ctx(ctx).Error().Err(err).Str("wa_id", logging.Phone(key.WaID)).Msg("failed to update the suppression list")
+		return fmt.Errorf("failed to update the suppression list: %w", err)
+	}
+	log.Ctx(ctx).Info().
+		Str("phone_number_id", key.PhoneNumberID).
+		Str("wa_id", logging.Phone(key.WaID)).
+		Str("language", keywords.Language).
+		Bool("opted_out", action == domain.OptOutStop).
+		Msg("contact changed its subscription")
+
+	if reply == "" {
+		return nil
+	}
+	return uc.reply(ctx, key, inboundID, reply, maxHistory)
+}
+
+// suppressed reports whether the contact opted out of the messages of the business phone number
+func (uc *WhatsAppUseCase) suppressed(ctx context.Context, phoneNumberID, waID string) (bool, error) {
+	if uc.suppressions == nil {
+		return false, nil
+	}
+	suppression, err := uc.suppressions.Get(ctx, phoneNumberID, suppressionContact(waID))
+	if err != nil {
+		return false, fmt.Errorf("failed to read the suppression list: %w", err)
+	}
+	return suppression != nil, nil
+}
+
+// suppressionContact identifies the contact in the suppression list by the number messages are sent to,
+// so that a wa_id and the recipient of /send given without its country quirks are the same contact
+func suppressionContact(waID string) string {
+	waID = strings.TrimPrefix(waID, "+")
+	if address, err := phone.SendAddress(waID); err == nil {
+		return address
+	}
+	return waID
+}
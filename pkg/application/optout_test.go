@@ -0,0 +1,130 @@
+package application
+
+import (
+	"anyzzapp/pkg/domain"
+	"context"
+	"errors"
+	"testing"
+
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/mock"
+	"github.com/stretchr/testify/require"
+)
+
+// suppressionList keeps the suppressions in a map
+type suppressionList map[string]domain.Suppression
+
+func (l suppressionList) Get(ctx context.Context, phoneNumberID, contact string) (*domain.Suppression, error) {
+	if suppression, ok := l[phoneNumberID+"-"+contact]; ok {
+		return &suppression, nil
+	}
+	return nil, nil
+}
+
+func (l suppressionList) Add(ctx context.Context, suppression *domain.Suppression) error {
+	l[suppression.PhoneNumberID+"-"+suppression.Contact] = *suppression
+	return nil
+}
+
+func (l suppressionList) Remove(ctx context.Context, phoneNumberID, contact string) error {
+	delete(l, phoneNumberID+"-"+contact)
+	return nil
+}
+
+// failingSuppressions can't be read
+type failingSuppressions struct{ suppressionList }
+
+func (failingSuppressions) Get(ctx context.Context, phoneNumberID, contact string) (*domain.Suppression, error) {
+	return nil, errors.New("disk failure")
+}
+
+var testOptOut = []domain.OptOutKeywords{
+	{Language: "en", OptOut: []string{"STOP"}, OptIn: []string{"START"}, OptOutReply: "Unsubscribed.", OptInReply: "Subscribed."},
+	{Language: "es", OptOut: []string{"BAJA"}, OptIn: []string{"ALTA"}, OptOutReply: "Baja confirmada."},
+}
+
+func TestMatchOptOut(t *testing.T) {
+	tests := []struct {
+		content  string
+		action   domain.OptOutAction
+		language string
+	}{
+		{"STOP", domain.OptOutStop, "en"},
+		{"  stop! ", domain.OptOutStop, "en"},
+		{"¡Baja!", domain.OptOutStop, "es"},
+		{"start", domain.OptOutStart, "en"},
+		{"Alta.", domain.OptOutStart, "es"},
+		{"please stop", domain.OptOutNone, ""},
+		{"", domain.OptOutNone, ""},
+	}
+	for _, tt := range tests {
+		t.Run(tt.content, func(t *testing.T) {
+			action, keywords := domain.MatchOptOut(testOptOut, tt.content)
+			assert.Equal(t, tt.action, action)
+			assert.Equal(t, tt.language, keywords.Language)
+		})
+	}
+}
+
+func TestWhatsAppUseCase_OptOut(t *testing.T) {
+	mockWhatsAppRepo := &MockWhatsAppRepository{}
+	mockLLMRepo := &MockLLMRepository{}
+	suppressions := suppressionList{}
+	useCase := NewWhatsAppUseCase(mockWhatsAppRepo, mockLLMRepo, WithOptOut(suppressions, testOptOut))
+	ctx := context.Background()
+
+	mockWhatsAppRepo.On("MarkAsRead", "123456789", "msg_123").Return(nil)
+	mockWhatsAppRepo.On("SendMessage", mock.MatchedBy(func(message domain.Message) bool {
+		return message.Content == "Baja confirmada."
+	})).Return(&domain.SendMessageResponse{MessageID: "confirmation"}, nil).Once()
+
+	require.NoError(t, useCase.ProcessIncomingWebhook(ctx, commandWebhook("baja")))
+	// The wa_id is listed as replies are sent to it, without the 9 of Argentina
+	require.Contains(t, suppressions, "123456789-541112345678")
+	assert.Equal(t, "baja", suppressions["123456789-541112345678"].Keyword)
+
+	// Opted-out contacts get no auto-replies
+	require.NoError(t, useCase.ProcessIncomingWebhook(ctx, commandWebhook("Hello")))
+	mockLLMRepo.AssertNotCalled(t, "SendMessage", mock.Anything)
+
+	// Only transactional messages are sent to them
+	promotion := domain.Message{PhoneNumberID: "123456789", To: "541112345678", Content: "Sale!", MessageType: "text"}
+	_, err := useCase.SendMessage(ctx, promotion)
+	assert.ErrorIs(t, err, domain.ErrContactOptedOut)
+
+	receipt := domain.Message{PhoneNumberID: "123456789", To: "5491112345678", Content: "Your receipt", MessageType: "text", Transactional: true}
+	mockWhatsAppRepo.On("SendMessage", receipt).Return(&domain.SendMessageResponse{MessageID: "receipt"}, nil).Once()
+	_, err = useCase.SendMessage(ctx, receipt)
+	assert.NoError(t, err)
+	mockWhatsAppRepo.AssertExpectations(t)
+}
+
+func TestWhatsAppUseCase_OptIn(t *testing.T) {
+	mockWhatsAppRepo := &MockWhatsAppRepository{}
+	mockLLMRepo := &MockLLMRepository{}
+	suppressions := suppressionList{"123456789-541112345678": {PhoneNumberID: "123456789", Contact: "541112345678"}}
+	useCase := NewWhatsAppUseCase(mockWhatsAppRepo, mockLLMRepo, WithOptOut(suppressions, testOptOut))
+
+	mockWhatsAppRepo.On("MarkAsRead", "123456789", "msg_123").Return(nil)
+	mockWhatsAppRepo.On("SendMessage", mock.MatchedBy(func(message domain.Message) bool {
+		return message.Content == "Subscribed."
+	})).Return(&domain.SendMessageResponse{MessageID: "confirmation"}, nil).Once()
+
+	require.NoError(t, useCase.ProcessIncomingWebhook(context.Background(), commandWebhook("START")))
+
+	assert.Empty(t, suppressions)
+	mockLLMRepo.AssertNotCalled(t, "SendMessage", mock.Anything)
+	mockWhatsAppRepo.AssertExpectations(t)
+}
+
+func TestWhatsAppUseCase_SendMessage_SuppressionListUnavailable(t *testing.T) {
+	mockWhatsAppRepo := &MockWhatsAppRepository{}
+	useCase := NewWhatsAppUseCase(mockWhatsAppRepo, &MockLLMRepository{},
+		WithOptOut(failingSuppressions{suppressionList{}}, testOptOut))
+
+	_, err := useCase.SendMessage(context.Background(), domain.Message{PhoneNumberID: "123456789", To: "541112345678", Content: "Sale!"})
+
+	// Nothing is sent when the list can't be checked
+	assert.ErrorContains(t, err, "suppression list")
+	mockWhatsAppRepo.AssertNotCalled(t, "SendMessage", mock.Anything)
+}
@@ -0,0 +1,308 @@
+package application
+
+import (
+	"anyzzapp/pkg/domain"
+	"anyzzapp/pkg/logging"
+	"context"
+	"crypto/rand"
+	"encoding/hex"
+	"fmt"
+	"strconv"
+	"sync"
+	"time"
+
+	"github.com/rs/zerolog/log"
+)
+
+// outboxRetention is how long sent and failed messages are kept, so that the reply to a webhook Meta redelivers,
+// for up to 7 days, is not sent twice
+const outboxRetention = 7 * 24 * time.Hour
+
+// outboxListener is told when a message of the outbox is sent, or given up on
+type outboxListener interface {
+	outboxSent(ctx context.Context, entry *domain.OutboxMessage)
+	outboxFailed(ctx context.Context, entry *domain.OutboxMessage)
+}
+
+// Outbox stores every outbound message before it is sent, so that a message is sent at least once even if the
+// process stops, and sends again the messages that failed for a temporary reason
+type Outbox struct {
+	entries       domain.OutboxRepository
+	whatsappRepo  domain.WhatsAppRepository
+	retrySchedule []time.Duration
+	now           func() time.Time
+	listener      outboxListener
+	// depth is told the number of messages waiting to be sent again when it changes, it is set by WithOutbox
+	depth func(pending int)
+	// inFlight keeps a message from being sent by a request and the dispatcher at the same time
+	mu       sync.Mutex
+	inFlight map[string]bool
+	pending  int
+}
+
+// NewOutbox creates a new instance of Outbox. A message failing for a temporary reason is sent again after each
+// delay of the schedule, then it is given up on.
+func NewOutbox(entries domain.OutboxRepository, whatsappRepo domain.WhatsAppRepository,
+	retrySchedule []time.Duration) *Outbox {
+	return &Outbox{
+		entries:       entries,
+		whatsappRepo:  whatsappRepo,
+		retrySchedule: retrySchedule,
+		now:           time.Now,
+		inFlight:      make(map[string]bool),
+	}
+}
+
+// Send stores the message and sends it. A message that was already added with the same ID is not sent again:
+// the response of the first one is returned, or the queued status while it waits to be sent. A temporary
+// failure also answers the queued status, the message is sent later by the dispatcher. The error of the WhatsApp
+// API is returned with the entry failed, any other error is a failure of the storage.
+func (o *Outbox) Send(ctx context.Context, entry *domain.OutboxMessage) (*domain.SendMessageResponse, error) {
+	// Claimed before it is added, so that the dispatcher does not send it too
+	if !o.claim(entry.ID) {
+		// The dispatcher is sending it
+		return &domain.SendMessageResponse{Status: domain.SendStatusQueued}, nil
+	}
+	defer o.release(entry.ID)
+
+	now := o.now()
+	entry.Status = domain.OutboxStatusPending
+	entry.CreatedAt = now
+	entry.NextAttemptAt = now
+	added, err := o.entries.Add(ctx, entry)
+	if err != nil {
+		return nil, fmt.Errorf("failed to store outbound message: %w", err)
+	}
+
+	if !added {
+		existing, err := o.entries.Get(ctx, entry.ID)
+		if err != nil {
+			return nil, fmt.Errorf("failed to get outbound message: %w", err)
+		}
+		switch {
+		case existing == nil:
+			// Deleted in between, it was sent long ago
+			return &domain.SendMessageResponse{Status: domain.SendStatusSent}, nil
+		case existing.Status == domain.OutboxStatusSent:
+			log.Ctx(ctx).Info().Str("outbox_id", entry.ID).Str("message_id", existing.MessageID).Msg("message already sent, not sending it again")
+			return &domain.SendMessageResponse{MessageID: existing.MessageID, Status: domain.SendStatusSent}, nil
+		case existing.Status == domain.OutboxStatusPending:
+			return &domain.SendMessageResponse{Status: domain.SendStatusQueued}, nil
+		}
+		// A message that was given up on is sent again from scratch
+		if err := o.entries.Save(ctx, entry); err != nil {
+			return nil, fmt.Errorf("failed to store outbound message: %w", err)
+		}
+	}
+	response, err := o.deliver(ctx, entry)
+	if entry.Status == domain.OutboxStatusPending {
+		o.setPending(func(pending int) int { return pending + 1 })
+	}
+	return response, err
+}
+
+// DispatchDue sends the messages whose next attempt is due, and returns how many were sent and failed again.
+// It also removes the sent and failed messages older than the retention.
+func (o *Outbox) DispatchDue(ctx context.Context) (sent, failed int, err error) {
+	pending, err := o.entries.List(ctx, domain.OutboxStatusPending)
+	if err != nil {
+		return 0, 0, fmt.Errorf("failed to list outbound messages: %w", err)
+	}
+	// The messages left pending, the ones not due included
+	waiting := len(pending)
+	for _, entry := range pending {
+		if ctx.Err() != nil {
+			break
+		}
+		if !entry.Due(o.now()) || !o.claim(entry.ID) {
+			continue
+		}
+		status, err := o.dispatch(ctx, entry.ID)
+		o.release(entry.ID)
+		switch {
+		case err != nil:
+			log.Ctx(ctx).Error().Err(err).Str("outbox_id", entry.ID).Msg("failed to dispatch outbound message")
+			failed++
+		case status == domain.OutboxStatusSent:
+			sent++
+			waiting--
+		case status == domain.OutboxStatusFailed:
+			failed++
+			waiting--
+		case status != "":
+			failed++
+		}
+	}
+	o.setPending(func(int) int { return waiting })
+	return sent, failed, o.purge(ctx)
+}
+
+// dispatch sends the message if it is still due, a request may have sent it since it was listed, and returns
+// its new status. The failures to send it are logged by deliver.
+func (o *Outbox) dispatch(ctx context.Context, id string) (domain.OutboxStatus, error) {
+	entry, err := o.entries.Get(ctx, id)
+	if err != nil {
+		return "", fmt.Errorf("failed to get outbound message: %w", err)
+	}
+	if entry == nil || !entry.Due(o.now()) {
+		return "", nil
+	}
+	_, _ = o.deliver(ctx, entry)
+	return entry.Status, nil
+}
+
+// deliver sends the message and records the result: sent with its message ID, pending until its next attempt
+// after a temporary failure, or failed
+func (o *Outbox) deliver(ctx context.Context, entry *domain.OutboxMessage) (*domain.SendMessageResponse, error) {
+	response, sendErr := o.whatsappRepo.SendMessage(ctx, entry.Message)
+	now := o.now()
+	entry.Attempts++
+	if sendErr == nil {
+		entry.Status = domain.OutboxStatusSent
+		entry.Error = ""
+		entry.SentAt = now
+		entry.NextAttemptAt = time.Time{}
+		if response != nil {
+			entry.MessageID = response.MessageID
+		}
+		if err := o.entries.Save(ctx, entry); err != nil {
+			// The message may be sent again after a restart
+			log.Ctx(ctx).Error().Err(err).Str("outbox_id", entry.ID).Msg("message sent but not marked as sent")
+		}
+		if o.listener != nil {
+			o.listener.outboxSent(ctx, entry)
+		}
+		if response == nil {
+			response = &domain.SendMessageResponse{Status: domain.SendStatusSent}
+		}
+		return response, nil
+	}
+
+	entry.Error = sendErr.Error()
+	entry.NextAttemptAt = time.Time{}
+	if domain.TemporarySendFailure(sendErr) {
+		entry.NextAttemptAt = nextAttempt(entry.Attempts, now, o.retrySchedule)
+	}
+	entry.Status = domain.OutboxStatusPending
+	if entry.NextAttemptAt.IsZero() {
+		entry.Status = domain.OutboxStatusFailed
+	}
+	if err := o.entries.Save(ctx, entry); err != nil {
+		log.Ctx(ctx).Error().Err(err).Str("outbox_id", entry.ID).Msg("failed to update outbound message")
+	}
+
+	if entry.Status == domain.OutboxStatusPending {
+		log.Ctx(ctx).Warn().Err(sendErr).
+			Str("outbox_id", entry.ID).
+			Str("wa_id", logging.Phone(entry.WaID)).
+			Int("attempts", entry.Attempts).
+			Time("next_attempt_at", entry.NextAttemptAt).
+			Msg("failed to send message, queued to send it again")
+		return &domain.SendMessageResponse{Status: domain.SendStatusQueued, Message: "The message will be sent again later"}, nil
+	}
+	log.Ctx(ctx).Error().Err(sendErr).
+		Str("outbox_id", entry.ID).
+		Str("wa_id", logging.Phone(entry.WaID)).
+		Int("attempts", entry.Attempts).
+		Msg("failed to send message, giving up")
+	if o.listener != nil {
+		o.listener.outboxFailed(ctx, entry)
+	}
+	return response, sendErr
+}
+
+// purge removes the sent and failed messages older than the retention
+func (o *Outbox) purge(ctx context.Context) error {
+	cutoff := o.now().Add(-outboxRetention)
+	var expired []string
+	for _, status := range []domain.OutboxStatus{domain.OutboxStatusSent, domain.OutboxStatusFailed} {
+		entries, err := o.entries.List(ctx, status)
+		if err != nil {
+			return fmt.Errorf("failed to list outbound messages: %w", err)
+		}
+		for _, entry := range entries {
+			if entry.CreatedAt.Before(cutoff) {
+				expired = append(expired, entry.ID)
+			}
+		}
+	}
+	if len(expired) == 0 {
+		return nil
+	}
+	if err := o.entries.Delete(ctx, expired...); err != nil {
+		return fmt.Errorf("failed to delete outbound messages: %w", err)
+	}
+	return nil
+}
+
+// setPending updates the number of messages waiting to be sent again, and tells depth
+func (o *Outbox) setPending(update func(pending int) int) {
+	o.mu.Lock()
+	o.pending = update(o.pending)
+	pending := o.pending
+	o.mu.Unlock()
+	if o.depth != nil {
+		o.depth(pending)
+	}
+}
+
+// claim marks the message as being sent, it reports false if it already is
+func (o *Outbox) claim(id string) bool {
+	o.mu.Lock()
+	defer o.mu.Unlock()
+	if o.inFlight[id] {
+		return false
+	}
+	o.inFlight[id] = true
+	return true
+}
+
+// release marks the message as no longer being sent
+func (o *Outbox) release(id string) {
+	o.mu.Lock()
+	defer o.mu.Unlock()
+	delete(o.inFlight, id)
+}
+
+// replyOutboxID identifies the reply to an inbound message, so that it is only sent once
+func replyOutboxID(messageID string) string {
+	return "reply:" + messageID
+}
+
+// newOutboxID returns a random identifier for a message of the author, sent with the API or by an agent
+func newOutboxID(author string) string {
+	b := make([]byte, 16)
+	if _, err := rand.Read(b); err != nil {
+		return author + ":" + strconv.FormatInt(time.Now().UnixNano(), 16)
+	}
+	return author + ":" + hex.EncodeToString(b)
+}
+
+// nextAttempt returns when to try again after the attempts, zero once every delay of the schedule was waited
+func nextAttempt(attempts int, now time.Time, retrySchedule []time.Duration) time.Time {
+	if attempts < 1 || attempts > len(retrySchedule) {
+		return time.Time{}
+	}
+	return now.Add(retrySchedule[attempts-1])
+}
+
+// RunOutbox sends the messages of the outbox that are due at start, the ones left by a previous run,
+// and then every interval, until ctx is done
+func RunOutbox(ctx context.Context, outbox *Outbox, interval time.Duration) {
+	ticker := time.NewTicker(interval)
+	defer ticker.Stop()
+	for {
+		sent, failed, err := outbox.DispatchDue(ctx)
+		switch {
+		case err != nil:
+			log.Error().Err(err).Msg("Failed to dispatch the outbox")
+		case sent > 0 || failed > 0:
+			log.Info().Int("sent", sent).Int("failed", failed).Msg("Outbox dispatched")
+		}
+		select {
+		case <-ctx.Done():
+			return
+		case <-ticker.C:
+		}
+	}
+}
@@ -0,0 +1,271 @@
+package application
+
+import (
+	"anyzzapp/pkg/domain"
+	"context"
+	"errors"
+	"sort"
+	"testing"
+	"time"
+
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/mock"
+	"github.com/stretchr/testify/require"
+)
+
+// outboxList keeps the messages of the outbox in a map
+type outboxList map[string]domain.OutboxMessage
+
+func (l outboxList) Add(ctx context.Context, message *domain.OutboxMessage) (bool, error) {
+	if _, ok := l[message.ID]; ok {
+		return false, nil
+	}
+	l[message.ID] = *message
+	return true, nil
+}
+
+func (l outboxList) Get(ctx context.Context, id string) (*domain.OutboxMessage, error) {
+	if message, ok := l[id]; ok {
+		return &message, nil
+	}
+	return nil, nil
+}
+
+func (l outboxList) Save(ctx context.Context, message *domain.OutboxMessage) error {
+	l[message.ID] = *message
+	return nil
+}
+
+func (l outboxList) List(ctx context.Context, status domain.OutboxStatus) ([]domain.OutboxMessage, error) {
+	var messages []domain.OutboxMessage
+	for _, message := range l {
+		if message.Status == status {
+			messages = append(messages, message)
+		}
+	}
+	sort.Slice(messages, func(i, j int) bool { return messages[i].ID < messages[j].ID })
+	return messages, nil
+}
+
+func (l outboxList) Delete(ctx context.Context, ids ...string) error {
+	for _, id := range ids {
+		delete(l, id)
+	}
+	return nil
+}
+
+// failingOutbox is an outbox whose storage fails
+type failingOutbox struct {
+	outboxList
+}
+
+func (failingOutbox) Add(ctx context.Context, message *domain.OutboxMessage) (bool, error) {
+	return false, errors.New("disk full")
+}
+
+// mapKeys returns the sorted keys of the outbox
+func mapKeys(entries outboxList) []string {
+	var keys []string
+	for key := range entries {
+		keys = append(keys, key)
+	}
+	sort.Strings(keys)
+	return keys
+}
+
+var (
+	rateLimited = &domain.APIError{StatusCode: 400, Code: 131056, Message: "Pair rate limit hit"}
+	invalidUser = &domain.APIError{StatusCode: 400, Code: 131026, Message: "Message undeliverable"}
+)
+
+func TestOutbox_Send(t *testing.T) {
+	mockWhatsAppRepo := &MockWhatsAppRepository{}
+	entries := outboxList{}
+	outbox := NewOutbox(entries, mockWhatsAppRepo, testRetrySchedule)
+	message := domain.Message{PhoneNumberID: "123", To: "+456", Content: "Hi", MessageType: "text"}
+
+	mockWhatsAppRepo.On("SendMessage", message).Return(&domain.SendMessageResponse{MessageID: "wamid.1", Status: "sent"}, nil).Once()
+
+	response, err := outbox.Send(context.Background(), &domain.OutboxMessage{ID: "reply:msg_1", Message: message})
+	require.NoError(t, err)
+	assert.Equal(t, "wamid.1", response.MessageID)
+	assert.Equal(t, domain.OutboxStatusSent, entries["reply:msg_1"].Status)
+	assert.Equal(t, "wamid.1", entries["reply:msg_1"].MessageID)
+	assert.Equal(t, 1, entries["reply:msg_1"].Attempts)
+
+	// The same message is not sent twice
+	response, err = outbox.Send(context.Background(), &domain.OutboxMessage{ID: "reply:msg_1", Message: message})
+	require.NoError(t, err)
+	assert.Equal(t, "wamid.1", response.MessageID)
+	mockWhatsAppRepo.AssertExpectations(t)
+}
+
+func TestOutbox_TemporaryFailure(t *testing.T) {
+	mockWhatsAppRepo := &MockWhatsAppRepository{}
+	entries := outboxList{}
+	outbox := NewOutbox(entries, mockWhatsAppRepo, testRetrySchedule)
+	now := time.Date(2024, 1, 1, 10, 0, 0, 0, time.UTC)
+	outbox.now = func() time.Time { return now }
+	ctx := context.Background()
+
+	mockWhatsAppRepo.On("SendMessage", mock.Anything).Return(&domain.SendMessageResponse{Status: "failed"}, rateLimited).Twice()
+	mockWhatsAppRepo.On("SendMessage", mock.Anything).Return(&domain.SendMessageResponse{MessageID: "wamid.1"}, nil).Once()
+
+	response, err := outbox.Send(ctx, &domain.OutboxMessage{ID: "api:1", Message: domain.Message{Content: "Hi"}})
+	require.NoError(t, err)
+	assert.Equal(t, "queued", response.Status)
+	entry := entries["api:1"]
+	assert.Equal(t, domain.OutboxStatusPending, entry.Status)
+	assert.Equal(t, now.Add(time.Minute), entry.NextAttemptAt)
+	assert.Contains(t, entry.Error, "Pair rate limit hit")
+
+	// Nothing is due yet
+	sent, failed, err := outbox.DispatchDue(ctx)
+	assert.NoError(t, err)
+	assert.Zero(t, sent+failed)
+
+	now = now.Add(time.Minute)
+	sent, failed, err = outbox.DispatchDue(ctx)
+	assert.NoError(t, err)
+	assert.Equal(t, 0, sent)
+	assert.Equal(t, 1, failed)
+	assert.Equal(t, now.Add(time.Hour), entries["api:1"].NextAttemptAt)
+
+	now = now.Add(time.Hour)
+	sent, failed, err = outbox.DispatchDue(ctx)
+	assert.NoError(t, err)
+	assert.Equal(t, 1, sent)
+	assert.Equal(t, 0, failed)
+	assert.Equal(t, domain.OutboxStatusSent, entries["api:1"].Status)
+	assert.Equal(t, "wamid.1", entries["api:1"].MessageID)
+	assert.Equal(t, 3, entries["api:1"].Attempts)
+	mockWhatsAppRepo.AssertExpectations(t)
+}
+
+func TestOutbox_PermanentFailure(t *testing.T) {
+	mockWhatsAppRepo := &MockWhatsAppRepository{}
+	entries := outboxList{}
+	outbox := NewOutbox(entries, mockWhatsAppRepo, testRetrySchedule)
+	ctx := context.Background()
+
+	mockWhatsAppRepo.On("SendMessage", mock.Anything).Return(&domain.SendMessageResponse{Status: "failed"}, invalidUser).Once()
+	mockWhatsAppRepo.On("SendMessage", mock.Anything).Return(&domain.SendMessageResponse{MessageID: "wamid.1"}, nil).Once()
+
+	_, err := outbox.Send(ctx, &domain.OutboxMessage{ID: "api:1"})
+	assert.ErrorIs(t, err, invalidUser)
+	assert.Equal(t, domain.OutboxStatusFailed, entries["api:1"].Status)
+	assert.True(t, entries["api:1"].NextAttemptAt.IsZero())
+
+	// It is not sent again on its own, only when it is sent again with the same ID
+	sent, failed, err := outbox.DispatchDue(ctx)
+	assert.NoError(t, err)
+	assert.Zero(t, sent+failed)
+	response, err := outbox.Send(ctx, &domain.OutboxMessage{ID: "api:1"})
+	assert.NoError(t, err)
+	assert.Equal(t, "wamid.1", response.MessageID)
+	assert.Equal(t, 1, entries["api:1"].Attempts)
+	mockWhatsAppRepo.AssertExpectations(t)
+}
+
+func TestOutbox_DispatchDue_Purges(t *testing.T) {
+	now := time.Date(2024, 1, 10, 10, 0, 0, 0, time.UTC)
+	entries := outboxList{
+		"old_sent":    {ID: "old_sent", Status: domain.OutboxStatusSent, CreatedAt: now.Add(-8 * 24 * time.Hour)},
+		"old_failed":  {ID: "old_failed", Status: domain.OutboxStatusFailed, CreatedAt: now.Add(-8 * 24 * time.Hour)},
+		"recent_sent": {ID: "recent_sent", Status: domain.OutboxStatusSent, CreatedAt: now.Add(-time.Hour)},
+	}
+	outbox := NewOutbox(entries, &MockWhatsAppRepository{}, testRetrySchedule)
+	outbox.now = func() time.Time { return now }
+
+	_, _, err := outbox.DispatchDue(context.Background())
+
+	assert.NoError(t, err)
+	assert.Equal(t, []string{"recent_sent"}, mapKeys(entries))
+}
+
+func TestWhatsAppUseCase_Outbox(t *testing.T) {
+	mockWhatsAppRepo := &MockWhatsAppRepository{}
+	mockLLMRepo := &MockLLMRepository{}
+	conversations := &MockConversationRepository{}
+	metrics := newCountingMetrics()
+	entries := outboxList{}
+	letters := deadLetterList{}
+	outbox := NewOutbox(entries, mockWhatsAppRepo, testRetrySchedule)
+	useCase := NewWhatsAppUseCase(mockWhatsAppRepo, mockLLMRepo,
+		WithConversations(conversations), WithMetrics(metrics), WithOutbox(outbox),
+		WithDeadLetters(letters, testRetrySchedule))
+	key := domain.ConversationKey{PhoneNumberID: "123456789", WaID: "5491112345678"}
+	ctx := context.Background()
+
+	conversations.On("Get", key).Return(&domain.Conversation{Key: key, State: domain.ConversationStateBot}, nil)
+	conversations.On("Save", mock.Anything).Return(nil)
+	mockWhatsAppRepo.On("MarkAsRead", "123456789", "msg_123").Return(nil)
+	mockLLMRepo.On("SendMessage", "Hello").Return("Hi there!", nil)
+	mockWhatsAppRepo.On("SendMessage", mock.Anything).Return(&domain.SendMessageResponse{Status: "failed"}, rateLimited).Once()
+	mockWhatsAppRepo.On("SendMessage", mock.Anything).Return(&domain.SendMessageResponse{MessageID: "reply_1"}, nil).Once()
+
+	// A reply failing for a temporary reason is queued, the message is not dead-lettered
+	assert.NoError(t, useCase.ProcessIncomingWebhook(ctx, commandWebhook("Hello")))
+	assert.Empty(t, letters)
+	require.Contains(t, entries, "reply:msg_123")
+	assert.Equal(t, domain.OutboxAuthorBot, entries["reply:msg_123"].Author)
+	assert.Empty(t, metrics.autoReplies)
+
+	// Webhooks redelivered while the reply is queued don't queue it twice
+	assert.NoError(t, useCase.ProcessIncomingWebhook(ctx, commandWebhook("Hello")))
+	assert.Len(t, entries, 1)
+
+	outbox.now = func() time.Time { return time.Now().Add(time.Minute) }
+	sent, _, err := outbox.DispatchDue(ctx)
+	assert.NoError(t, err)
+	assert.Equal(t, 1, sent)
+	assert.Equal(t, 1, metrics.autoReplies[domain.AutoReplySent])
+	// The reply is added to the history once it is sent
+	saved := conversations.Calls[len(conversations.Calls)-1].Arguments.Get(0).(*domain.Conversation)
+	last := saved.Messages[len(saved.Messages)-1]
+	assert.Equal(t, "reply_1", last.ID)
+	assert.Equal(t, "Hi there!", last.Content)
+	mockWhatsAppRepo.AssertNumberOfCalls(t, "SendMessage", 2)
+	// The reply waited in the outbox until it was sent
+	assert.Equal(t, []int{1, 0}, metrics.queueDepths(domain.QueueOutbox))
+}
+
+func TestWhatsAppUseCase_Outbox_Failures(t *testing.T) {
+	tests := []struct {
+		name       string
+		entries    domain.OutboxRepository
+		downstream bool
+	}{
+		// Meta is made to redeliver the webhook when the reply could not be stored
+		{"storage", failingOutbox{outboxList{}}, false},
+		{"whatsapp", outboxList{}, true},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			mockWhatsAppRepo := &MockWhatsAppRepository{}
+			mockLLMRepo := &MockLLMRepository{}
+			useCase := NewWhatsAppUseCase(mockWhatsAppRepo, mockLLMRepo,
+				WithOutbox(NewOutbox(tt.entries, mockWhatsAppRepo, testRetrySchedule)))
+
+			mockWhatsAppRepo.On("MarkAsRead", "123456789", "msg_123").Return(nil)
+			mockLLMRepo.On("SendMessage", "Hello").Return("Hi there!", nil)
+			mockWhatsAppRepo.On("SendMessage", mock.Anything).Return((*domain.SendMessageResponse)(nil), invalidUser).Maybe()
+
+			err := useCase.ProcessIncomingWebhook(context.Background(), commandWebhook("Hello"))
+
+			require.Error(t, err)
+			assert.Equal(t, tt.downstream, domain.IsDownstream(err))
+		})
+	}
+}
+
+func TestNextAttempt(t *testing.T) {
+	now := time.Date(2024, 1, 1, 10, 0, 0, 0, time.UTC)
+	assert.Equal(t, now.Add(time.Minute), nextAttempt(1, now, testRetrySchedule))
+	assert.Equal(t, now.Add(time.Hour), nextAttempt(2, now, testRetrySchedule))
+	assert.True(t, nextAttempt(3, now, testRetrySchedule).IsZero())
+	assert.True(t, nextAttempt(1, now, nil).IsZero())
+	assert.False(t, domain.TemporarySendFailure(invalidUser))
+	assert.True(t, domain.TemporarySendFailure(errors.New("connection reset")))
+	assert.True(t, domain.TemporarySendFailure(&domain.APIError{StatusCode: 503}))
+}
@@ -0,0 +1,367 @@
+package application
+
+import (
+	"anyzzapp/pkg/domain"
+	"anyzzapp/pkg/logging"
+	"context"
+	"crypto/rand"
+	"encoding/hex"
+	"fmt"
+	"strconv"
+	"strings"
+	"time"
+
+	"github.com/rs/zerolog/log"
+)
+
+// Audit actors
+const (
+	auditActorAdmin     = "admin"
+	auditActorRetention = "retention"
+)
+
+// PrivacyUseCase implements PrivacyUseCaseInterface
+type PrivacyUseCase struct {
+	messages      domain.MessageRepository
+	conversations domain.ConversationRepository
+	audit         domain.AuditRepository
+	settings      domain.SettingsProvider
+	deadLetters   domain.DeadLetterRepository
+	outbox        domain.OutboxRepository
+	debouncer     *Debouncer
+	now           func() time.Time
+}
+
+// PrivacyOption configures an optional collaborator of PrivacyUseCase
+type PrivacyOption func(*PrivacyUseCase)
+
+// WithPrivacyDeadLetters also deletes the dead letters, which keep the whole webhook message of the contact
+func WithPrivacyDeadLetters(letters domain.DeadLetterRepository) PrivacyOption {
+	return func(uc *PrivacyUseCase) {
+		uc.deadLetters = letters
+	}
+}
+
+// WithPrivacyOutbox also deletes the outbound messages of the outbox, which keep their text and recipient
+func WithPrivacyOutbox(entries domain.OutboxRepository) PrivacyOption {
+	return func(uc *PrivacyUseCase) {
+		uc.outbox = entries
+	}
+}
+
+// WithPrivacyDebouncer also discards the messages of the contact held by the debouncer, which would be answered
+// and recorded again after the erasure
+func WithPrivacyDebouncer(debouncer *Debouncer) PrivacyOption {
+	return func(uc *PrivacyUseCase) {
+		uc.debouncer = debouncer
+	}
+}
+
+// NewPrivacyUseCase creates a new instance of PrivacyUseCase, the retention of each tenant is read from settings
+func NewPrivacyUseCase(messages domain.MessageRepository, conversations domain.ConversationRepository,
+	audit domain.AuditRepository, settings domain.SettingsProvider, opts ...PrivacyOption) domain.PrivacyUseCaseInterface {
+	uc := &PrivacyUseCase{
+		messages:      messages,
+		conversations: conversations,
+		audit:         audit,
+		settings:      settings,
+		now:           time.Now,
+	}
+	for _, opt := range opts {
+		opt(uc)
+	}
+	return uc
+}
+
+// EraseContact deletes the messages, media references, delivery statuses, conversation memory, dead letters and
+// outbound messages of a contact, in every tenant or only in the one given, and discards its messages waiting to be
+// answered. A dry run only counts them.
+func (uc *PrivacyUseCase) EraseContact(ctx context.Context, waID, phoneNumberID string, dryRun bool) (*domain.ErasureReport, error) {
+	waID = strings.TrimPrefix(waID, "+")
+	if waID == "" {
+		return nil, fmt.Errorf("wa_id is required")
+	}
+	report := &domain.ErasureReport{DryRun: dryRun, Subject: domain.SubjectHash(waID)}
+
+	var ids []string
+	err := eachMessage(ctx, uc.messages, domain.MessageFilter{PhoneNumberID: phoneNumberID, WaID: waID}, func(message domain.StoredMessage) error {
+		ids = append(ids, message.ID)
+		if message.Media != nil {
+			report.MediaFiles++
+		}
+		return nil
+	})
+	if err != nil {
+		return nil, err
+	}
+	conversations, err := uc.conversationsWhere(func(conversation domain.Conversation) bool {
+		return conversation.Key.WaID == waID && (phoneNumberID == "" || conversation.Key.PhoneNumberID == phoneNumberID)
+	})
+	if err != nil {
+		return nil, err
+	}
+	letters, err := uc.deadLettersWhere(ctx, func(letter domain.DeadLetter) bool {
+		return letter.Message.From == waID && (phoneNumberID == "" || letter.PhoneNumberID == phoneNumberID)
+	})
+	if err != nil {
+		return nil, err
+	}
+	// The messages waiting to be sent to the contact are not sent either
+	outbound, err := uc.outboxWhere(ctx, func(entry domain.OutboxMessage) bool {
+		return entry.WaID == waID && (phoneNumberID == "" || entry.Message.PhoneNumberID == phoneNumberID)
+	}, domain.OutboxStatusPending, domain.OutboxStatusSent, domain.OutboxStatusFailed)
+	if err != nil {
+		return nil, err
+	}
+	report.Messages = len(ids)
+	report.Conversations = len(conversations)
+	report.DeadLetters = len(letters)
+	report.OutboxMessages = len(outbound)
+	// Held messages are discarded first, answering them would record the contact again
+	if uc.debouncer != nil {
+		report.HeldMessages = uc.debouncer.discard(func(key domain.ConversationKey) bool {
+			return key.WaID == waID && (phoneNumberID == "" || key.PhoneNumberID == phoneNumberID)
+		}, dryRun)
+	}
+	if dryRun {
+		return report, nil
+	}
+
+	if err := uc.delete(ctx, ids, conversations, letters, outbound); err != nil {
+		return nil, err
+	}
+	record := uc.auditRecord(ctx, domain.AuditActionContactErased, auditActorAdmin, report.Messages, report.Conversations)
+	record.Subject = report.Subject
+	if err := uc.audit.Append(ctx, record); err != nil {
+		return nil, fmt.Errorf("contact erased but the audit record failed: %w", err)
+	}
+	report.AuditID = record.ID
+	log.Ctx(ctx).Info().
+		Str("subject", report.Subject).
+		Int("messages", report.Messages).
+		Int("conversations", report.Conversations).
+		Int("dead_letters", report.DeadLetters).
+		Int("outbox_messages", report.OutboxMessages).
+		Int("held_messages", report.HeldMessages).
+		Msg("Contact data erased")
+	return report, nil
+}
+
+// ApplyRetention deletes the messages, conversations, dead letters and outbound messages already sent or given up on
+// older than the retention of their tenant. A dry run only counts them. The messages held by the debouncer are left
+// alone, they are never older than its maximum wait.
+func (uc *PrivacyUseCase) ApplyRetention(ctx context.Context, dryRun bool) (*domain.RetentionReport, error) {
+	now := uc.now()
+	cutoffs := make(map[string]time.Time)
+	// expired reports whether data of the tenant last changed at is past its retention
+	expired := func(phoneNumberID string, at time.Time) bool {
+		cutoff, ok := cutoffs[phoneNumberID]
+		if !ok {
+			if retention := uc.retention(phoneNumberID); retention > 0 {
+				cutoff = now.Add(-retention)
+			}
+			cutoffs[phoneNumberID] = cutoff
+		}
+		return !cutoff.IsZero() && at.Before(cutoff)
+	}
+
+	report := &domain.RetentionReport{DryRun: dryRun, MessagesByTenant: make(map[string]int)}
+	var ids []string
+	err := eachMessage(ctx, uc.messages, domain.MessageFilter{Until: now}, func(message domain.StoredMessage) error {
+		if expired(message.PhoneNumberID, message.Timestamp) {
+			ids = append(ids, message.ID)
+			report.MessagesByTenant[message.PhoneNumberID]++
+		}
+		return nil
+	})
+	if err != nil {
+		return nil, err
+	}
+	conversations, err := uc.conversationsWhere(func(conversation domain.Conversation) bool {
+		return expired(conversation.Key.PhoneNumberID, conversation.UpdatedAt)
+	})
+	if err != nil {
+		return nil, err
+	}
+	letters, err := uc.deadLettersWhere(ctx, func(letter domain.DeadLetter) bool {
+		return expired(letter.PhoneNumberID, letter.FirstFailedAt)
+	})
+	if err != nil {
+		return nil, err
+	}
+	// The outbox keeps them for a week otherwise, to recognize the webhooks Meta redelivers
+	outbound, err := uc.outboxWhere(ctx, func(entry domain.OutboxMessage) bool {
+		return expired(entry.Message.PhoneNumberID, entry.CreatedAt)
+	}, domain.OutboxStatusSent, domain.OutboxStatusFailed)
+	if err != nil {
+		return nil, err
+	}
+	report.Messages = len(ids)
+	report.Conversations = len(conversations)
+	report.DeadLetters = len(letters)
+	report.OutboxMessages = len(outbound)
+	if dryRun || (report.Messages == 0 && report.Conversations == 0 && report.DeadLetters == 0 && report.OutboxMessages == 0) {
+		return report, nil
+	}
+
+	if err := uc.delete(ctx, ids, conversations, letters, outbound); err != nil {
+		return nil, err
+	}
+	record := uc.auditRecord(ctx, domain.AuditActionRetentionPurge, auditActorRetention, report.Messages, report.Conversations)
+	if err := uc.audit.Append(ctx, record); err != nil {
+		return nil, fmt.Errorf("retention applied but the audit record failed: %w", err)
+	}
+	report.AuditID = record.ID
+	return report, nil
+}
+
+// AuditLog returns the most recent audit records first
+func (uc *PrivacyUseCase) AuditLog(ctx context.Context, limit int) ([]domain.AuditRecord, error) {
+	records, err := uc.audit.List(ctx, pageSize(limit))
+	if err != nil {
+		return nil, fmt.Errorf("failed to list audit records: %w", err)
+	}
+	return records, nil
+}
+
+// retention returns how long the data of the tenant is kept, forever when 0
+func (uc *PrivacyUseCase) retention(phoneNumberID string) time.Duration {
+	if uc.settings == nil {
+		return 0
+	}
+	return uc.settings.TenantSettings(phoneNumberID).Retention
+}
+
+// conversationsWhere returns the keys of the conversations in memory passing the predicate
+func (uc *PrivacyUseCase) conversationsWhere(keep func(domain.Conversation) bool) ([]domain.ConversationKey, error) {
+	if uc.conversations == nil {
+		return nil, nil
+	}
+	conversations, err := uc.conversations.List("")
+	if err != nil {
+		return nil, fmt.Errorf("failed to list conversations: %w", err)
+	}
+	var keys []domain.ConversationKey
+	for _, conversation := range conversations {
+		if keep(conversation) {
+			keys = append(keys, conversation.Key)
+		}
+	}
+	return keys, nil
+}
+
+// deadLettersWhere returns the IDs of the dead letters passing the predicate
+func (uc *PrivacyUseCase) deadLettersWhere(ctx context.Context, keep func(domain.DeadLetter) bool) ([]string, error) {
+	if uc.deadLetters == nil {
+		return nil, nil
+	}
+	letters, err := uc.deadLetters.List(ctx)
+	if err != nil {
+		return nil, fmt.Errorf("failed to list dead letters: %w", err)
+	}
+	var ids []string
+	for _, letter := range letters {
+		if keep(letter) {
+			ids = append(ids, letter.ID)
+		}
+	}
+	return ids, nil
+}
+
+// outboxWhere returns the IDs of the outbound messages in the statuses passing the predicate
+func (uc *PrivacyUseCase) outboxWhere(ctx context.Context, keep func(domain.OutboxMessage) bool,
+	statuses ...domain.OutboxStatus) ([]string, error) {
+	if uc.outbox == nil {
+		return nil, nil
+	}
+	var ids []string
+	for _, status := range statuses {
+		entries, err := uc.outbox.List(ctx, status)
+		if err != nil {
+			return nil, fmt.Errorf("failed to list outbound messages: %w", err)
+		}
+		for _, entry := range entries {
+			if keep(entry) {
+				ids = append(ids, entry.ID)
+			}
+		}
+	}
+	return ids, nil
+}
+
+// delete removes the messages, in batches, the conversations, the dead letters and the outbound messages
+func (uc *PrivacyUseCase) delete(ctx context.Context, ids []string, conversations []domain.ConversationKey,
+	letters, outbound []string) error {
+	for start := 0; start < len(ids); start += exportBatchSize {
+		end := min(start+exportBatchSize, len(ids))
+		if _, err := uc.messages.Delete(ctx, ids[start:end]...); err != nil {
+			return fmt.Errorf("failed to delete messages: %w", err)
+		}
+	}
+	for _, key := range conversations {
+		if err := uc.conversations.Delete(key); err != nil {
+			return fmt.Errorf("failed to delete conversation: %w", err)
+		}
+	}
+	for _, id := range letters {
+		if err := uc.deadLetters.Delete(ctx, id); err != nil {
+			return fmt.Errorf("failed to delete dead letter: %w", err)
+		}
+	}
+	if len(outbound) > 0 {
+		if err := uc.outbox.Delete(ctx, outbound...); err != nil {
+			return fmt.Errorf("failed to delete outbound messages: %w", err)
+		}
+	}
+	return nil
+}
+
+// auditRecord creates the record of an operation run for the request in ctx, if any
+func (uc *PrivacyUseCase) auditRecord(ctx context.Context, action, actor string, messages, conversations int) *domain.AuditRecord {
+	return &domain.AuditRecord{
+		ID:            newAuditID(),
+		Action:        action,
+		Actor:         actor,
+		RequestID:     logging.RequestID(ctx),
+		Messages:      messages,
+		Conversations: conversations,
+		At:            uc.now(),
+	}
+}
+
+// newAuditID returns a random identifier for an audit record
+func newAuditID() string {
+	b := make([]byte, 16)
+	if _, err := rand.Read(b); err != nil {
+		return strconv.FormatInt(time.Now().UnixNano(), 16)
+	}
+	return hex.EncodeToString(b)
+}
+
+// RunRetention applies the retention at start and then every interval, until ctx is done.
+// A dry run only logs what would be deleted.
+func RunRetention(ctx context.Context, privacy domain.PrivacyUseCaseInterface, interval time.Duration, dryRun bool) {
+	ticker := time.NewTicker(interval)
+	defer ticker.Stop()
+	for {
+		report, err := privacy.ApplyRetention(ctx, dryRun)
+		switch {
+		case err != nil:
+			log.Error().Err(err).Msg("Failed to apply the retention")
+		case report.Messages > 0 || report.Conversations > 0 || report.DeadLetters > 0 || report.OutboxMessages > 0:
+			log.Info().
+				Bool("dry_run", dryRun).
+				Int("messages", report.Messages).
+				Int("conversations", report.Conversations).
+				Int("dead_letters", report.DeadLetters).
+				Int("outbox_messages", report.OutboxMessages).
+				Interface("messages_by_tenant", report.MessagesByTenant).
+				Msg("Retention applied")
+		}
+		select {
+		case <-ctx.Done():
+			return
+		case <-ticker.C:
+		}
+	}
+}
@@ -0,0 +1,237 @@
+package application
+
+import (
+	"anyzzapp/pkg/domain"
+	"anyzzapp/pkg/logging"
+	"context"
+	"errors"
+	"maps"
+	"slices"
+	"testing"
+	"time"
+
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/mock"
+	"github.com/stretchr/testify/require"
+)
+
+// deletingMessages records the deleted messages of a fixed set
+type deletingMessages struct {
+	storedMessages
+	deleted []string
+}
+
+func (d *deletingMessages) Delete(ctx context.Context, ids ...string) (int, error) {
+	d.deleted = append(d.deleted, ids...)
+	return len(ids), nil
+}
+
+// recordedAudit keeps the appended audit records
+type recordedAudit struct {
+	records []domain.AuditRecord
+	err     error
+}
+
+func (a *recordedAudit) Append(ctx context.Context, record *domain.AuditRecord) error {
+	a.records = append(a.records, *record)
+	return a.err
+}
+
+func (a *recordedAudit) List(ctx context.Context, limit int) ([]domain.AuditRecord, error) {
+	return a.records, nil
+}
+
+func privacyMessages() *deletingMessages {
+	now := time.Now()
+	return &deletingMessages{storedMessages: storedMessages{
+		{ID: "old-1", PhoneNumberID: "111", WaID: "456", Timestamp: now.Add(-60 * 24 * time.Hour),
+			Media: &domain.MediaReference{ID: "media_1"}},
+		{ID: "old-2", PhoneNumberID: "222", WaID: "789", Timestamp: now.Add(-40 * 24 * time.Hour)},
+		{ID: "new-1", PhoneNumberID: "111", WaID: "456", Timestamp: now.Add(-time.Hour)},
+		{ID: "new-2", PhoneNumberID: "222", WaID: "456", Timestamp: now.Add(-time.Hour)},
+	}}
+}
+
+// privacyDeadLetters are the dead letters of contact 456 in both tenants and of contact 789
+func privacyDeadLetters() deadLetterList {
+	now := time.Now()
+	return deadLetterList{
+		"old-1": {ID: "old-1", PhoneNumberID: "111", Message: domain.WebhookMessage{ID: "old-1", From: "456"},
+			FirstFailedAt: now.Add(-60 * 24 * time.Hour)},
+		"old-2": {ID: "old-2", PhoneNumberID: "222", Message: domain.WebhookMessage{ID: "old-2", From: "789"},
+			FirstFailedAt: now.Add(-40 * 24 * time.Hour)},
+		"new-2": {ID: "new-2", PhoneNumberID: "222", Message: domain.WebhookMessage{ID: "new-2", From: "456"},
+			FirstFailedAt: now.Add(-time.Hour)},
+	}
+}
+
+// privacyOutbox are outbound messages to contact 456 in both tenants and to contact 789
+func privacyOutbox() outboxList {
+	now := time.Now()
+	return outboxList{
+		"old-1": {ID: "old-1", Message: domain.Message{PhoneNumberID: "111"}, WaID: "456", Status: domain.OutboxStatusSent,
+			CreatedAt: now.Add(-31 * 24 * time.Hour)},
+		"old-2": {ID: "old-2", Message: domain.Message{PhoneNumberID: "111"}, WaID: "789", Status: domain.OutboxStatusPending,
+			CreatedAt: now.Add(-31 * 24 * time.Hour)},
+		"new-1": {ID: "new-1", Message: domain.Message{PhoneNumberID: "111"}, WaID: "456", Status: domain.OutboxStatusPending,
+			CreatedAt: now},
+		"new-2": {ID: "new-2", Message: domain.Message{PhoneNumberID: "222"}, WaID: "456", Status: domain.OutboxStatusFailed,
+			CreatedAt: now},
+	}
+}
+
+func TestPrivacyUseCase_EraseContact(t *testing.T) {
+	messages := privacyMessages()
+	conversations := &MockConversationRepository{}
+	audit := &recordedAudit{}
+	letters := privacyDeadLetters()
+	outbox := privacyOutbox()
+	useCase := NewPrivacyUseCase(messages, conversations, audit, nil, WithPrivacyDeadLetters(letters), WithPrivacyOutbox(outbox))
+	ctx := logging.WithRequestID(context.Background(), "req-1")
+
+	conversations.On("List", domain.ConversationState("")).Return([]domain.Conversation{
+		{Key: domain.ConversationKey{PhoneNumberID: "111", WaID: "456"}},
+		{Key: domain.ConversationKey{PhoneNumberID: "111", WaID: "789"}},
+	}, nil)
+	conversations.On("Delete", domain.ConversationKey{PhoneNumberID: "111", WaID: "456"}).Return(nil)
+
+	report, err := useCase.EraseContact(ctx, "+456", "", false)
+
+	require.NoError(t, err)
+	assert.Equal(t, []string{"old-1", "new-1", "new-2"}, messages.deleted)
+	assert.Equal(t, 3, report.Messages)
+	assert.Equal(t, 1, report.MediaFiles)
+	assert.Equal(t, 1, report.Conversations)
+	// The dead letters keep the whole webhook message
+	assert.Equal(t, 2, report.DeadLetters)
+	assert.Equal(t, []string{"old-2"}, slices.Sorted(maps.Keys(letters)))
+	// The outbound messages keep their text, the ones waiting to be sent are not sent either
+	assert.Equal(t, 3, report.OutboxMessages)
+	assert.Equal(t, []string{"old-2"}, mapKeys(outbox))
+	assert.Equal(t, domain.SubjectHash("456"), report.Subject)
+	require.Len(t, audit.records, 1)
+	record := audit.records[0]
+	assert.Equal(t, report.AuditID, record.ID)
+	assert.Equal(t, domain.AuditActionContactErased, record.Action)
+	assert.Equal(t, "admin", record.Actor)
+	assert.Equal(t, "req-1", record.RequestID)
+	assert.NotContains(t, record.Subject, "456")
+	conversations.AssertExpectations(t)
+}
+
+func TestPrivacyUseCase_EraseContact_DryRunAndTenant(t *testing.T) {
+	messages := privacyMessages()
+	conversations := &MockConversationRepository{}
+	audit := &recordedAudit{}
+	letters := privacyDeadLetters()
+	useCase := NewPrivacyUseCase(messages, conversations, audit, nil, WithPrivacyDeadLetters(letters))
+	conversations.On("List", domain.ConversationState("")).Return([]domain.Conversation{}, nil)
+
+	report, err := useCase.EraseContact(context.Background(), "456", "222", true)
+
+	require.NoError(t, err)
+	assert.True(t, report.DryRun)
+	assert.Equal(t, 1, report.Messages)
+	assert.Equal(t, 1, report.DeadLetters)
+	assert.Len(t, letters, 3)
+	assert.Empty(t, report.AuditID)
+	assert.Empty(t, messages.deleted)
+	assert.Empty(t, audit.records)
+	conversations.AssertNotCalled(t, "Delete", mock.Anything)
+}
+
+func TestPrivacyUseCase_EraseContact_HeldMessages(t *testing.T) {
+	conversations := &MockConversationRepository{}
+	debouncer := NewDebouncer(time.Hour, time.Hour)
+	useCase := NewPrivacyUseCase(privacyMessages(), conversations, &recordedAudit{}, nil, WithPrivacyDebouncer(debouncer))
+	conversations.On("List", domain.ConversationState("")).Return([]domain.Conversation{}, nil)
+	contact := domain.ConversationKey{PhoneNumberID: "111", WaID: "456"}
+	other := domain.ConversationKey{PhoneNumberID: "111", WaID: "789"}
+	debouncer.add(context.Background(), contact, domain.WebhookMessage{ID: "held-1"})
+	debouncer.add(context.Background(), contact, domain.WebhookMessage{ID: "held-2"})
+	debouncer.add(context.Background(), other, domain.WebhookMessage{ID: "held-3"})
+
+	report, err := useCase.EraseContact(context.Background(), "456", "", true)
+	require.NoError(t, err)
+	assert.Equal(t, 2, report.HeldMessages)
+
+	// The messages held are not answered, which would record the contact again
+	report, err = useCase.EraseContact(context.Background(), "456", "", false)
+	require.NoError(t, err)
+	assert.Equal(t, 2, report.HeldMessages)
+	assert.Nil(t, debouncer.take(contact))
+	assert.Len(t, debouncer.take(other), 1)
+}
+
+func TestPrivacyUseCase_EraseContact_AuditFailure(t *testing.T) {
+	conversations := &MockConversationRepository{}
+	useCase := NewPrivacyUseCase(privacyMessages(), conversations, &recordedAudit{err: errors.New("disk full")}, nil)
+	conversations.On("List", domain.ConversationState("")).Return([]domain.Conversation{}, nil)
+
+	_, err := useCase.EraseContact(context.Background(), "456", "", false)
+
+	assert.ErrorContains(t, err, "audit record failed")
+}
+
+// tenantRetention keeps the data of tenant 111 for 30 days and of the others for 50 days
+type tenantRetention struct{}
+
+func (tenantRetention) TenantSettings(phoneNumberID string) domain.TenantSettings {
+	if phoneNumberID == "111" {
+		return domain.TenantSettings{Retention: 30 * 24 * time.Hour}
+	}
+	return domain.TenantSettings{Retention: 50 * 24 * time.Hour}
+}
+
+func TestPrivacyUseCase_ApplyRetention(t *testing.T) {
+	messages := privacyMessages()
+	conversations := &MockConversationRepository{}
+	audit := &recordedAudit{}
+	letters := privacyDeadLetters()
+	outbox := privacyOutbox()
+	useCase := NewPrivacyUseCase(messages, conversations, audit, tenantRetention{},
+		WithPrivacyDeadLetters(letters), WithPrivacyOutbox(outbox))
+	stale := domain.ConversationKey{PhoneNumberID: "222", WaID: "789"}
+
+	conversations.On("List", domain.ConversationState("")).Return([]domain.Conversation{
+		{Key: stale, UpdatedAt: time.Now().Add(-51 * 24 * time.Hour)},
+		{Key: domain.ConversationKey{PhoneNumberID: "222", WaID: "456"}, UpdatedAt: time.Now()},
+	}, nil)
+	conversations.On("Delete", stale).Return(nil)
+
+	dryRun, err := useCase.ApplyRetention(context.Background(), true)
+	require.NoError(t, err)
+	assert.Equal(t, 1, dryRun.Messages)
+	assert.Equal(t, map[string]int{"111": 1}, dryRun.MessagesByTenant)
+	assert.Equal(t, 1, dryRun.DeadLetters)
+	assert.Empty(t, messages.deleted)
+
+	report, err := useCase.ApplyRetention(context.Background(), false)
+	require.NoError(t, err)
+	// old-2 is 40 days old, within the 50 days of its tenant
+	assert.Equal(t, []string{"old-1"}, messages.deleted)
+	assert.Equal(t, 1, report.Conversations)
+	assert.Equal(t, 1, report.DeadLetters)
+	assert.Equal(t, []string{"new-2", "old-2"}, slices.Sorted(maps.Keys(letters)))
+	// The sent and failed messages past the retention of their tenant are deleted, the pending ones are still to be sent
+	assert.Equal(t, 1, report.OutboxMessages)
+	assert.Equal(t, []string{"new-1", "new-2", "old-2"}, mapKeys(outbox))
+	require.Len(t, audit.records, 1)
+	assert.Equal(t, domain.AuditActionRetentionPurge, audit.records[0].Action)
+	assert.Equal(t, "retention", audit.records[0].Actor)
+	conversations.AssertExpectations(t)
+}
+
+func TestPrivacyUseCase_ApplyRetention_Disabled(t *testing.T) {
+	messages := privacyMessages()
+	audit := &recordedAudit{}
+	useCase := NewPrivacyUseCase(messages, nil, audit, staticSettings{})
+
+	report, err := useCase.ApplyRetention(context.Background(), false)
+
+	require.NoError(t, err)
+	assert.Zero(t, report.Messages)
+	assert.Empty(t, messages.deleted)
+	// Nothing deleted, nothing to audit
+	assert.Empty(t, audit.records)
+}
@@ -0,0 +1,60 @@
+package application
+
+import (
+	"anyzzapp/pkg/domain"
+	"sync"
+	"time"
+)
+
+// rateWindow is the period the rate limit counts the auto-replies in
+const rateWindow = time.Minute
+
+// rateLimiter counts the auto-replies sent to each contact over a sliding window
+type rateLimiter struct {
+	mu        sync.Mutex
+	sent      map[domain.ConversationKey][]time.Time
+	lastSweep time.Time
+}
+
+// newRateLimiter creates a new instance of rateLimiter
+func newRateLimiter() *rateLimiter {
+	return &rateLimiter{sent: make(map[domain.ConversationKey][]time.Time)}
+}
+
+// Allow reports whether the contact may get another auto-reply and counts it if so.
+// A limit of 0 or less means unlimited.
+func (l *rateLimiter) Allow(key domain.ConversationKey, limit int, now time.Time) bool {
+	if l == nil || limit <= 0 {
+		return true
+	}
+	l.mu.Lock()
+	defer l.mu.Unlock()
+
+	// Forget the contacts that stopped writing so the map doesn't grow forever
+	if now.Sub(l.lastSweep) > rateWindow {
+		for k, times := range l.sent {
+			if len(recent(times, now)) == 0 {
+				delete(l.sent, k)
+			}
+		}
+		l.lastSweep = now
+	}
+
+	times := recent(l.sent[key], now)
+	if len(times) >= limit {
+		l.sent[key] = times
+		return false
+	}
+	l.sent[key] = append(times, now)
+	return true
+}
+
+// recent drops the times older than the window, they are sorted oldest first
+func recent(times []time.Time, now time.Time) []time.Time {
+	for i, t := range times {
+		if now.Sub(t) < rateWindow {
+			return times[i:]
+		}
+	}
+	return nil
+}
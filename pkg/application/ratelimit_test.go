@@ -0,0 +1,43 @@
+package application
+
+import (
+	"testing"
+	"time"
+
+	"github.com/stretchr/testify/assert"
+)
+
+func TestRateLimiter_Allow(t *testing.T) {
+	limiter := newRateLimiter()
+	now := time.Now()
+
+	assert.True(t, limiter.Allow(testKey, 2, now))
+	assert.True(t, limiter.Allow(testKey, 2, now.Add(10*time.Second)))
+	assert.False(t, limiter.Allow(testKey, 2, now.Add(20*time.Second)))
+
+	// The first reply leaves the window
+	assert.True(t, limiter.Allow(testKey, 2, now.Add(61*time.Second)))
+}
+
+func TestRateLimiter_Unlimited(t *testing.T) {
+	limiter := newRateLimiter()
+	now := time.Now()
+
+	for i := 0; i < 100; i++ {
+		assert.True(t, limiter.Allow(testKey, 0, now))
+	}
+	assert.Empty(t, limiter.sent)
+}
+
+func TestRateLimiter_ForgetsIdleContacts(t *testing.T) {
+	limiter := newRateLimiter()
+	now := time.Now()
+
+	assert.True(t, limiter.Allow(testKey, 1, now))
+	other := testKey
+	other.WaID = "5491100000000"
+	assert.True(t, limiter.Allow(other, 1, now.Add(2*time.Minute)))
+
+	assert.Len(t, limiter.sent, 1)
+	assert.Contains(t, limiter.sent, other)
+}
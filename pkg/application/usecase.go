@@ -2,28 +2,149 @@ package application
 
 import (
 	"anyzzapp/pkg/domain"
+	"anyzzapp/pkg/domain/phone"
+	"anyzzapp/pkg/logging"
+	"context"
+	"errors"
 	"fmt"
+	"slices"
+	"sort"
+	"strings"
+	"time"
 
 	"github.com/rs/zerolog/log"
+	"go.opentelemetry.io/otel"
+	"go.opentelemetry.io/otel/attribute"
+	"go.opentelemetry.io/otel/codes"
+	"go.opentelemetry.io/otel/trace"
 )
 
+// tracer creates the spans of the use cases
+var tracer = otel.Tracer("anyzzapp/pkg/application")
+
 // WhatsAppUseCase implements WhatsAppUseCaseInterface
 type WhatsAppUseCase struct {
-	whatsappRepo domain.WhatsAppRepository
-	llmRepo      domain.LLMRepository
+	whatsappRepo  domain.WhatsAppRepository
+	llmRepo       domain.LLMRepository
+	conversations domain.ConversationRepository
+	commands      *CommandRouter
+	metrics       domain.Metrics
+	settings      domain.SettingsProvider
+	llmBackends   map[string]domain.LLMRepository
+	messages      domain.MessageRepository
+	suppressions  domain.SuppressionRepository
+	optOut        []domain.OptOutKeywords
+	limiter       *rateLimiter
+	deadLetters   domain.DeadLetterRepository
+	retrySchedule []time.Duration
+	outbox        *Outbox
+	executor      *KeyedExecutor
+	debouncer     *Debouncer
+}
+
+// Option configures an optional collaborator of WhatsAppUseCase
+type Option func(*WhatsAppUseCase)
+
+// WithConversations sets the conversation memory used to keep per-contact state
+func WithConversations(conversations domain.ConversationRepository) Option {
+	return func(uc *WhatsAppUseCase) {
+		uc.conversations = conversations
+	}
+}
+
+// WithCommandRouter sets the router that answers in-chat commands before the LLM
+func WithCommandRouter(commands *CommandRouter) Option {
+	return func(uc *WhatsAppUseCase) {
+		uc.commands = commands
+	}
+}
+
+// WithMetrics sets where inbound messages and auto-replies are counted
+func WithMetrics(metrics domain.Metrics) Option {
+	return func(uc *WhatsAppUseCase) {
+		uc.metrics = metrics
+	}
+}
+
+// WithSettings sets where the persona, LLM backend, allowlist and limits of each tenant are read from
+func WithSettings(settings domain.SettingsProvider) Option {
+	return func(uc *WhatsAppUseCase) {
+		uc.settings = settings
+	}
+}
+
+// WithLLMBackends sets the named LLM backends tenants can use instead of the default one
+func WithLLMBackends(backends map[string]domain.LLMRepository) Option {
+	return func(uc *WhatsAppUseCase) {
+		uc.llmBackends = backends
+	}
+}
+
+// WithMessages sets the store recording every message received and sent, with its delivery status
+func WithMessages(messages domain.MessageRepository) Option {
+	return func(uc *WhatsAppUseCase) {
+		uc.messages = messages
+	}
+}
+
+// WithOptOut sets the keywords contacts opt out and back in with, and the list of the contacts that opted out
+func WithOptOut(suppressions domain.SuppressionRepository, keywords []domain.OptOutKeywords) Option {
+	return func(uc *WhatsAppUseCase) {
+		uc.suppressions = suppressions
+		uc.optOut = keywords
+	}
+}
+
+// WithDeadLetters keeps the messages that failed to be answered because the LLM or the WhatsApp API failed,
+// to retry them after each delay of the schedule
+func WithDeadLetters(deadLetters domain.DeadLetterRepository, retrySchedule []time.Duration) Option {
+	return func(uc *WhatsAppUseCase) {
+		uc.deadLetters = deadLetters
+		uc.retrySchedule = retrySchedule
+	}
+}
+
+// WithOutbox stores every auto-reply and message sent with the API in the outbox before it is sent
+func WithOutbox(outbox *Outbox) Option {
+	return func(uc *WhatsAppUseCase) {
+		uc.outbox = outbox
+		outbox.listener = uc
+		outbox.depth = func(pending int) { uc.recorder().SetQueueDepth(domain.QueueOutbox, pending) }
+	}
+}
+
+// WithKeyedExecutor processes the messages of different contacts concurrently, and the ones of a contact in order
+func WithKeyedExecutor(executor *KeyedExecutor) Option {
+	return func(uc *WhatsAppUseCase) {
+		uc.executor = executor
+		executor.depth = func(pending int) { uc.recorder().SetQueueDepth(domain.QueueProcessing, pending) }
+	}
+}
+
+// WithDebouncer answers the text messages a contact sends within the window of each other with a single prompt
+func WithDebouncer(debouncer *Debouncer) Option {
+	return func(uc *WhatsAppUseCase) {
+		uc.debouncer = debouncer
+		debouncer.flush = uc.flushExpired
+	}
 }
 
 // NewWhatsAppUseCase creates a new instance of WhatsAppUseCase
 func NewWhatsAppUseCase(whatsappRepo domain.WhatsAppRepository,
-	llmRepo domain.LLMRepository) domain.WhatsAppUseCaseInterface {
-	return &WhatsAppUseCase{
+	llmRepo domain.LLMRepository, opts ...Option) domain.WhatsAppUseCaseInterface {
+	uc := &WhatsAppUseCase{
 		whatsappRepo: whatsappRepo,
 		llmRepo:      llmRepo,
+		limiter:      newRateLimiter(),
+	}
+	for _, opt := range opts {
+		opt(uc)
 	}
+	return uc
 }
 
 // SendMessage handles the business logic for sending a message
-func (uc *WhatsAppUseCase) SendMessage(message domain.Message) (*domain.SendMessageResponse, error) {
+func (uc *WhatsAppUseCase) SendMessage(ctx context.Context, message domain.Message) (*domain.SendMessageResponse, error) {
 	// Validate input
 	if message.PhoneNumberID == "" {
 		return nil, fmt.Errorf("phone number ID is required")
@@ -34,80 +155,435 @@ func (uc *WhatsAppUseCase) SendMessage(message domain.Message) (*domain.SendMess
 	if message.Content == "" {
 		return nil, fmt.Errorf("message content is required")
 	}
+	// Contacts that opted out only get transactional messages
+	if !message.Transactional {
+		suppressed, err := uc.suppressed(ctx, message.PhoneNumberID, message.To)
+		if err != nil {
+			return nil, err
+		}
+		if suppressed {
+			return nil, domain.ErrContactOptedOut
+		}
+	}
+	waID := strings.TrimPrefix(message.To, "+")
+	if uc.outbox != nil {
+		response, err := uc.outbox.Send(ctx, &domain.OutboxMessage{
+			ID:      newOutboxID(domain.OutboxAuthorAPI),
+			Message: message,
+			WaID:    waID,
+			Author:  domain.OutboxAuthorAPI,
+		})
+		if err != nil {
+			return response, fmt.Errorf("failed to send message: %w", err)
+		}
+		return response, nil
+	}
 	// Send message through WhatsApp API
-	response, err := uc.whatsappRepo.SendMessage(message)
+	response, err := uc.whatsappRepo.SendMessage(ctx, message)
 	if err != nil {
 		return response, fmt.Errorf("failed to send message: %w", err)
 	}
+	if response != nil {
+		storeMessage(ctx, uc.messages, outboundMessage(message, waID, domain.OutboxAuthorAPI, response.MessageID))
+	}
 	return response, nil
 }
 
 // ProcessIncomingWebhook processes incoming webhook data from WhatsApp
-func (uc *WhatsAppUseCase) ProcessIncomingWebhook(webhook *domain.WebhookRequest) error {
+func (uc *WhatsAppUseCase) ProcessIncomingWebhook(ctx context.Context, webhook *domain.WebhookRequest) error {
 	if webhook == nil {
 		return fmt.Errorf("webhook data cannot be nil")
 	}
+	ctx, span := tracer.Start(ctx, "ProcessIncomingWebhook")
+	defer span.End()
 
-	// Process each entry in the webhook
+	// Every message is processed on its own, a failure does not keep the rest of the batch from being answered
+	var errs []error
 	for _, entry := range webhook.Entry {
 		for _, change := range entry.Changes {
 			// Process incoming messages
-			log.Debug().Msgf("message received: %v - metadata phone number id: %s", change.Value.Messages, change.Value.Metadata.PhoneNumberID)
+			log.Ctx(ctx).Debug().
+				Int("messages", len(change.Value.Messages)).
+				Str("phone_number_id", change.Value.Metadata.PhoneNumberID).
+				Msg("webhook change received")
 
-			if err := uc.processMessages(change.Value.Messages, change.Value.Metadata.PhoneNumberID); err != nil {
-				return fmt.Errorf("failed to process messages: %w", err)
+			if err := uc.processMessages(ctx, change.Value.Messages, change.Value.Metadata.PhoneNumberID); err != nil {
+				errs = append(errs, err)
+			}
+			if err := uc.processStatuses(ctx, change.Value.Statuses); err != nil {
+				log.Ctx(ctx).Warn().Err(err).Msg("failed to store message statuses")
 			}
 		}
 	}
+	if err := errors.Join(errs...); err != nil {
+		span.RecordError(err)
+		span.SetStatus(codes.Error, err.Error())
+		return fmt.Errorf("failed to process messages: %w", err)
+	}
 	return nil
 }
 
-// processMessages handles incoming messages
-func (uc *WhatsAppUseCase) processMessages(messages []domain.WebhookMessage, phoneNumberID string) error {
-	for _, msg := range messages {
-		// Extract message content based on type
-		var content string
-		var err error
+// processMessages handles incoming messages, returning the failures of all of them joined. With an executor the
+// messages of different contacts are processed concurrently, and the ones of a contact in the order of their timestamps.
+func (uc *WhatsAppUseCase) processMessages(ctx context.Context, messages []domain.WebhookMessage, phoneNumberID string) error {
+	process := func(ctx context.Context, msg domain.WebhookMessage) error {
+		err := uc.processMessage(ctx, msg, phoneNumberID)
+		if err == nil {
+			return nil
+		}
+		if domain.IsDownstream(err) {
+			uc.deadLetter(ctx, phoneNumberID, msg, err)
+		}
+		return fmt.Errorf("message %s: %w", msg.ID, err)
+	}
 
-		if msg.Text != nil {
-			content = msg.Text.Body
+	var errs []error
+	if uc.executor == nil {
+		for _, msg := range messages {
+			if err := process(ctx, msg); err != nil {
+				errs = append(errs, err)
+			}
 		}
-		// Future: handle other message types (image, audio, etc.)TODO
+		return errors.Join(errs...)
+	}
 
+	// The messages of the batch are queued in order, the first one may start before the next one is queued
+	sorted := slices.Clone(messages)
+	sort.SliceStable(sorted, func(i, j int) bool { return messageTime(sorted[i]).Before(messageTime(sorted[j])) })
+	results := make([]<-chan error, 0, len(sorted))
+	for _, msg := range sorted {
+		key := domain.ConversationKey{PhoneNumberID: phoneNumberID, WaID: msg.From}
+		done, err := uc.executor.Submit(ctx, key, messageTime(msg), func(ctx context.Context) error {
+			return process(ctx, msg)
+		})
+		if err != nil {
+			errs = append(errs, fmt.Errorf("message %s: %w", msg.ID, err))
+			continue
+		}
+		results = append(results, done)
+	}
+	for _, done := range results {
+		if err := <-done; err != nil {
+			errs = append(errs, err)
+		}
+	}
+	return errors.Join(errs...)
+}
+
+// processMessage handles a single incoming message in its own span
+func (uc *WhatsAppUseCase) processMessage(ctx context.Context, msg domain.WebhookMessage, phoneNumberID string) (err error) {
+	ctx, span := tracer.Start(ctx, "processMessage", trace.WithAttributes(
+		attribute.String("messaging.message.id", msg.ID),
+		attribute.String("anyzzapp.tenant", phoneNumberID),
+		attribute.String("anyzzapp.message.type", msg.Type),
+	))
+	defer func() {
+		if err != nil {
+			span.RecordError(err)
+			span.SetStatus(codes.Error, err.Error())
+		}
+		span.End()
+	}()
+	uc.recorder().IncInboundMessage(msg.Type)
+	storeMessage(ctx, uc.messages, inboundMessage(phoneNumberID, msg))
+
+	// Extract message content based on type
+	var content string
+
+	if msg.Text != nil {
+		content = msg.Text.Body
+	}
+	// Future: handle other message types (image, audio, etc.)TODO
+
+	key := domain.ConversationKey{PhoneNumberID: phoneNumberID, WaID: msg.From}
+	settings := uc.tenantSettings(phoneNumberID)
+	debounced := uc.debounces(msg, content)
+	if !debounced {
+		// The messages held before this one are answered first
+		if uc.debouncer != nil {
+			if err := uc.answerBurst(ctx, key, uc.debouncer.take(key)); err != nil {
+				log.Ctx(ctx).Warn().Err(err).Str("message_id", msg.ID).Msg("failed to answer the previous messages")
+			}
+		}
 		// Mark message as read
-		if err = uc.whatsappRepo.MarkAsRead(phoneNumberID, msg.ID); err != nil {
+		if err = uc.whatsappRepo.MarkAsRead(ctx, phoneNumberID, msg.ID); err != nil {
 			// Log error but don't fail the operation
-			log.Warn().Msgf("failed to mark message as read: %v\n", err)
-		}
-		// Auto-reply
-		if content != "" && msg.Type == "text" {
-			replyMessage := ""
-			// Send the question to LLM
-			if replyMessage, err = uc.llmRepo.SendMessage(content); err != nil {
-				log.Err(fmt.Errorf("failed to send message: %v\n", err))
+			log.Ctx(ctx).Warn().Err(err).Str("message_id", msg.ID).Msg("failed to mark message as read")
+		}
+	}
+	if content == "" || msg.Type != "text" {
+		return nil
+	}
+
+	conversation, err := uc.receive(key, msg, content, settings.MaxHistory)
+	if err != nil {
+		log.Ctx(ctx).Error().Err(err).Str("message_id", msg.ID).Msg("failed to store message")
+		return err
+	}
+	if debounced {
+		// Marked as read and answered with the next ones, once the window passes
+		uc.debouncer.add(ctx, key, msg)
+		return nil
+	}
+	return uc.answer(ctx, key, msg, content, conversation, settings)
+}
+
+// ReprocessMessage answers again a text message that was received and stored but failed to be answered,
+// without storing it or marking it as read again
+func (uc *WhatsAppUseCase) ReprocessMessage(ctx context.Context, phoneNumberID string, msg domain.WebhookMessage) (err error) {
+	ctx, span := tracer.Start(ctx, "ReprocessMessage", trace.WithAttributes(
+		attribute.String("messaging.message.id", msg.ID),
+		attribute.String("anyzzapp.tenant", phoneNumberID),
+	))
+	defer func() {
+		if err != nil {
+			span.RecordError(err)
+			span.SetStatus(codes.Error, err.Error())
+		}
+		span.End()
+	}()
+	if msg.Text == nil || msg.Text.Body == "" || msg.Type != "text" {
+		return nil
+	}
+	key := domain.ConversationKey{PhoneNumberID: phoneNumberID, WaID: msg.From}
+	return uc.inOrder(ctx, key, messageTime(msg), func(ctx context.Context) error {
+		conversation := &domain.Conversation{Key: key, State: domain.ConversationStateBot}
+		if uc.conversations != nil {
+			var err error
+			if conversation, err = loadConversation(uc.conversations, key); err != nil {
 				return err
 			}
-			// Send the reply
-			if _, err = uc.whatsappRepo.SendMessage(domain.Message{
-				PhoneNumberID: phoneNumberID,
-				To:            removeNine(msg.From),
-				Content:       replyMessage,
-				MessageType:   "text",
-			}); err != nil {
-				log.Err(fmt.Errorf("failed to send auto-reply: %v\n", err))
+		}
+		return uc.answer(ctx, key, msg, msg.Text.Body, conversation, uc.tenantSettings(phoneNumberID))
+	})
+}
+
+// inOrder runs fn with the other messages of the conversation, in the order of at, when there is an executor
+func (uc *WhatsAppUseCase) inOrder(ctx context.Context, key domain.ConversationKey, at time.Time, fn func(context.Context) error) error {
+	if uc.executor == nil {
+		return fn(ctx)
+	}
+	done, err := uc.executor.Submit(ctx, key, at, fn)
+	if err != nil {
+		return err
+	}
+	return <-done
+}
+
+// answer replies to a received message: opt-outs, commands and the LLM, unless the contact can't be answered
+func (uc *WhatsAppUseCase) answer(ctx context.Context, key domain.ConversationKey, msg domain.WebhookMessage, content string,
+	conversation *domain.Conversation, settings domain.TenantSettings) error {
+	// Opt-outs are honored whoever is answering the conversation
+	if action, keywords := domain.MatchOptOut(uc.optOut, content); action != domain.OptOutNone && uc.suppressions != nil {
+		return uc.changeSubscription(ctx, key, msg.ID, action, keywords, content, settings.MaxHistory)
+	}
+	// Conversations taken over by an agent are only stored
+	if conversation.State == domain.ConversationStateHuman {
+		log.Ctx(ctx).Debug().
+			Str("phone_number_id", key.PhoneNumberID).
+			Str("wa_id", logging.Phone(key.WaID)).
+			Msg("conversation is handled by a human agent, skipping auto-reply")
+		return nil
+	}
+	suppressed, err := uc.suppressed(ctx, key.PhoneNumberID, msg.From)
+	if err != nil {
+		log.Ctx(ctx).Error().Err(err).Str("message_id", msg.ID).Msg("failed to check the suppression list")
+		return err
+	}
+	if suppressed {
+		log.Ctx(ctx).Debug().Str("wa_id", logging.Phone(msg.From)).Msg("contact opted out, skipping auto-reply")
+		return nil
+	}
+	if !settings.Allows(msg.From) {
+		log.Ctx(ctx).Debug().Str("wa_id", logging.Phone(msg.From)).Msg("contact is not in the allowlist, skipping auto-reply")
+		return nil
+	}
+	if !uc.limiter.Allow(key, settings.RateLimitPerMinute, time.Now()) {
+		log.Ctx(ctx).Warn().Str("wa_id", logging.Phone(msg.From)).Msg("contact exceeded the rate limit, skipping auto-reply")
+		return nil
+	}
+
+	// In-chat commands are answered without going through the LLM
+	if uc.commands != nil && uc.commands.IsCommand(content) {
+		replyMessage, err := uc.commands.Dispatch(ctx, key, msg)
+		if err != nil {
+			log.Ctx(ctx).Error().Err(err).Str("message_id", msg.ID).Msg("failed to run command")
+			return err
+		}
+		return uc.reply(ctx, key, msg.ID, replyMessage, settings.MaxHistory)
+	}
+	// Auto-reply
+	replyMessage := ""
+	// Send the question to LLM
+	llm := uc.llmFor(settings.LLMBackend)
+	if replyMessage, err = llm.SendMessage(ctx, prompt(settings.Persona, conversation.Language, content)); err != nil {
+		log.Ctx(ctx).Error().Err(err).Str("message_id", msg.ID).Msg("failed to send message to the LLM")
+		return &domain.DownstreamError{Service: "llm", Err: err}
+	}
+	return uc.reply(ctx, key, msg.ID, replyMessage, settings.MaxHistory)
+}
+
+// receive records an inbound message in the conversation history, reopening closed conversations
+func (uc *WhatsAppUseCase) receive(key domain.ConversationKey, msg domain.WebhookMessage, content string, maxHistory int) (*domain.Conversation, error) {
+	if uc.conversations == nil {
+		return &domain.Conversation{Key: key, State: domain.ConversationStateBot}, nil
+	}
+	var received domain.Conversation
+	err := uc.conversations.Update(key, func(conversation *domain.Conversation) error {
+		if conversation.State == domain.ConversationStateClosed {
+			conversation.State = domain.ConversationStateBot
+		}
+		appendHistory(conversation, domain.ConversationMessage{
+			ID:        msg.ID,
+			Direction: "inbound",
+			Author:    "contact",
+			Content:   content,
+			Timestamp: messageTime(msg),
+		}, maxHistory)
+		received = *conversation
+		return nil
+	})
+	if err != nil {
+		return nil, fmt.Errorf("failed to save conversation: %w", err)
+	}
+	return &received, nil
+}
+
+// tenantSettings returns the current settings of the tenant, the defaults if there is no provider
+func (uc *WhatsAppUseCase) tenantSettings(phoneNumberID string) domain.TenantSettings {
+	if uc.settings == nil {
+		return domain.TenantSettings{}
+	}
+	return uc.settings.TenantSettings(phoneNumberID)
+}
+
+// llmFor returns the named LLM backend, or the default one if the name is empty or unknown
+func (uc *WhatsAppUseCase) llmFor(backend string) domain.LLMRepository {
+	if llm, ok := uc.llmBackends[backend]; ok && backend != "" {
+		return llm
+	}
+	return uc.llmRepo
+}
+
+// recorder returns the configured metrics, or one discarding everything if there is none
+func (uc *WhatsAppUseCase) recorder() domain.Metrics {
+	if uc.metrics == nil {
+		return nopMetrics{}
+	}
+	return uc.metrics
+}
+
+// reply sends a text message back to the contact that wrote to the inbound message. With an outbox, a reply that
+// failed for a temporary reason is not an error, the outbox sends it later.
+func (uc *WhatsAppUseCase) reply(ctx context.Context, key domain.ConversationKey, inboundID, content string, maxHistory int) error {
+	message := domain.Message{
+		PhoneNumberID: key.PhoneNumberID,
+		To:            sendAddress(ctx, key.WaID),
+		Content:       content,
+		MessageType:   "text",
+	}
+	if uc.outbox != nil {
+		entry := &domain.OutboxMessage{
+			ID:      replyOutboxID(inboundID),
+			Message: message,
+			WaID:    key.WaID,
+			Author:  domain.OutboxAuthorBot,
+		}
+		if _, err := uc.outbox.Send(ctx, entry); err != nil {
+			// A reply that could not be stored is not a failure of the WhatsApp API, Meta redelivers the webhook
+			if entry.Status != domain.OutboxStatusFailed {
 				return err
 			}
+			return &domain.DownstreamError{Service: "whatsapp", Err: err}
 		}
+		return nil
+	}
+	response, err := uc.whatsappRepo.SendMessage(ctx, message)
+	if err != nil {
+		uc.recorder().IncAutoReply(domain.AutoReplyFailed)
+		log.Ctx(ctx).Error().Err(err).Str("wa_id", logging.Phone(key.WaID)).Msg("failed to send auto-reply")
+		return &domain.DownstreamError{Service: "whatsapp", Err: err}
+	}
+	if response != nil {
+		uc.replied(ctx, key, message, response.MessageID, maxHistory)
+	} else {
+		uc.recorder().IncAutoReply(domain.AutoReplySent)
 	}
 	return nil
 }
 
-// removeNine for Argentinian numbers it's necessary to remove the 9 from the reception phone number to send messages to it.
-func removeNine(phoneNumber string) string {
-	// example: phoneNumber = "5491112345678"
-	if len(phoneNumber) == 13 && phoneNumber[:2] == "54" && phoneNumber[2] == '9' {
-		return phoneNumber[:2] + phoneNumber[3:]
+// replied records an auto-reply that was sent, in the message store and the conversation history
+func (uc *WhatsAppUseCase) replied(ctx context.Context, key domain.ConversationKey, message domain.Message, messageID string, maxHistory int) {
+	uc.recorder().IncAutoReply(domain.AutoReplySent)
+	storeMessage(ctx, uc.messages, outboundMessage(message, key.WaID, domain.OutboxAuthorBot, messageID))
+	if uc.conversations == nil {
+		return
+	}
+	if err := recordMessage(uc.conversations, key, domain.ConversationMessage{
+		ID:        messageID,
+		Direction: "outbound",
+		Author:    "bot",
+		Content:   message.Content,
+		Timestamp: time.Now(),
+	}, maxHistory); err != nil {
+		log.Ctx(ctx).Warn().Err(err).Str("message_id", messageID).Msg("failed to store auto-reply")
+	}
+}
+
+// outboxSent records a message of the outbox once it is sent, whenever it is: auto-replies and agent replies
+// also go to the conversation history
+func (uc *WhatsAppUseCase) outboxSent(ctx context.Context, entry *domain.OutboxMessage) {
+	key := domain.ConversationKey{PhoneNumberID: entry.Message.PhoneNumberID, WaID: entry.WaID}
+	maxHistory := uc.tenantSettings(key.PhoneNumberID).MaxHistory
+	switch entry.Author {
+	case domain.OutboxAuthorBot:
+		uc.replied(ctx, key, entry.Message, entry.MessageID, maxHistory)
+	case domain.OutboxAuthorAgent:
+		storeMessage(ctx, uc.messages, outboundMessage(entry.Message, entry.WaID, entry.Author, entry.MessageID))
+		if uc.conversations == nil {
+			return
+		}
+		if err := recordMessage(uc.conversations, key, domain.ConversationMessage{
+			ID:        entry.MessageID,
+			Direction: "outbound",
+			Author:    "agent",
+			Content:   entry.Message.Content,
+			Timestamp: time.Now(),
+		}, maxHistory); err != nil {
+			log.Ctx(ctx).Warn().Err(err).Str("message_id", entry.MessageID).Msg("failed to store agent reply")
+		}
+	default:
+		storeMessage(ctx, uc.messages, outboundMessage(entry.Message, entry.WaID, entry.Author, entry.MessageID))
+	}
+}
+
+// outboxFailed counts the auto-replies of the outbox that were given up on
+func (uc *WhatsAppUseCase) outboxFailed(ctx context.Context, entry *domain.OutboxMessage) {
+	if entry.Author == domain.OutboxAuthorBot {
+		uc.recorder().IncAutoReply(domain.AutoReplyFailed)
+	}
+}
+
+// prompt builds the LLM prompt: the persona of the tenant and the reply language chosen with /lang, if any,
+// before the message of the contact. The conversation history is not part of it, each message is answered on its own.
+func prompt(persona, language, content string) string {
+	var parts []string
+	if persona != "" {
+		parts = append(parts, persona)
+	}
+	if language != "" {
+		parts = append(parts, fmt.Sprintf("Reply in %s.", language))
+	}
+	return strings.Join(append(parts, content), "\n\n")
+}
+
+// sendAddress returns the number a reply to the wa_id must be sent to
+func sendAddress(ctx context.Context, waID string) string {
+	address, err := phone.SendAddress(waID)
+	if err != nil {
+		// The error quotes the number, so only the masked wa_id is logged
+		log.Ctx(ctx).Warn().Str("wa_id", logging.Phone(waID)).Msg("failed to normalize wa_id, using it as is")
+		return waID
 	}
-	// result: "541112345678"
-	return phoneNumber
+	return address
 }
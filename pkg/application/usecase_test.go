@@ -2,11 +2,18 @@ package application
 
 import (
 	"anyzzapp/pkg/domain"
+	"context"
 	"errors"
+	"sync"
 	"testing"
 
 	"github.com/stretchr/testify/assert"
 	"github.com/stretchr/testify/mock"
+	"go.opentelemetry.io/otel"
+	"go.opentelemetry.io/otel/attribute"
+	"go.opentelemetry.io/otel/codes"
+	sdktrace "go.opentelemetry.io/otel/sdk/trace"
+	"go.opentelemetry.io/otel/sdk/trace/tracetest"
 )
 
 // MockWhatsAppRepository is a mock implementation of WhatsAppRepository
@@ -14,12 +21,12 @@ type MockWhatsAppRepository struct {
 	mock.Mock
 }
 
-func (m *MockWhatsAppRepository) SendMessage(message domain.Message) (*domain.SendMessageResponse, error) {
+func (m *MockWhatsAppRepository) SendMessage(ctx context.Context, message domain.Message) (*domain.SendMessageResponse, error) {
 	args := m.Called(message)
 	return args.Get(0).(*domain.SendMessageResponse), args.Error(1)
 }
 
-func (m *MockWhatsAppRepository) MarkAsRead(phoneNumberID, messageID string) error {
+func (m *MockWhatsAppRepository) MarkAsRead(ctx context.Context, phoneNumberID, messageID string) error {
 	args := m.Called(phoneNumberID, messageID)
 	return args.Error(0)
 }
@@ -29,7 +36,7 @@ type MockLLMRepository struct {
 	mock.Mock
 }
 
-func (m *MockLLMRepository) SendMessage(prompt string) (string, error) {
+func (m *MockLLMRepository) SendMessage(ctx context.Context, prompt string) (string, error) {
 	args := m.Called(prompt)
 	return args.String(0), args.Error(1)
 }
@@ -65,7 +72,7 @@ func TestWhatsAppUseCase_SendMessage_Success(t *testing.T) {
 
 	mockWhatsAppRepo.On("SendMessage", message).Return(expectedResponse, nil)
 
-	result, err := useCase.SendMessage(message)
+	result, err := useCase.SendMessage(context.Background(), message)
 
 	assert.NoError(t, err)
 	assert.Equal(t, expectedResponse, result)
@@ -87,7 +94,7 @@ func TestWhatsAppUseCase_SendMessage_EmptyPhoneNumberID(t *testing.T) {
 		MessageType:   "text",
 	}
 
-	result, err := useCase.SendMessage(message)
+	result, err := useCase.SendMessage(context.Background(), message)
 
 	assert.Error(t, err)
 	assert.Nil(t, result)
@@ -109,7 +116,7 @@ func TestWhatsAppUseCase_SendMessage_EmptyTo(t *testing.T) {
 		MessageType:   "text",
 	}
 
-	result, err := useCase.SendMessage(message)
+	result, err := useCase.SendMessage(context.Background(), message)
 
 	assert.Error(t, err)
 	assert.Nil(t, result)
@@ -131,7 +138,7 @@ func TestWhatsAppUseCase_SendMessage_EmptyContent(t *testing.T) {
 		MessageType:   "text",
 	}
 
-	result, err := useCase.SendMessage(message)
+	result, err := useCase.SendMessage(context.Background(), message)
 
 	assert.Error(t, err)
 	assert.Nil(t, result)
@@ -156,7 +163,7 @@ func TestWhatsAppUseCase_SendMessage_RepositoryError(t *testing.T) {
 	expectedError := errors.New("API connection failed")
 	mockWhatsAppRepo.On("SendMessage", message).Return((*domain.SendMessageResponse)(nil), expectedError)
 
-	result, err := useCase.SendMessage(message)
+	result, err := useCase.SendMessage(context.Background(), message)
 
 	assert.Error(t, err)
 	assert.Nil(t, result)
@@ -207,7 +214,7 @@ func TestWhatsAppUseCase_ProcessIncomingWebhook_Success(t *testing.T) {
 	expectedLLMResponse := "I'm doing well, thank you!"
 	expectedReplyMessage := domain.Message{
 		PhoneNumberID: "123456789",
-		To:            "541112345678", // removeNine applied
+		To:            "541112345678", // Argentine 9 removed
 		Content:       expectedLLMResponse,
 		MessageType:   "text",
 	}
@@ -220,7 +227,7 @@ func TestWhatsAppUseCase_ProcessIncomingWebhook_Success(t *testing.T) {
 	mockLLMRepo.On("SendMessage", "Hello, how are you?").Return(expectedLLMResponse, nil)
 	mockWhatsAppRepo.On("SendMessage", expectedReplyMessage).Return(expectedSendResponse, nil)
 
-	err := useCase.ProcessIncomingWebhook(webhook)
+	err := useCase.ProcessIncomingWebhook(context.Background(), webhook)
 
 	assert.NoError(t, err)
 	mockWhatsAppRepo.AssertExpectations(t)
@@ -235,7 +242,7 @@ func TestWhatsAppUseCase_ProcessIncomingWebhook_NilWebhook(t *testing.T) {
 		llmRepo:      mockLLMRepo,
 	}
 
-	err := useCase.ProcessIncomingWebhook(nil)
+	err := useCase.ProcessIncomingWebhook(context.Background(), nil)
 
 	assert.Error(t, err)
 	assert.Contains(t, err.Error(), "webhook data cannot be nil")
@@ -286,7 +293,7 @@ func TestWhatsAppUseCase_ProcessIncomingWebhook_LLMError(t *testing.T) {
 	mockWhatsAppRepo.On("MarkAsRead", "123456789", "msg_123").Return(nil)
 	mockLLMRepo.On("SendMessage", "Hello, how are you?").Return("", expectedError)
 
-	err := useCase.ProcessIncomingWebhook(webhook)
+	err := useCase.ProcessIncomingWebhook(context.Background(), webhook)
 
 	assert.Error(t, err)
 	assert.Contains(t, err.Error(), "failed to process messages")
@@ -294,38 +301,366 @@ func TestWhatsAppUseCase_ProcessIncomingWebhook_LLMError(t *testing.T) {
 	mockLLMRepo.AssertExpectations(t)
 }
 
-func TestRemoveNine(t *testing.T) {
+func TestSendAddress(t *testing.T) {
 	tests := []struct {
-		name        string
-		phoneNumber string
-		expected    string
+		name     string
+		waID     string
+		expected string
 	}{
 		{
-			name:        "Argentine number with 9",
-			phoneNumber: "5491112345678",
-			expected:    "541112345678",
+			name:     "Argentine number with 9",
+			waID:     "5491112345678",
+			expected: "541112345678",
 		},
 		{
-			name:        "Argentine number without 9",
-			phoneNumber: "541112345678",
-			expected:    "541112345678",
+			name:     "Non-Argentine number",
+			waID:     "1234567890123",
+			expected: "1234567890123",
 		},
 		{
-			name:        "Non-Argentine number",
-			phoneNumber: "1234567890123",
-			expected:    "1234567890123",
-		},
-		{
-			name:        "Short number",
-			phoneNumber: "123456789",
-			expected:    "123456789",
+			name:     "Malformed wa_id is used as is",
+			waID:     "not-a-number",
+			expected: "not-a-number",
 		},
 	}
 
 	for _, tt := range tests {
 		t.Run(tt.name, func(t *testing.T) {
-			result := removeNine(tt.phoneNumber)
-			assert.Equal(t, tt.expected, result)
+			assert.Equal(t, tt.expected, sendAddress(context.Background(), tt.waID))
 		})
 	}
 }
+
+func commandWebhook(body string) *domain.WebhookRequest {
+	return &domain.WebhookRequest{
+		Object: "whatsapp_business_account",
+		Entry: []domain.WebhookEntry{
+			{
+				ID: "entry_123",
+				Changes: []domain.WebhookChange{
+					{
+						Value: domain.WebhookValue{
+							MessagingProduct: "whatsapp",
+							Metadata: domain.WebhookMetadata{
+								PhoneNumberID: "123456789",
+							},
+							Messages: []domain.WebhookMessage{
+								{
+									From:      "5491112345678",
+									ID:        "msg_123",
+									Timestamp: "1234567890",
+									Text:      &domain.WebhookText{Body: body},
+									Type:      "text",
+								},
+							},
+						},
+						Field: "messages",
+					},
+				},
+			},
+		},
+	}
+}
+
+func TestWhatsAppUseCase_ProcessIncomingWebhook_Command(t *testing.T) {
+	mockWhatsAppRepo := &MockWhatsAppRepository{}
+	mockLLMRepo := &MockLLMRepository{}
+	commands := NewCommandRouter("/")
+	assert.NoError(t, commands.Register(Command{
+		Name: "ping",
+		Handler: func(ctx context.Context, cmd CommandContext) (string, error) {
+			return "pong", nil
+		},
+	}))
+	useCase := NewWhatsAppUseCase(mockWhatsAppRepo, mockLLMRepo, WithCommandRouter(commands))
+
+	expectedReplyMessage := domain.Message{
+		PhoneNumberID: "123456789",
+		To:            "541112345678",
+		Content:       "pong",
+		MessageType:   "text",
+	}
+
+	mockWhatsAppRepo.On("MarkAsRead", "123456789", "msg_123").Return(nil)
+	mockWhatsAppRepo.On("SendMessage", expectedReplyMessage).Return(&domain.SendMessageResponse{MessageID: "reply_msg_123"}, nil)
+
+	err := useCase.ProcessIncomingWebhook(context.Background(), commandWebhook("/ping"))
+
+	assert.NoError(t, err)
+	mockWhatsAppRepo.AssertExpectations(t)
+	mockLLMRepo.AssertNotCalled(t, "SendMessage", mock.Anything)
+}
+
+func TestWhatsAppUseCase_ProcessIncomingWebhook_ReplyLanguage(t *testing.T) {
+	mockWhatsAppRepo := &MockWhatsAppRepository{}
+	mockLLMRepo := &MockLLMRepository{}
+	conversations := &MockConversationRepository{}
+	useCase := NewWhatsAppUseCase(mockWhatsAppRepo, mockLLMRepo,
+		WithConversations(conversations), WithCommandRouter(NewCommandRouter("/")))
+
+	key := domain.ConversationKey{PhoneNumberID: "123456789", WaID: "5491112345678"}
+	conversations.On("Get", key).Return(&domain.Conversation{Key: key, State: domain.ConversationStateBot, Language: "es"}, nil)
+	conversations.On("Save", mock.Anything).Return(nil)
+	mockWhatsAppRepo.On("MarkAsRead", "123456789", "msg_123").Return(nil)
+	mockLLMRepo.On("SendMessage", "Reply in es.\n\nHello").Return("Hola", nil)
+	mockWhatsAppRepo.On("SendMessage", mock.MatchedBy(func(message domain.Message) bool {
+		return message.Content == "Hola"
+	})).Return(&domain.SendMessageResponse{MessageID: "reply_msg_123"}, nil)
+
+	err := useCase.ProcessIncomingWebhook(context.Background(), commandWebhook("Hello"))
+
+	assert.NoError(t, err)
+	mockWhatsAppRepo.AssertExpectations(t)
+	mockLLMRepo.AssertExpectations(t)
+}
+
+func TestWhatsAppUseCase_ProcessIncomingWebhook_HumanMode(t *testing.T) {
+	mockWhatsAppRepo := &MockWhatsAppRepository{}
+	mockLLMRepo := &MockLLMRepository{}
+	conversations := &MockConversationRepository{}
+	useCase := NewWhatsAppUseCase(mockWhatsAppRepo, mockLLMRepo, WithConversations(conversations))
+
+	key := domain.ConversationKey{PhoneNumberID: "123456789", WaID: "5491112345678"}
+	conversations.On("Get", key).Return(&domain.Conversation{Key: key, State: domain.ConversationStateHuman}, nil)
+	conversations.On("Save", mock.MatchedBy(func(conversation *domain.Conversation) bool {
+		return conversation.State == domain.ConversationStateHuman &&
+			len(conversation.Messages) == 1 &&
+			conversation.Messages[0].Content == "I need help" &&
+			conversation.Messages[0].Direction == "inbound"
+	})).Return(nil)
+	mockWhatsAppRepo.On("MarkAsRead", "123456789", "msg_123").Return(nil)
+
+	err := useCase.ProcessIncomingWebhook(context.Background(), commandWebhook("I need help"))
+
+	assert.NoError(t, err)
+	conversations.AssertExpectations(t)
+	mockLLMRepo.AssertNotCalled(t, "SendMessage", mock.Anything)
+	mockWhatsAppRepo.AssertNotCalled(t, "SendMessage", mock.Anything)
+}
+
+func TestWhatsAppUseCase_ProcessIncomingWebhook_ReopensClosedConversation(t *testing.T) {
+	mockWhatsAppRepo := &MockWhatsAppRepository{}
+	mockLLMRepo := &MockLLMRepository{}
+	conversations := &MockConversationRepository{}
+	useCase := NewWhatsAppUseCase(mockWhatsAppRepo, mockLLMRepo, WithConversations(conversations))
+
+	key := domain.ConversationKey{PhoneNumberID: "123456789", WaID: "5491112345678"}
+	conversations.On("Get", key).Return(&domain.Conversation{Key: key, State: domain.ConversationStateClosed}, nil)
+	conversations.On("Save", mock.MatchedBy(func(conversation *domain.Conversation) bool {
+		return conversation.State == domain.ConversationStateBot
+	})).Return(nil)
+	mockWhatsAppRepo.On("MarkAsRead", "123456789", "msg_123").Return(nil)
+	mockLLMRepo.On("SendMessage", "Hello again").Return("Welcome back", nil)
+	mockWhatsAppRepo.On("SendMessage", mock.Anything).Return(&domain.SendMessageResponse{MessageID: "reply_msg_123"}, nil)
+
+	err := useCase.ProcessIncomingWebhook(context.Background(), commandWebhook("Hello again"))
+
+	assert.NoError(t, err)
+	mockLLMRepo.AssertExpectations(t)
+	mockWhatsAppRepo.AssertExpectations(t)
+}
+
+// countingMetrics counts inbound messages and auto-replies, and keeps every depth recorded for each queue
+type countingMetrics struct {
+	nopMetrics
+	inbound     map[string]int
+	autoReplies map[string]int
+
+	mu     sync.Mutex
+	depths map[string][]int
+}
+
+func newCountingMetrics() *countingMetrics {
+	return &countingMetrics{inbound: map[string]int{}, autoReplies: map[string]int{}, depths: map[string][]int{}}
+}
+
+func (m *countingMetrics) IncInboundMessage(messageType string) { m.inbound[messageType]++ }
+func (m *countingMetrics) IncAutoReply(result string)           { m.autoReplies[result]++ }
+
+func (m *countingMetrics) SetQueueDepth(queue string, depth int) {
+	m.mu.Lock()
+	defer m.mu.Unlock()
+	m.depths[queue] = append(m.depths[queue], depth)
+}
+
+// queueDepths returns the depths recorded for the queue, the latest last
+func (m *countingMetrics) queueDepths(queue string) []int {
+	m.mu.Lock()
+	defer m.mu.Unlock()
+	return append([]int(nil), m.depths[queue]...)
+}
+
+func TestWhatsAppUseCase_ProcessIncomingWebhook_Metrics(t *testing.T) {
+	mockWhatsAppRepo := &MockWhatsAppRepository{}
+	mockLLMRepo := &MockLLMRepository{}
+	metrics := newCountingMetrics()
+	useCase := NewWhatsAppUseCase(mockWhatsAppRepo, mockLLMRepo, WithMetrics(metrics))
+
+	webhook := commandWebhook("Hello")
+	webhook.Entry[0].Changes[0].Value.Messages = append(webhook.Entry[0].Changes[0].Value.Messages,
+		domain.WebhookMessage{From: "5491112345678", ID: "msg_124", Type: "image", Image: &domain.WebhookMedia{ID: "media_1"}})
+
+	mockWhatsAppRepo.On("MarkAsRead", "123456789", mock.Anything).Return(nil)
+	mockLLMRepo.On("SendMessage", "Hello").Return("Hi", nil)
+	mockWhatsAppRepo.On("SendMessage", mock.Anything).Return(&domain.SendMessageResponse{MessageID: "reply_msg_123"}, nil)
+
+	err := useCase.ProcessIncomingWebhook(context.Background(), webhook)
+
+	assert.NoError(t, err)
+	assert.Equal(t, 1, metrics.inbound["text"])
+	assert.Equal(t, 1, metrics.inbound["image"])
+	assert.Equal(t, 1, metrics.autoReplies[domain.AutoReplySent])
+}
+
+func TestWhatsAppUseCase_ProcessIncomingWebhook_MetricsFailedReply(t *testing.T) {
+	mockWhatsAppRepo := &MockWhatsAppRepository{}
+	mockLLMRepo := &MockLLMRepository{}
+	metrics := newCountingMetrics()
+	useCase := NewWhatsAppUseCase(mockWhatsAppRepo, mockLLMRepo, WithMetrics(metrics))
+
+	mockWhatsAppRepo.On("MarkAsRead", "123456789", "msg_123").Return(nil)
+	mockLLMRepo.On("SendMessage", "Hello").Return("Hi", nil)
+	mockWhatsAppRepo.On("SendMessage", mock.Anything).Return((*domain.SendMessageResponse)(nil), errors.New("API down"))
+
+	err := useCase.ProcessIncomingWebhook(context.Background(), commandWebhook("Hello"))
+
+	assert.Error(t, err)
+	assert.Equal(t, 1, metrics.autoReplies[domain.AutoReplyFailed])
+	assert.Equal(t, 0, metrics.autoReplies[domain.AutoReplySent])
+}
+
+func TestWhatsAppUseCase_ProcessIncomingWebhook_IsolatesFailures(t *testing.T) {
+	mockWhatsAppRepo := &MockWhatsAppRepository{}
+	mockLLMRepo := &MockLLMRepository{}
+	useCase := NewWhatsAppUseCase(mockWhatsAppRepo, mockLLMRepo)
+
+	webhook := commandWebhook("Hello")
+	webhook.Entry[0].Changes[0].Value.Messages = append(webhook.Entry[0].Changes[0].Value.Messages,
+		domain.WebhookMessage{From: "5491112345678", ID: "msg_124", Type: "text", Text: &domain.WebhookText{Body: "Bye"}})
+	webhook.Entry = append(webhook.Entry, commandWebhook("Again").Entry...)
+	webhook.Entry[1].Changes[0].Value.Messages[0].ID = "msg_125"
+
+	mockWhatsAppRepo.On("MarkAsRead", "123456789", mock.Anything).Return(nil)
+	mockLLMRepo.On("SendMessage", "Hello").Return("", errors.New("LLM down"))
+	mockLLMRepo.On("SendMessage", "Bye").Return("Goodbye", nil)
+	mockLLMRepo.On("SendMessage", "Again").Return("Hi again", nil)
+	mockWhatsAppRepo.On("SendMessage", mock.MatchedBy(func(m domain.Message) bool { return m.Content == "Goodbye" })).
+		Return(&domain.SendMessageResponse{MessageID: "reply_1"}, nil)
+	mockWhatsAppRepo.On("SendMessage", mock.MatchedBy(func(m domain.Message) bool { return m.Content == "Hi again" })).
+		Return((*domain.SendMessageResponse)(nil), errors.New("API down"))
+
+	err := useCase.ProcessIncomingWebhook(context.Background(), webhook)
+
+	// The messages after a failure, and those of later entries, are still answered
+	assert.ErrorContains(t, err, "message msg_123: llm: LLM down")
+	assert.ErrorContains(t, err, "message msg_125: whatsapp: API down")
+	assert.NotContains(t, err.Error(), "msg_124")
+	assert.True(t, domain.IsDownstream(err))
+	mockLLMRepo.AssertExpectations(t)
+	mockWhatsAppRepo.AssertExpectations(t)
+}
+
+func TestWhatsAppUseCase_ProcessIncomingWebhook_LocalFailure(t *testing.T) {
+	mockWhatsAppRepo := &MockWhatsAppRepository{}
+	mockLLMRepo := &MockLLMRepository{}
+	useCase := NewWhatsAppUseCase(mockWhatsAppRepo, mockLLMRepo,
+		WithOptOut(failingSuppressions{suppressionList{}}, testOptOut))
+
+	webhook := commandWebhook("Hello")
+	webhook.Entry = append(webhook.Entry, commandWebhook("Hello").Entry...)
+	webhook.Entry[1].Changes[0].Value.Messages[0].ID = "msg_124"
+
+	mockWhatsAppRepo.On("MarkAsRead", "123456789", mock.Anything).Return(nil)
+	mockLLMRepo.On("SendMessage", "Hello").Return("", errors.New("LLM down")).Maybe()
+
+	err := useCase.ProcessIncomingWebhook(context.Background(), webhook)
+
+	// A failure of the bot itself is not a downstream one
+	assert.ErrorContains(t, err, "suppression list")
+	assert.False(t, domain.IsDownstream(err))
+}
+
+func TestWhatsAppUseCase_ProcessIncomingWebhook_Spans(t *testing.T) {
+	recorder := tracetest.NewSpanRecorder()
+	otel.SetTracerProvider(sdktrace.NewTracerProvider(sdktrace.WithSpanProcessor(recorder)))
+
+	mockWhatsAppRepo := &MockWhatsAppRepository{}
+	mockLLMRepo := &MockLLMRepository{}
+	useCase := NewWhatsAppUseCase(mockWhatsAppRepo, mockLLMRepo)
+
+	mockWhatsAppRepo.On("MarkAsRead", "123456789", "msg_123").Return(nil)
+	mockLLMRepo.On("SendMessage", "Hello").Return("", errors.New("LLM down"))
+
+	err := useCase.ProcessIncomingWebhook(context.Background(), commandWebhook("Hello"))
+	assert.Error(t, err)
+
+	spans := recorder.Ended()
+	assert.Len(t, spans, 2)
+	assert.Equal(t, "processMessage", spans[0].Name())
+	assert.Equal(t, codes.Error, spans[0].Status().Code)
+	assert.Contains(t, spans[0].Attributes(), attribute.String("messaging.message.id", "msg_123"))
+	assert.Contains(t, spans[0].Attributes(), attribute.String("anyzzapp.tenant", "123456789"))
+	assert.Contains(t, spans[0].Attributes(), attribute.String("anyzzapp.message.type", "text"))
+	assert.Equal(t, "ProcessIncomingWebhook", spans[1].Name())
+	assert.Equal(t, spans[1].SpanContext().SpanID(), spans[0].Parent().SpanID())
+}
+
+// staticSettings returns the same settings for every tenant
+type staticSettings domain.TenantSettings
+
+func (s staticSettings) TenantSettings(phoneNumberID string) domain.TenantSettings {
+	return domain.TenantSettings(s)
+}
+
+func TestWhatsAppUseCase_ProcessIncomingWebhook_PersonaAndBackend(t *testing.T) {
+	mockWhatsAppRepo := &MockWhatsAppRepository{}
+	defaultLLM := &MockLLMRepository{}
+	supportLLM := &MockLLMRepository{}
+	useCase := NewWhatsAppUseCase(mockWhatsAppRepo, defaultLLM,
+		WithSettings(staticSettings{Persona: "You are a pirate.", LLMBackend: "support"}),
+		WithLLMBackends(map[string]domain.LLMRepository{"support": supportLLM}))
+
+	mockWhatsAppRepo.On("MarkAsRead", "123456789", "msg_123").Return(nil)
+	supportLLM.On("SendMessage", "You are a pirate.\n\nHello").Return("Ahoy", nil)
+	mockWhatsAppRepo.On("SendMessage", mock.MatchedBy(func(message domain.Message) bool {
+		return message.Content == "Ahoy"
+	})).Return(&domain.SendMessageResponse{MessageID: "reply_msg_123"}, nil)
+
+	err := useCase.ProcessIncomingWebhook(context.Background(), commandWebhook("Hello"))
+
+	assert.NoError(t, err)
+	supportLLM.AssertExpectations(t)
+	defaultLLM.AssertNotCalled(t, "SendMessage", mock.Anything)
+}
+
+func TestWhatsAppUseCase_ProcessIncomingWebhook_NotInAllowlist(t *testing.T) {
+	mockWhatsAppRepo := &MockWhatsAppRepository{}
+	mockLLMRepo := &MockLLMRepository{}
+	useCase := NewWhatsAppUseCase(mockWhatsAppRepo, mockLLMRepo,
+		WithSettings(staticSettings{Allowlist: []string{"5491100000000"}}))
+
+	mockWhatsAppRepo.On("MarkAsRead", "123456789", "msg_123").Return(nil)
+
+	err := useCase.ProcessIncomingWebhook(context.Background(), commandWebhook("Hello"))
+
+	assert.NoError(t, err)
+	mockLLMRepo.AssertNotCalled(t, "SendMessage", mock.Anything)
+	mockWhatsAppRepo.AssertNotCalled(t, "SendMessage", mock.Anything)
+}
+
+func TestWhatsAppUseCase_ProcessIncomingWebhook_RateLimit(t *testing.T) {
+	mockWhatsAppRepo := &MockWhatsAppRepository{}
+	mockLLMRepo := &MockLLMRepository{}
+	useCase := NewWhatsAppUseCase(mockWhatsAppRepo, mockLLMRepo,
+		WithSettings(staticSettings{RateLimitPerMinute: 1}))
+
+	mockWhatsAppRepo.On("MarkAsRead", "123456789", "msg_123").Return(nil)
+	mockLLMRepo.On("SendMessage", "Hello").Return("Hi", nil).Once()
+	mockWhatsAppRepo.On("SendMessage", mock.Anything).Return(&domain.SendMessageResponse{MessageID: "reply_msg_123"}, nil).Once()
+
+	assert.NoError(t, useCase.ProcessIncomingWebhook(context.Background(), commandWebhook("Hello")))
+	assert.NoError(t, useCase.ProcessIncomingWebhook(context.Background(), commandWebhook("Hello")))
+
+	mockLLMRepo.AssertNumberOfCalls(t, "SendMessage", 1)
+	mockWhatsAppRepo.AssertNumberOfCalls(t, "SendMessage", 1)
+}
@@ -0,0 +1,37 @@
+package domain
+
+import "time"
+
+// DeadLetterStatus is the retry state of a dead letter
+type DeadLetterStatus string
+
+const (
+	// DeadLetterStatusPending letters are retried automatically at their next attempt
+	DeadLetterStatusPending DeadLetterStatus = "pending"
+	// DeadLetterStatusExpired letters ran out of attempts or failed for good, they wait for an operator until the
+	// retention removes them
+	DeadLetterStatusExpired DeadLetterStatus = "expired"
+)
+
+// DeadLetter is an inbound message that could not be answered because the LLM or the WhatsApp API failed,
+// kept to answer it later
+type DeadLetter struct {
+	// ID is the ID of the WhatsApp message
+	ID            string           `json:"id"`
+	PhoneNumberID string           `json:"phone_number_id"`
+	Message       WebhookMessage   `json:"message"`
+	Status        DeadLetterStatus `json:"status"`
+	// Error is the error of the last attempt
+	Error string `json:"error"`
+	// Attempts counts the failed attempts, the first processing of the webhook included
+	Attempts      int       `json:"attempts"`
+	FirstFailedAt time.Time `json:"first_failed_at"`
+	LastFailedAt  time.Time `json:"last_failed_at"`
+	// NextAttemptAt is when the message is retried automatically, never when zero
+	NextAttemptAt time.Time `json:"next_attempt_at"`
+}
+
+// Due reports whether the message is to be retried automatically at now
+func (l *DeadLetter) Due(now time.Time) bool {
+	return !l.NextAttemptAt.IsZero() && !now.Before(l.NextAttemptAt)
+}
@@ -0,0 +1,95 @@
+package domain
+
+import (
+	"errors"
+	"fmt"
+	"net/http"
+)
+
+var (
+	// ErrConversationNotFound is returned when there is no conversation for the given id
+	ErrConversationNotFound = errors.New("conversation not found")
+	// ErrConversationNotInHumanMode is returned when an agent acts on a conversation the bot is handling
+	ErrConversationNotInHumanMode = errors.New("conversation is not in human mode")
+	// ErrMessageNotFound is returned when there is no stored message with the given id
+	ErrMessageNotFound = errors.New("message not found")
+	// ErrInvalidCursor is returned when a pagination cursor was not returned by a previous page
+	ErrInvalidCursor = errors.New("invalid cursor")
+	// ErrContactOptedOut is returned when a non-transactional message is sent to a contact that opted out
+	ErrContactOptedOut = errors.New("contact opted out")
+	// ErrDeadLetterNotFound is returned when there is no dead letter with the given id
+	ErrDeadLetterNotFound = errors.New("dead letter not found")
+	// ErrDeadLetterInProgress is returned when a dead letter is already being retried
+	ErrDeadLetterInProgress = errors.New("dead letter is being retried")
+)
+
+// APIError represents an error response of the WhatsApp Graph API
+type APIError struct {
+	StatusCode int
+	Code       int
+	Message    string
+}
+
+// Error returns the error message
+func (e *APIError) Error() string {
+	if e.Message == "" {
+		return fmt.Sprintf("API error: status code %d", e.StatusCode)
+	}
+	return fmt.Sprintf("API error: %s (code: %d)", e.Message, e.Code)
+}
+
+// Temporary reports whether sending again may succeed: the rate limits and the failures of the Graph API itself
+func (e *APIError) Temporary() bool {
+	switch e.Code {
+	// Application, business account and pair rate limits
+	case 4, 80007, 130429, 131056:
+		return true
+	}
+	return e.StatusCode == http.StatusTooManyRequests || e.StatusCode >= http.StatusInternalServerError
+}
+
+// TemporarySendFailure reports whether a message that failed to be sent may be sent later: every failure other
+// than an error answered by the Graph API for the message itself, such as a network error or a timeout
+func TemporarySendFailure(err error) bool {
+	var apiErr *APIError
+	if errors.As(err, &apiErr) {
+		return apiErr.Temporary()
+	}
+	return true
+}
+
+// DownstreamError is a failure of a service the bot depends on, the LLM or the WhatsApp API, while
+// processing a message. Processing the message again later may succeed, unless TemporarySendFailure says otherwise.
+type DownstreamError struct {
+	Service string
+	Err     error
+}
+
+// Error returns the error message
+func (e *DownstreamError) Error() string {
+	return fmt.Sprintf("%s: %v", e.Service, e.Err)
+}
+
+// Unwrap returns the error of the service
+func (e *DownstreamError) Unwrap() error {
+	return e.Err
+}
+
+// IsDownstream reports whether err is a downstream failure, or only joins downstream failures
+func IsDownstream(err error) bool {
+	switch e := err.(type) {
+	case *DownstreamError:
+		return true
+	case interface{ Unwrap() []error }:
+		errs := e.Unwrap()
+		for _, err := range errs {
+			if !IsDownstream(err) {
+				return false
+			}
+		}
+		return len(errs) > 0
+	case interface{ Unwrap() error }:
+		return IsDownstream(e.Unwrap())
+	}
+	return false
+}
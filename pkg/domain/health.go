@@ -0,0 +1,33 @@
+package domain
+
+import (
+	"context"
+	"time"
+)
+
+const (
+	// HealthStatusOK means every check passed
+	HealthStatusOK = "ok"
+	// HealthStatusUnavailable means at least one check failed
+	HealthStatusUnavailable = "unavailable"
+)
+
+// HealthCheckResult is the outcome of one readiness check
+type HealthCheckResult struct {
+	Name       string `json:"name"`
+	Status     string `json:"status"`
+	Error      string `json:"error,omitempty"`
+	DurationMs int64  `json:"duration_ms"`
+}
+
+// HealthReport is the outcome of all the readiness checks
+type HealthReport struct {
+	Status    string              `json:"status"`
+	CheckedAt time.Time           `json:"checked_at"`
+	Checks    []HealthCheckResult `json:"checks"`
+}
+
+// ReadinessChecker reports whether the dependencies needed to answer messages are usable
+type ReadinessChecker interface {
+	Ready(ctx context.Context) HealthReport
+}
@@ -0,0 +1,180 @@
+package domain
+
+import (
+	"fmt"
+	"slices"
+	"sort"
+	"strings"
+	"time"
+)
+
+// ConversationSummary describes a conversation from its stored messages
+type ConversationSummary struct {
+	ID            string            `json:"id"`
+	PhoneNumberID string            `json:"phone_number_id"`
+	WaID          string            `json:"wa_id"`
+	State         ConversationState `json:"state"`
+	// MessageCount, FirstMessageAt and LastMessageAt cover the messages in the date range of the query
+	MessageCount   int            `json:"message_count"`
+	FirstMessageAt time.Time      `json:"first_message_at"`
+	LastMessageAt  time.Time      `json:"last_message_at"`
+	LastMessage    *StoredMessage `json:"last_message,omitempty"`
+}
+
+// ConversationQuery selects conversations, the zero value selects them all
+type ConversationQuery struct {
+	PhoneNumberID string
+	WaID          string
+	// Since and Until select the conversations with messages in the range, Until excluded
+	Since time.Time
+	Until time.Time
+	// State selects the conversations in the state, any state when empty
+	State ConversationState
+	// Cursor is the NextCursor of the previous page, the first page when empty
+	Cursor string
+	Limit  int
+}
+
+// ConversationFilter selects the conversations with stored messages, the zero value selects them all
+type ConversationFilter struct {
+	PhoneNumberID string
+	WaID          string
+	// Since and Until bound the message timestamps, Until excluded
+	Since time.Time
+	Until time.Time
+	// Before and BeforeID resume a listing after the conversation last active at Before with the ID BeforeID
+	Before   time.Time
+	BeforeID string
+	// Limit is the maximum number of conversations returned, no limit when 0
+	Limit int
+}
+
+// Follows reports whether the summary comes after the position the filter resumes from
+func (f ConversationFilter) Follows(summary ConversationSummary) bool {
+	if f.Before.IsZero() {
+		return true
+	}
+	return summary.LastMessageAt.Before(f.Before) ||
+		(summary.LastMessageAt.Equal(f.Before) && summary.ID > f.BeforeID)
+}
+
+// SortConversations sorts the summaries by most recent activity, the ID breaks the ties so that a listing can resume
+func SortConversations(summaries []ConversationSummary) {
+	sort.Slice(summaries, func(i, j int) bool {
+		if summaries[i].LastMessageAt.Equal(summaries[j].LastMessageAt) {
+			return summaries[i].ID < summaries[j].ID
+		}
+		return summaries[i].LastMessageAt.After(summaries[j].LastMessageAt)
+	})
+}
+
+// SummarizeConversations groups the messages, sorted oldest first, by conversation and returns the summaries the
+// filter selects, the most recently active first. The states are left empty.
+func SummarizeConversations(messages []StoredMessage, filter ConversationFilter) []ConversationSummary {
+	messageFilter := MessageFilter{PhoneNumberID: filter.PhoneNumberID, WaID: filter.WaID, Since: filter.Since, Until: filter.Until}
+	byKey := make(map[ConversationKey]*ConversationSummary)
+	var keys []ConversationKey
+	for i := range messages {
+		message := &messages[i]
+		if !messageFilter.Matches(*message) {
+			continue
+		}
+		summary, ok := byKey[message.Key()]
+		if !ok {
+			summary = &ConversationSummary{
+				ID:             message.Key().ID(),
+				PhoneNumberID:  message.PhoneNumberID,
+				WaID:           message.WaID,
+				FirstMessageAt: message.Timestamp,
+			}
+			byKey[message.Key()] = summary
+			keys = append(keys, message.Key())
+		}
+		summary.MessageCount++
+		summary.LastMessageAt = message.Timestamp
+		summary.LastMessage = message
+	}
+
+	summaries := make([]ConversationSummary, 0, len(keys))
+	for _, key := range keys {
+		if filter.Follows(*byKey[key]) {
+			summaries = append(summaries, *byKey[key])
+		}
+	}
+	SortConversations(summaries)
+	if filter.Limit > 0 && len(summaries) > filter.Limit {
+		summaries = summaries[:filter.Limit]
+	}
+	return summaries
+}
+
+// ConversationPage is a page of conversations, the most recently active first
+type ConversationPage struct {
+	Conversations []ConversationSummary `json:"conversations"`
+	// NextCursor fetches the next page, empty on the last one
+	NextCursor string `json:"next_cursor,omitempty"`
+}
+
+// MessageQuery selects stored messages, oldest first
+type MessageQuery struct {
+	PhoneNumberID string
+	WaID          string
+	// Text selects the messages whose content contains every word, ignoring case
+	Text  string
+	Since time.Time
+	Until time.Time
+	// Cursor is the NextCursor of the previous page, the first page when empty
+	Cursor string
+	Limit  int
+}
+
+// MessagePage is a page of stored messages, oldest first
+type MessagePage struct {
+	Messages []StoredMessage `json:"messages"`
+	// NextCursor fetches the next page, empty on the last one
+	NextCursor string `json:"next_cursor,omitempty"`
+}
+
+// Export formats
+const (
+	// ExportFormatJSONL writes one JSON message per line
+	ExportFormatJSONL = "jsonl"
+	// ExportFormatCSV writes one message per row after a header row
+	ExportFormatCSV = "csv"
+	// ExportFormatTranscript writes the conversations as readable WhatsApp-style chats
+	ExportFormatTranscript = "transcript"
+)
+
+// ExportMaskFields are the fields holding personal data that an export can drop
+var ExportMaskFields = []string{"wa_id", "content", "media"}
+
+// ExportQuery selects the conversations to export and how
+type ExportQuery struct {
+	PhoneNumberID string
+	// Since and Until bound the message timestamps, Until excluded
+	Since  time.Time
+	Until  time.Time
+	Format string
+	// Mask lists the ExportMaskFields to drop, a masked wa_id is replaced by a pseudonym unique in the export
+	Mask []string
+}
+
+// Validate checks the format and the masked fields
+func (q ExportQuery) Validate() error {
+	switch q.Format {
+	case ExportFormatJSONL, ExportFormatCSV, ExportFormatTranscript:
+	default:
+		return fmt.Errorf("export format %q must be jsonl, csv or transcript", q.Format)
+	}
+	for _, field := range q.Mask {
+		if !slices.Contains(ExportMaskFields, field) {
+			return fmt.Errorf("masked field %q must be one of %s", field, strings.Join(ExportMaskFields, ", "))
+		}
+	}
+	return nil
+}
+
+// Masks reports whether the export drops the field
+func (q ExportQuery) Masks(field string) bool {
+	return slices.Contains(q.Mask, field)
+}
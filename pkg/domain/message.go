@@ -0,0 +1,115 @@
+package domain
+
+import (
+	"strings"
+	"time"
+)
+
+const (
+	// MessageDirectionInbound is a message written by a contact
+	MessageDirectionInbound = "inbound"
+	// MessageDirectionOutbound is a message sent to a contact
+	MessageDirectionOutbound = "outbound"
+)
+
+const (
+	// MessageStatusReceived is the status of the inbound messages
+	MessageStatusReceived = "received"
+	// MessageStatusSent means the Graph API accepted the outbound message
+	MessageStatusSent = "sent"
+	// MessageStatusDelivered means the message reached the phone of the contact
+	MessageStatusDelivered = "delivered"
+	// MessageStatusRead means the contact read the message
+	MessageStatusRead = "read"
+	// MessageStatusFailed means WhatsApp could not deliver the message
+	MessageStatusFailed = "failed"
+)
+
+// statusRanks orders the outbound statuses, WhatsApp may notify them out of order
+var statusRanks = map[string]int{
+	MessageStatusSent:      1,
+	MessageStatusDelivered: 2,
+	MessageStatusRead:      3,
+	MessageStatusFailed:    4,
+}
+
+// StoredMessage is a message that went through anyzzapp, received from or sent to a contact
+type StoredMessage struct {
+	ID            string          `json:"id"`
+	PhoneNumberID string          `json:"phone_number_id"`
+	WaID          string          `json:"wa_id"`
+	Direction     string          `json:"direction"`
+	Author        string          `json:"author"` // contact, bot, agent or api
+	Type          string          `json:"type"`
+	Content       string          `json:"content,omitempty"`
+	Media         *MediaReference `json:"media,omitempty"`
+	Status        string          `json:"status"`
+	Error         string          `json:"error,omitempty"`
+	Timestamp     time.Time       `json:"timestamp"`
+	UpdatedAt     time.Time       `json:"updated_at"`
+}
+
+// MediaReference points to a media file kept by WhatsApp, it can be downloaded with its ID
+type MediaReference struct {
+	ID       string `json:"id"`
+	MimeType string `json:"mime_type,omitempty"`
+	Caption  string `json:"caption,omitempty"`
+	Filename string `json:"filename,omitempty"`
+}
+
+// Key returns the conversation the message belongs to
+func (m StoredMessage) Key() ConversationKey {
+	return ConversationKey{PhoneNumberID: m.PhoneNumberID, WaID: m.WaID}
+}
+
+// ApplyStatus moves the message to a later status and reports whether it changed.
+// Statuses older than the current one are ignored.
+func (m *StoredMessage) ApplyStatus(status, errorMessage string, at time.Time) bool {
+	if statusRanks[status] <= statusRanks[m.Status] {
+		return false
+	}
+	m.Status = status
+	m.Error = errorMessage
+	m.UpdatedAt = at
+	return true
+}
+
+// MessageFilter selects stored messages, the zero value selects them all
+type MessageFilter struct {
+	PhoneNumberID string
+	WaID          string
+	// Since and Until bound the message timestamps, Until excluded
+	Since time.Time
+	Until time.Time
+	// AfterID resumes a listing: the messages at Since up to this ID, included, are skipped
+	AfterID string
+	// Text selects the messages whose content contains every word, ignoring case
+	Text string
+	// Limit is the maximum number of messages returned, no limit when 0
+	Limit int
+}
+
+// Matches reports whether the message passes the filter, ignoring the limit
+func (f MessageFilter) Matches(message StoredMessage) bool {
+	switch {
+	case f.PhoneNumberID != "" && message.PhoneNumberID != f.PhoneNumberID:
+		return false
+	case f.WaID != "" && message.WaID != f.WaID:
+		return false
+	case !f.Since.IsZero() && message.Timestamp.Before(f.Since):
+		return false
+	case !f.Until.IsZero() && !message.Timestamp.Before(f.Until):
+		return false
+	case f.AfterID != "" && message.Timestamp.Equal(f.Since) && message.ID <= f.AfterID:
+		return false
+	}
+	if f.Text != "" {
+		content := strings.ToLower(message.Content)
+		for _, word := range strings.Fields(strings.ToLower(f.Text)) {
+			if !strings.Contains(content, word) {
+				return false
+			}
+		}
+	}
+	return true
+}
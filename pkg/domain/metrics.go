@@ -0,0 +1,31 @@
+package domain
+
+import "time"
+
+const (
+	// AutoReplySent is the result recorded when an auto-reply reached the Graph API
+	AutoReplySent = "sent"
+	// AutoReplyFailed is the result recorded when an auto-reply couldn't be sent
+	AutoReplyFailed = "failed"
+)
+
+// Queues whose depth is recorded
+const (
+	// QueueProcessing holds the inbound messages waiting for, or being processed by, the keyed executor
+	QueueProcessing = "processing"
+	// QueueOutbox holds the outbound messages waiting to be sent again after a temporary failure
+	QueueOutbox = "outbox"
+	// QueueDeadLetters holds the inbound messages that failed to be answered
+	QueueDeadLetters = "dead_letters"
+)
+
+// Metrics defines the contract to record operational metrics
+type Metrics interface {
+	IncInboundMessage(messageType string)
+	IncAutoReply(result string)
+	ObserveLLMLatency(backend string, duration time.Duration)
+	ObserveGraphAPILatency(operation string, duration time.Duration)
+	IncGraphAPIError(operation, code string)
+	ObserveHTTPRequest(method, route string, status int, duration time.Duration)
+	SetQueueDepth(queue string, depth int)
+}
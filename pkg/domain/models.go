@@ -1,11 +1,22 @@
 package domain
 
+import (
+	"fmt"
+	"strings"
+	"time"
+)
+
 // Message represents the request to send a message
 type Message struct {
 	PhoneNumberID string `json:"phone_number_id" binding:"required"`
 	To            string `json:"to" binding:"required"`
-	Content       string `json:"content" binding:"required"`
-	MessageType   string `json:"message_type,omitempty"`
+	// Content is the text of text messages and the name of the approved template of template messages
+	Content     string `json:"content" binding:"required"`
+	MessageType string `json:"message_type,omitempty"`
+	// Language is the language code of template messages, such as en_US
+	Language string `json:"language,omitempty"`
+	// Transactional messages, such as receipts, are also sent to the contacts that opted out
+	Transactional bool `json:"transactional,omitempty"`
 }
 
 // SendMessageResponse represents the entity after sending a message
@@ -100,6 +111,14 @@ type WebhookStatus struct {
 	RecipientID  string               `json:"recipient_id"`
 	Conversation *WebhookConversation `json:"conversation,omitempty"`
 	Pricing      *WebhookPricing      `json:"pricing,omitempty"`
+	Errors       []WebhookError       `json:"errors,omitempty"`
+}
+
+// WebhookError represents why a message could not be delivered
+type WebhookError struct {
+	Code    int    `json:"code"`
+	Title   string `json:"title"`
+	Message string `json:"message,omitempty"`
 }
 
 // WebhookConversation represents conversation information in status
@@ -119,3 +138,70 @@ type WebhookPricing struct {
 	PricingModel string `json:"pricing_model"`
 	Category     string `json:"category"`
 }
+
+// ConversationKey identifies a conversation between a business phone number and a contact
+type ConversationKey struct {
+	PhoneNumberID string `json:"phone_number_id"`
+	WaID          string `json:"wa_id"`
+}
+
+// ID returns the identifier used to address the conversation in the API
+func (k ConversationKey) ID() string {
+	return k.PhoneNumberID + "-" + k.WaID
+}
+
+// ParseConversationID builds the conversation key from an identifier returned by ID
+func ParseConversationID(id string) (ConversationKey, error) {
+	phoneNumberID, waID, ok := strings.Cut(id, "-")
+	if !ok || phoneNumberID == "" || waID == "" {
+		return ConversationKey{}, fmt.Errorf("invalid conversation id: %q", id)
+	}
+	return ConversationKey{PhoneNumberID: phoneNumberID, WaID: waID}, nil
+}
+
+// ConversationState represents who is in charge of answering a conversation
+type ConversationState string
+
+const (
+	// ConversationStateBot means the LLM answers the contact
+	ConversationStateBot ConversationState = "bot"
+	// ConversationStateHuman means the contact is waiting for or talking to a human agent
+	ConversationStateHuman ConversationState = "human"
+	// ConversationStateClosed means an agent closed the conversation, a new message reopens it with the bot
+	ConversationStateClosed ConversationState = "closed"
+)
+
+// IsValid reports whether the state is one of the known conversation states
+func (s ConversationState) IsValid() bool {
+	switch s {
+	case ConversationStateBot, ConversationStateHuman, ConversationStateClosed:
+		return true
+	}
+	return false
+}
+
+// Conversation represents the state the bot keeps for a single contact
+type Conversation struct {
+	ID        string                `json:"id"`
+	Key       ConversationKey       `json:"key"`
+	State     ConversationState     `json:"state"`
+	Language  string                `json:"language,omitempty"`
+	Messages  []ConversationMessage `json:"messages,omitempty"`
+	UpdatedAt time.Time             `json:"updated_at"`
+}
+
+// ConversationMessage represents a message in the conversation history
+type ConversationMessage struct {
+	ID        string    `json:"id,omitempty"`
+	Direction string    `json:"direction"` // inbound or outbound
+	Author    string    `json:"author"`    // contact, bot or agent
+	Content   string    `json:"content"`
+	Timestamp time.Time `json:"timestamp"`
+}
+
+// AgentReplyRequest represents the request of an agent answering a conversation
+type AgentReplyRequest struct {
+	Content string `json:"content" binding:"required"`
+	// Transactional replies are also sent to the contacts that opted out
+	Transactional bool `json:"transactional,omitempty"`
+}
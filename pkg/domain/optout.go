@@ -0,0 +1,66 @@
+package domain
+
+import (
+	"strings"
+	"time"
+)
+
+// Suppression records a contact that asked not to get messages from a business phone number
+type Suppression struct {
+	PhoneNumberID string `json:"phone_number_id"`
+	// Contact is the number of the contact as messages are sent to it, see phone.SendAddress
+	Contact string `json:"contact"`
+	// Keyword is the message the contact opted out with
+	Keyword string    `json:"keyword"`
+	At      time.Time `json:"at"`
+}
+
+// OptOutKeywords are the opt-out and opt-in keywords of a language and the replies confirming them
+type OptOutKeywords struct {
+	Language string
+	OptOut   []string
+	OptIn    []string
+	// OptOutReply and OptInReply confirm the change to the contact, nothing is sent when empty
+	OptOutReply string
+	OptInReply  string
+}
+
+// OptOutAction is the change a message asks of the suppression list
+type OptOutAction int
+
+const (
+	// OptOutNone means the message is not a keyword
+	OptOutNone OptOutAction = iota
+	// OptOutStop means the contact doesn't want more messages
+	OptOutStop
+	// OptOutStart means the contact wants messages again
+	OptOutStart
+)
+
+// MatchOptOut returns the change asked by a message that is only a keyword, ignoring case and punctuation
+// around it, and the keywords of its language
+func MatchOptOut(languages []OptOutKeywords, content string) (OptOutAction, OptOutKeywords) {
+	word := strings.Trim(content, " \t\r\n.,;:!¡?¿\"'")
+	if word == "" {
+		return OptOutNone, OptOutKeywords{}
+	}
+	for _, language := range languages {
+		if containsFold(language.OptOut, word) {
+			return OptOutStop, language
+		}
+		if containsFold(language.OptIn, word) {
+			return OptOutStart, language
+		}
+	}
+	return OptOutNone, OptOutKeywords{}
+}
+
+// containsFold reports whether the keywords contain the word, ignoring case
+func containsFold(keywords []string, word string) bool {
+	for _, keyword := range keywords {
+		if strings.EqualFold(keyword, word) {
+			return true
+		}
+	}
+	return false
+}
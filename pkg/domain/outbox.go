@@ -0,0 +1,57 @@
+package domain
+
+import "time"
+
+// OutboxStatus is the delivery state of a message of the outbox
+type OutboxStatus string
+
+const (
+	// OutboxStatusPending messages are waiting to be sent, or to be sent again after a temporary failure
+	OutboxStatusPending OutboxStatus = "pending"
+	// OutboxStatusSent messages were accepted by the WhatsApp API
+	OutboxStatusSent OutboxStatus = "sent"
+	// OutboxStatusFailed messages were rejected or ran out of attempts, they are not sent again on their own
+	OutboxStatusFailed OutboxStatus = "failed"
+)
+
+// Authors of the messages of the outbox
+const (
+	// OutboxAuthorBot is an auto-reply to a message of a contact
+	OutboxAuthorBot = "bot"
+	// OutboxAuthorAPI is a message sent with POST /send
+	OutboxAuthorAPI = "api"
+	// OutboxAuthorAgent is a reply of a human agent
+	OutboxAuthorAgent = "agent"
+)
+
+// Statuses of SendMessageResponse
+const (
+	SendStatusSent = "sent"
+	// SendStatusQueued messages failed to be sent for now, the outbox sends them later
+	SendStatusQueued = "queued"
+)
+
+// OutboxMessage is an outbound message stored before it is sent, so that it is not lost if the process stops
+type OutboxMessage struct {
+	// ID identifies the message in the outbox, a message is only added once with the same ID
+	ID      string  `json:"id"`
+	Message Message `json:"message"`
+	// WaID is the contact of the conversation the message belongs to
+	WaID string `json:"wa_id"`
+	// Author is OutboxAuthorBot, OutboxAuthorAPI or OutboxAuthorAgent
+	Author   string       `json:"author"`
+	Status   OutboxStatus `json:"status"`
+	Attempts int          `json:"attempts"`
+	// Error is the error of the last failed attempt
+	Error         string    `json:"error,omitempty"`
+	CreatedAt     time.Time `json:"created_at"`
+	NextAttemptAt time.Time `json:"next_attempt_at"`
+	// MessageID is the ID returned by the WhatsApp API once sent
+	MessageID string    `json:"message_id,omitempty"`
+	SentAt    time.Time `json:"sent_at,omitempty"`
+}
+
+// Due reports whether the message is waiting to be sent at now
+func (m *OutboxMessage) Due(now time.Time) bool {
+	return m.Status == OutboxStatusPending && !now.Before(m.NextAttemptAt)
+}
@@ -0,0 +1,81 @@
+package phone
+
+import (
+	"errors"
+	"fmt"
+	"strings"
+)
+
+const (
+	// minDigits is the shortest number accepted, country code included
+	minDigits = 8
+	// maxDigits is the longest number allowed by E.164, country code included
+	maxDigits = 15
+)
+
+// ErrInvalidNumber is returned when a phone number is not a valid E.164 number
+var ErrInvalidNumber = errors.New("invalid phone number")
+
+// Number represents a phone number in E.164 format
+type Number struct {
+	// Digits is the full number without the leading +, e.g. 541112345678
+	Digits string
+	// Country is the ISO 3166-1 alpha-2 code of the country, empty if it has no rule in the Rules table
+	Country string
+	// CallingCode is the country calling code, empty if the country has no rule in the Rules table
+	CallingCode string
+}
+
+// String returns the number as WhatsApp expects it: digits only, without +
+func (n Number) String() string {
+	return n.Digits
+}
+
+// E164 returns the number with the leading +
+func (n Number) E164() string {
+	return "+" + n.Digits
+}
+
+// National returns the number without the calling code, or every digit if the country is unknown
+func (n Number) National() string {
+	return strings.TrimPrefix(n.Digits, n.CallingCode)
+}
+
+// Parse parses and validates an E.164 number, with or without the leading +
+func Parse(value string) (Number, error) {
+	digits := strings.TrimPrefix(strings.TrimSpace(value), "+")
+	if digits == "" {
+		return Number{}, fmt.Errorf("%w: number is empty", ErrInvalidNumber)
+	}
+	for _, r := range digits {
+		if r < '0' || r > '9' {
+			return Number{}, fmt.Errorf("%w: %q must contain only digits in E.164 format, without spaces or hyphens", ErrInvalidNumber, value)
+		}
+	}
+	if digits[0] == '0' {
+		return Number{}, fmt.Errorf("%w: %q must start with the country code, not 0", ErrInvalidNumber, value)
+	}
+	if len(digits) < minDigits || len(digits) > maxDigits {
+		return Number{}, fmt.Errorf("%w: %q must have between %d and %d digits", ErrInvalidNumber, value, minDigits, maxDigits)
+	}
+
+	number := Number{Digits: digits}
+	if rule, ok := ruleFor(digits); ok {
+		number.Country = rule.Country
+		number.CallingCode = rule.CallingCode
+	}
+	return number, nil
+}
+
+// SendAddress turns the wa_id of an incoming message into the number replies must be sent to
+func SendAddress(waID string) (string, error) {
+	number, err := Parse(waID)
+	if err != nil {
+		return "", err
+	}
+	rule, ok := ruleFor(number.Digits)
+	if !ok || rule.SendAddress == nil {
+		return number.Digits, nil
+	}
+	return rule.CallingCode + rule.SendAddress(number.National()), nil
+}
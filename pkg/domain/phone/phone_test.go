@@ -0,0 +1,147 @@
+package phone
+
+import (
+	"testing"
+
+	"github.com/stretchr/testify/assert"
+)
+
+func TestParse(t *testing.T) {
+	tests := []struct {
+		name        string
+		value       string
+		expected    Number
+		expectedErr string
+	}{
+		{
+			name:     "Digits only",
+			value:    "541112345678",
+			expected: Number{Digits: "541112345678", Country: "AR", CallingCode: "54"},
+		},
+		{
+			name:     "Leading plus",
+			value:    "+14155550123",
+			expected: Number{Digits: "14155550123"},
+		},
+		{
+			name:        "Empty",
+			value:       "",
+			expectedErr: "number is empty",
+		},
+		{
+			name:        "Spaces and hyphens",
+			value:       "+54 11 1234-5678",
+			expectedErr: "must contain only digits",
+		},
+		{
+			name:        "Letters",
+			value:       "54abc",
+			expectedErr: "must contain only digits",
+		},
+		{
+			name:        "Trunk prefix instead of country code",
+			value:       "01112345678",
+			expectedErr: "must start with the country code",
+		},
+		{
+			name:        "Too short",
+			value:       "1234567",
+			expectedErr: "between 8 and 15 digits",
+		},
+		{
+			name:        "Too long",
+			value:       "1234567890123456",
+			expectedErr: "between 8 and 15 digits",
+		},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			number, err := Parse(tt.value)
+			if tt.expectedErr != "" {
+				assert.ErrorIs(t, err, ErrInvalidNumber)
+				assert.Contains(t, err.Error(), tt.expectedErr)
+				return
+			}
+			assert.NoError(t, err)
+			assert.Equal(t, tt.expected, number)
+		})
+	}
+}
+
+func TestNumber_Formats(t *testing.T) {
+	number, err := Parse("+5491112345678")
+
+	assert.NoError(t, err)
+	assert.Equal(t, "5491112345678", number.String())
+	assert.Equal(t, "+5491112345678", number.E164())
+	assert.Equal(t, "91112345678", number.National())
+}
+
+func TestSendAddress(t *testing.T) {
+	tests := []struct {
+		name     string
+		waID     string
+		expected string
+	}{
+		{
+			name:     "Argentine number with 9",
+			waID:     "5491112345678",
+			expected: "541112345678",
+		},
+		{
+			name:     "Argentine number without 9",
+			waID:     "541112345678",
+			expected: "541112345678",
+		},
+		{
+			name:     "Mexican number with 1",
+			waID:     "5215512345678",
+			expected: "525512345678",
+		},
+		{
+			name:     "Mexican number without 1",
+			waID:     "525512345678",
+			expected: "525512345678",
+		},
+		{
+			name:     "Brazilian mobile without ninth digit",
+			waID:     "551187654321",
+			expected: "5511987654321",
+		},
+		{
+			name:     "Brazilian mobile with ninth digit",
+			waID:     "5511987654321",
+			expected: "5511987654321",
+		},
+		{
+			name:     "Brazilian landline",
+			waID:     "551132654321",
+			expected: "551132654321",
+		},
+		{
+			name:     "Non-Argentine number",
+			waID:     "1234567890123",
+			expected: "1234567890123",
+		},
+		{
+			name:     "Short number",
+			waID:     "123456789",
+			expected: "123456789",
+		},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			address, err := SendAddress(tt.waID)
+			assert.NoError(t, err)
+			assert.Equal(t, tt.expected, address)
+		})
+	}
+}
+
+func TestSendAddress_InvalidNumber(t *testing.T) {
+	_, err := SendAddress("54-11")
+
+	assert.ErrorIs(t, err, ErrInvalidNumber)
+}
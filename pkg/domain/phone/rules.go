@@ -0,0 +1,63 @@
+package phone
+
+import "strings"
+
+// CountryRule describes how the wa_id of a country differs from the number WhatsApp accepts as recipient
+type CountryRule struct {
+	// Country is the ISO 3166-1 alpha-2 code
+	Country string
+	// CallingCode is the country calling code without +
+	CallingCode string
+	// SendAddress receives the national part of the wa_id and returns the national part to send to
+	SendAddress func(national string) string
+}
+
+// Rules is the table of per-country rules applied by SendAddress
+var Rules = []CountryRule{
+	{
+		// Mobile wa_ids come as 54 9 + area code + number, the 9 must be removed to send messages.
+		// example: 5491112345678 -> 541112345678
+		Country:     "AR",
+		CallingCode: "54",
+		SendAddress: func(national string) string {
+			if len(national) == 11 && national[0] == '9' {
+				return national[1:]
+			}
+			return national
+		},
+	},
+	{
+		// Mobile wa_ids may still come with the 1 that Mexico dropped in 2019.
+		// example: 5215512345678 -> 525512345678
+		Country:     "MX",
+		CallingCode: "52",
+		SendAddress: func(national string) string {
+			if len(national) == 11 && national[0] == '1' {
+				return national[1:]
+			}
+			return national
+		},
+	},
+	{
+		// Mobile wa_ids registered before the ninth digit was introduced come without it.
+		// example: 551187654321 -> 5511987654321
+		Country:     "BR",
+		CallingCode: "55",
+		SendAddress: func(national string) string {
+			if len(national) == 10 && strings.ContainsRune("6789", rune(national[2])) {
+				return national[:2] + "9" + national[2:]
+			}
+			return national
+		},
+	},
+}
+
+// ruleFor returns the rule of the country the number belongs to
+func ruleFor(digits string) (CountryRule, bool) {
+	for _, rule := range Rules {
+		if strings.HasPrefix(digits, rule.CallingCode) {
+			return rule, true
+		}
+	}
+	return CountryRule{}, false
+}
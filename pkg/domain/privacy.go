@@ -0,0 +1,66 @@
+package domain
+
+import (
+	"crypto/sha256"
+	"encoding/hex"
+	"time"
+)
+
+// Audit actions
+const (
+	// AuditActionContactErased records the erasure of the data of a contact
+	AuditActionContactErased = "contact_erased"
+	// AuditActionRetentionPurge records the deletion of the data past the retention of the tenants
+	AuditActionRetentionPurge = "retention_purge"
+)
+
+// AuditRecord is an operation on personal data, it must not hold personal data itself
+type AuditRecord struct {
+	ID     string `json:"id"`
+	Action string `json:"action"`
+	// Actor is who ran the operation: admin or retention
+	Actor string `json:"actor"`
+	// Subject identifies the contact of an erasure, see SubjectHash
+	Subject   string `json:"subject,omitempty"`
+	RequestID string `json:"request_id,omitempty"`
+	// Messages and Conversations are the number of items deleted
+	Messages      int       `json:"messages"`
+	Conversations int       `json:"conversations"`
+	At            time.Time `json:"at"`
+}
+
+// SubjectHash identifies a wa_id in the audit records without keeping it: the SHA-256 of the wa_id in hex.
+// Whether a number was erased can be checked by hashing it.
+func SubjectHash(waID string) string {
+	sum := sha256.Sum256([]byte(waID))
+	return hex.EncodeToString(sum[:])
+}
+
+// ErasureReport describes the data of a contact deleted, or that would be deleted by a dry run
+type ErasureReport struct {
+	DryRun        bool   `json:"dry_run"`
+	Subject       string `json:"subject"`
+	Messages      int    `json:"messages"`
+	MediaFiles    int    `json:"media_files"`
+	Conversations int    `json:"conversations"`
+	DeadLetters   int    `json:"dead_letters"`
+	// OutboxMessages counts the outbound messages, the ones waiting to be sent included
+	OutboxMessages int `json:"outbox_messages"`
+	// HeldMessages counts the messages of the contact waiting to be answered together, which are discarded
+	HeldMessages int `json:"held_messages"`
+	// AuditID is the audit record of the erasure, none for a dry run
+	AuditID string `json:"audit_id,omitempty"`
+}
+
+// RetentionReport describes the data past the retention of the tenants deleted, or that would be deleted by a dry run
+type RetentionReport struct {
+	DryRun        bool `json:"dry_run"`
+	Messages      int  `json:"messages"`
+	Conversations int  `json:"conversations"`
+	DeadLetters   int  `json:"dead_letters"`
+	// OutboxMessages counts the outbound messages already sent or given up on
+	OutboxMessages int `json:"outbox_messages"`
+	// MessagesByTenant counts the messages per phone number ID
+	MessagesByTenant map[string]int `json:"messages_by_tenant"`
+	AuditID          string         `json:"audit_id,omitempty"`
+}
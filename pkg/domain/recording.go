@@ -0,0 +1,22 @@
+package domain
+
+import (
+	"context"
+	"net/http"
+	"time"
+)
+
+// WebhookRecording is a webhook request as it was received, kept to replay it later
+type WebhookRecording struct {
+	At     time.Time   `json:"at"`
+	Method string      `json:"method"`
+	Path   string      `json:"path"`
+	Header http.Header `json:"header"`
+	// Body is the raw body, kept as a string so it is replayed byte for byte
+	Body string `json:"body"`
+}
+
+// WebhookRecorder saves the webhooks received
+type WebhookRecorder interface {
+	Record(ctx context.Context, recording WebhookRecording) error
+}
@@ -1,12 +1,91 @@
 package domain
 
+import (
+	"context"
+	"time"
+)
+
 // WhatsAppRepository interface defines the contract for WhatsApp operations
 type WhatsAppRepository interface {
-	SendMessage(message Message) (*SendMessageResponse, error)
-	MarkAsRead(phoneNumberID, messageID string) error
+	SendMessage(ctx context.Context, message Message) (*SendMessageResponse, error)
+	MarkAsRead(ctx context.Context, phoneNumberID, messageID string) error
 }
 
 // LLMRepository interface defines the contract for the LLM repository responses
 type LLMRepository interface {
-	SendMessage(prompt string) (string, error)
+	SendMessage(ctx context.Context, prompt string) (string, error)
+}
+
+// ConversationRepository interface defines the contract for the conversation memory
+type ConversationRepository interface {
+	// Get returns the conversation for the key, or nil if the bot has no memory of it
+	Get(key ConversationKey) (*Conversation, error)
+	Save(conversation *Conversation) error
+	// Update runs fn on the conversation of the key, a new one with the bot when there is none, and saves it.
+	// No other change of the conversation happens in between. Nothing is saved when fn returns an error, which is returned.
+	Update(key ConversationKey, fn func(conversation *Conversation) error) error
+	Delete(key ConversationKey) error
+	// List returns the conversations in the given state, or every conversation if the state is empty
+	List(state ConversationState) ([]Conversation, error)
+}
+
+// MessageRepository interface defines the contract for the record of every message received and sent
+type MessageRepository interface {
+	// Save stores the message, replacing any previous one with the same ID
+	Save(ctx context.Context, message *StoredMessage) error
+	// Get returns the message with the ID, or nil if there is none
+	Get(ctx context.Context, id string) (*StoredMessage, error)
+	// UpdateStatus applies a status notified by WhatsApp, it returns ErrMessageNotFound for unknown IDs
+	UpdateStatus(ctx context.Context, id, status, errorMessage string, at time.Time) error
+	// List returns the messages matching the filter, oldest first
+	List(ctx context.Context, filter MessageFilter) ([]StoredMessage, error)
+	// ListConversations returns the summaries of the conversations with messages matching the filter, the most
+	// recently active first. The states are left empty.
+	ListConversations(ctx context.Context, filter ConversationFilter) ([]ConversationSummary, error)
+	// Delete removes the messages with the IDs and returns how many existed
+	Delete(ctx context.Context, ids ...string) (int, error)
+}
+
+// AuditRepository interface defines the contract for the record of the operations on personal data
+type AuditRepository interface {
+	// Append stores the record, the records are never changed
+	Append(ctx context.Context, record *AuditRecord) error
+	// List returns the most recent records first, at most limit of them
+	List(ctx context.Context, limit int) ([]AuditRecord, error)
+}
+
+// SuppressionRepository interface defines the contract for the list of contacts that opted out
+type SuppressionRepository interface {
+	// Get returns the suppression of the contact, or nil if the contact didn't opt out
+	Get(ctx context.Context, phoneNumberID, contact string) (*Suppression, error)
+	// Add stores the suppression, replacing any previous one of the contact
+	Add(ctx context.Context, suppression *Suppression) error
+	// Remove deletes the suppression of the contact, if any
+	Remove(ctx context.Context, phoneNumberID, contact string) error
+}
+
+// DeadLetterRepository interface defines the contract for the inbound messages that failed to be answered
+type DeadLetterRepository interface {
+	// Save stores the dead letter, replacing any previous one with the same ID
+	Save(ctx context.Context, letter *DeadLetter) error
+	// Get returns the dead letter with the ID, or nil if there is none
+	Get(ctx context.Context, id string) (*DeadLetter, error)
+	// List returns every dead letter, the oldest failure first
+	List(ctx context.Context) ([]DeadLetter, error)
+	// Delete removes the dead letter with the ID, if any
+	Delete(ctx context.Context, id string) error
+}
+
+// OutboxRepository interface defines the contract for the outbound messages waiting to be sent
+type OutboxRepository interface {
+	// Add stores a new message, it returns false and changes nothing when there is one with the same ID
+	Add(ctx context.Context, message *OutboxMessage) (bool, error)
+	// Get returns the message with the ID, or nil if there is none
+	Get(ctx context.Context, id string) (*OutboxMessage, error)
+	// Save replaces the message with the same ID
+	Save(ctx context.Context, message *OutboxMessage) error
+	// List returns the messages in the status, the oldest first
+	List(ctx context.Context, status OutboxStatus) ([]OutboxMessage, error)
+	// Delete removes the messages with the IDs
+	Delete(ctx context.Context, ids ...string) error
 }
@@ -0,0 +1,38 @@
+package domain
+
+import "time"
+
+// TenantSettings are the settings of a WhatsApp business phone number applied to its auto-replies
+type TenantSettings struct {
+	// Persona is the system prompt given to the LLM before the message, none when empty
+	Persona string
+	// LLMBackend names the LLM answering for the number, the default one when empty
+	LLMBackend string
+	// Allowlist restricts the auto-replies to these wa_ids, everybody is answered when empty
+	Allowlist []string
+	// RateLimitPerMinute is the number of auto-replies per contact and minute, unlimited when 0
+	RateLimitPerMinute int
+	// MaxHistory is the number of messages kept per conversation, the default when 0
+	MaxHistory int
+	// Retention is how long the messages and conversations are kept, forever when 0
+	Retention time.Duration
+}
+
+// Allows reports whether the contact may get auto-replies
+func (s TenantSettings) Allows(waID string) bool {
+	if len(s.Allowlist) == 0 {
+		return true
+	}
+	for _, allowed := range s.Allowlist {
+		if allowed == waID {
+			return true
+		}
+	}
+	return false
+}
+
+// SettingsProvider returns the current settings of a tenant.
+// They are read for every message so a configuration reload applies to the next one.
+type SettingsProvider interface {
+	TenantSettings(phoneNumberID string) TenantSettings
+}
@@ -1,7 +1,47 @@
 package domain
 
+import (
+	"context"
+	"io"
+)
+
 // WhatsAppUseCaseInterface defines the business logic operations
 type WhatsAppUseCaseInterface interface {
-	SendMessage(message Message) (*SendMessageResponse, error)
-	ProcessIncomingWebhook(webhook *WebhookRequest) error
+	SendMessage(ctx context.Context, message Message) (*SendMessageResponse, error)
+	ProcessIncomingWebhook(ctx context.Context, webhook *WebhookRequest) error
+	// ReprocessMessage answers again a received message that failed to be answered
+	ReprocessMessage(ctx context.Context, phoneNumberID string, msg WebhookMessage) error
+}
+
+// AgentUseCaseInterface defines the operations available to human agents
+type AgentUseCaseInterface interface {
+	ListConversations(state ConversationState) ([]Conversation, error)
+	GetConversation(id string) (*Conversation, error)
+	Reply(ctx context.Context, id string, reply AgentReplyRequest) (*SendMessageResponse, error)
+	HandBack(id string) error
+	Close(id string) error
+}
+
+// HistoryUseCaseInterface defines the operations support staff use to read the stored messages
+type HistoryUseCaseInterface interface {
+	ListConversations(ctx context.Context, query ConversationQuery) (*ConversationPage, error)
+	Transcript(ctx context.Context, id string, query MessageQuery) (*MessagePage, error)
+	SearchMessages(ctx context.Context, query MessageQuery) (*MessagePage, error)
+	Export(ctx context.Context, query ExportQuery, w io.Writer) error
+}
+
+// PrivacyUseCaseInterface defines the operations deleting personal data
+type PrivacyUseCaseInterface interface {
+	EraseContact(ctx context.Context, waID, phoneNumberID string, dryRun bool) (*ErasureReport, error)
+	ApplyRetention(ctx context.Context, dryRun bool) (*RetentionReport, error)
+	AuditLog(ctx context.Context, limit int) ([]AuditRecord, error)
+}
+
+// DeadLetterUseCaseInterface defines the operations on the inbound messages that failed to be answered
+type DeadLetterUseCaseInterface interface {
+	ListDeadLetters(ctx context.Context) ([]DeadLetter, error)
+	GetDeadLetter(ctx context.Context, id string) (*DeadLetter, error)
+	// RetryDeadLetter answers the message again, the dead letter is removed when it succeeds
+	RetryDeadLetter(ctx context.Context, id string) error
+	DiscardDeadLetter(ctx context.Context, id string) error
 }
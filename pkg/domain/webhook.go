@@ -0,0 +1,33 @@
+package domain
+
+import (
+	"strconv"
+	"time"
+)
+
+// NewWebhook wraps a value in the envelope of the webhooks Meta sends for a business phone number
+func NewWebhook(businessAccountID, phoneNumberID string, value WebhookValue) WebhookRequest {
+	value.MessagingProduct = "whatsapp"
+	value.Metadata = WebhookMetadata{DisplayPhoneNumber: phoneNumberID, PhoneNumberID: phoneNumberID}
+	return WebhookRequest{
+		Object: "whatsapp_business_account",
+		Entry: []WebhookEntry{{
+			ID:      businessAccountID,
+			Changes: []WebhookChange{{Field: "messages", Value: value}},
+		}},
+	}
+}
+
+// NewTextWebhook builds the webhook of a text message sent at the time by a contact to a business phone number
+func NewTextWebhook(businessAccountID, phoneNumberID string, contact WebhookContact, messageID, body string, at time.Time) WebhookRequest {
+	return NewWebhook(businessAccountID, phoneNumberID, WebhookValue{
+		Contacts: []WebhookContact{contact},
+		Messages: []WebhookMessage{{
+			From:      contact.WaID,
+			ID:        messageID,
+			Timestamp: strconv.FormatInt(at.Unix(), 10),
+			Type:      "text",
+			Text:      &WebhookText{Body: body},
+		}},
+	})
+}
@@ -0,0 +1,59 @@
+// Package logging carries the request-scoped logger and masks personal data before it is logged
+package logging
+
+import (
+	"context"
+	"crypto/rand"
+	"encoding/hex"
+
+	"github.com/rs/zerolog"
+	"github.com/rs/zerolog/log"
+)
+
+// RequestIDHeader is the header carrying the request ID, both on incoming requests and on outbound calls
+const RequestIDHeader = "X-Request-ID"
+
+// maxRequestIDLength bounds the request IDs accepted from callers
+const maxRequestIDLength = 128
+
+type requestIDKey struct{}
+
+// Setup makes log.Ctx fall back to the global logger and sets whether personal data is logged in clear
+func Setup(debugPII bool) {
+	zerolog.DefaultContextLogger = &log.Logger
+	SetDebugPII(debugPII)
+}
+
+// NewRequestID returns a random request ID
+func NewRequestID() string {
+	b := make([]byte, 16)
+	if _, err := rand.Read(b); err != nil {
+		return "unknown"
+	}
+	return hex.EncodeToString(b)
+}
+
+// ValidRequestID reports whether a request ID received from a caller can be reused
+func ValidRequestID(id string) bool {
+	if id == "" || len(id) > maxRequestIDLength {
+		return false
+	}
+	for _, r := range id {
+		if r < '!' || r > '~' {
+			return false
+		}
+	}
+	return true
+}
+
+// WithRequestID returns a context carrying the request ID and a logger that adds it to every entry
+func WithRequestID(ctx context.Context, id string) context.Context {
+	logger := log.Ctx(ctx).With().Str("request_id", id).Logger()
+	return logger.WithContext(context.WithValue(ctx, requestIDKey{}, id))
+}
+
+// RequestID returns the request ID carried by the context, or an empty string if there is none
+func RequestID(ctx context.Context) string {
+	id, _ := ctx.Value(requestIDKey{}).(string)
+	return id
+}
@@ -0,0 +1,59 @@
+package logging
+
+import (
+	"bytes"
+	"context"
+	"encoding/json"
+	"strings"
+	"testing"
+
+	"github.com/rs/zerolog"
+	"github.com/rs/zerolog/log"
+	"github.com/stretchr/testify/assert"
+)
+
+func TestNewRequestID(t *testing.T) {
+	first := NewRequestID()
+	second := NewRequestID()
+
+	assert.Len(t, first, 32)
+	assert.NotEqual(t, first, second)
+	assert.True(t, ValidRequestID(first))
+}
+
+func TestValidRequestID(t *testing.T) {
+	tests := []struct {
+		name     string
+		id       string
+		expected bool
+	}{
+		{name: "UUID", id: "3f2b8c1e-7a4d-4e1b-9c2f-0d6e5a4b3c21", expected: true},
+		{name: "Empty", id: "", expected: false},
+		{name: "Too long", id: strings.Repeat("a", 129), expected: false},
+		{name: "Spaces", id: "abc def", expected: false},
+		{name: "Line break", id: "abc\ndef", expected: false},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			assert.Equal(t, tt.expected, ValidRequestID(tt.id))
+		})
+	}
+}
+
+func TestWithRequestID(t *testing.T) {
+	var buf bytes.Buffer
+	base := zerolog.New(&buf)
+	ctx := WithRequestID(base.WithContext(context.Background()), "req-123")
+
+	log.Ctx(ctx).Info().Msg("hello")
+
+	var entry map[string]interface{}
+	assert.NoError(t, json.Unmarshal(buf.Bytes(), &entry))
+	assert.Equal(t, "req-123", entry["request_id"])
+	assert.Equal(t, "req-123", RequestID(ctx))
+}
+
+func TestRequestID_Missing(t *testing.T) {
+	assert.Equal(t, "", RequestID(context.Background()))
+}
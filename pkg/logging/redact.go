@@ -0,0 +1,156 @@
+package logging
+
+import (
+	"encoding/json"
+	"fmt"
+	"strings"
+	"sync/atomic"
+)
+
+// debugPII disables the redaction when set, to troubleshoot locally
+var debugPII atomic.Bool
+
+// fieldKind tells how the value of a JSON field is masked
+type fieldKind int
+
+const (
+	phoneField fieldKind = iota + 1
+	contentField
+	tokenField
+)
+
+// sensitiveFields maps the JSON fields exchanged with WhatsApp and the LLM to the way they are masked
+var sensitiveFields = map[string]fieldKind{
+	"to":                   phoneField,
+	"from":                 phoneField,
+	"wa_id":                phoneField,
+	"recipient_id":         phoneField,
+	"display_phone_number": phoneField,
+	"phone_number":         phoneField,
+	"body":                 contentField,
+	"prompt":               contentField,
+	"response":             contentField,
+	"content":              contentField,
+	"caption":              contentField,
+	"text":                 contentField,
+	"authorization":        tokenField,
+	"token":                tokenField,
+	"access_token":         tokenField,
+	"api_key":              tokenField,
+	"bearer_token":         tokenField,
+	"verify_token":         tokenField,
+	"hub.verify_token":     tokenField,
+}
+
+// SetDebugPII sets whether phone numbers, message content and tokens are logged in clear
+func SetDebugPII(enabled bool) {
+	debugPII.Store(enabled)
+}
+
+// DebugPII reports whether personal data is logged in clear
+func DebugPII() bool {
+	return debugPII.Load()
+}
+
+// Phone masks a phone number, keeping its last 4 digits
+func Phone(number string) string {
+	if debugPII.Load() {
+		return number
+	}
+	return maskPhone(number)
+}
+
+// maskPhone keeps the last 4 digits of a phone number
+func maskPhone(number string) string {
+	if number == "" {
+		return number
+	}
+	if len(number) <= 4 {
+		return strings.Repeat("*", len(number))
+	}
+	return strings.Repeat("*", len(number)-4) + number[len(number)-4:]
+}
+
+// Content masks a message text, keeping only its length
+func Content(text string) string {
+	if debugPII.Load() {
+		return text
+	}
+	return maskContent(text)
+}
+
+// maskContent keeps the length of a message text
+func maskContent(text string) string {
+	if text == "" {
+		return text
+	}
+	return fmt.Sprintf("[redacted %d chars]", len([]rune(text)))
+}
+
+// Token masks a secret entirely
+func Token(token string) string {
+	if debugPII.Load() {
+		return token
+	}
+	return maskToken(token)
+}
+
+// maskToken hides a secret entirely
+func maskToken(token string) string {
+	if token == "" {
+		return token
+	}
+	return "[redacted]"
+}
+
+// JSON masks the phone numbers, message content and tokens of a JSON payload.
+// A payload that is not valid JSON is masked as a whole.
+func JSON(payload []byte) string {
+	if debugPII.Load() {
+		return string(payload)
+	}
+	return MaskJSON(payload)
+}
+
+// MaskJSON is like JSON but masks the payload even when personal data is logged in clear,
+// for the payloads written somewhere else than the logs
+func MaskJSON(payload []byte) string {
+	var value interface{}
+	if err := json.Unmarshal(payload, &value); err != nil {
+		return maskContent(string(payload))
+	}
+	redacted, err := json.Marshal(redactValue(value))
+	if err != nil {
+		return maskContent(string(payload))
+	}
+	return string(redacted)
+}
+
+// redactValue walks a decoded JSON value masking the sensitive fields
+func redactValue(value interface{}) interface{} {
+	switch v := value.(type) {
+	case map[string]interface{}:
+		for key, field := range v {
+			text, isString := field.(string)
+			kind := sensitiveFields[strings.ToLower(key)]
+			switch {
+			case kind == 0 || !isString:
+				v[key] = redactValue(field)
+			case kind == phoneField:
+				v[key] = maskPhone(text)
+			case kind == contentField:
+				v[key] = maskContent(text)
+			case kind == tokenField:
+				v[key] = maskToken(text)
+			}
+		}
+		return v
+	case []interface{}:
+		for i, item := range v {
+			v[i] = redactValue(item)
+		}
+		return v
+	default:
+		return v
+	}
+}
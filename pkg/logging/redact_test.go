@@ -0,0 +1,67 @@
+package logging
+
+import (
+	"encoding/json"
+	"testing"
+
+	"github.com/stretchr/testify/assert"
+)
+
+func TestPhone(t *testing.T) {
+	assert.Equal(t, "*********5678", Phone("5491112345678"))
+	assert.Equal(t, "***", Phone("123"))
+	assert.Equal(t, "", Phone(""))
+}
+
+func TestContent(t *testing.T) {
+	assert.Equal(t, "[redacted 5 chars]", Content("hólá!"))
+	assert.Equal(t, "", Content(""))
+}
+
+func TestToken(t *testing.T) {
+	assert.Equal(t, "[redacted]", Token("secret"))
+}
+
+func TestJSON(t *testing.T) {
+	payload := []byte(`{
+		"messaging_product": "whatsapp",
+		"to": "5491112345678",
+		"type": "text",
+		"text": {"body": "my address is 742 Evergreen Terrace"},
+		"contacts": [{"wa_id": "5491112345678"}],
+		"access_token": "EAAB"
+	}`)
+
+	var redacted map[string]interface{}
+	assert.NoError(t, json.Unmarshal([]byte(JSON(payload)), &redacted))
+
+	assert.Equal(t, "whatsapp", redacted["messaging_product"])
+	assert.Equal(t, "text", redacted["type"])
+	assert.Equal(t, "*********5678", redacted["to"])
+	assert.Equal(t, "[redacted 35 chars]", redacted["text"].(map[string]interface{})["body"])
+	assert.Equal(t, "*********5678", redacted["contacts"].([]interface{})[0].(map[string]interface{})["wa_id"])
+	assert.Equal(t, "[redacted]", redacted["access_token"])
+}
+
+func TestJSON_Invalid(t *testing.T) {
+	assert.Equal(t, "[redacted 8 chars]", JSON([]byte("not json")))
+}
+
+func TestDebugPII(t *testing.T) {
+	SetDebugPII(true)
+	defer SetDebugPII(false)
+
+	assert.True(t, DebugPII())
+	assert.Equal(t, "5491112345678", Phone("5491112345678"))
+	assert.Equal(t, "hello", Content("hello"))
+	assert.Equal(t, "secret", Token("secret"))
+	assert.Equal(t, `{"to":"5491112345678"}`, JSON([]byte(`{"to":"5491112345678"}`)))
+}
+
+func TestMaskJSON_IgnoresDebugPII(t *testing.T) {
+	SetDebugPII(true)
+	defer SetDebugPII(false)
+
+	assert.Equal(t, `{"to":"*********5678"}`, MaskJSON([]byte(`{"to":"5491112345678"}`)))
+	assert.Equal(t, "[redacted 8 chars]", MaskJSON([]byte("not json")))
+}